@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"github.com/atlant1da-404/droplet/config"
 	controller "github.com/atlant1da-404/droplet/internal/controller/http"
 	"github.com/atlant1da-404/droplet/internal/entity"
@@ -11,6 +12,9 @@ import (
 	"github.com/atlant1da-404/droplet/pkg/hash"
 	"github.com/atlant1da-404/droplet/pkg/httpserver"
 	"github.com/atlant1da-404/droplet/pkg/logger"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"github.com/atlant1da-404/droplet/pkg/oidc"
+	"github.com/atlant1da-404/droplet/pkg/password"
 	"github.com/gin-gonic/gin"
 	"os"
 	"os/signal"
@@ -36,36 +40,177 @@ func Run(cfg *config.Config) {
 		&entity.Account{},
 		&entity.AccountDevices{},
 		&entity.AccountSettings{},
+		&entity.Node{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.UserToken{},
+		&entity.RecoveryCode{},
+		&entity.LoginThrottle{},
+		&entity.UserIdentity{},
+		&entity.OIDCLoginState{},
+		&entity.PersonalAccessToken{},
+		&entity.AuditEvent{},
+		&entity.DataExport{},
+		&entity.DeviceKey{},
+		&entity.Contact{},
+		&entity.Block{},
+		&entity.Group{},
+		&entity.GroupMember{},
+		&entity.RateLimit{},
 	)
 	if err != nil {
 		log.Fatal("automigration failed", "err", err)
 	}
 
+	err = storage.MigrateAccountSettings(sql)
+	if err != nil {
+		log.Fatal("account settings migration failed", "err", err)
+	}
+
 	storages := service.Storages{
-		UserStorage:    storage.NewUserStorage(sql),
-		AccountStorage: storage.NewAccountStorage(sql),
-		NodeStorage:    storage.NewNodeStorage(sql),
+		UserStorage:           storage.NewUserStorage(sql),
+		AccountStorage:        storage.NewAccountStorage(sql),
+		NodeStorage:           storage.NewNodeStorage(sql),
+		RefreshTokenStorage:   storage.NewRefreshTokenStorage(sql),
+		RevokedTokenStorage:   storage.NewRevokedTokenStorage(sql),
+		UserTokenStorage:      storage.NewUserTokenStorage(sql),
+		RecoveryCodeStorage:   storage.NewRecoveryCodeStorage(sql),
+		LoginThrottleStorage:  storage.NewLoginThrottleStorage(sql),
+		UserIdentityStorage:   storage.NewUserIdentityStorage(sql),
+		OIDCLoginStateStorage: storage.NewOIDCLoginStateStorage(sql),
+
+		PersonalAccessTokenStorage: storage.NewPersonalAccessTokenStorage(sql),
+		AuditEventStorage:          storage.NewAuditEventStorage(sql),
+		DataExportStorage:          storage.NewDataExportStorage(sql),
+		DeviceStorage:              storage.NewDeviceStorage(sql),
+		DeviceKeyStorage:           storage.NewDeviceKeyStorage(sql),
+		ContactStorage:             storage.NewContactStorage(sql),
+		BlockStorage:               storage.NewBlockStorage(sql),
+		GroupStorage:               storage.NewGroupStorage(sql),
+		RateLimitStorage:           storage.NewRateLimitStorage(sql),
 	}
 
 	databases := map[string]database.Database{
 		"postgreSQL": sql,
 	}
 
+	authOptions := []auth.Option{
+		auth.AccessTokenTTL(cfg.JWT.AccessTokenTTL),
+		auth.SignKey(cfg.JWT.SignKey),
+	}
+	if len(cfg.JWT.PrivateKeys) > 0 {
+		signingKeys := make([]*auth.SigningKey, 0, len(cfg.JWT.PrivateKeys))
+		for keyId, privateKey := range cfg.JWT.PrivateKeys {
+			signingKey, err := auth.LoadSigningKey(keyId, privateKey)
+			if err != nil {
+				log.Fatal("failed to load jwt signing key", "keyId", keyId, "err", err)
+			}
+			signingKeys = append(signingKeys, signingKey)
+		}
+		authOptions = append(authOptions, auth.SigningKeys(cfg.JWT.SigningKeyId, signingKeys...))
+	}
+
+	authenticator, err := auth.NewAuth(authOptions...)
+	if err != nil {
+		log.Fatal("failed to init authenticator", "err", err)
+	}
+
+	var hasher hash.Hash
+	switch cfg.Hash.Algorithm {
+	case "argon2id":
+		hasher = hash.NewArgon2idHash(hash.Argon2Params{
+			Memory:      cfg.Hash.Argon2Memory,
+			Iterations:  cfg.Hash.Argon2Iterations,
+			Parallelism: cfg.Hash.Argon2Parallelism,
+			SaltLength:  cfg.Hash.Argon2SaltLength,
+			KeyLength:   cfg.Hash.Argon2KeyLength,
+		})
+	case "bcrypt":
+		hasher = hash.NewHash()
+	default:
+		log.Fatal("unknown hash algorithm", "algorithm", cfg.Hash.Algorithm)
+	}
+
+	var mailer mail.Mailer
+	switch cfg.Mail.Driver {
+	case "smtp":
+		mailer = mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+			Timeout:  cfg.Mail.SMTPTimeout,
+		})
+	case "outbox":
+		mailer = mail.NewOutbox(cfg.Mail.OutboxDir, cfg.Mail.From)
+	default:
+		log.Fatal("unknown mail driver", "driver", cfg.Mail.Driver)
+	}
+
+	oidcProviders := make(map[string]oidc.Provider, len(cfg.OIDC.Providers))
+	for _, provider := range cfg.OIDC.Providers {
+		if provider.Name == "" {
+			log.Fatal("oidc provider has no name", "issuerUrl", provider.IssuerURL)
+		}
+		oidcProviders[provider.Name] = oidc.NewProvider(oidc.ProviderConfig{
+			IssuerURL:    provider.IssuerURL,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		})
+	}
+
+	var breachCorpus password.BreachCorpus
+	if cfg.Password.BreachCorpus != "" {
+		breachCorpus, err = password.LoadBreachCorpus(cfg.Password.BreachCorpus)
+		if err != nil {
+			log.Fatal("failed to load breach corpus", "err", err)
+		}
+	}
+
 	serviceOptions := &service.Options{
 		Storages: &storages,
 		Config:   cfg,
 		Logger:   log,
-		Hash:     hash.NewHash(),
-		Auth:     auth.NewAuth(),
+		Hash:     hasher,
+		Auth:     authenticator,
+		Mailer:   mailer,
+
+		OIDCProviders: oidcProviders,
+		PasswordPolicy: password.Policy{
+			MinLength:           cfg.Password.MinLength,
+			MaxLength:           cfg.Password.MaxLength,
+			MinCharacterClasses: cfg.Password.MinCharacterClasses,
+		},
+		BreachCorpus: breachCorpus,
 	}
 
 	services := service.Services{
 		AuthService:    service.NewAuthService(serviceOptions),
 		AccountService: service.NewAccountService(serviceOptions),
 		NodeService:    service.NewNodeService(serviceOptions),
+		AdminService:   service.NewAdminService(serviceOptions),
+		ContactService: service.NewContactService(serviceOptions),
+		GroupService:   service.NewGroupService(serviceOptions),
 	}
 
+	// background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go runPeriodically(jobsCtx, cfg.JWT.RevokedTokensPruneInterval, services.AuthService.PruneRevokedTokens)
+	go runPeriodically(jobsCtx, cfg.OIDC.LoginTTL, services.AuthService.PruneOIDCLoginStates)
+	go runPeriodically(jobsCtx, cfg.Account.DeletionSweepInterval, services.AccountService.EraseScheduledAccounts)
+	go runPeriodically(jobsCtx, cfg.Account.ExportProcessInterval, services.AccountService.ProcessDataExports)
+	go runPeriodically(jobsCtx, cfg.Account.PresenceSweepInterval, services.AccountService.MarkStaleDevicesOffline)
+
 	httpHandler := gin.New()
+	err = httpHandler.SetTrustedProxies(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		log.Fatal("invalid trusted proxies", "err", err)
+	}
 
 	controller.New(&controller.Options{
 		Handler:  httpHandler,
@@ -106,3 +251,19 @@ func Run(cfg *config.Config) {
 		}
 	}
 }
+
+// runPeriodically runs job every interval until ctx is done.
+func runPeriodically(ctx context.Context, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// errors are already logged by the job itself
+			_ = job(ctx)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 )
 
 type (
@@ -15,6 +18,15 @@ type (
 		HTTP       HTTP
 		Log        Log
 		PostgreSQL PostgreSQL
+		JWT        JWT
+		Auth       Auth
+		Hash       Hash
+		Mail       Mail
+		OIDC       OIDC
+		Password   Password
+		Account    Account
+		Group      Group
+		Search     Search
 	}
 
 	// App - represent application configuration.
@@ -23,8 +35,10 @@ type (
 	}
 
 	// HTTP - represents http configuration.
+	// TrustedProxies are allowed to set client ip via X-Forwarded-For header.
 	HTTP struct {
-		Port string `env:"HTTP_PORT" env-default:"8082"`
+		Port           string   `env:"HTTP_PORT" env-default:"8082"`
+		TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES"`
 	}
 
 	// Log - represents logger configuration.
@@ -41,11 +55,139 @@ type (
 	}
 
 	// JWT - represents jwt configuration.
+	// PrivateKeys maps key id to PEM encoded private key or path to PEM file
+	// (e.g. "2023-10:/keys/2023-10.pem"), SignKey is used only when no private keys are set.
+	// There is no default secret, so the app does not start until one of them is configured.
 	JWT struct {
-		SignKey string `env:"JWT_SIGN_KEY"     env-default:"sajkdjk1ndansdnan"`
+		SignKey                    string            `env:"JWT_SIGN_KEY"`
+		SigningKeyId               string            `env:"JWT_SIGNING_KEY_ID"`
+		PrivateKeys                map[string]string `env:"JWT_PRIVATE_KEYS"`
+		AccessTokenTTL             time.Duration     `env:"JWT_ACCESS_TOKEN_TTL"              env-default:"15m"`
+		RefreshTokenTTL            time.Duration     `env:"JWT_REFRESH_TOKEN_TTL"             env-default:"720h"`
+		RevokedTokensPruneInterval time.Duration     `env:"JWT_REVOKED_TOKENS_PRUNE_INTERVAL" env-default:"1h"`
 	}
+
+	// Auth - represents authentication flows configuration.
+	// RequireVerifiedEmail blocks transfer-related operations until user confirms email.
+	// Sign in is locked for LockoutBaseDuration once failures reach a threshold, lock duration doubles
+	// with every further failure up to LockoutMaxDuration; counters start over after LockoutResetAfter.
+	// PersonalAccessTokenMaxTTL limits how far in the future personal access token may expire.
+	Auth struct {
+		PasswordResetTokenTTL     time.Duration `env:"AUTH_PASSWORD_RESET_TOKEN_TTL"      env-default:"1h"`
+		EmailVerificationTokenTTL time.Duration `env:"AUTH_EMAIL_VERIFICATION_TOKEN_TTL"  env-default:"48h"`
+		RequireVerifiedEmail      bool          `env:"AUTH_REQUIRE_VERIFIED_EMAIL"        env-default:"true"`
+		MFAChallengeTTL           time.Duration `env:"AUTH_MFA_CHALLENGE_TTL"             env-default:"5m"`
+		TOTPIssuer                string        `env:"AUTH_TOTP_ISSUER"                   env-default:"droplet"`
+		LockoutThreshold          int           `env:"AUTH_LOCKOUT_THRESHOLD"             env-default:"5"`
+		IPLockoutThreshold        int           `env:"AUTH_IP_LOCKOUT_THRESHOLD"          env-default:"20"`
+		LockoutBaseDuration       time.Duration `env:"AUTH_LOCKOUT_BASE_DURATION"         env-default:"1m"`
+		LockoutMaxDuration        time.Duration `env:"AUTH_LOCKOUT_MAX_DURATION"          env-default:"1h"`
+		LockoutResetAfter         time.Duration `env:"AUTH_LOCKOUT_RESET_AFTER"           env-default:"24h"`
+		PersonalAccessTokenMaxTTL time.Duration `env:"AUTH_PERSONAL_ACCESS_TOKEN_MAX_TTL" env-default:"8760h"`
+		EmailChangeTokenTTL       time.Duration `env:"AUTH_EMAIL_CHANGE_TOKEN_TTL"        env-default:"24h"`
+	}
+
+	// Hash - represents password hashing configuration.
+	// Algorithm is either "argon2id" or legacy "bcrypt", Argon2Memory is in KiB.
+	Hash struct {
+		Algorithm         string `env:"HASH_ALGORITHM"          env-default:"argon2id"`
+		Argon2Memory      uint32 `env:"HASH_ARGON2_MEMORY"      env-default:"65536"`
+		Argon2Iterations  uint32 `env:"HASH_ARGON2_ITERATIONS"  env-default:"3"`
+		Argon2Parallelism uint8  `env:"HASH_ARGON2_PARALLELISM" env-default:"2"`
+		Argon2SaltLength  uint32 `env:"HASH_ARGON2_SALT_LENGTH" env-default:"16"`
+		Argon2KeyLength   uint32 `env:"HASH_ARGON2_KEY_LENGTH"  env-default:"32"`
+	}
+
+	// Mail - represents mail delivery configuration.
+	// Driver is either "smtp" or "outbox" which keeps messages locally.
+	// SMTPTimeout limits delivery of single message, so slow SMTP server does not hang requests.
+	Mail struct {
+		Driver       string        `env:"MAIL_DRIVER"       env-default:"outbox"`
+		From         string        `env:"MAIL_FROM"         env-default:"droplet <no-reply@droplet.local>"`
+		OutboxDir    string        `env:"MAIL_OUTBOX_DIR"   env-default:"outbox"`
+		SMTPHost     string        `env:"MAIL_SMTP_HOST"    env-default:"127.0.0.1"`
+		SMTPPort     string        `env:"MAIL_SMTP_PORT"    env-default:"25"`
+		SMTPUsername string        `env:"MAIL_SMTP_USERNAME"`
+		SMTPPassword string        `env:"MAIL_SMTP_PASSWORD"`
+		SMTPTimeout  time.Duration `env:"MAIL_SMTP_TIMEOUT" env-default:"10s"`
+	}
+
+	// OIDC - represents external OpenID Connect providers configuration.
+	// Providers are set as JSON array, e.g.
+	// [{"name":"company","issuerUrl":"https://idp.example.com","clientId":"droplet","clientSecret":"secret",
+	// "redirectUrl":"http://localhost:8082/api/v1/auth/oidc/company/callback"}].
+	// LoginTTL limits time between redirect to the provider and callback.
+	OIDC struct {
+		Providers OIDCProviders `env:"OIDC_PROVIDERS"`
+		LoginTTL  time.Duration `env:"OIDC_LOGIN_TTL" env-default:"10m"`
+	}
+
+	// Password - represents password policy configuration.
+	// BreachCorpus is path to SHA-1 breach corpus file or directory of range files, screening is off when empty.
+	Password struct {
+		MinLength           int    `env:"PASSWORD_MIN_LENGTH"            env-default:"10"`
+		MaxLength           int    `env:"PASSWORD_MAX_LENGTH"            env-default:"128"`
+		MinCharacterClasses int    `env:"PASSWORD_MIN_CHARACTER_CLASSES" env-default:"2"`
+		BreachCorpus        string `env:"PASSWORD_BREACH_CORPUS"`
+	}
+
+	// Account - represents account lifecycle configuration.
+	// Deleted accounts are erased once DeletionGracePeriod passes, due deletions are looked up every DeletionSweepInterval.
+	// Requested data exports are built every ExportProcessInterval and can be downloaded during ExportArchiveTTL,
+	// exports still processing after ExportProcessingTimeout are failed.
+	// MaxDevices limits number of devices registered to single account.
+	// Device is online until PresenceTTL passes since its last heartbeat, stale devices are flipped offline every PresenceSweepInterval.
+	Account struct {
+		DeletionGracePeriod     time.Duration `env:"ACCOUNT_DELETION_GRACE_PERIOD"     env-default:"720h"`
+		DeletionSweepInterval   time.Duration `env:"ACCOUNT_DELETION_SWEEP_INTERVAL"   env-default:"1h"`
+		ExportProcessInterval   time.Duration `env:"ACCOUNT_EXPORT_PROCESS_INTERVAL"   env-default:"30s"`
+		ExportArchiveTTL        time.Duration `env:"ACCOUNT_EXPORT_ARCHIVE_TTL"        env-default:"72h"`
+		ExportProcessingTimeout time.Duration `env:"ACCOUNT_EXPORT_PROCESSING_TIMEOUT" env-default:"15m"`
+		MaxDevices              int           `env:"ACCOUNT_MAX_DEVICES"               env-default:"10"`
+		PresenceTTL             time.Duration `env:"ACCOUNT_PRESENCE_TTL"              env-default:"2m"`
+		PresenceSweepInterval   time.Duration `env:"ACCOUNT_PRESENCE_SWEEP_INTERVAL"   env-default:"1m"`
+	}
+
+	// Group - represents sharing groups configuration.
+	// MaxMembers limits number of members of single group including its owners.
+	Group struct {
+		MaxMembers int `env:"GROUP_MAX_MEMBERS" env-default:"50"`
+	}
+
+	// Search - represents user search configuration.
+	// Every user may search RateLimit times per RateLimitWindow, username queries need at least MinQueryLength characters.
+	// MaxResults limits number of users returned by single search.
+	Search struct {
+		RateLimit       int           `env:"SEARCH_RATE_LIMIT"        env-default:"30"`
+		RateLimitWindow time.Duration `env:"SEARCH_RATE_LIMIT_WINDOW" env-default:"1m"`
+		MinQueryLength  int           `env:"SEARCH_MIN_QUERY_LENGTH"  env-default:"3"`
+		MaxResults      int           `env:"SEARCH_MAX_RESULTS"       env-default:"20"`
+	}
+
+	// OIDCProvider - represents single OpenID Connect provider client registration.
+	OIDCProvider struct {
+		Name         string   `json:"name"`
+		IssuerURL    string   `json:"issuerUrl"`
+		ClientID     string   `json:"clientId"`
+		ClientSecret string   `json:"clientSecret"`
+		RedirectURL  string   `json:"redirectUrl"`
+		Scopes       []string `json:"scopes"`
+	}
+
+	// OIDCProviders - represents list of OpenID Connect providers decoded from JSON.
+	OIDCProviders []OIDCProvider
 )
 
+// SetValue implements cleanenv.Setter and decodes providers from JSON array.
+func (p *OIDCProviders) SetValue(value string) error {
+	if strings.TrimSpace(value) == "" {
+		*p = nil
+		return nil
+	}
+
+	return json.Unmarshal([]byte(value), p)
+}
+
 // Replace is used to replace values in static files with populated values from config.
 // It replaces config variables (e.g. {{BaseURL}}) with real values from config
 // and serves updated files.
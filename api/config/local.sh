@@ -6,4 +6,52 @@ export POSTGRESQL_DATABASE="api"
 
 export APP_BASE_URL="http://localhost:8082"
 export LOG_LEVEL="debug"
-export JWT_SIGN_KEY="sajkdjk1ndansdnan"
\ No newline at end of file
+export JWT_SIGN_KEY="local-development-only-hmac-secret"
+export JWT_ACCESS_TOKEN_TTL="15m"
+export JWT_REFRESH_TOKEN_TTL="720h"
+export JWT_REVOKED_TOKENS_PRUNE_INTERVAL="1h"
+# asymmetric keys take precedence over JWT_SIGN_KEY, e.g. "2023-10:./config/keys/2023-10.pem"
+export JWT_SIGNING_KEY_ID=""
+export JWT_PRIVATE_KEYS=""
+
+export AUTH_PASSWORD_RESET_TOKEN_TTL="1h"
+export AUTH_EMAIL_VERIFICATION_TOKEN_TTL="48h"
+export AUTH_REQUIRE_VERIFIED_EMAIL="true"
+export AUTH_MFA_CHALLENGE_TTL="5m"
+export AUTH_TOTP_ISSUER="droplet"
+export AUTH_LOCKOUT_THRESHOLD="5"
+export AUTH_IP_LOCKOUT_THRESHOLD="20"
+export AUTH_PERSONAL_ACCESS_TOKEN_MAX_TTL="8760h"
+export AUTH_EMAIL_CHANGE_TOKEN_TTL="24h"
+
+export MAIL_DRIVER="outbox"
+export MAIL_FROM="droplet <no-reply@droplet.local>"
+export MAIL_OUTBOX_DIR="outbox"
+
+export HASH_ALGORITHM="argon2id"
+export HASH_ARGON2_MEMORY="65536"
+export HASH_ARGON2_ITERATIONS="3"
+export HASH_ARGON2_PARALLELISM="2"
+
+# JSON array of providers, e.g. '[{"name":"company","issuerUrl":"https://idp.example.com","clientId":"droplet","clientSecret":"secret","redirectUrl":"http://localhost:8082/api/v1/auth/oidc/company/callback"}]'
+export OIDC_PROVIDERS=""
+export OIDC_LOGIN_TTL="10m"
+
+export PASSWORD_MIN_LENGTH="10"
+export PASSWORD_MAX_LENGTH="128"
+export PASSWORD_MIN_CHARACTER_CLASSES="2"
+# file with "SHA1:COUNT" lines or directory of k-anonymity range files
+export PASSWORD_BREACH_CORPUS=""
+export ACCOUNT_DELETION_GRACE_PERIOD="720h"
+export ACCOUNT_DELETION_SWEEP_INTERVAL="1h"
+export ACCOUNT_EXPORT_PROCESS_INTERVAL="30s"
+export ACCOUNT_EXPORT_ARCHIVE_TTL="72h"
+export ACCOUNT_EXPORT_PROCESSING_TIMEOUT="15m"
+export ACCOUNT_MAX_DEVICES="10"
+export ACCOUNT_PRESENCE_TTL="2m"
+export ACCOUNT_PRESENCE_SWEEP_INTERVAL="1m"
+export GROUP_MAX_MEMBERS="50"
+export SEARCH_RATE_LIMIT="30"
+export SEARCH_RATE_LIMIT_WINDOW="1m"
+export SEARCH_MIN_QUERY_LENGTH="3"
+export SEARCH_MAX_RESULTS="20"
@@ -1,12 +1,21 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/errs"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	_contentTypeMergePatch = "application/merge-patch+json"
+	_contentTypeJSONPatch  = "application/json-patch+json"
 )
 
 type accountRouter struct {
@@ -24,9 +33,23 @@ func setupAccountRoutes(options RouterOptions) {
 
 	routerGroup := options.Handler.Group("/account")
 	{
-		routerGroup.POST("", authMiddleware(options), wrapHandler(options, router.createAccount))
-		routerGroup.GET("/:id", authMiddleware(options), wrapHandler(options, router.getAccount))
-		routerGroup.PATCH("/:id", authMiddleware(options), wrapHandler(options, router.updateAccount))
+		routerGroup.POST("", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.createAccount))
+		routerGroup.GET("/:id", authMiddleware(options, entity.ScopeAccountRead), wrapHandler(options, router.getAccount))
+		routerGroup.PATCH("/:id", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.updateAccount))
+		routerGroup.DELETE("/:id", authMiddleware(options), wrapHandler(options, router.deleteAccount))
+		routerGroup.POST("/deletion/cancel", wrapHandler(options, router.cancelAccountDeletion))
+		routerGroup.POST("/:id/export", authMiddleware(options), wrapHandler(options, router.requestDataExport))
+		routerGroup.GET("/:id/export/:exportId", authMiddleware(options), wrapHandler(options, router.getDataExport))
+		routerGroup.GET("/export/download", wrapHandler(options, router.downloadDataExport))
+		routerGroup.GET("/:id/devices", authMiddleware(options, entity.ScopeAccountRead), wrapHandler(options, router.listDevices))
+		routerGroup.POST("/:id/devices", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.registerDevice))
+		routerGroup.PATCH("/:id/devices/:deviceId", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.updateDevice))
+		routerGroup.DELETE("/:id/devices/:deviceId", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.removeDevice))
+		routerGroup.PUT("/:id/devices/:deviceId/key", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.registerDeviceKey))
+		routerGroup.POST("/:id/devices/:deviceId/heartbeat", authMiddleware(options, entity.ScopeAccountWrite), wrapHandler(options, router.deviceHeartbeat))
+		routerGroup.GET("/tokens", authMiddleware(options), wrapHandler(options, router.listPersonalAccessTokens))
+		routerGroup.POST("/tokens", authMiddleware(options), wrapHandler(options, router.createPersonalAccessToken))
+		routerGroup.DELETE("/tokens/:tokenId", authMiddleware(options), wrapHandler(options, router.revokePersonalAccessToken))
 	}
 }
 
@@ -39,15 +62,17 @@ type createAccountResponseBody struct {
 } // @name createAccountResponseBody
 
 type createAccountResponseError struct {
-	Message string `json:"message"`
-	Code    string `json:"code" enums:"user_not_found"`
+	Message       string            `json:"message"`
+	Code          string            `json:"code" enums:"user_not_found,invalid_device_name,invalid_device_os,invalid_mac_address,invalid_settings"`
+	InvalidFields []errs.FieldError `json:"invalidFields,omitempty"`
 } // @name createAccountResponseError
 
 func (e createAccountResponseError) Error() *httpResponseError {
 	return &httpResponseError{
-		Type:    ErrorTypeClient,
-		Message: e.Message,
-		Code:    e.Code,
+		Type:          ErrorTypeClient,
+		Message:       e.Message,
+		Code:          e.Code,
+		InvalidFields: e.InvalidFields,
 	}
 }
 
@@ -62,12 +87,15 @@ func (e createAccountResponseError) Error() *httpResponseError {
 func (a *accountRouter) createAccount(requestContext *gin.Context) (interface{}, *httpResponseError) {
 	logger := a.logger.Named("createAccount").WithContext(requestContext)
 
-	var body createAccountRequestBody
+	// options are allocated up front, as empty body leaves embedded pointer nil
+	body := createAccountRequestBody{&service.CreateAccountOptions{}}
 	err := requestContext.ShouldBindJSON(&body)
 	if err != nil {
 		logger.Info("failed to parse request body", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
 	}
+	// account always belongs to the authenticated user, owner is never taken from the body
+	body.UserId = requestContext.GetString("userId")
 	logger = logger.With("body", body)
 	logger.Debug("parsed request body")
 
@@ -75,7 +103,7 @@ func (a *accountRouter) createAccount(requestContext *gin.Context) (interface{},
 	if err != nil {
 		if errs.IsExpected(err) {
 			logger.Info(err.Error())
-			return nil, createAccountResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+			return nil, createAccountResponseError{Message: err.Error(), Code: errs.GetCode(err), InvalidFields: errs.GetFields(err)}.Error()
 		}
 		logger.Error("failed to create account", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to create account", Details: err}
@@ -87,7 +115,7 @@ func (a *accountRouter) createAccount(requestContext *gin.Context) (interface{},
 }
 
 type getAccountResponseBody struct {
-	*entity.Account
+	*accountDTO
 } // @name getAccountResponseBody
 
 type getAccountResponseError struct {
@@ -109,6 +137,7 @@ func (e getAccountResponseError) Error() *httpResponseError {
 // @Produce      application/json
 // @Param        id path string true "Account ID"
 // @Success      200 {object} getAccountResponseBody
+// @Header       200 {string} ETag "Account version"
 // @Failure      422,500 {object} getAccountResponseError
 // @Router       /account/{id} [GET]
 func (a *accountRouter) getAccount(requestContext *gin.Context) (interface{}, *httpResponseError) {
@@ -144,24 +173,66 @@ func (a *accountRouter) getAccount(requestContext *gin.Context) (interface{}, *h
 			logger.Info(err.Error())
 			return nil, getAccountResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
 		}
+		logger.Error("failed to get account", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to get account", Details: err}
 	}
 	logger = logger.With("account", account)
+	requestContext.Header("ETag", accountETag(account))
 
 	logger.Info("successfully got account")
-	return getAccountResponseBody{account}, nil
+	return getAccountResponseBody{newAccountDTO(account)}, nil
 }
 
+type updateAccountRequestBody struct {
+	*service.UpdateAccountOptions
+} // @name updateAccountRequestBody
+
 type updateAccountResponseBody struct {
-	*entity.Account
+	*accountDTO
 } // @name updateAccountResponseBody
 
+type updateAccountResponseError struct {
+	Message       string            `json:"message"`
+	Code          string            `json:"code" enums:"account_not_found,invalid_settings,version_mismatch,if_match_required,invalid_if_match,invalid_patch,patch_failed,invalid_device_name"`
+	Details       map[string]string `json:"details,omitempty"`
+	InvalidFields []errs.FieldError `json:"invalidFields,omitempty"`
+} // @name updateAccountResponseError
+
+func (e updateAccountResponseError) Error() *httpResponseError {
+	status := 0
+	switch e.Code {
+	case "version_mismatch":
+		status = http.StatusPreconditionFailed
+	case "if_match_required":
+		status = http.StatusPreconditionRequired
+	}
+
+	return &httpResponseError{
+		Type:          ErrorTypeClient,
+		Status:        status,
+		Message:       e.Message,
+		Code:          e.Code,
+		Details:       e.Details,
+		InvalidFields: e.InvalidFields,
+	}
+}
+
 // @id           UpdateAccount
-// @Summary      Updates account entity account.
-// @Accept       application/json
+// @Summary      Updates account settings, settings which are not sent are left unchanged.
+// @Description  If-Match must hold ETag from the last read of the account (or "*" to skip the check),
+// @Description  stale version is rejected with 412 so concurrent edits are not lost.
+// @Description  Besides settings object, body may be JSON Merge Patch (application/merge-patch+json) or
+// @Description  JSON Patch (application/json-patch+json) of the account returned by GetAccount. Only settings
+// @Description  and name and activity of devices can be patched, devices are addressed by index, so guard
+// @Description  device operations with test of the device id.
+// @Accept       application/json,application/merge-patch+json,application/json-patch+json
 // @Produce      application/json
 // @Param        id path string true "Account ID"
+// @Param        If-Match header string true "Account ETag"
+// @Param        fields body updateAccountRequestBody true "data"
 // @Success      200 {object} updateAccountResponseBody
-// @Failure      422,500 {object} httpResponseError
+// @Header       200 {string} ETag "Account version"
+// @Failure      412,422,428,500 {object} updateAccountResponseError
 // @Router       /account/{id} [PATCH]
 func (a *accountRouter) updateAccount(requestContext *gin.Context) (interface{}, *httpResponseError) {
 	logger := a.logger.Named("updateAccount").WithContext(requestContext)
@@ -190,30 +261,192 @@ func (a *accountRouter) updateAccount(requestContext *gin.Context) (interface{},
 	logger = logger.With("userId", userId)
 	logger.Debug("validated uuid userId")
 
-	account, err := a.services.AccountService.GetAccount(requestContext, &service.GetAccountOptions{AccountId: accountId, UserId: userId})
-	if err != nil {
-		if errs.IsExpected(err) {
-			logger.Info(err.Error())
-			return nil, getAccountResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
-		}
+	ifMatch := requestContext.GetHeader("If-Match")
+	if ifMatch == "" {
+		logger.Info("if-match header is missing")
+		return nil, updateAccountResponseError{Message: "If-Match header is required", Code: "if_match_required"}.Error()
 	}
-	logger = logger.With("account", account)
+	version, ok := parseAccountETag(ifMatch)
+	if !ok {
+		logger.Info("invalid if-match header", "ifMatch", ifMatch)
+		return nil, updateAccountResponseError{Message: "invalid If-Match header", Code: "invalid_if_match"}.Error()
+	}
+	logger = logger.With("version", version)
 
-	err = requestContext.ShouldBindJSON(&account)
+	// options are allocated up front, as empty patch leaves embedded pointer nil
+	body := updateAccountRequestBody{&service.UpdateAccountOptions{}}
+	var err error
+	switch requestContext.ContentType() {
+	case _contentTypeMergePatch:
+		body.MergePatch, err = requestContext.GetRawData()
+		if err == nil && !json.Valid(body.MergePatch) {
+			err = fmt.Errorf("merge patch is not valid JSON")
+		}
+	case _contentTypeJSONPatch:
+		err = requestContext.ShouldBindJSON(&body.JSONPatch)
+		if err == nil && body.JSONPatch == nil {
+			err = fmt.Errorf("JSON patch must be array of operations")
+		}
+	default:
+		err = requestContext.ShouldBindJSON(&body)
+	}
 	if err != nil {
 		logger.Info("failed to parse request body", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
 	}
-	logger = logger.With("account", account)
+	body.AccountId = accountId
+	body.UserId = userId
+	body.Version = version
+	logger = logger.With("body", body)
 	logger.Debug("parsed request body")
 
-	updatedAccount, err := a.services.AccountService.UpdateAccount(requestContext, account)
+	updatedAccount, err := a.services.AccountService.UpdateAccount(requestContext, body.UpdateAccountOptions)
 	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, updateAccountResponseError{
+				Message:       err.Error(),
+				Code:          errs.GetCode(err),
+				Details:       errs.GetDetails(err),
+				InvalidFields: errs.GetFields(err),
+			}.Error()
+		}
 		logger.Error("failed to update account: ", err)
 		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to update account", Details: err}
 	}
 	logger = logger.With("updatedAccount", updatedAccount)
+	requestContext.Header("ETag", accountETag(updatedAccount))
 
 	logger.Info("successfully updated account")
-	return updateAccountResponseBody{updatedAccount}, nil
+	return updateAccountResponseBody{newAccountDTO(updatedAccount)}, nil
+}
+
+// accountETag formats account version as strong entity tag.
+func accountETag(account *entity.Account) string {
+	return strconv.Quote(strconv.Itoa(account.Version))
+}
+
+// parseAccountETag returns version from If-Match header, "*" matches any version and gives zero.
+// Weak tags are not accepted, as If-Match requires strong comparison.
+func parseAccountETag(ifMatch string) (int, bool) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "*" {
+		return 0, true
+	}
+
+	if len(ifMatch) < 2 || ifMatch[0] != '"' || ifMatch[len(ifMatch)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(ifMatch[1 : len(ifMatch)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+type deleteAccountResponseBody struct {
+	*service.DeleteAccountOutput
+} // @name deleteAccountResponseBody
+
+type deleteAccountResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"account_not_found,deletion_already_scheduled"`
+} // @name deleteAccountResponseError
+
+func (e deleteAccountResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           DeleteAccount
+// @Summary      Schedules erase of the account owner and all user data, sessions are revoked immediately.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Success      200 {object} deleteAccountResponseBody
+// @Failure      422,500 {object} deleteAccountResponseError
+// @Router       /account/{id} [DELETE]
+func (a *accountRouter) deleteAccount(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("deleteAccount").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "userId", userId)
+	logger.Debug("parsed params")
+
+	output, err := a.services.AccountService.DeleteAccount(requestContext, &service.DeleteAccountOptions{
+		AccountId: accountId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deleteAccountResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to delete account", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to delete account", Details: err}
+	}
+
+	logger.Info("account deletion successfully scheduled")
+	return &deleteAccountResponseBody{output}, nil
+}
+
+type cancelAccountDeletionRequestBody struct {
+	*service.CancelAccountDeletionOptions
+} // @name cancelAccountDeletionRequestBody
+
+type cancelAccountDeletionResponseBody struct{} // @name cancelAccountDeletionResponseBody
+
+type cancelAccountDeletionResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"invalid_deletion_cancel_token"`
+} // @name cancelAccountDeletionResponseError
+
+func (e cancelAccountDeletionResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           CancelAccountDeletion
+// @Summary      Cancels scheduled account deletion via emailed token.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body cancelAccountDeletionRequestBody true "data"
+// @Success      200 {object} cancelAccountDeletionResponseBody
+// @Failure      422,500 {object} cancelAccountDeletionResponseError
+// @Router       /account/deletion/cancel [POST]
+func (a *accountRouter) cancelAccountDeletion(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("cancelAccountDeletion").WithContext(requestContext)
+
+	body := cancelAccountDeletionRequestBody{&service.CancelAccountDeletionOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger.Debug("parsed request body")
+
+	err = a.services.AccountService.CancelAccountDeletion(requestContext, body.CancelAccountDeletionOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, cancelAccountDeletionResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to cancel account deletion", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to cancel account deletion", Details: err}
+	}
+
+	logger.Info("account deletion successfully cancelled")
+	return &cancelAccountDeletionResponseBody{}, nil
 }
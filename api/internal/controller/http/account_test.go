@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+)
+
+func TestParseAccountETag(t *testing.T) {
+	tests := []struct {
+		ifMatch     string
+		wantVersion int
+		wantOk      bool
+	}{
+		{ifMatch: `"3"`, wantVersion: 3, wantOk: true},
+		{ifMatch: ` "12" `, wantVersion: 12, wantOk: true},
+		{ifMatch: `*`, wantVersion: 0, wantOk: true},
+		{ifMatch: `W/"3"`},
+		{ifMatch: `3`},
+		{ifMatch: `"x"`},
+		{ifMatch: `"0"`},
+		{ifMatch: `"-1"`},
+		{ifMatch: `"3", "4"`},
+		{ifMatch: `"`},
+		{ifMatch: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifMatch, func(t *testing.T) {
+			version, ok := parseAccountETag(tt.ifMatch)
+			if version != tt.wantVersion || ok != tt.wantOk {
+				t.Fatalf("parseAccountETag(%q) = %d, %v, want %d, %v", tt.ifMatch, version, ok, tt.wantVersion, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAccountETagRoundTrip(t *testing.T) {
+	for _, version := range []int{1, 2, 100} {
+		etag := accountETag(&entity.Account{Version: version})
+		got, ok := parseAccountETag(etag)
+		if !ok || got != version {
+			t.Errorf("parseAccountETag(%s) = %d, %v, want %d", etag, got, ok, version)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"strconv"
+)
+
+type adminRouter struct {
+	RouterContext
+}
+
+func setupAdminRoutes(options RouterOptions) {
+	router := &adminRouter{
+		RouterContext{
+			logger:   options.Logger,
+			services: options.Services,
+			config:   options.Config,
+		},
+	}
+
+	routerGroup := options.Handler.Group("/admin")
+	{
+		routerGroup.GET("/users", authMiddleware(options), requirePermission(options, entity.PermissionUsersRead), wrapHandler(options, router.listUsers))
+		routerGroup.GET("/users/:id/devices", authMiddleware(options), requirePermission(options, entity.PermissionDevicesRead), wrapHandler(options, router.listUserDevices))
+		routerGroup.GET("/users/:id/transfers", authMiddleware(options), requirePermission(options, entity.PermissionTransfersRead), wrapHandler(options, router.listUserTransfers))
+		routerGroup.POST("/users/:id/disable", authMiddleware(options), requirePermission(options, entity.PermissionUsersManage), wrapHandler(options, router.disableUser))
+		routerGroup.POST("/users/:id/enable", authMiddleware(options), requirePermission(options, entity.PermissionUsersManage), wrapHandler(options, router.enableUser))
+		routerGroup.DELETE("/users/:id", authMiddleware(options), requirePermission(options, entity.PermissionUsersManage), wrapHandler(options, router.deleteUser))
+		routerGroup.PUT("/users/:id/role", authMiddleware(options), requireRole(options, entity.RoleAdmin), wrapHandler(options, router.setUserRole))
+		routerGroup.POST("/users/unlock", authMiddleware(options), requirePermission(options, entity.PermissionUsersManage), wrapHandler(options, router.unlockAccount))
+	}
+}
+
+type adminResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"user_not_found,invalid_role,cannot_modify_self"`
+} // @name adminResponseError
+
+func (e adminResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+type listUsersResponseBody struct {
+	*service.ListUsersOutput
+} // @name listUsersResponseBody
+
+// @id           ListUsers
+// @Summary      Lists users for operators.
+// @Produce      application/json
+// @Param        limit query int false "page size, 50 by default"
+// @Param        offset query int false "page offset"
+// @Success      200 {object} listUsersResponseBody
+// @Failure      403,422,500 {object} httpResponseError
+// @Router       /admin/users [GET]
+func (a *adminRouter) listUsers(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listUsers").WithContext(requestContext)
+
+	var options service.ListUsersOptions
+	if limit := requestContext.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			logger.Info("invalid limit parameter", "param", limit)
+			return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid limit parameter"}
+		}
+		options.Limit = parsed
+	}
+	if offset := requestContext.Query("offset"); offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil {
+			logger.Info("invalid offset parameter", "param", offset)
+			return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid offset parameter"}
+		}
+		options.Offset = parsed
+	}
+	logger = logger.With("options", options)
+
+	users, err := a.services.AdminService.ListUsers(requestContext, &options)
+	if err != nil {
+		logger.Error("failed to list users", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list users", Details: err}
+	}
+
+	logger.Info("successfully listed users")
+	return &listUsersResponseBody{users}, nil
+}
+
+type listUserDevicesResponseBody struct {
+	Devices []deviceDTO
+} // @name listUserDevicesResponseBody
+
+// @id           ListUserDevices
+// @Summary      Lists devices of the user.
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Success      200 {object} listUserDevicesResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id}/devices [GET]
+func (a *adminRouter) listUserDevices(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listUserDevices").WithContext(requestContext)
+
+	userId := requestContext.Param("id")
+	if _, ok := uuid.Parse(userId); ok != nil {
+		logger.Info("invalid user id parameter", "param", userId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	logger = logger.With("userId", userId)
+
+	devices, err := a.services.AdminService.ListUserDevices(requestContext, &service.ListUserDevicesOptions{UserId: userId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, adminResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to list user devices", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list user devices", Details: err}
+	}
+
+	logger.Info("successfully listed user devices")
+	return &listUserDevicesResponseBody{Devices: newDeviceDTOs(devices.Devices)}, nil
+}
+
+type listUserTransfersResponseBody struct {
+	Transfers []transferDTO
+} // @name listUserTransfersResponseBody
+
+// @id           ListUserTransfers
+// @Summary      Lists transfers the user sends or receives.
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Success      200 {object} listUserTransfersResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id}/transfers [GET]
+func (a *adminRouter) listUserTransfers(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listUserTransfers").WithContext(requestContext)
+
+	userId := requestContext.Param("id")
+	if _, ok := uuid.Parse(userId); ok != nil {
+		logger.Info("invalid user id parameter", "param", userId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	logger = logger.With("userId", userId)
+
+	transfers, err := a.services.AdminService.ListUserTransfers(requestContext, &service.ListUserTransfersOptions{UserId: userId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, adminResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to list user transfers", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list user transfers", Details: err}
+	}
+
+	logger.Info("successfully listed user transfers")
+	return &listUserTransfersResponseBody{Transfers: newTransferDTOs(transfers.Transfers)}, nil
+}
+
+type setUserDisabledResponseBody struct{} // @name setUserDisabledResponseBody
+
+// @id           DisableUser
+// @Summary      Disables user and revokes all user sessions.
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Success      200 {object} setUserDisabledResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id}/disable [POST]
+func (a *adminRouter) disableUser(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	return a.setUserDisabled(requestContext, true)
+}
+
+// @id           EnableUser
+// @Summary      Enables previously disabled user.
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Success      200 {object} setUserDisabledResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id}/enable [POST]
+func (a *adminRouter) enableUser(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	return a.setUserDisabled(requestContext, false)
+}
+
+func (a *adminRouter) setUserDisabled(requestContext *gin.Context, disabled bool) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("setUserDisabled").WithContext(requestContext)
+
+	userId := requestContext.Param("id")
+	if _, ok := uuid.Parse(userId); ok != nil {
+		logger.Info("invalid user id parameter", "param", userId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	actorUserId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "actorUserId", actorUserId, "disabled", disabled)
+
+	err := a.services.AdminService.SetUserDisabled(requestContext, &service.SetUserDisabledOptions{
+		ActorUserId: actorUserId,
+		UserId:      userId,
+		Disabled:    disabled,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, adminResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to change user status", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to change user status", Details: err}
+	}
+
+	logger.Info("successfully changed user status")
+	return &setUserDisabledResponseBody{}, nil
+}
+
+type setUserRoleRequestBody struct {
+	*service.SetUserRoleOptions
+} // @name setUserRoleRequestBody
+
+type setUserRoleResponseBody struct{} // @name setUserRoleResponseBody
+
+// @id           SetUserRole
+// @Summary      Changes user role and revokes all user sessions.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Param        fields body setUserRoleRequestBody true "data"
+// @Success      200 {object} setUserRoleResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id}/role [PUT]
+func (a *adminRouter) setUserRole(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("setUserRole").WithContext(requestContext)
+
+	userId := requestContext.Param("id")
+	if _, ok := uuid.Parse(userId); ok != nil {
+		logger.Info("invalid user id parameter", "param", userId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+
+	body := setUserRoleRequestBody{&service.SetUserRoleOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = userId
+	body.ActorUserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	err = a.services.AdminService.SetUserRole(requestContext, body.SetUserRoleOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, adminResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to change user role", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to change user role", Details: err}
+	}
+
+	logger.Info("successfully changed user role")
+	return &setUserRoleResponseBody{}, nil
+}
+
+type unlockAccountRequestBody struct {
+	*service.UnlockAccountOptions
+} // @name unlockAccountRequestBody
+
+type unlockAccountResponseBody struct{} // @name unlockAccountResponseBody
+
+// @id           UnlockAccount
+// @Summary      Resets failed sign in attempts of the account.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body unlockAccountRequestBody true "data"
+// @Success      200 {object} unlockAccountResponseBody
+// @Failure      403,422,500 {object} httpResponseError
+// @Router       /admin/users/unlock [POST]
+func (a *adminRouter) unlockAccount(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("unlockAccount").WithContext(requestContext)
+
+	body := unlockAccountRequestBody{&service.UnlockAccountOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger = logger.With("body", body, "actorUserId", requestContext.GetString("userId"))
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.UnlockAccount(requestContext, body.UnlockAccountOptions)
+	if err != nil {
+		logger.Error("failed to unlock account", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to unlock account", Details: err}
+	}
+
+	logger.Info("successfully unlocked account")
+	return &unlockAccountResponseBody{}, nil
+}
+
+type deleteUserResponseBody struct{} // @name deleteUserResponseBody
+
+// @id           DeleteUser
+// @Summary      Erases user and all user data immediately.
+// @Produce      application/json
+// @Param        id path string true "User ID"
+// @Success      200 {object} deleteUserResponseBody
+// @Failure      403,422,500 {object} adminResponseError
+// @Router       /admin/users/{id} [DELETE]
+func (a *adminRouter) deleteUser(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("deleteUser").WithContext(requestContext)
+
+	userId := requestContext.Param("id")
+	if _, ok := uuid.Parse(userId); ok != nil {
+		logger.Info("invalid user id parameter", "param", userId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	actorUserId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "actorUserId", actorUserId)
+
+	err := a.services.AdminService.DeleteUser(requestContext, &service.DeleteUserOptions{
+		ActorUserId: actorUserId,
+		UserId:      userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, adminResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to delete user", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to delete user", Details: err}
+	}
+
+	logger.Info("successfully deleted user")
+	return &deleteUserResponseBody{}, nil
+}
@@ -22,7 +22,24 @@ func setupAuthRoutes(options RouterOptions) {
 	routerGroup := options.Handler.Group("/auth")
 	{
 		routerGroup.POST("/sign-in", wrapHandler(options, router.signIn))
+		routerGroup.POST("/sign-in/mfa", wrapHandler(options, router.signInMFA))
+		routerGroup.GET("/oidc/:provider/login", wrapHandler(options, router.startOIDCLogin))
+		routerGroup.GET("/oidc/:provider/callback", wrapHandler(options, router.completeOIDCLogin))
 		routerGroup.POST("/sign-up", wrapHandler(options, router.signUp))
+		routerGroup.POST("/refresh", wrapHandler(options, router.refreshToken))
+		routerGroup.POST("/sign-out", authMiddleware(options), wrapHandler(options, router.signOut))
+		routerGroup.POST("/sign-out-all", authMiddleware(options), wrapHandler(options, router.signOutAll))
+		routerGroup.POST("/password/forgot", wrapHandler(options, router.forgotPassword))
+		routerGroup.POST("/password/reset", wrapHandler(options, router.resetPassword))
+		routerGroup.POST("/password/change", authMiddleware(options), wrapHandler(options, router.changePassword))
+		routerGroup.POST("/email/change", authMiddleware(options), wrapHandler(options, router.changeEmail))
+		routerGroup.POST("/email/confirm", wrapHandler(options, router.confirmEmailChange))
+		routerGroup.POST("/verify-email", wrapHandler(options, router.verifyEmail))
+		routerGroup.POST("/verify-email/resend", authMiddleware(options), wrapHandler(options, router.resendVerificationEmail))
+		routerGroup.POST("/2fa/enroll", authMiddleware(options), wrapHandler(options, router.enrollTOTP))
+		routerGroup.POST("/2fa/confirm", authMiddleware(options), wrapHandler(options, router.confirmTOTP))
+		routerGroup.POST("/2fa/disable", authMiddleware(options), wrapHandler(options, router.disableTOTP))
+		routerGroup.POST("/2fa/recovery-codes", authMiddleware(options), wrapHandler(options, router.regenerateRecoveryCodes))
 	}
 }
 
@@ -35,8 +52,9 @@ type signInResponseBody struct {
 } // @name signInResponseBody
 
 type signInResponseError struct {
-	Message string `json:"message"`
-	Code    string `json:"code" enums:"user_not_found,wrong_password"`
+	Message string            `json:"message"`
+	Code    string            `json:"code" enums:"user_not_found,wrong_password,account_locked"`
+	Details map[string]string `json:"details,omitempty"`
 } // @name signInResponseError
 
 func (e signInResponseError) Error() *httpResponseError {
@@ -44,6 +62,7 @@ func (e signInResponseError) Error() *httpResponseError {
 		Type:    ErrorTypeClient,
 		Message: e.Message,
 		Code:    e.Code,
+		Details: e.Details,
 	}
 }
 
@@ -58,20 +77,25 @@ func (e signInResponseError) Error() *httpResponseError {
 func (a *authRouter) signIn(requestContext *gin.Context) (interface{}, *httpResponseError) {
 	logger := a.logger.Named("signIn").WithContext(requestContext)
 
-	var body signInRequestBody
+	body := signInRequestBody{&service.SignInOptions{}}
 	err := requestContext.ShouldBindJSON(&body)
 	if err != nil {
 		logger.Info("failed to parse request body", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
 	}
-	logger = logger.With("body", body)
+	body.ClientIP = requestContext.ClientIP()
+	logger = logger.With("email", body.Email, "clientIP", body.ClientIP)
 	logger.Debug("parsed request body")
 
 	signed, err := a.services.AuthService.SignIn(requestContext, body.SignInOptions)
 	if err != nil {
 		if errs.IsExpected(err) {
 			logger.Info(err.Error())
-			return nil, signInResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+			details := errs.GetDetails(err)
+			if retryAfter, ok := details["retryAfter"]; ok {
+				requestContext.Header("Retry-After", retryAfter)
+			}
+			return nil, signInResponseError{Message: err.Error(), Code: errs.GetCode(err), Details: details}.Error()
 		}
 		logger.Error("failed to sign in", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to sign in", Details: err}
@@ -90,15 +114,17 @@ type signUpResponseBody struct {
 } // @name signUpResponseBody
 
 type signUpResponseError struct {
-	Message string `json:"message"`
-	Code    string `json:"code" enums:"user_already_created"`
+	Message       string            `json:"message"`
+	Code          string            `json:"code" enums:"user_already_created,invalid_password"`
+	InvalidFields []errs.FieldError `json:"invalidFields,omitempty"`
 } // @name signUpResponseError
 
 func (e signUpResponseError) Error() *httpResponseError {
 	return &httpResponseError{
-		Type:    ErrorTypeClient,
-		Message: e.Message,
-		Code:    e.Code,
+		Type:          ErrorTypeClient,
+		Message:       e.Message,
+		Code:          e.Code,
+		InvalidFields: e.InvalidFields,
 	}
 }
 
@@ -113,7 +139,7 @@ func (e signUpResponseError) Error() *httpResponseError {
 func (a *authRouter) signUp(requestContext *gin.Context) (interface{}, *httpResponseError) {
 	logger := a.logger.Named("signUp").WithContext(requestContext)
 
-	var body signUpRequestBody
+	body := signUpRequestBody{&service.SignUpOptions{}}
 	err := requestContext.ShouldBindJSON(&body)
 	if err != nil {
 		logger.Info("failed to parse request body", "err", err)
@@ -126,7 +152,7 @@ func (a *authRouter) signUp(requestContext *gin.Context) (interface{}, *httpResp
 	if err != nil {
 		if errs.IsExpected(err) {
 			logger.Info(err.Error())
-			return nil, signUpResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+			return nil, signUpResponseError{Message: err.Error(), Code: errs.GetCode(err), InvalidFields: errs.GetFields(err)}.Error()
 		}
 		logger.Error("failed to create and return user", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to create and return user", Details: err}
@@ -136,3 +162,298 @@ func (a *authRouter) signUp(requestContext *gin.Context) (interface{}, *httpResp
 	logger.Info("user created and returned")
 	return &signUpResponseBody{createdUser}, nil
 }
+
+type refreshTokenRequestBody struct {
+	*service.RefreshTokenOptions
+} // @name refreshTokenRequestBody
+
+type refreshTokenResponseBody struct {
+	*service.RefreshTokenOutput
+} // @name refreshTokenResponseBody
+
+type refreshTokenResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"invalid_refresh_token,refresh_token_expired,refresh_token_reused"`
+} // @name refreshTokenResponseError
+
+func (e refreshTokenResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           RefreshToken
+// @Summary      Rotates refresh token and returns new token pair.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body refreshTokenRequestBody true "data"
+// @Success      200 {object} refreshTokenResponseBody
+// @Failure      422,500 {object} refreshTokenResponseError
+// @Router       /auth/refresh [POST]
+func (a *authRouter) refreshToken(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("refreshToken").WithContext(requestContext)
+
+	body := refreshTokenRequestBody{&service.RefreshTokenOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger.Debug("parsed request body")
+
+	refreshed, err := a.services.AuthService.RefreshToken(requestContext, body.RefreshTokenOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, refreshTokenResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to refresh token", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to refresh token", Details: err}
+	}
+
+	logger.Info("successfully refreshed token")
+	return &refreshTokenResponseBody{refreshed}, nil
+}
+
+type signOutResponseBody struct{} // @name signOutResponseBody
+
+type signOutResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"invalid_token"`
+} // @name signOutResponseError
+
+func (e signOutResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           SignOut
+// @Summary      Revokes current access token and its refresh tokens.
+// @Produce      application/json
+// @Success      200 {object} signOutResponseBody
+// @Failure      422,500 {object} signOutResponseError
+// @Router       /auth/sign-out [POST]
+func (a *authRouter) signOut(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("signOut").WithContext(requestContext)
+
+	accessToken, err := getAuthToken(requestContext.GetHeader("Authorization"))
+	if err != nil {
+		logger.Info(err.Error())
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: err.Error()}
+	}
+
+	err = a.services.AuthService.SignOut(requestContext, &service.SignOutOptions{AccessToken: accessToken})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, signOutResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to sign out", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to sign out", Details: err}
+	}
+
+	logger.Info("successfully signed out")
+	return &signOutResponseBody{}, nil
+}
+
+// @id           SignOutAll
+// @Summary      Revokes all sessions of current user.
+// @Produce      application/json
+// @Success      200 {object} signOutResponseBody
+// @Failure      422,500 {object} signOutResponseError
+// @Router       /auth/sign-out-all [POST]
+func (a *authRouter) signOutAll(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("signOutAll").WithContext(requestContext)
+
+	accessToken, err := getAuthToken(requestContext.GetHeader("Authorization"))
+	if err != nil {
+		logger.Info(err.Error())
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: err.Error()}
+	}
+
+	err = a.services.AuthService.SignOutAll(requestContext, &service.SignOutAllOptions{AccessToken: accessToken})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, signOutResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to sign out from all sessions", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to sign out from all sessions", Details: err}
+	}
+
+	logger.Info("successfully signed out from all sessions")
+	return &signOutResponseBody{}, nil
+}
+
+type forgotPasswordRequestBody struct {
+	*service.ForgotPasswordOptions
+} // @name forgotPasswordRequestBody
+
+type forgotPasswordResponseBody struct{} // @name forgotPasswordResponseBody
+
+// @id           ForgotPassword
+// @Summary      Sends password reset link if the email is registered.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body forgotPasswordRequestBody true "data"
+// @Success      200 {object} forgotPasswordResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /auth/password/forgot [POST]
+func (a *authRouter) forgotPassword(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("forgotPassword").WithContext(requestContext)
+
+	body := forgotPasswordRequestBody{&service.ForgotPasswordOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.ForgotPassword(requestContext, body.ForgotPasswordOptions)
+	if err != nil {
+		logger.Error("failed to handle forgot password", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to handle forgot password", Details: err}
+	}
+
+	logger.Info("successfully handled forgot password")
+	return &forgotPasswordResponseBody{}, nil
+}
+
+type resetPasswordRequestBody struct {
+	*service.ResetPasswordOptions
+} // @name resetPasswordRequestBody
+
+type resetPasswordResponseBody struct{} // @name resetPasswordResponseBody
+
+type resetPasswordResponseError struct {
+	Message       string            `json:"message"`
+	Code          string            `json:"code" enums:"invalid_reset_token,invalid_password"`
+	InvalidFields []errs.FieldError `json:"invalidFields,omitempty"`
+} // @name resetPasswordResponseError
+
+func (e resetPasswordResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:          ErrorTypeClient,
+		Message:       e.Message,
+		Code:          e.Code,
+		InvalidFields: e.InvalidFields,
+	}
+}
+
+// @id           ResetPassword
+// @Summary      Sets new password using reset token and signs out all sessions.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body resetPasswordRequestBody true "data"
+// @Success      200 {object} resetPasswordResponseBody
+// @Failure      422,500 {object} resetPasswordResponseError
+// @Router       /auth/password/reset [POST]
+func (a *authRouter) resetPassword(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("resetPassword").WithContext(requestContext)
+
+	body := resetPasswordRequestBody{&service.ResetPasswordOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.ResetPassword(requestContext, body.ResetPasswordOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, resetPasswordResponseError{Message: err.Error(), Code: errs.GetCode(err), InvalidFields: errs.GetFields(err)}.Error()
+		}
+		logger.Error("failed to reset password", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to reset password", Details: err}
+	}
+
+	logger.Info("successfully reset password")
+	return &resetPasswordResponseBody{}, nil
+}
+
+type verifyEmailRequestBody struct {
+	*service.VerifyEmailOptions
+} // @name verifyEmailRequestBody
+
+type verifyEmailResponseBody struct{} // @name verifyEmailResponseBody
+
+type verifyEmailResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"invalid_verification_token,user_not_found,email_already_verified"`
+} // @name verifyEmailResponseError
+
+func (e verifyEmailResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           VerifyEmail
+// @Summary      Confirms user email via verification token.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body verifyEmailRequestBody true "data"
+// @Success      200 {object} verifyEmailResponseBody
+// @Failure      422,500 {object} verifyEmailResponseError
+// @Router       /auth/verify-email [POST]
+func (a *authRouter) verifyEmail(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("verifyEmail").WithContext(requestContext)
+
+	body := verifyEmailRequestBody{&service.VerifyEmailOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.VerifyEmail(requestContext, body.VerifyEmailOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, verifyEmailResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to verify email", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to verify email", Details: err}
+	}
+
+	logger.Info("successfully verified email")
+	return &verifyEmailResponseBody{}, nil
+}
+
+// @id           ResendVerificationEmail
+// @Summary      Sends new email verification link to current user.
+// @Produce      application/json
+// @Success      200 {object} verifyEmailResponseBody
+// @Failure      422,500 {object} verifyEmailResponseError
+// @Router       /auth/verify-email/resend [POST]
+func (a *authRouter) resendVerificationEmail(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("resendVerificationEmail").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	err := a.services.AuthService.ResendVerificationEmail(requestContext, &service.ResendVerificationEmailOptions{UserId: userId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, verifyEmailResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to resend verification email", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to resend verification email", Details: err}
+	}
+
+	logger.Info("successfully resent verification email")
+	return &verifyEmailResponseBody{}, nil
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeSignUpService struct {
+	service.AuthService
+	err error
+}
+
+func (f *fakeSignUpService) SignUp(ctx context.Context, options *service.SignUpOptions) (*service.SignUpOutput, error) {
+	return nil, f.err
+}
+
+func TestSignUpReturnsInvalidFields(t *testing.T) {
+	fields := []errs.FieldError{
+		{Field: "password", Code: "password_too_short", Message: "password must be at least 10 characters long"},
+		{Field: "password", Code: "password_breached", Message: "password has appeared in a data breach, choose another one"},
+	}
+	engine, routerOptions := newTestRouter(&fakeSignUpService{err: service.ErrPasswordPolicyViolation.WithFields(fields...)})
+	router := &authRouter{RouterContext{logger: routerOptions.Logger, services: routerOptions.Services}}
+	routerOptions.Handler.POST("/sign-up", wrapHandler(routerOptions, router.signUp))
+
+	request := httptest.NewRequest(http.MethodPost, "/sign-up", strings.NewReader(`{"email":"user@droplet.local","username":"user","password":"short"}`))
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnprocessableEntity)
+	}
+
+	var response signUpResponseError
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Code != service.ErrPasswordPolicyViolation.Code || !reflect.DeepEqual(response.InvalidFields, fields) {
+		t.Fatalf("response = %+v, want code %s with fields %+v", response, service.ErrPasswordPolicyViolation.Code, fields)
+	}
+}
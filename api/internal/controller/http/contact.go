@@ -0,0 +1,331 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type contactRouter struct {
+	RouterContext
+}
+
+func setupContactRoutes(options RouterOptions) {
+	router := &contactRouter{
+		RouterContext{
+			logger:   options.Logger,
+			services: options.Services,
+			config:   options.Config,
+		},
+	}
+
+	routerGroup := options.Handler.Group("/contacts")
+	{
+		routerGroup.GET("", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listContacts))
+		routerGroup.DELETE("/:contactId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.removeContact))
+		routerGroup.GET("/requests", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listContactRequests))
+		routerGroup.POST("/requests", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.sendContactRequest))
+		routerGroup.POST("/requests/:contactId/respond", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.respondContactRequest))
+		routerGroup.GET("/blocked", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listBlockedUsers))
+		routerGroup.POST("/blocked", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.blockUser))
+		routerGroup.DELETE("/blocked/:userId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.unblockUser))
+	}
+}
+
+type contactResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"user_not_found,cannot_contact_self,contact_already_exists,contact_request_declined,user_blocked,contact_not_found,invalid_action,invalid_direction,block_not_found"`
+} // @name contactResponseError
+
+func (e contactResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+type listContactsResponseBody struct {
+	*service.ListContactsOutput
+} // @name listContactsResponseBody
+
+// @id           ListContacts
+// @Summary      Lists accepted contacts of the user.
+// @Produce      application/json
+// @Success      200 {object} listContactsResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /contacts [GET]
+func (a *contactRouter) listContacts(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listContacts").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	contacts, err := a.services.ContactService.ListContacts(requestContext, &service.ListContactsOptions{UserId: userId})
+	if err != nil {
+		logger.Error("failed to list contacts", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list contacts", Details: err}
+	}
+
+	logger.Info("successfully listed contacts")
+	return &listContactsResponseBody{contacts}, nil
+}
+
+// @id           ListContactRequests
+// @Summary      Lists contact requests sent to or by the user.
+// @Produce      application/json
+// @Param        direction query string true "Direction" Enums(incoming, outgoing)
+// @Success      200 {object} listContactsResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/requests [GET]
+func (a *contactRouter) listContactRequests(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listContactRequests").WithContext(requestContext)
+
+	options := &service.ListContactRequestsOptions{
+		UserId:    requestContext.GetString("userId"),
+		Direction: requestContext.Query("direction"),
+	}
+	logger = logger.With("options", options)
+
+	requests, err := a.services.ContactService.ListContactRequests(requestContext, options)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to list contact requests", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list contact requests", Details: err}
+	}
+
+	logger.Info("successfully listed contact requests")
+	return &listContactsResponseBody{requests}, nil
+}
+
+type sendContactRequestRequestBody struct {
+	*service.SendContactRequestOptions
+} // @name sendContactRequestRequestBody
+
+type contactResponseBody struct {
+	*service.ContactOutput
+} // @name contactResponseBody
+
+// @id           SendContactRequest
+// @Summary      Asks another user to become a contact.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body sendContactRequestRequestBody true "data"
+// @Success      200 {object} contactResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/requests [POST]
+func (a *contactRouter) sendContactRequest(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("sendContactRequest").WithContext(requestContext)
+
+	body := sendContactRequestRequestBody{&service.SendContactRequestOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	contact, err := a.services.ContactService.SendContactRequest(requestContext, body.SendContactRequestOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to send contact request", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to send contact request", Details: err}
+	}
+
+	logger.Info("successfully sent contact request")
+	return &contactResponseBody{contact}, nil
+}
+
+type respondContactRequestRequestBody struct {
+	*service.RespondContactRequestOptions
+} // @name respondContactRequestRequestBody
+
+// @id           RespondContactRequest
+// @Summary      Accepts, declines or ignores incoming contact request.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        contactId path string true "Contact request ID"
+// @Param        fields body respondContactRequestRequestBody true "data"
+// @Success      200 {object} contactResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/requests/{contactId}/respond [POST]
+func (a *contactRouter) respondContactRequest(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("respondContactRequest").WithContext(requestContext)
+
+	contactId := requestContext.Param("contactId")
+	if _, ok := uuid.Parse(contactId); ok != nil {
+		logger.Info("invalid contact id parameter", "param", contactId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid contact id parameter"}
+	}
+
+	body := respondContactRequestRequestBody{&service.RespondContactRequestOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.ContactId = contactId
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	contact, err := a.services.ContactService.RespondContactRequest(requestContext, body.RespondContactRequestOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to respond contact request", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to respond contact request", Details: err}
+	}
+
+	logger.Info("successfully responded contact request")
+	return &contactResponseBody{contact}, nil
+}
+
+type removeContactResponseBody struct{} // @name removeContactResponseBody
+
+// @id           RemoveContact
+// @Summary      Removes contact or cancels contact request sent by the user.
+// @Produce      application/json
+// @Param        contactId path string true "Contact ID"
+// @Success      200 {object} removeContactResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/{contactId} [DELETE]
+func (a *contactRouter) removeContact(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("removeContact").WithContext(requestContext)
+
+	contactId := requestContext.Param("contactId")
+	if _, ok := uuid.Parse(contactId); ok != nil {
+		logger.Info("invalid contact id parameter", "param", contactId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid contact id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "contactId", contactId)
+
+	err := a.services.ContactService.RemoveContact(requestContext, &service.RemoveContactOptions{UserId: userId, ContactId: contactId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to remove contact", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to remove contact", Details: err}
+	}
+
+	logger.Info("successfully removed contact")
+	return &removeContactResponseBody{}, nil
+}
+
+type listBlockedUsersResponseBody struct {
+	*service.ListBlockedUsersOutput
+} // @name listBlockedUsersResponseBody
+
+// @id           ListBlockedUsers
+// @Summary      Lists users blocked by the user.
+// @Produce      application/json
+// @Success      200 {object} listBlockedUsersResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /contacts/blocked [GET]
+func (a *contactRouter) listBlockedUsers(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listBlockedUsers").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	users, err := a.services.ContactService.ListBlockedUsers(requestContext, &service.ListBlockedUsersOptions{UserId: userId})
+	if err != nil {
+		logger.Error("failed to list blocked users", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list blocked users", Details: err}
+	}
+
+	logger.Info("successfully listed blocked users")
+	return &listBlockedUsersResponseBody{users}, nil
+}
+
+type blockUserRequestBody struct {
+	*service.BlockUserOptions
+} // @name blockUserRequestBody
+
+type blockUserResponseBody struct {
+	*service.BlockedUserOutput
+} // @name blockUserResponseBody
+
+// @id           BlockUser
+// @Summary      Blocks user, removes contact with them and drops their transfers.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body blockUserRequestBody true "data"
+// @Success      200 {object} blockUserResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/blocked [POST]
+func (a *contactRouter) blockUser(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("blockUser").WithContext(requestContext)
+
+	body := blockUserRequestBody{&service.BlockUserOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	blocked, err := a.services.ContactService.BlockUser(requestContext, body.BlockUserOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to block user", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to block user", Details: err}
+	}
+
+	logger.Info("successfully blocked user")
+	return &blockUserResponseBody{blocked}, nil
+}
+
+type unblockUserResponseBody struct{} // @name unblockUserResponseBody
+
+// @id           UnblockUser
+// @Summary      Unblocks user.
+// @Produce      application/json
+// @Param        userId path string true "Blocked user ID"
+// @Success      200 {object} unblockUserResponseBody
+// @Failure      422,500 {object} contactResponseError
+// @Router       /contacts/blocked/{userId} [DELETE]
+func (a *contactRouter) unblockUser(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("unblockUser").WithContext(requestContext)
+
+	blockedUserId := requestContext.Param("userId")
+	if _, ok := uuid.Parse(blockedUserId); ok != nil {
+		logger.Info("invalid user id parameter", "param", blockedUserId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "blockedUserId", blockedUserId)
+
+	err := a.services.ContactService.UnblockUser(requestContext, &service.UnblockUserOptions{UserId: userId, BlockedUserId: blockedUserId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, contactResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to unblock user", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to unblock user", Details: err}
+	}
+
+	logger.Info("successfully unblocked user")
+	return &unblockUserResponseBody{}, nil
+}
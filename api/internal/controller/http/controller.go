@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/DataDog/gostackparse"
 	"github.com/atlant1da-404/droplet/config"
+	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/logger"
 	"github.com/gin-contrib/zap"
@@ -53,7 +54,20 @@ func New(options *Options) {
 	{
 		setupAuthRoutes(routerOptions)
 		setupAccountRoutes(routerOptions)
+		setupNodeRoutes(routerOptions)
+		setupAdminRoutes(routerOptions)
+		setupUserRoutes(routerOptions)
+		setupContactRoutes(routerOptions)
+		setupGroupRoutes(routerOptions)
 	}
+
+	// well-known routes are served from the root, as other services look them up there
+	setupWellKnownRoutes(RouterOptions{
+		Handler:  options.Handler.Group("/.well-known"),
+		Services: options.Services,
+		Logger:   options.Logger.Named("HTTPController"),
+		Config:   options.Config,
+	})
 }
 
 // requestIDMiddleware is used to add request id to gin context.
@@ -76,8 +90,10 @@ func corsMiddleware(c *gin.Context) {
 }
 
 // httpResponseError provides a base error type for all errors.
+// Status overrides default status of client errors.
 type httpResponseError struct {
 	Type          httpErrType `json:"-"`
+	Status        int         `json:"-"`
 	Message       string      `json:"message"`
 	Code          string      `json:"code,omitempty"`
 	Details       interface{} `json:"details,omitempty"`
@@ -141,7 +157,11 @@ func wrapHandler(options RouterOptions, handler func(c *gin.Context) (interface{
 
 			} else {
 				lgr.Info("client error")
-				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, err)
+				status := http.StatusUnprocessableEntity
+				if err.Status != 0 {
+					status = err.Status
+				}
+				c.AbortWithStatusJSON(status, err)
 			}
 			return
 		}
@@ -150,7 +170,9 @@ func wrapHandler(options RouterOptions, handler func(c *gin.Context) (interface{
 	}
 }
 
-func authMiddleware(routerOptions RouterOptions) gin.HandlerFunc {
+// authMiddleware authenticates request by access token. Personal access tokens are accepted
+// only when route lists scopes and the token has been granted all of them.
+func authMiddleware(routerOptions RouterOptions, scopes ...string) gin.HandlerFunc {
 	logger := routerOptions.Logger.Named("authMiddleware")
 	return wrapHandler(routerOptions, func(requestContext *gin.Context) (interface{}, *httpResponseError) {
 		tokenStringRaw := requestContext.GetHeader("Authorization")
@@ -168,14 +190,85 @@ func authMiddleware(routerOptions RouterOptions) gin.HandlerFunc {
 			return nil, &httpResponseError{Type: ErrorTypeClient, Message: err.Error(), Details: "invalid_token"}
 		}
 
+		if claims.PersonalAccessTokenId != "" && !hasScopes(claims.Scopes, scopes) {
+			logger.Info("personal access token has insufficient scope", "tokenId", claims.PersonalAccessTokenId, "required", scopes)
+			return nil, &httpResponseError{Type: ErrorTypeClient, Message: "insufficient token scope", Code: "insufficient_scope", Details: scopes}
+		}
+
 		requestContext.Set("userId", claims.UserId)
 		requestContext.Set("username", claims.Username)
+		requestContext.Set("role", requestRole(claims.Role))
+		requestContext.Set("sessionId", claims.SessionId)
 
 		logger.Info("successfully authenticated user")
 		return nil, nil
 	})
 }
 
+// requireRole allows request only when authenticated user has one of the roles.
+// It has to be placed after authMiddleware.
+func requireRole(routerOptions RouterOptions, roles ...string) gin.HandlerFunc {
+	logger := routerOptions.Logger.Named("requireRole")
+	return wrapHandler(routerOptions, func(requestContext *gin.Context) (interface{}, *httpResponseError) {
+		role := requestContext.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				return nil, nil
+			}
+		}
+
+		logger.Info("role is not allowed", "userId", requestContext.GetString("userId"), "role", role, "allowed", roles)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Status: http.StatusForbidden, Message: "access denied", Code: "access_denied"}
+	})
+}
+
+// requirePermission allows request only when role of authenticated user is granted the permission.
+// It has to be placed after authMiddleware.
+func requirePermission(routerOptions RouterOptions, permission string) gin.HandlerFunc {
+	logger := routerOptions.Logger.Named("requirePermission")
+	return wrapHandler(routerOptions, func(requestContext *gin.Context) (interface{}, *httpResponseError) {
+		role := requestContext.GetString("role")
+		if entity.HasPermission(role, permission) {
+			return nil, nil
+		}
+
+		logger.Info("permission is not granted", "userId", requestContext.GetString("userId"), "role", role, "permission", permission)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Status: http.StatusForbidden, Message: "access denied", Code: "access_denied"}
+	})
+}
+
+// requestRole treats tokens issued before roles were introduced as regular user ones.
+func requestRole(role string) string {
+	if role == "" {
+		return entity.RoleUser
+	}
+
+	return role
+}
+
+// hasScopes reports whether granted scopes contain every required one.
+// Route which requires no scopes is not available for personal access tokens.
+func hasScopes(granted, required []string) bool {
+	if len(required) == 0 {
+		return false
+	}
+
+	for _, scope := range required {
+		found := false
+		for _, grantedScope := range granted {
+			if grantedScope == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
 func getAuthToken(rawToken string) (string, error) {
 	if rawToken == "" {
 		return "", fmt.Errorf("empty auth token")
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAuthService resolves access tokens from the map; other methods are not faked.
+type fakeAuthService struct {
+	service.AuthService
+	tokens map[string]*service.VerifyTokenOutput
+}
+
+func (f *fakeAuthService) VerifyToken(ctx context.Context, options *service.VerifyTokenOptions) (*service.VerifyTokenOutput, error) {
+	claims, ok := f.tokens[options.AccessToken]
+	if !ok {
+		return nil, service.ErrVerifyTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// newTestRouter returns router options with given auth service and engine serving their group.
+func newTestRouter(authService service.AuthService) (*gin.Engine, RouterOptions) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+
+	return engine, RouterOptions{
+		Handler:  engine.Group("/"),
+		Logger:   logger.New("fatal"),
+		Services: service.Services{AuthService: authService},
+	}
+}
+
+func serveTestRequest(engine *gin.Engine, path, token string) int {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+
+	return recorder.Code
+}
+
+func TestAuthMiddlewareScopes(t *testing.T) {
+	authService := &fakeAuthService{tokens: map[string]*service.VerifyTokenOutput{
+		"jwt": {UserId: "user"},
+		"pat-read": {
+			UserId:                "user",
+			PersonalAccessTokenId: "read",
+			Scopes:                []string{entity.ScopeAccountRead},
+		},
+		"pat-read-write": {
+			UserId:                "user",
+			PersonalAccessTokenId: "read-write",
+			Scopes:                []string{entity.ScopeAccountRead, entity.ScopeAccountWrite},
+		},
+	}}
+	engine, routerOptions := newTestRouter(authService)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	routerOptions.Handler.GET("/unscoped", authMiddleware(routerOptions), ok)
+	routerOptions.Handler.GET("/read", authMiddleware(routerOptions, entity.ScopeAccountRead), ok)
+	routerOptions.Handler.GET("/write", authMiddleware(routerOptions, entity.ScopeAccountRead, entity.ScopeAccountWrite), ok)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "jwt on unscoped route", path: "/unscoped", token: "jwt", wantStatus: http.StatusOK},
+		{name: "jwt on scoped route", path: "/write", token: "jwt", wantStatus: http.StatusOK},
+		{name: "pat on unscoped route", path: "/unscoped", token: "pat-read-write", wantStatus: http.StatusUnprocessableEntity},
+		{name: "pat with scope", path: "/read", token: "pat-read", wantStatus: http.StatusOK},
+		{name: "pat missing scope", path: "/write", token: "pat-read", wantStatus: http.StatusUnprocessableEntity},
+		{name: "pat with all scopes", path: "/write", token: "pat-read-write", wantStatus: http.StatusOK},
+		{name: "unknown token", path: "/read", token: "unknown", wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing token", path: "/read", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := serveTestRequest(engine, tt.path, tt.token)
+			if status != tt.wantStatus {
+				t.Fatalf("GET %s with %q status = %d, want %d", tt.path, tt.token, status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRoleMiddleware(t *testing.T) {
+	authService := &fakeAuthService{tokens: map[string]*service.VerifyTokenOutput{
+		"admin":   {UserId: "admin", Role: entity.RoleAdmin},
+		"support": {UserId: "support", Role: entity.RoleSupport},
+		"user":    {UserId: "user", Role: entity.RoleUser},
+		// tokens issued before roles were introduced carry no role
+		"legacy": {UserId: "legacy"},
+	}}
+	engine, routerOptions := newTestRouter(authService)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	routerOptions.Handler.GET("/admin", authMiddleware(routerOptions), requireRole(routerOptions, entity.RoleAdmin), ok)
+	routerOptions.Handler.GET("/users", authMiddleware(routerOptions), requirePermission(routerOptions, entity.PermissionUsersRead), ok)
+	routerOptions.Handler.GET("/own", authMiddleware(routerOptions), requireRole(routerOptions, entity.RoleUser), ok)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "admin role allowed", path: "/admin", token: "admin", wantStatus: http.StatusOK},
+		{name: "support role denied", path: "/admin", token: "support", wantStatus: http.StatusForbidden},
+		{name: "user role denied", path: "/admin", token: "user", wantStatus: http.StatusForbidden},
+		{name: "permission granted", path: "/users", token: "support", wantStatus: http.StatusOK},
+		{name: "permission not granted", path: "/users", token: "user", wantStatus: http.StatusForbidden},
+		{name: "empty role denied admin", path: "/admin", token: "legacy", wantStatus: http.StatusForbidden},
+		{name: "empty role defaults to user", path: "/own", token: "legacy", wantStatus: http.StatusOK},
+		{name: "unauthenticated", path: "/admin", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := serveTestRequest(engine, tt.path, tt.token)
+			if status != tt.wantStatus {
+				t.Fatalf("GET %s with %q status = %d, want %d", tt.path, tt.token, status, tt.wantStatus)
+			}
+		})
+	}
+}
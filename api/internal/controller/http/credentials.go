@@ -0,0 +1,147 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+)
+
+type credentialsResponseError struct {
+	Message       string            `json:"message"`
+	Code          string            `json:"code" enums:"user_not_found,wrong_password,invalid_password,invalid_email,same_email,email_already_taken,invalid_email_change_token"`
+	InvalidFields []errs.FieldError `json:"invalidFields,omitempty"`
+} // @name credentialsResponseError
+
+func (e credentialsResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:          ErrorTypeClient,
+		Message:       e.Message,
+		Code:          e.Code,
+		InvalidFields: e.InvalidFields,
+	}
+}
+
+type changePasswordRequestBody struct {
+	*service.ChangePasswordOptions
+} // @name changePasswordRequestBody
+
+type changePasswordResponseBody struct{} // @name changePasswordResponseBody
+
+// @id           ChangePassword
+// @Summary      Changes password and signs out other sessions.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body changePasswordRequestBody true "data"
+// @Success      200 {object} changePasswordResponseBody
+// @Failure      422,500 {object} credentialsResponseError
+// @Router       /auth/password/change [POST]
+func (a *authRouter) changePassword(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("changePassword").WithContext(requestContext)
+
+	body := changePasswordRequestBody{&service.ChangePasswordOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.SessionId = requestContext.GetString("sessionId")
+	body.ClientIP = requestContext.ClientIP()
+	logger = logger.With("userId", body.UserId)
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.ChangePassword(requestContext, body.ChangePasswordOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, credentialsResponseError{Message: err.Error(), Code: errs.GetCode(err), InvalidFields: errs.GetFields(err)}.Error()
+		}
+		logger.Error("failed to change password", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to change password", Details: err}
+	}
+
+	logger.Info("successfully changed password")
+	return &changePasswordResponseBody{}, nil
+}
+
+type changeEmailRequestBody struct {
+	*service.ChangeEmailOptions
+} // @name changeEmailRequestBody
+
+type changeEmailResponseBody struct{} // @name changeEmailResponseBody
+
+// @id           ChangeEmail
+// @Summary      Sends confirmation link to the new email.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body changeEmailRequestBody true "data"
+// @Success      200 {object} changeEmailResponseBody
+// @Failure      422,500 {object} credentialsResponseError
+// @Router       /auth/email/change [POST]
+func (a *authRouter) changeEmail(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("changeEmail").WithContext(requestContext)
+
+	body := changeEmailRequestBody{&service.ChangeEmailOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.SessionId = requestContext.GetString("sessionId")
+	body.ClientIP = requestContext.ClientIP()
+	logger = logger.With("userId", body.UserId, "newEmail", body.NewEmail)
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.ChangeEmail(requestContext, body.ChangeEmailOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, credentialsResponseError{Message: err.Error(), Code: errs.GetCode(err), InvalidFields: errs.GetFields(err)}.Error()
+		}
+		logger.Error("failed to change email", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to change email", Details: err}
+	}
+
+	logger.Info("successfully requested email change")
+	return &changeEmailResponseBody{}, nil
+}
+
+type confirmEmailChangeRequestBody struct {
+	*service.ConfirmEmailChangeOptions
+} // @name confirmEmailChangeRequestBody
+
+type confirmEmailChangeResponseBody struct{} // @name confirmEmailChangeResponseBody
+
+// @id           ConfirmEmailChange
+// @Summary      Applies email change using confirmation token and signs out other sessions.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body confirmEmailChangeRequestBody true "data"
+// @Success      200 {object} confirmEmailChangeResponseBody
+// @Failure      422,500 {object} credentialsResponseError
+// @Router       /auth/email/confirm [POST]
+func (a *authRouter) confirmEmailChange(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("confirmEmailChange").WithContext(requestContext)
+
+	body := confirmEmailChangeRequestBody{&service.ConfirmEmailChangeOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.ConfirmEmailChange(requestContext, body.ConfirmEmailChangeOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, credentialsResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to confirm email change", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to confirm email change", Details: err}
+	}
+
+	logger.Info("successfully changed email")
+	return &confirmEmailChangeResponseBody{}, nil
+}
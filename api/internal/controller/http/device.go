@@ -0,0 +1,315 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type deviceResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"account_not_found,invalid_device_name,invalid_device_os,invalid_mac_address,device_already_registered,device_limit_reached,device_not_found,device_inactive,invalid_device_key,device_key_signature_required,invalid_device_key_signature"`
+} // @name deviceResponseError
+
+func (e deviceResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+type listDevicesResponseBody struct {
+	Devices []deviceDTO `json:"devices"`
+} // @name listDevicesResponseBody
+
+// @id           ListDevices
+// @Summary      Lists devices of the account.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Success      200 {object} listDevicesResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices [GET]
+func (a *accountRouter) listDevices(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listDevices").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "userId", userId)
+	logger.Debug("parsed params")
+
+	devices, err := a.services.AccountService.ListDevices(requestContext, &service.ListDevicesOptions{
+		AccountId: accountId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to list devices", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list devices", Details: err}
+	}
+
+	logger.Info("successfully listed devices")
+	return &listDevicesResponseBody{Devices: newDeviceDTOs(devices.Devices)}, nil
+}
+
+type registerDeviceRequestBody struct {
+	*service.RegisterDeviceOptions
+} // @name registerDeviceRequestBody
+
+type deviceResponseBody struct {
+	*deviceDTO
+} // @name deviceResponseBody
+
+// @id           RegisterDevice
+// @Summary      Registers device to the account.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        fields body registerDeviceRequestBody true "data"
+// @Success      200 {object} deviceResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices [POST]
+func (a *accountRouter) registerDevice(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("registerDevice").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+
+	body := registerDeviceRequestBody{&service.RegisterDeviceOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.AccountId = accountId
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	device, err := a.services.AccountService.RegisterDevice(requestContext, body.RegisterDeviceOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to register device", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to register device", Details: err}
+	}
+
+	logger.Info("successfully registered device")
+	return &deviceResponseBody{newDeviceDTO(device)}, nil
+}
+
+type updateDeviceRequestBody struct {
+	*service.UpdateDeviceOptions
+} // @name updateDeviceRequestBody
+
+// @id           UpdateDevice
+// @Summary      Renames, activates or deactivates device.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        deviceId path string true "Device ID"
+// @Param        fields body updateDeviceRequestBody true "data"
+// @Success      200 {object} deviceResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices/{deviceId} [PATCH]
+func (a *accountRouter) updateDevice(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("updateDevice").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	deviceId := requestContext.Param("deviceId")
+	if _, ok := uuid.Parse(deviceId); ok != nil {
+		logger.Info("invalid device id parameter", "param", deviceId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid device id parameter"}
+	}
+
+	body := updateDeviceRequestBody{&service.UpdateDeviceOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.AccountId = accountId
+	body.DeviceId = deviceId
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	device, err := a.services.AccountService.UpdateDevice(requestContext, body.UpdateDeviceOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to update device", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to update device", Details: err}
+	}
+
+	logger.Info("successfully updated device")
+	return &deviceResponseBody{newDeviceDTO(device)}, nil
+}
+
+type removeDeviceResponseBody struct{} // @name removeDeviceResponseBody
+
+// @id           RemoveDevice
+// @Summary      Removes device from the account.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        deviceId path string true "Device ID"
+// @Success      200 {object} removeDeviceResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices/{deviceId} [DELETE]
+func (a *accountRouter) removeDevice(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("removeDevice").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	deviceId := requestContext.Param("deviceId")
+	if _, ok := uuid.Parse(deviceId); ok != nil {
+		logger.Info("invalid device id parameter", "param", deviceId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid device id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "deviceId", deviceId, "userId", userId)
+	logger.Debug("parsed params")
+
+	err := a.services.AccountService.RemoveDevice(requestContext, &service.RemoveDeviceOptions{
+		AccountId: accountId,
+		DeviceId:  deviceId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to remove device", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to remove device", Details: err}
+	}
+
+	logger.Info("successfully removed device")
+	return &removeDeviceResponseBody{}, nil
+}
+
+type deviceHeartbeatResponseBody struct {
+	*service.DeviceHeartbeatOutput
+} // @name deviceHeartbeatResponseBody
+
+// @id           DeviceHeartbeat
+// @Summary      Marks device online, devices call it periodically within returned ttl.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        deviceId path string true "Device ID"
+// @Success      200 {object} deviceHeartbeatResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices/{deviceId}/heartbeat [POST]
+func (a *accountRouter) deviceHeartbeat(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("deviceHeartbeat").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	deviceId := requestContext.Param("deviceId")
+	if _, ok := uuid.Parse(deviceId); ok != nil {
+		logger.Info("invalid device id parameter", "param", deviceId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid device id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "deviceId", deviceId, "userId", userId)
+
+	heartbeat, err := a.services.AccountService.DeviceHeartbeat(requestContext, &service.DeviceHeartbeatOptions{
+		AccountId: accountId,
+		DeviceId:  deviceId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to record device heartbeat", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to record device heartbeat", Details: err}
+	}
+
+	logger.Debug("successfully recorded device heartbeat")
+	return &deviceHeartbeatResponseBody{heartbeat}, nil
+}
+
+type registerDeviceKeyRequestBody struct {
+	*service.RegisterDeviceKeyOptions
+} // @name registerDeviceKeyRequestBody
+
+type registerDeviceKeyResponseBody struct {
+	*service.PublicDeviceKey
+} // @name registerDeviceKeyResponseBody
+
+// @id           RegisterDeviceKey
+// @Summary      Publishes X25519 and Ed25519 public keys of the device, keys must be signed by trusted device of the account once the account has one.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        deviceId path string true "Device ID"
+// @Param        fields body registerDeviceKeyRequestBody true "data"
+// @Success      200 {object} registerDeviceKeyResponseBody
+// @Failure      422,500 {object} deviceResponseError
+// @Router       /account/{id}/devices/{deviceId}/key [PUT]
+func (a *accountRouter) registerDeviceKey(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("registerDeviceKey").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	deviceId := requestContext.Param("deviceId")
+	if _, ok := uuid.Parse(deviceId); ok != nil {
+		logger.Info("invalid device id parameter", "param", deviceId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid device id parameter"}
+	}
+
+	body := registerDeviceKeyRequestBody{&service.RegisterDeviceKeyOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.AccountId = accountId
+	body.DeviceId = deviceId
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "deviceId", deviceId, "userId", body.UserId)
+	logger.Debug("parsed request body")
+
+	key, err := a.services.AccountService.RegisterDeviceKey(requestContext, body.RegisterDeviceKeyOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, deviceResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to register device key", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to register device key", Details: err}
+	}
+
+	logger.Info("successfully registered device key")
+	return &registerDeviceKeyResponseBody{key}, nil
+}
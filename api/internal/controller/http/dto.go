@@ -0,0 +1,158 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"time"
+)
+
+// Entities are never serialized by handlers directly, they are mapped to DTOs below,
+// so new columns and foreign keys do not leak into the API by accident.
+
+// accountDTO - represents account as its owner sees it. Field names match paths accepted by account patches.
+type accountDTO struct {
+	Id              string              `json:"id"`
+	UserId          string              `json:"userId"`
+	Version         int                 `json:"version"`
+	AccountDevices  []deviceDTO         `json:"accountDevices"`
+	AccountSettings *accountSettingsDTO `json:"accountSettings"`
+} // @name accountDTO
+
+type accountSettingsDTO struct {
+	Version             int                     `json:"version"`
+	Language            string                  `json:"language"`
+	Theme               string                  `json:"theme" enums:"system,light,dark"`
+	Discoverability     string                  `json:"discoverability" enums:"everyone,contacts_of_contacts,nobody"`
+	SharePresence       bool                    `json:"sharePresence"`
+	Notifications       notificationSettingsDTO `json:"notifications"`
+	AutoAccept          string                  `json:"autoAccept" enums:"never,contacts,own_devices"`
+	DownloadAction      string                  `json:"downloadAction" enums:"ask,save,open"`
+	MaxIncomingFileSize int64                   `json:"maxIncomingFileSize"`
+	NonContactTransfers string                  `json:"nonContactTransfers" enums:"allow,hold,reject"`
+} // @name accountSettingsDTO
+
+type notificationSettingsDTO struct {
+	IncomingTransfers  bool `json:"incomingTransfers"`
+	CompletedTransfers bool `json:"completedTransfers"`
+	SecurityEvents     bool `json:"securityEvents"`
+} // @name notificationSettingsDTO
+
+type deviceDTO struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	OS         string     `json:"os" enums:"windows,macos,linux,android,ios"`
+	MacAddress string     `json:"macAddress"`
+	Active     bool       `json:"active"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+} // @name deviceDTO
+
+type personalAccessTokenDTO struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+} // @name personalAccessTokenDTO
+
+type transferDTO struct {
+	Id                 string    `json:"id"`
+	SenderEmail        string    `json:"senderEmail"`
+	SenderMacAddress   string    `json:"senderMacAddress"`
+	ReceiverEmail      string    `json:"receiverEmail"`
+	ReceiverMacAddress string    `json:"receiverMacAddress"`
+	Status             string    `json:"status" enums:"delivered,held,declined,blocked"`
+	PayloadId          string    `json:"payloadId"`
+	GroupId            *string   `json:"groupId"`
+	CreatedAt          time.Time `json:"createdAt"`
+} // @name transferDTO
+
+func newAccountDTO(account *entity.Account) *accountDTO {
+	dto := &accountDTO{
+		Id:             account.Id,
+		UserId:         account.UserId,
+		Version:        account.Version,
+		AccountDevices: newDeviceDTOs(account.AccountDevices),
+	}
+
+	if account.AccountSettings != nil {
+		settings := account.AccountSettings
+		dto.AccountSettings = &accountSettingsDTO{
+			Version:         settings.Version,
+			Language:        settings.Language,
+			Theme:           settings.Theme,
+			Discoverability: settings.Discoverability,
+			SharePresence:   settings.SharePresence,
+			Notifications: notificationSettingsDTO{
+				IncomingTransfers:  settings.Notifications.IncomingTransfers,
+				CompletedTransfers: settings.Notifications.CompletedTransfers,
+				SecurityEvents:     settings.Notifications.SecurityEvents,
+			},
+			AutoAccept:          settings.AutoAccept,
+			DownloadAction:      settings.DownloadAction,
+			MaxIncomingFileSize: settings.MaxIncomingFileSize,
+			NonContactTransfers: settings.NonContactTransfers,
+		}
+	}
+
+	return dto
+}
+
+func newDeviceDTO(device *entity.AccountDevices) *deviceDTO {
+	return &deviceDTO{
+		Id:         device.Id,
+		Name:       device.Name,
+		OS:         device.OS,
+		MacAddress: device.MacAddress,
+		Active:     device.Active,
+		Online:     device.Online,
+		LastSeenAt: device.LastSeenAt,
+	}
+}
+
+// newDeviceDTOs never returns nil, so empty list is serialized as [] and not as null.
+func newDeviceDTOs(devices []entity.AccountDevices) []deviceDTO {
+	dtos := make([]deviceDTO, 0, len(devices))
+	for i := range devices {
+		dtos = append(dtos, *newDeviceDTO(&devices[i]))
+	}
+
+	return dtos
+}
+
+func newPersonalAccessTokenDTOs(tokens []entity.PersonalAccessToken) []personalAccessTokenDTO {
+	dtos := make([]personalAccessTokenDTO, 0, len(tokens))
+	for _, token := range tokens {
+		dtos = append(dtos, personalAccessTokenDTO{
+			Id:         token.Id,
+			Name:       token.Name,
+			Scopes:     token.Scopes,
+			ExpiresAt:  token.ExpiresAt,
+			LastUsedAt: token.LastUsedAt,
+			RevokedAt:  token.RevokedAt,
+			CreatedAt:  token.CreatedAt,
+		})
+	}
+
+	return dtos
+}
+
+func newTransferDTOs(nodes []entity.Node) []transferDTO {
+	dtos := make([]transferDTO, 0, len(nodes))
+	for _, node := range nodes {
+		dtos = append(dtos, transferDTO{
+			Id:                 node.Id,
+			SenderEmail:        node.SenderEmail,
+			SenderMacAddress:   node.SenderMacAddress,
+			ReceiverEmail:      node.ReceiverEmail,
+			ReceiverMacAddress: node.ReceiverMacAddress,
+			Status:             node.Status,
+			PayloadId:          node.PayloadId,
+			GroupId:            node.GroupId,
+			CreatedAt:          node.CreatedAt,
+		})
+	}
+
+	return dtos
+}
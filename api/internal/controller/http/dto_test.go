@@ -0,0 +1,378 @@
+package http
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// _bodies lists every request and response body of the package. Handlers may only bind requests
+// into and return values of these types, which are checked by reflection not to refer to entities.
+var _bodies = []interface{}{
+	addGroupMemberRequestBody{},
+	blockUserRequestBody{},
+	blockUserResponseBody{},
+	cancelAccountDeletionRequestBody{},
+	cancelAccountDeletionResponseBody{},
+	changeEmailRequestBody{},
+	changeEmailResponseBody{},
+	changePasswordRequestBody{},
+	changePasswordResponseBody{},
+	confirmEmailChangeRequestBody{},
+	confirmEmailChangeResponseBody{},
+	confirmTOTPRequestBody{},
+	confirmTOTPResponseBody{},
+	contactResponseBody{},
+	createAccountRequestBody{},
+	createAccountResponseBody{},
+	createGroupRequestBody{},
+	createGroupTransferResponseBody{},
+	createNodeRequestBody{},
+	createNodeResponseBody{},
+	createPersonalAccessTokenRequestBody{},
+	createPersonalAccessTokenResponseBody{},
+	dataExportResponseBody{},
+	deleteAccountResponseBody{},
+	deleteGroupResponseBody{},
+	deleteUserResponseBody{},
+	deviceHeartbeatResponseBody{},
+	deviceResponseBody{},
+	disableTOTPRequestBody{},
+	disableTOTPResponseBody{},
+	enrollTOTPResponseBody{},
+	forgotPasswordRequestBody{},
+	forgotPasswordResponseBody{},
+	getAccountResponseBody{},
+	getJSONWebKeySetResponseBody{},
+	getPresenceResponseBody{},
+	groupResponseBody{},
+	listBlockedUsersResponseBody{},
+	listContactsResponseBody{},
+	listDevicesResponseBody{},
+	listGroupsResponseBody{},
+	listHeldTransfersResponseBody{},
+	listPersonalAccessTokensResponseBody{},
+	listUserDevicesResponseBody{},
+	listUsersResponseBody{},
+	listUserTransfersResponseBody{},
+	lookupDeviceKeysResponseBody{},
+	refreshTokenRequestBody{},
+	refreshTokenResponseBody{},
+	regenerateRecoveryCodesRequestBody{},
+	regenerateRecoveryCodesResponseBody{},
+	registerDeviceKeyRequestBody{},
+	registerDeviceKeyResponseBody{},
+	registerDeviceRequestBody{},
+	removeContactResponseBody{},
+	removeDeviceResponseBody{},
+	removeGroupMemberResponseBody{},
+	renameGroupRequestBody{},
+	resetPasswordRequestBody{},
+	resetPasswordResponseBody{},
+	respondContactRequestRequestBody{},
+	reviewHeldTransferResponseBody{},
+	revokePersonalAccessTokenResponseBody{},
+	searchUsersResponseBody{},
+	sendContactRequestRequestBody{},
+	setUserDisabledResponseBody{},
+	setUserRoleRequestBody{},
+	setUserRoleResponseBody{},
+	signInMFARequestBody{},
+	signInRequestBody{},
+	signInResponseBody{},
+	signOutResponseBody{},
+	signUpRequestBody{},
+	signUpResponseBody{},
+	startOIDCLoginResponseBody{},
+	unblockUserResponseBody{},
+	unlockAccountRequestBody{},
+	unlockAccountResponseBody{},
+	updateAccountRequestBody{},
+	updateAccountResponseBody{},
+	updateDeviceRequestBody{},
+	updateGroupMemberRequestBody{},
+	verifyEmailRequestBody{},
+	verifyEmailResponseBody{},
+}
+
+const _entityPackage = "github.com/atlant1da-404/droplet/internal/entity"
+
+func TestBodiesDoNotExposeEntities(t *testing.T) {
+	bodies := make(map[string]reflect.Type, len(_bodies))
+	for _, body := range _bodies {
+		bodyType := reflect.TypeOf(body)
+		bodies[bodyType.Name()] = bodyType
+
+		for _, path := range findEntityFields(bodyType, bodyType.Name(), map[reflect.Type]bool{}) {
+			t.Errorf("%s refers to entity, map it to DTO instead", path)
+		}
+	}
+
+	handlers := parseHandlers(t)
+	if len(handlers) == 0 {
+		t.Fatal("no handlers found")
+	}
+
+	for _, handler := range handlers {
+		for _, name := range handler.returned {
+			if _, ok := bodies[name]; !ok {
+				t.Errorf("%s returns %s, return one of _bodies instead", handler.name, name)
+			}
+		}
+
+		for _, name := range handler.bound {
+			bodyType, ok := bodies[name]
+			if !ok {
+				t.Errorf("%s binds request into %s, bind one of _bodies instead", handler.name, name)
+				continue
+			}
+			for _, path := range findBoundOwnerFields(bodyType, name) {
+				t.Errorf("%s binds %s from request, owner must come from the token", handler.name, path)
+			}
+		}
+	}
+
+	for _, name := range declaredBodies(t) {
+		if _, ok := bodies[name]; !ok {
+			t.Errorf("%s is not listed in _bodies", name)
+		}
+	}
+}
+
+// findEntityFields returns paths of fields whose type refers to entity package,
+// including fields of embedded service options and outputs.
+func findEntityFields(fieldType reflect.Type, path string, visited map[reflect.Type]bool) []string {
+	if fieldType.PkgPath() == _entityPackage {
+		return []string{path}
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return findEntityFields(fieldType.Elem(), path, visited)
+	case reflect.Map:
+		return append(
+			findEntityFields(fieldType.Key(), path, visited),
+			findEntityFields(fieldType.Elem(), path, visited)...,
+		)
+	case reflect.Struct:
+		if visited[fieldType] {
+			return nil
+		}
+		visited[fieldType] = true
+
+		var paths []string
+		for i := 0; i < fieldType.NumField(); i++ {
+			field := fieldType.Field(i)
+			paths = append(paths, findEntityFields(field.Type, path+"."+field.Name, visited)...)
+		}
+		return paths
+	}
+
+	return nil
+}
+
+// findBoundOwnerFields returns paths of UserId fields which JSON decoding would fill from request body.
+func findBoundOwnerFields(bodyType reflect.Type, path string) []string {
+	for bodyType.Kind() == reflect.Pointer {
+		bodyType = bodyType.Elem()
+	}
+	if bodyType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var paths []string
+	for i := 0; i < bodyType.NumField(); i++ {
+		field := bodyType.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous {
+			paths = append(paths, findBoundOwnerFields(field.Type, path)...)
+			continue
+		}
+		if field.Name == "UserId" {
+			paths = append(paths, path+"."+field.Name)
+		}
+	}
+
+	return paths
+}
+
+// parsedHandler - represents types a handler binds requests into and returns, as found in its source.
+type parsedHandler struct {
+	name     string
+	bound    []string
+	returned []string
+}
+
+// parseHandlers finds functions of the package with handler signature and types of values they
+// bind requests into and return. Expressions whose type cannot be told from the source are reported
+// by their position, so they fail the check as well.
+func parseHandlers(t *testing.T) []parsedHandler {
+	t.Helper()
+
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, ".", isSourceFile, 0)
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+
+	var handlers []parsedHandler
+	for _, file := range packages["http"].Files {
+		for _, declaration := range file.Decls {
+			function, ok := declaration.(*ast.FuncDecl)
+			if !ok || function.Body == nil || !isHandlerSignature(function.Type) {
+				continue
+			}
+
+			handler := parsedHandler{name: function.Name.Name}
+			locals := make(map[string]string)
+			ast.Inspect(function.Body, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.FuncLit:
+					// nested functions return their own values
+					return false
+				case *ast.AssignStmt:
+					if node.Tok == token.DEFINE && len(node.Lhs) == len(node.Rhs) {
+						for i, lhs := range node.Lhs {
+							if ident, ok := lhs.(*ast.Ident); ok {
+								locals[ident.Name] = expressionType(fileSet, node.Rhs[i], nil)
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for i, ident := range node.Names {
+						if node.Type != nil {
+							locals[ident.Name] = expressionType(fileSet, node.Type, nil)
+						} else if i < len(node.Values) {
+							locals[ident.Name] = expressionType(fileSet, node.Values[i], nil)
+						}
+					}
+				case *ast.CallExpr:
+					selector, ok := node.Fun.(*ast.SelectorExpr)
+					if ok && isBindMethod(selector.Sel.Name) && len(node.Args) == 1 {
+						handler.bound = append(handler.bound, expressionType(fileSet, rootValue(node.Args[0]), locals))
+					}
+				case *ast.ReturnStmt:
+					if len(node.Results) == 2 && !isNil(node.Results[0]) {
+						handler.returned = append(handler.returned, expressionType(fileSet, node.Results[0], locals))
+					}
+				}
+				return true
+			})
+			handlers = append(handlers, handler)
+		}
+	}
+
+	return handlers
+}
+
+func isSourceFile(info fs.FileInfo) bool {
+	return !strings.HasSuffix(info.Name(), "_test.go")
+}
+
+// isHandlerSignature reports whether function returns (interface{}, *httpResponseError) as wrapHandler expects.
+func isHandlerSignature(function *ast.FuncType) bool {
+	if function.Results == nil || len(function.Results.List) != 2 {
+		return false
+	}
+
+	_, returnsInterface := function.Results.List[0].Type.(*ast.InterfaceType)
+	errorType, ok := function.Results.List[1].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	errorIdent, ok := errorType.X.(*ast.Ident)
+
+	return returnsInterface && ok && errorIdent.Name == "httpResponseError"
+}
+
+func isBindMethod(name string) bool {
+	return strings.HasPrefix(name, "ShouldBind") || strings.HasPrefix(name, "Bind")
+}
+
+func isNil(expression ast.Expr) bool {
+	ident, ok := expression.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// rootValue returns variable whose field or address is given, as binding into a field of the body
+// is covered by checking the body itself.
+func rootValue(expression ast.Expr) ast.Expr {
+	for {
+		switch value := expression.(type) {
+		case *ast.UnaryExpr:
+			expression = value.X
+		case *ast.SelectorExpr:
+			expression = value.X
+		case *ast.ParenExpr:
+			expression = value.X
+		default:
+			return expression
+		}
+	}
+}
+
+// expressionType returns name of the type of a composite literal, its address or a local variable
+// holding one, types of other packages are qualified. Anything else is returned as its position, which is never a body name.
+func expressionType(fileSet *token.FileSet, expression ast.Expr, locals map[string]string) string {
+	switch value := expression.(type) {
+	case *ast.UnaryExpr:
+		if value.Op == token.AND {
+			return expressionType(fileSet, value.X, locals)
+		}
+	case *ast.ParenExpr:
+		return expressionType(fileSet, value.X, locals)
+	case *ast.StarExpr:
+		return expressionType(fileSet, value.X, locals)
+	case *ast.CompositeLit:
+		return expressionType(fileSet, value.Type, nil)
+	case *ast.SelectorExpr:
+		if pkg, ok := value.X.(*ast.Ident); ok && locals == nil {
+			return pkg.Name + "." + value.Sel.Name
+		}
+	case *ast.Ident:
+		if locals == nil {
+			return value.Name
+		}
+		if name, ok := locals[value.Name]; ok {
+			return name
+		}
+	}
+
+	position := fileSet.Position(expression.Pos())
+	return fmt.Sprintf("expression at %s:%d", position.Filename, position.Line)
+}
+
+// declaredBodies returns names of request and response bodies declared in the package sources.
+func declaredBodies(t *testing.T) []string {
+	t.Helper()
+
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", isSourceFile, 0)
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+
+	var names []string
+	for _, file := range packages["http"].Files {
+		for _, declaration := range file.Decls {
+			genDecl, ok := declaration.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				if strings.HasSuffix(name, "RequestBody") || strings.HasSuffix(name, "ResponseBody") {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type dataExportResponseBody struct {
+	*service.DataExportOutput
+} // @name dataExportResponseBody
+
+type dataExportResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"account_not_found,export_in_progress,export_not_found,invalid_export_token"`
+} // @name dataExportResponseError
+
+func (e dataExportResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           RequestDataExport
+// @Summary      Queues export of all user data, download link is emailed once the archive is ready.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Success      200 {object} dataExportResponseBody
+// @Failure      422,500 {object} dataExportResponseError
+// @Router       /account/{id}/export [POST]
+func (a *accountRouter) requestDataExport(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("requestDataExport").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "userId", userId)
+	logger.Debug("parsed params")
+
+	export, err := a.services.AccountService.RequestDataExport(requestContext, &service.RequestDataExportOptions{
+		AccountId: accountId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, dataExportResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to request data export", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to request data export", Details: err}
+	}
+
+	logger.Info("successfully requested data export")
+	return &dataExportResponseBody{export}, nil
+}
+
+// @id           GetDataExport
+// @Summary      Gets status of user data export.
+// @Produce      application/json
+// @Param        id path string true "Account ID"
+// @Param        exportId path string true "Export ID"
+// @Success      200 {object} dataExportResponseBody
+// @Failure      422,500 {object} dataExportResponseError
+// @Router       /account/{id}/export/{exportId} [GET]
+func (a *accountRouter) getDataExport(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("getDataExport").WithContext(requestContext)
+
+	accountId := requestContext.Param("id")
+	if _, ok := uuid.Parse(accountId); ok != nil {
+		logger.Info("invalid account id parameter", "param", accountId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid account id parameter"}
+	}
+	exportId := requestContext.Param("exportId")
+	if _, ok := uuid.Parse(exportId); ok != nil {
+		logger.Info("invalid export id parameter", "param", exportId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid export id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("accountId", accountId, "exportId", exportId, "userId", userId)
+	logger.Debug("parsed params")
+
+	export, err := a.services.AccountService.GetDataExport(requestContext, &service.GetDataExportOptions{
+		AccountId: accountId,
+		ExportId:  exportId,
+		UserId:    userId,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, dataExportResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to get data export", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to get data export", Details: err}
+	}
+
+	logger.Info("successfully got data export")
+	return &dataExportResponseBody{export}, nil
+}
+
+// @id           DownloadDataExport
+// @Summary      Downloads zip archive with user data via emailed link.
+// @Produce      application/zip
+// @Param        token query string true "download token"
+// @Success      200 {file} file
+// @Failure      422,500 {object} dataExportResponseError
+// @Router       /account/export/download [GET]
+func (a *accountRouter) downloadDataExport(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("downloadDataExport").WithContext(requestContext)
+
+	token := requestContext.Query("token")
+	if token == "" {
+		logger.Info("missing token parameter")
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "missing token parameter"}
+	}
+
+	download, err := a.services.AccountService.DownloadDataExport(requestContext, &service.DownloadDataExportOptions{Token: token})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, dataExportResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to download data export", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to download data export", Details: err}
+	}
+
+	// cors middleware presets json content type, so it is replaced before writing the archive
+	requestContext.Header("Content-Type", "application/zip")
+	requestContext.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", download.FileName))
+	requestContext.Data(http.StatusOK, "application/zip", download.Archive)
+
+	logger.Info("successfully downloaded data export")
+	return nil, nil
+}
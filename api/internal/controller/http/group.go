@@ -0,0 +1,428 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type groupRouter struct {
+	RouterContext
+}
+
+func setupGroupRoutes(options RouterOptions) {
+	router := &groupRouter{
+		RouterContext{
+			logger:   options.Logger,
+			services: options.Services,
+			config:   options.Config,
+		},
+	}
+
+	routerGroup := options.Handler.Group("/groups")
+	{
+		routerGroup.GET("", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listGroups))
+		routerGroup.POST("", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.createGroup))
+		routerGroup.GET("/:groupId", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.getGroup))
+		routerGroup.PATCH("/:groupId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.renameGroup))
+		routerGroup.DELETE("/:groupId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.deleteGroup))
+		routerGroup.POST("/:groupId/members", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.addGroupMember))
+		routerGroup.PATCH("/:groupId/members/:userId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.updateGroupMember))
+		routerGroup.DELETE("/:groupId/members/:userId", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.removeGroupMember))
+		routerGroup.POST("/:groupId/transfers", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.createGroupTransfer))
+	}
+}
+
+type groupResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"group_not_found,group_owner_required,invalid_group_name,invalid_group_role,user_not_found,member_already_exists,member_not_found,group_full,last_group_owner"`
+} // @name groupResponseError
+
+func (e groupResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+type groupResponseBody struct {
+	*service.GroupOutput
+} // @name groupResponseBody
+
+type listGroupsResponseBody struct {
+	*service.ListGroupsOutput
+} // @name listGroupsResponseBody
+
+// @id           ListGroups
+// @Summary      Lists groups the user is member of.
+// @Produce      application/json
+// @Success      200 {object} listGroupsResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /groups [GET]
+func (a *groupRouter) listGroups(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listGroups").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	groups, err := a.services.GroupService.ListGroups(requestContext, &service.ListGroupsOptions{UserId: userId})
+	if err != nil {
+		logger.Error("failed to list groups", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list groups", Details: err}
+	}
+
+	logger.Info("successfully listed groups")
+	return &listGroupsResponseBody{groups}, nil
+}
+
+type createGroupRequestBody struct {
+	*service.CreateGroupOptions
+} // @name createGroupRequestBody
+
+// @id           CreateGroup
+// @Summary      Creates group, the user becomes its owner.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body createGroupRequestBody true "data"
+// @Success      200 {object} groupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups [POST]
+func (a *groupRouter) createGroup(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("createGroup").WithContext(requestContext)
+
+	body := createGroupRequestBody{&service.CreateGroupOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	group, err := a.services.GroupService.CreateGroup(requestContext, body.CreateGroupOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to create group", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to create group", Details: err}
+	}
+
+	logger.Info("successfully created group")
+	return &groupResponseBody{group}, nil
+}
+
+// @id           GetGroup
+// @Summary      Gets group with its members.
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Success      200 {object} groupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId} [GET]
+func (a *groupRouter) getGroup(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("getGroup").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "groupId", groupId)
+
+	group, err := a.services.GroupService.GetGroup(requestContext, &service.GetGroupOptions{UserId: userId, GroupId: groupId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to get group", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to get group", Details: err}
+	}
+
+	logger.Info("successfully got group")
+	return &groupResponseBody{group}, nil
+}
+
+type renameGroupRequestBody struct {
+	*service.RenameGroupOptions
+} // @name renameGroupRequestBody
+
+// @id           RenameGroup
+// @Summary      Renames group.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Param        fields body renameGroupRequestBody true "data"
+// @Success      200 {object} groupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId} [PATCH]
+func (a *groupRouter) renameGroup(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("renameGroup").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+
+	body := renameGroupRequestBody{&service.RenameGroupOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.GroupId = groupId
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	group, err := a.services.GroupService.RenameGroup(requestContext, body.RenameGroupOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to rename group", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to rename group", Details: err}
+	}
+
+	logger.Info("successfully renamed group")
+	return &groupResponseBody{group}, nil
+}
+
+type deleteGroupResponseBody struct{} // @name deleteGroupResponseBody
+
+// @id           DeleteGroup
+// @Summary      Deletes group, transfers sent to the group are kept.
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Success      200 {object} deleteGroupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId} [DELETE]
+func (a *groupRouter) deleteGroup(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("deleteGroup").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "groupId", groupId)
+
+	err := a.services.GroupService.DeleteGroup(requestContext, &service.DeleteGroupOptions{UserId: userId, GroupId: groupId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to delete group", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to delete group", Details: err}
+	}
+
+	logger.Info("successfully deleted group")
+	return &deleteGroupResponseBody{}, nil
+}
+
+type addGroupMemberRequestBody struct {
+	*service.AddGroupMemberOptions
+} // @name addGroupMemberRequestBody
+
+// @id           AddGroupMember
+// @Summary      Adds contact of the owner to the group.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Param        fields body addGroupMemberRequestBody true "data"
+// @Success      200 {object} groupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId}/members [POST]
+func (a *groupRouter) addGroupMember(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("addGroupMember").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+
+	body := addGroupMemberRequestBody{&service.AddGroupMemberOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.GroupId = groupId
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	group, err := a.services.GroupService.AddGroupMember(requestContext, body.AddGroupMemberOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to add group member", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to add group member", Details: err}
+	}
+
+	logger.Info("successfully added group member")
+	return &groupResponseBody{group}, nil
+}
+
+type updateGroupMemberRequestBody struct {
+	*service.UpdateGroupMemberOptions
+} // @name updateGroupMemberRequestBody
+
+// @id           UpdateGroupMember
+// @Summary      Changes role of the group member.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Param        userId path string true "Member user ID"
+// @Param        fields body updateGroupMemberRequestBody true "data"
+// @Success      200 {object} groupResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId}/members/{userId} [PATCH]
+func (a *groupRouter) updateGroupMember(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("updateGroupMember").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+	memberUserId := requestContext.Param("userId")
+	if _, ok := uuid.Parse(memberUserId); ok != nil {
+		logger.Info("invalid user id parameter", "param", memberUserId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+
+	body := updateGroupMemberRequestBody{&service.UpdateGroupMemberOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	body.GroupId = groupId
+	body.MemberUserId = memberUserId
+	logger = logger.With("body", body)
+	logger.Debug("parsed request body")
+
+	group, err := a.services.GroupService.UpdateGroupMember(requestContext, body.UpdateGroupMemberOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to update group member", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to update group member", Details: err}
+	}
+
+	logger.Info("successfully updated group member")
+	return &groupResponseBody{group}, nil
+}
+
+type removeGroupMemberResponseBody struct{} // @name removeGroupMemberResponseBody
+
+// @id           RemoveGroupMember
+// @Summary      Removes member from the group, members may remove themselves to leave the group.
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Param        userId path string true "Member user ID"
+// @Success      200 {object} removeGroupMemberResponseBody
+// @Failure      422,500 {object} groupResponseError
+// @Router       /groups/{groupId}/members/{userId} [DELETE]
+func (a *groupRouter) removeGroupMember(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("removeGroupMember").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+	memberUserId := requestContext.Param("userId")
+	if _, ok := uuid.Parse(memberUserId); ok != nil {
+		logger.Info("invalid user id parameter", "param", memberUserId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid user id parameter"}
+	}
+	options := &service.RemoveGroupMemberOptions{
+		UserId:       requestContext.GetString("userId"),
+		GroupId:      groupId,
+		MemberUserId: memberUserId,
+	}
+	logger = logger.With("options", options)
+
+	err := a.services.GroupService.RemoveGroupMember(requestContext, options)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, groupResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to remove group member", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to remove group member", Details: err}
+	}
+
+	logger.Info("successfully removed group member")
+	return &removeGroupMemberResponseBody{}, nil
+}
+
+type createGroupTransferResponseBody struct {
+	*service.CreateGroupNodeOutput
+} // @name createGroupTransferResponseBody
+
+type createGroupTransferResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"user_not_found,email_not_verified,group_not_found,group_has_no_receivers"`
+} // @name createGroupTransferResponseError
+
+func (e createGroupTransferResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           CreateGroupTransfer
+// @Summary      Sends transfer to every other member of the group, receivers share the same payload.
+// @Produce      application/json
+// @Param        groupId path string true "Group ID"
+// @Success      200 {object} createGroupTransferResponseBody
+// @Failure      422,500 {object} createGroupTransferResponseError
+// @Router       /groups/{groupId}/transfers [POST]
+func (a *groupRouter) createGroupTransfer(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("createGroupTransfer").WithContext(requestContext)
+
+	groupId := requestContext.Param("groupId")
+	if _, ok := uuid.Parse(groupId); ok != nil {
+		logger.Info("invalid group id parameter", "param", groupId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid group id parameter"}
+	}
+	options := &service.CreateGroupNodeOptions{
+		SenderUserId: requestContext.GetString("userId"),
+		GroupId:      groupId,
+	}
+	logger = logger.With("options", options)
+
+	transfers, err := a.services.NodeService.CreateGroupNode(requestContext, options)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, createGroupTransferResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to create group transfer", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to create group transfer", Details: err}
+	}
+
+	logger.Info("successfully created group transfer")
+	return &createGroupTransferResponseBody{transfers}, nil
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+)
+
+type mfaResponseError struct {
+	Message string            `json:"message"`
+	Code    string            `json:"code" enums:"invalid_mfa_token,invalid_mfa_code,user_not_found,mfa_already_enabled,mfa_not_enrolled,mfa_not_enabled,account_locked"`
+	Details map[string]string `json:"details,omitempty"`
+} // @name mfaResponseError
+
+func (e mfaResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+	}
+}
+
+type signInMFARequestBody struct {
+	*service.SignInMFAOptions
+} // @name signInMFARequestBody
+
+// @id           SignInMFA
+// @Summary      Exchanges mfa challenge token and second factor code for tokens.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body signInMFARequestBody true "data"
+// @Success      200 {object} signInResponseBody
+// @Failure      422,500 {object} mfaResponseError
+// @Router       /auth/sign-in/mfa [POST]
+func (a *authRouter) signInMFA(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("signInMFA").WithContext(requestContext)
+
+	body := signInMFARequestBody{&service.SignInMFAOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.ClientIP = requestContext.ClientIP()
+	logger = logger.With("clientIP", body.ClientIP)
+	logger.Debug("parsed request body")
+
+	signed, err := a.services.AuthService.SignInMFA(requestContext, body.SignInMFAOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			details := errs.GetDetails(err)
+			if retryAfter, ok := details["retryAfter"]; ok {
+				requestContext.Header("Retry-After", retryAfter)
+			}
+			return nil, mfaResponseError{Message: err.Error(), Code: errs.GetCode(err), Details: details}.Error()
+		}
+		logger.Error("failed to sign in with second factor", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to sign in with second factor", Details: err}
+	}
+
+	logger.Info("successfully signed in with second factor")
+	return &signInResponseBody{signed}, nil
+}
+
+type enrollTOTPResponseBody struct {
+	*service.EnrollTOTPOutput
+} // @name enrollTOTPResponseBody
+
+// @id           EnrollTOTP
+// @Summary      Starts 2FA enrollment and returns TOTP secret.
+// @Produce      application/json
+// @Success      200 {object} enrollTOTPResponseBody
+// @Failure      422,500 {object} mfaResponseError
+// @Router       /auth/2fa/enroll [POST]
+func (a *authRouter) enrollTOTP(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("enrollTOTP").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	enrolled, err := a.services.AuthService.EnrollTOTP(requestContext, &service.EnrollTOTPOptions{UserId: userId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, mfaResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to enroll totp", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to enroll totp", Details: err}
+	}
+
+	logger.Info("successfully enrolled totp")
+	return &enrollTOTPResponseBody{enrolled}, nil
+}
+
+type confirmTOTPRequestBody struct {
+	*service.ConfirmTOTPOptions
+} // @name confirmTOTPRequestBody
+
+type confirmTOTPResponseBody struct {
+	*service.ConfirmTOTPOutput
+} // @name confirmTOTPResponseBody
+
+// @id           ConfirmTOTP
+// @Summary      Enables 2FA and returns recovery codes.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body confirmTOTPRequestBody true "data"
+// @Success      200 {object} confirmTOTPResponseBody
+// @Failure      422,500 {object} mfaResponseError
+// @Router       /auth/2fa/confirm [POST]
+func (a *authRouter) confirmTOTP(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("confirmTOTP").WithContext(requestContext)
+
+	body := confirmTOTPRequestBody{&service.ConfirmTOTPOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("userId", body.UserId)
+	logger.Debug("parsed request body")
+
+	confirmed, err := a.services.AuthService.ConfirmTOTP(requestContext, body.ConfirmTOTPOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, mfaResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to confirm totp", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to confirm totp", Details: err}
+	}
+
+	logger.Info("successfully confirmed totp")
+	return &confirmTOTPResponseBody{confirmed}, nil
+}
+
+type disableTOTPRequestBody struct {
+	*service.DisableTOTPOptions
+} // @name disableTOTPRequestBody
+
+type disableTOTPResponseBody struct{} // @name disableTOTPResponseBody
+
+// @id           DisableTOTP
+// @Summary      Disables 2FA.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body disableTOTPRequestBody true "data"
+// @Success      200 {object} disableTOTPResponseBody
+// @Failure      422,500 {object} mfaResponseError
+// @Router       /auth/2fa/disable [POST]
+func (a *authRouter) disableTOTP(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("disableTOTP").WithContext(requestContext)
+
+	body := disableTOTPRequestBody{&service.DisableTOTPOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("userId", body.UserId)
+	logger.Debug("parsed request body")
+
+	err = a.services.AuthService.DisableTOTP(requestContext, body.DisableTOTPOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, mfaResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to disable totp", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to disable totp", Details: err}
+	}
+
+	logger.Info("successfully disabled totp")
+	return &disableTOTPResponseBody{}, nil
+}
+
+type regenerateRecoveryCodesRequestBody struct {
+	*service.RegenerateRecoveryCodesOptions
+} // @name regenerateRecoveryCodesRequestBody
+
+type regenerateRecoveryCodesResponseBody struct {
+	*service.RegenerateRecoveryCodesOutput
+} // @name regenerateRecoveryCodesResponseBody
+
+// @id           RegenerateRecoveryCodes
+// @Summary      Replaces 2FA recovery codes.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body regenerateRecoveryCodesRequestBody true "data"
+// @Success      200 {object} regenerateRecoveryCodesResponseBody
+// @Failure      422,500 {object} mfaResponseError
+// @Router       /auth/2fa/recovery-codes [POST]
+func (a *authRouter) regenerateRecoveryCodes(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("regenerateRecoveryCodes").WithContext(requestContext)
+
+	body := regenerateRecoveryCodesRequestBody{&service.RegenerateRecoveryCodesOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("userId", body.UserId)
+	logger.Debug("parsed request body")
+
+	regenerated, err := a.services.AuthService.RegenerateRecoveryCodes(requestContext, body.RegenerateRecoveryCodesOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, mfaResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to regenerate recovery codes", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to regenerate recovery codes", Details: err}
+	}
+
+	logger.Info("successfully regenerated recovery codes")
+	return &regenerateRecoveryCodesResponseBody{regenerated}, nil
+}
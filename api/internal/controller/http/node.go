@@ -1,9 +1,11 @@
 package http
 
 import (
+	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/errs"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type nodeRouter struct {
@@ -11,7 +13,7 @@ type nodeRouter struct {
 }
 
 func setupNodeRoutes(options RouterOptions) {
-	router := &authRouter{
+	router := &nodeRouter{
 		RouterContext{
 			logger:   options.Logger,
 			services: options.Services,
@@ -21,7 +23,10 @@ func setupNodeRoutes(options RouterOptions) {
 
 	routerGroup := options.Handler.Group("/node")
 	{
-		routerGroup.POST("", wrapHandler(options, router.signIn))
+		routerGroup.POST("", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.createNode))
+		routerGroup.GET("/held", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listHeldTransfers))
+		routerGroup.POST("/:nodeId/approve", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.approveHeldTransfer))
+		routerGroup.POST("/:nodeId/decline", authMiddleware(options, entity.ScopeTransfersWrite), wrapHandler(options, router.declineHeldTransfer))
 	}
 }
 
@@ -35,7 +40,7 @@ type createNodeResponseBody struct {
 
 type createNodeResponseError struct {
 	Message string `json:"message"`
-	Code    string `json:"code" enums:""`
+	Code    string `json:"code" enums:"user_not_found,email_not_verified,transfer_rejected,receiver_required,receiver_not_found"`
 } // @name createNodeResponseError
 
 func (e createNodeResponseError) Error() *httpResponseError {
@@ -55,14 +60,15 @@ func (e createNodeResponseError) Error() *httpResponseError {
 // @Failure      422,500 {object} createNodeResponseError
 // @Router       /node [POST]
 func (a *nodeRouter) createNode(requestContext *gin.Context) (interface{}, *httpResponseError) {
-	logger := a.logger.Named("signIn").WithContext(requestContext)
+	logger := a.logger.Named("createNode").WithContext(requestContext)
 
-	var body createNodeRequestBody
+	body := createNodeRequestBody{&service.CreateNodeOptions{}}
 	err := requestContext.ShouldBindJSON(&body)
 	if err != nil {
 		logger.Info("failed to parse request body", "err", err)
 		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
 	}
+	body.SenderUserId = requestContext.GetString("userId")
 	logger = logger.With("body", body)
 	logger.Debug("parsed request body")
 
@@ -79,3 +85,95 @@ func (a *nodeRouter) createNode(requestContext *gin.Context) (interface{}, *http
 	logger.Info("successfully created a node")
 	return &createNodeResponseBody{nodeID}, nil
 }
+
+type listHeldTransfersResponseBody struct {
+	*service.ListHeldTransfersOutput
+} // @name listHeldTransfersResponseBody
+
+// @id           ListHeldTransfers
+// @Summary      Lists transfers from non-contacts which wait for the user approval.
+// @Produce      application/json
+// @Success      200 {object} listHeldTransfersResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /node/held [GET]
+func (a *nodeRouter) listHeldTransfers(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listHeldTransfers").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	transfers, err := a.services.NodeService.ListHeldTransfers(requestContext, &service.ListHeldTransfersOptions{UserId: userId})
+	if err != nil {
+		logger.Error("failed to list held transfers", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list held transfers", Details: err}
+	}
+
+	logger.Info("successfully listed held transfers")
+	return &listHeldTransfersResponseBody{transfers}, nil
+}
+
+type reviewHeldTransferResponseBody struct{} // @name reviewHeldTransferResponseBody
+
+type reviewHeldTransferResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"transfer_not_found"`
+} // @name reviewHeldTransferResponseError
+
+func (e reviewHeldTransferResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+// @id           ApproveHeldTransfer
+// @Summary      Approves held transfer, so it is delivered.
+// @Produce      application/json
+// @Param        nodeId path string true "Node ID"
+// @Success      200 {object} reviewHeldTransferResponseBody
+// @Failure      422,500 {object} reviewHeldTransferResponseError
+// @Router       /node/{nodeId}/approve [POST]
+func (a *nodeRouter) approveHeldTransfer(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	return a.reviewHeldTransfer(requestContext, true)
+}
+
+// @id           DeclineHeldTransfer
+// @Summary      Declines held transfer.
+// @Produce      application/json
+// @Param        nodeId path string true "Node ID"
+// @Success      200 {object} reviewHeldTransferResponseBody
+// @Failure      422,500 {object} reviewHeldTransferResponseError
+// @Router       /node/{nodeId}/decline [POST]
+func (a *nodeRouter) declineHeldTransfer(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	return a.reviewHeldTransfer(requestContext, false)
+}
+
+func (a *nodeRouter) reviewHeldTransfer(requestContext *gin.Context, approve bool) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("reviewHeldTransfer").WithContext(requestContext)
+
+	nodeId := requestContext.Param("nodeId")
+	if _, ok := uuid.Parse(nodeId); ok != nil {
+		logger.Info("invalid node id parameter", "param", nodeId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid node id parameter"}
+	}
+	options := &service.ReviewHeldTransferOptions{
+		UserId:  requestContext.GetString("userId"),
+		NodeId:  nodeId,
+		Approve: approve,
+	}
+	logger = logger.With("options", options)
+
+	err := a.services.NodeService.ReviewHeldTransfer(requestContext, options)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, reviewHeldTransferResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to review held transfer", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to review held transfer", Details: err}
+	}
+
+	logger.Info("successfully reviewed held transfer")
+	return &reviewHeldTransferResponseBody{}, nil
+}
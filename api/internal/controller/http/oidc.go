@@ -0,0 +1,90 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+)
+
+type oidcResponseError struct {
+	Message string `json:"message"`
+	Code    string `json:"code" enums:"oidc_provider_not_found,invalid_oidc_state,oidc_login_failed,oidc_email_not_verified"`
+} // @name oidcResponseError
+
+func (e oidcResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+	}
+}
+
+type startOIDCLoginResponseBody struct {
+	*service.StartOIDCLoginOutput
+} // @name startOIDCLoginResponseBody
+
+// @id           StartOIDCLogin
+// @Summary      Starts sign in via external OpenID Connect provider and returns url to redirect the user to.
+// @Produce      application/json
+// @Param        provider path string true "provider name"
+// @Success      200 {object} startOIDCLoginResponseBody
+// @Failure      422,500 {object} oidcResponseError
+// @Router       /auth/oidc/{provider}/login [GET]
+func (a *authRouter) startOIDCLogin(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("startOIDCLogin").WithContext(requestContext)
+
+	provider := requestContext.Param("provider")
+	logger = logger.With("provider", provider)
+
+	login, err := a.services.AuthService.StartOIDCLogin(requestContext, &service.StartOIDCLoginOptions{Provider: provider})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, oidcResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to start oidc login", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to start oidc login", Details: err}
+	}
+
+	logger.Info("successfully started oidc login")
+	return &startOIDCLoginResponseBody{login}, nil
+}
+
+// @id           CompleteOIDCLogin
+// @Summary      Handles OpenID Connect provider callback and returns tokens.
+// @Produce      application/json
+// @Param        provider path string true "provider name"
+// @Param        code query string true "authorization code"
+// @Param        state query string true "login state"
+// @Success      200 {object} signInResponseBody
+// @Failure      422,500 {object} oidcResponseError
+// @Router       /auth/oidc/{provider}/callback [GET]
+func (a *authRouter) completeOIDCLogin(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("completeOIDCLogin").WithContext(requestContext)
+
+	provider := requestContext.Param("provider")
+	logger = logger.With("provider", provider)
+
+	// provider reports denied consent and similar failures via error parameter
+	if providerError := requestContext.Query("error"); providerError != "" {
+		logger.Info("provider returned error", "error", providerError)
+		return nil, oidcResponseError{Message: service.ErrOIDCLoginFailed.Error(), Code: errs.GetCode(service.ErrOIDCLoginFailed)}.Error()
+	}
+
+	signed, err := a.services.AuthService.CompleteOIDCLogin(requestContext, &service.CompleteOIDCLoginOptions{
+		Provider: provider,
+		Code:     requestContext.Query("code"),
+		State:    requestContext.Query("state"),
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, oidcResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to complete oidc login", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to complete oidc login", Details: err}
+	}
+
+	logger.Info("successfully signed in via oidc")
+	return &signInResponseBody{signed}, nil
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type personalAccessTokenResponseError struct {
+	Message string            `json:"message"`
+	Code    string            `json:"code" enums:"invalid_token_name,invalid_scope,invalid_token_expiration,token_not_found"`
+	Details map[string]string `json:"details,omitempty"`
+} // @name personalAccessTokenResponseError
+
+func (e personalAccessTokenResponseError) Error() *httpResponseError {
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Message: e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+	}
+}
+
+type createPersonalAccessTokenRequestBody struct {
+	*service.CreatePersonalAccessTokenOptions
+} // @name createPersonalAccessTokenRequestBody
+
+type createPersonalAccessTokenResponseBody struct {
+	*service.CreatePersonalAccessTokenOutput
+} // @name createPersonalAccessTokenResponseBody
+
+// @id           CreatePersonalAccessToken
+// @Summary      Creates personal access token, the token is returned only once.
+// @Accept       application/json
+// @Produce      application/json
+// @Param        fields body createPersonalAccessTokenRequestBody true "data"
+// @Success      200 {object} createPersonalAccessTokenResponseBody
+// @Failure      422,500 {object} personalAccessTokenResponseError
+// @Router       /account/tokens [POST]
+func (a *accountRouter) createPersonalAccessToken(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("createPersonalAccessToken").WithContext(requestContext)
+
+	body := createPersonalAccessTokenRequestBody{&service.CreatePersonalAccessTokenOptions{}}
+	err := requestContext.ShouldBindJSON(&body)
+	if err != nil {
+		logger.Info("failed to parse request body", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid request body", Details: err}
+	}
+	body.UserId = requestContext.GetString("userId")
+	logger = logger.With("userId", body.UserId, "name", body.Name, "scopes", body.Scopes)
+	logger.Debug("parsed request body")
+
+	created, err := a.services.AuthService.CreatePersonalAccessToken(requestContext, body.CreatePersonalAccessTokenOptions)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, personalAccessTokenResponseError{Message: err.Error(), Code: errs.GetCode(err), Details: errs.GetDetails(err)}.Error()
+		}
+		logger.Error("failed to create personal access token", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to create personal access token", Details: err}
+	}
+
+	logger.Info("successfully created personal access token")
+	return &createPersonalAccessTokenResponseBody{created}, nil
+}
+
+type listPersonalAccessTokensResponseBody struct {
+	Tokens []personalAccessTokenDTO
+} // @name listPersonalAccessTokensResponseBody
+
+// @id           ListPersonalAccessTokens
+// @Summary      Lists personal access tokens of the user.
+// @Produce      application/json
+// @Success      200 {object} listPersonalAccessTokensResponseBody
+// @Failure      422,500 {object} httpResponseError
+// @Router       /account/tokens [GET]
+func (a *accountRouter) listPersonalAccessTokens(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("listPersonalAccessTokens").WithContext(requestContext)
+
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId)
+
+	tokens, err := a.services.AuthService.ListPersonalAccessTokens(requestContext, &service.ListPersonalAccessTokensOptions{UserId: userId})
+	if err != nil {
+		logger.Error("failed to list personal access tokens", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list personal access tokens", Details: err}
+	}
+
+	logger.Info("successfully listed personal access tokens")
+	return &listPersonalAccessTokensResponseBody{Tokens: newPersonalAccessTokenDTOs(tokens.Tokens)}, nil
+}
+
+type revokePersonalAccessTokenResponseBody struct{} // @name revokePersonalAccessTokenResponseBody
+
+// @id           RevokePersonalAccessToken
+// @Summary      Revokes personal access token.
+// @Produce      application/json
+// @Param        tokenId path string true "Token ID"
+// @Success      200 {object} revokePersonalAccessTokenResponseBody
+// @Failure      422,500 {object} personalAccessTokenResponseError
+// @Router       /account/tokens/{tokenId} [DELETE]
+func (a *accountRouter) revokePersonalAccessToken(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := a.logger.Named("revokePersonalAccessToken").WithContext(requestContext)
+
+	tokenId := requestContext.Param("tokenId")
+	if _, ok := uuid.Parse(tokenId); ok != nil {
+		logger.Info("invalid token id parameter", "param", tokenId)
+		return nil, &httpResponseError{Type: ErrorTypeClient, Message: "invalid token id parameter"}
+	}
+	userId := requestContext.GetString("userId")
+	logger = logger.With("userId", userId, "tokenId", tokenId)
+
+	err := a.services.AuthService.RevokePersonalAccessToken(requestContext, &service.RevokePersonalAccessTokenOptions{UserId: userId, TokenId: tokenId})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, personalAccessTokenResponseError{Message: err.Error(), Code: errs.GetCode(err)}.Error()
+		}
+		logger.Error("failed to revoke personal access token", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to revoke personal access token", Details: err}
+	}
+
+	logger.Info("successfully revoked personal access token")
+	return &revokePersonalAccessTokenResponseBody{}, nil
+}
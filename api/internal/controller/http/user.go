@@ -0,0 +1,176 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type userRouter struct {
+	RouterContext
+}
+
+func setupUserRoutes(options RouterOptions) {
+	router := &userRouter{
+		RouterContext{
+			logger:   options.Logger,
+			services: options.Services,
+			config:   options.Config,
+		},
+	}
+
+	routerGroup := options.Handler.Group("/users")
+	{
+		routerGroup.GET("/presence", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.getPresence))
+		routerGroup.GET("/keys", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.lookupDeviceKeys))
+		routerGroup.GET("/search", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.searchUsers))
+		routerGroup.GET("/keys/history", authMiddleware(options, entity.ScopeTransfersRead), wrapHandler(options, router.listDeviceKeyChanges))
+	}
+}
+
+type getPresenceResponseBody struct {
+	*service.GetPresenceOutput
+} // @name getPresenceResponseBody
+
+// @id           GetPresence
+// @Summary      Lists devices of the contact which are shared with others and whether they are online.
+// @Produce      application/json
+// @Param        email query string true "user email"
+// @Success      200 {object} getPresenceResponseBody
+// @Failure      500 {object} httpResponseError
+// @Router       /users/presence [GET]
+func (u *userRouter) getPresence(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := u.logger.Named("getPresence").WithContext(requestContext)
+
+	email := requestContext.Query("email")
+	logger = logger.With("email", email, "userId", requestContext.GetString("userId"))
+
+	presence, err := u.services.AccountService.GetPresence(requestContext, &service.GetPresenceOptions{
+		UserId: requestContext.GetString("userId"),
+		Email:  email,
+	})
+	if err != nil {
+		logger.Error("failed to get presence", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to get presence", Details: err}
+	}
+
+	logger.Info("successfully got presence")
+	return &getPresenceResponseBody{presence}, nil
+}
+
+type lookupDeviceKeysResponseBody struct {
+	*service.LookupDeviceKeysOutput
+} // @name lookupDeviceKeysResponseBody
+
+// @id           LookupDeviceKeys
+// @Summary      Lists current public keys of active devices of the user, senders encrypt transfers to them.
+// @Produce      application/json
+// @Param        email query string true "user email"
+// @Success      200 {object} lookupDeviceKeysResponseBody
+// @Failure      500 {object} httpResponseError
+// @Router       /users/keys [GET]
+func (u *userRouter) lookupDeviceKeys(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := u.logger.Named("lookupDeviceKeys").WithContext(requestContext)
+
+	email := requestContext.Query("email")
+	logger = logger.With("email", email, "userId", requestContext.GetString("userId"))
+
+	keys, err := u.services.AccountService.LookupDeviceKeys(requestContext, &service.LookupDeviceKeysOptions{
+		UserId: requestContext.GetString("userId"),
+		Email:  email,
+	})
+	if err != nil {
+		logger.Error("failed to look up device keys", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to look up device keys", Details: err}
+	}
+
+	logger.Info("successfully looked up device keys")
+	return &lookupDeviceKeysResponseBody{keys}, nil
+}
+
+// @id           ListDeviceKeyChanges
+// @Summary      Lists all keys the user's devices ever published, newest first, so clients can warn about key changes.
+// @Produce      application/json
+// @Param        email query string true "user email"
+// @Success      200 {object} lookupDeviceKeysResponseBody
+// @Failure      500 {object} httpResponseError
+// @Router       /users/keys/history [GET]
+func (u *userRouter) listDeviceKeyChanges(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := u.logger.Named("listDeviceKeyChanges").WithContext(requestContext)
+
+	email := requestContext.Query("email")
+	logger = logger.With("email", email, "userId", requestContext.GetString("userId"))
+
+	keys, err := u.services.AccountService.ListDeviceKeyChanges(requestContext, &service.LookupDeviceKeysOptions{
+		UserId: requestContext.GetString("userId"),
+		Email:  email,
+	})
+	if err != nil {
+		logger.Error("failed to list device key changes", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to list device key changes", Details: err}
+	}
+
+	logger.Info("successfully listed device key changes")
+	return &lookupDeviceKeysResponseBody{keys}, nil
+}
+
+type searchUsersResponseBody struct {
+	*service.SearchUsersOutput
+} // @name searchUsersResponseBody
+
+type searchUsersResponseError struct {
+	Message string            `json:"message"`
+	Code    string            `json:"code" enums:"query_too_short,rate_limited"`
+	Details map[string]string `json:"details,omitempty"`
+} // @name searchUsersResponseError
+
+func (e searchUsersResponseError) Error() *httpResponseError {
+	status := 0
+	if e.Code == "rate_limited" {
+		status = http.StatusTooManyRequests
+	}
+
+	return &httpResponseError{
+		Type:    ErrorTypeClient,
+		Status:  status,
+		Message: e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+	}
+}
+
+// @id           SearchUsers
+// @Summary      Finds discoverable users by username prefix or exact email, returns only their public profiles.
+// @Produce      application/json
+// @Param        q query string true "username prefix or email"
+// @Success      200 {object} searchUsersResponseBody
+// @Failure      422,429,500 {object} searchUsersResponseError
+// @Router       /users/search [GET]
+func (u *userRouter) searchUsers(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := u.logger.Named("searchUsers").WithContext(requestContext)
+
+	options := &service.SearchUsersOptions{
+		UserId: requestContext.GetString("userId"),
+		Query:  requestContext.Query("q"),
+	}
+	logger = logger.With("userId", options.UserId)
+
+	users, err := u.services.AccountService.SearchUsers(requestContext, options)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			details := errs.GetDetails(err)
+			if retryAfter, ok := details["retryAfter"]; ok {
+				requestContext.Header("Retry-After", retryAfter)
+			}
+			return nil, searchUsersResponseError{Message: err.Error(), Code: errs.GetCode(err), Details: details}.Error()
+		}
+		logger.Error("failed to search users", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to search users", Details: err}
+	}
+
+	logger.Info("successfully searched users")
+	return &searchUsersResponseBody{users}, nil
+}
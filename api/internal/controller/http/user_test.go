@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeSearchUsersService struct {
+	service.AccountService
+	err error
+}
+
+func (f *fakeSearchUsersService) SearchUsers(ctx context.Context, options *service.SearchUsersOptions) (*service.SearchUsersOutput, error) {
+	return nil, f.err
+}
+
+func TestSearchUsersRateLimited(t *testing.T) {
+	engine, routerOptions := newTestRouter(nil)
+	routerOptions.Services.AccountService = &fakeSearchUsersService{
+		err: service.ErrSearchUsersRateLimited.WithDetails(map[string]string{"retryAfter": "42"}),
+	}
+	router := &userRouter{RouterContext{logger: routerOptions.Logger, services: routerOptions.Services}}
+	routerOptions.Handler.GET("/users/search", wrapHandler(routerOptions, router.searchUsers))
+
+	request := httptest.NewRequest(http.MethodGet, "/users/search?q=user", nil)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "42" {
+		t.Errorf("Retry-After = %q, want %q", retryAfter, "42")
+	}
+
+	var response searchUsersResponseError
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Code != service.ErrSearchUsersRateLimited.Code || response.Details["retryAfter"] != "42" {
+		t.Fatalf("response = %+v, want code %s with retry after", response, service.ErrSearchUsersRateLimited.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type wellKnownRouter struct {
+	RouterContext
+}
+
+func setupWellKnownRoutes(options RouterOptions) {
+	router := &wellKnownRouter{
+		RouterContext{
+			logger:   options.Logger,
+			services: options.Services,
+			config:   options.Config,
+		},
+	}
+
+	options.Handler.GET("/jwks.json", wrapHandler(options, router.getJSONWebKeySet))
+}
+
+type getJSONWebKeySetResponseBody struct {
+	*service.GetJSONWebKeySetOutput
+} // @name getJSONWebKeySetResponseBody
+
+// @id           GetJSONWebKeySet
+// @Summary      Returns public keys which verify access tokens.
+// @Produce      application/json
+// @Success      200 {object} getJSONWebKeySetResponseBody
+// @Failure      500 {object} httpResponseError
+// @Router       /.well-known/jwks.json [GET]
+func (w *wellKnownRouter) getJSONWebKeySet(requestContext *gin.Context) (interface{}, *httpResponseError) {
+	logger := w.logger.Named("getJSONWebKeySet").WithContext(requestContext)
+
+	keySet, err := w.services.AuthService.GetJSONWebKeySet(requestContext)
+	if err != nil {
+		logger.Error("failed to get json web key set", "err", err)
+		return nil, &httpResponseError{Type: ErrorTypeServer, Message: "failed to get json web key set", Details: err}
+	}
+
+	logger.Debug("successfully got json web key set")
+	return &getJSONWebKeySetResponseBody{keySet}, nil
+}
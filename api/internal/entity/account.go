@@ -1,8 +1,12 @@
 package entity
 
+import "time"
+
 type Account struct {
-	Id              string           `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	UserId          string           `json:"userId" gorm:"type:uuid;index"`
+	Id     string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId string `json:"userId" gorm:"type:uuid;index"`
+	// Version is incremented by every update, clients send it back via If-Match to detect concurrent edits.
+	Version         int              `json:"version" gorm:"not null;default:1"`
 	AccountDevices  []AccountDevices `json:"accountDevices" gorm:"foreignkey:AccountID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	AccountSettings *AccountSettings `json:"accountSettings" gorm:"foreignkey:AccountID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -14,10 +18,117 @@ type AccountDevices struct {
 	OS         string `json:"os"`
 	MacAddress string `json:"macAddress"`
 	Active     bool   `json:"active"`
+	// Online is set by heartbeats and cleared once LastSeenAt is older than presence TTL.
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+	Online     bool       `json:"online" gorm:"default:false;index"`
 }
 
+// AccountSettingsVersion is current schema version of account settings. Version 1 had only
+// free-form language, version 2 called contacts of contacts discoverability just contacts.
+// Rows of older versions are upgraded when read, renamed values are rewritten on startup.
+const AccountSettingsVersion = 3
+
+// AccountSettings - column defaults fill settings of rows created before the column existed.
 type AccountSettings struct {
 	Id        string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	AccountID string `json:"AccountID" gorm:"type:uuid;index"`
-	Language  string `json:"language"`
+	Version   int    `json:"version" gorm:"default:1"`
+	// Language is BCP 47 language tag.
+	Language string `json:"language"`
+	Theme    string `json:"theme" gorm:"default:system" enums:"system,light,dark"`
+	// Discoverability controls who can find the user by search, contacts_of_contacts allows contacts
+	// of the user and their contacts.
+	Discoverability string `json:"discoverability" gorm:"default:everyone" enums:"everyone,contacts_of_contacts,nobody"`
+	// SharePresence allows other users to see whether devices of the account are online.
+	SharePresence bool                 `json:"sharePresence" gorm:"default:true"`
+	Notifications NotificationSettings `json:"notifications" gorm:"embedded;embeddedPrefix:notify_"`
+	// AutoAccept selects senders whose transfers are accepted without asking.
+	AutoAccept     string `json:"autoAccept" gorm:"default:never" enums:"never,contacts,own_devices"`
+	DownloadAction string `json:"downloadAction" gorm:"default:ask" enums:"ask,save,open"`
+	// MaxIncomingFileSize is in bytes, zero means no limit.
+	MaxIncomingFileSize int64 `json:"maxIncomingFileSize" gorm:"default:0"`
+	// NonContactTransfers decides whether transfers from users who are not contacts are delivered,
+	// held until the user approves them or rejected.
+	NonContactTransfers string `json:"nonContactTransfers" gorm:"default:allow" enums:"allow,hold,reject"`
+}
+
+// NotificationSettings - represents which events the user is notified about.
+type NotificationSettings struct {
+	IncomingTransfers  bool `json:"incomingTransfers" gorm:"default:true"`
+	CompletedTransfers bool `json:"completedTransfers" gorm:"default:true"`
+	SecurityEvents     bool `json:"securityEvents" gorm:"default:true"`
+}
+
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+
+	DiscoverabilityEveryone           = "everyone"
+	DiscoverabilityContactsOfContacts = "contacts_of_contacts"
+	DiscoverabilityNobody             = "nobody"
+
+	AutoAcceptNever      = "never"
+	AutoAcceptContacts   = "contacts"
+	AutoAcceptOwnDevices = "own_devices"
+
+	DownloadActionAsk  = "ask"
+	DownloadActionSave = "save"
+	DownloadActionOpen = "open"
+
+	NonContactTransfersAllow  = "allow"
+	NonContactTransfersHold   = "hold"
+	NonContactTransfersReject = "reject"
+
+	DefaultLanguage = "en"
+)
+
+var (
+	Themes            = []string{ThemeSystem, ThemeLight, ThemeDark}
+	Discoverabilities = []string{DiscoverabilityEveryone, DiscoverabilityContactsOfContacts, DiscoverabilityNobody}
+	AutoAcceptRules   = []string{AutoAcceptNever, AutoAcceptContacts, AutoAcceptOwnDevices}
+	DownloadActions   = []string{DownloadActionAsk, DownloadActionSave, DownloadActionOpen}
+	// NonContactTransferPolicies is ordered from the most permissive to the strictest policy.
+	NonContactTransferPolicies = []string{NonContactTransfersAllow, NonContactTransfersHold, NonContactTransfersReject}
+)
+
+// DefaultAccountSettings returns settings of new account.
+func DefaultAccountSettings() *AccountSettings {
+	return &AccountSettings{
+		Version:         AccountSettingsVersion,
+		Language:        DefaultLanguage,
+		Theme:           ThemeSystem,
+		Discoverability: DiscoverabilityEveryone,
+		SharePresence:   true,
+		Notifications: NotificationSettings{
+			IncomingTransfers:  true,
+			CompletedTransfers: true,
+			SecurityEvents:     true,
+		},
+		AutoAccept:          AutoAcceptNever,
+		DownloadAction:      DownloadActionAsk,
+		NonContactTransfers: NonContactTransfersAllow,
+	}
+}
+
+const (
+	DeviceOSWindows = "windows"
+	DeviceOSMacOS   = "macos"
+	DeviceOSLinux   = "linux"
+	DeviceOSAndroid = "android"
+	DeviceOSIOS     = "ios"
+)
+
+// DeviceOSes lists operating systems devices may report.
+var DeviceOSes = []string{DeviceOSWindows, DeviceOSMacOS, DeviceOSLinux, DeviceOSAndroid, DeviceOSIOS}
+
+// IsKnownDeviceOS reports whether os is one of DeviceOSes.
+func IsKnownDeviceOS(os string) bool {
+	for _, known := range DeviceOSes {
+		if os == known {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,26 @@
+package entity
+
+import "testing"
+
+func TestIsKnownDeviceOS(t *testing.T) {
+	tests := []struct {
+		os   string
+		want bool
+	}{
+		{os: DeviceOSWindows, want: true},
+		{os: DeviceOSMacOS, want: true},
+		{os: DeviceOSLinux, want: true},
+		{os: DeviceOSAndroid, want: true},
+		{os: DeviceOSIOS, want: true},
+		// callers normalize case before the check
+		{os: "Linux", want: false},
+		{os: "", want: false},
+		{os: "beos", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsKnownDeviceOS(tt.os); got != tt.want {
+			t.Errorf("IsKnownDeviceOS(%q) = %v, want %v", tt.os, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+const (
+	AuditActionPasswordChanged      = "password_changed"
+	AuditActionEmailChangeRequested = "email_change_requested"
+	AuditActionEmailChanged         = "email_changed"
+	AuditActionDeletionScheduled    = "deletion_scheduled"
+	AuditActionDeletionCancelled    = "deletion_cancelled"
+	AuditActionDeviceKeyChanged     = "device_key_changed"
+)
+
+// AuditEvent records security relevant action performed on user account.
+// ActorUserId differs from UserId when an operator acts on behalf of the user.
+type AuditEvent struct {
+	Id          string            `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId      string            `json:"userId" gorm:"type:uuid;index"`
+	ActorUserId string            `json:"actorUserId" gorm:"type:uuid"`
+	Action      string            `json:"action" gorm:"index"`
+	ClientIP    string            `json:"clientIp"`
+	Details     map[string]string `json:"details" gorm:"serializer:json"`
+	CreatedAt   time.Time         `json:"createdAt" gorm:"index"`
+}
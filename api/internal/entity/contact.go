@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+const (
+	ContactStatusPending  = "pending"
+	ContactStatusAccepted = "accepted"
+	ContactStatusDeclined = "declined"
+	// ContactStatusIgnored hides request from the addressee, requester still sees it as pending.
+	ContactStatusIgnored = "ignored"
+)
+
+// Contact is contact request from RequesterId to AddresseeId, users are contacts once it is accepted.
+// Single row is kept for a pair of users regardless of who asked first.
+type Contact struct {
+	Id          string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	RequesterId string     `json:"requesterId" gorm:"type:uuid;uniqueIndex:idx_contacts_pair"`
+	AddresseeId string     `json:"addresseeId" gorm:"type:uuid;uniqueIndex:idx_contacts_pair;index"`
+	Status      string     `json:"status" gorm:"index"`
+	RespondedAt *time.Time `json:"respondedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// Block stops BlockedUserId from reaching UserId. Blocked user is never told about it,
+// requests and transfers to the blocker are answered as if nothing happened.
+type Block struct {
+	Id            string    `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId        string    `json:"userId" gorm:"type:uuid;uniqueIndex:idx_blocks_pair"`
+	BlockedUserId string    `json:"blockedUserId" gorm:"type:uuid;uniqueIndex:idx_blocks_pair;index"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
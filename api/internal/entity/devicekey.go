@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// DeviceKey is public key pair device publishes for end-to-end encrypted transfers.
+// Keys are never updated in place: rotation revokes current key and stores new one,
+// so revoked keys form history of key changes.
+type DeviceKey struct {
+	Id            string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	DeviceId      string `json:"deviceId" gorm:"type:uuid;index"`
+	AccountId     string `json:"accountId" gorm:"type:uuid;index"`
+	UserId        string `json:"userId" gorm:"type:uuid;index"`
+	EncryptionKey string `json:"encryptionKey"`
+	SigningKey    string `json:"signingKey"`
+	Fingerprint   string `json:"fingerprint"`
+	// SignedByDeviceId is empty for the first key of the account which nobody could vouch for.
+	SignedByDeviceId string     `json:"signedByDeviceId"`
+	RevokedAt        *time.Time `json:"revokedAt" gorm:"index"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusReady      = "ready"
+	DataExportStatusFailed     = "failed"
+)
+
+// DataExport is an archive with everything stored about the user. Archive is
+// downloaded via link with opaque token, only its hash is stored. ClaimedAt is set
+// once processing starts, so exports abandoned by crashed instance can be failed.
+type DataExport struct {
+	Id          string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId      string     `json:"userId" gorm:"type:uuid;index"`
+	Status      string     `json:"status" gorm:"index"`
+	Archive     []byte     `json:"-"`
+	TokenHash   string     `json:"-" gorm:"index"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	ClaimedAt   *time.Time `json:"claimedAt" gorm:"index"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleMember = "member"
+)
+
+// Group is user-owned list of people transfers are shared with at once. Owners manage the group
+// and its members, every member may send to the group.
+type Group struct {
+	Id        string        `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Name      string        `json:"name"`
+	Members   []GroupMember `json:"members" gorm:"foreignkey:GroupId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type GroupMember struct {
+	Id        string    `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	GroupId   string    `json:"groupId" gorm:"type:uuid;uniqueIndex:idx_group_members_pair"`
+	UserId    string    `json:"userId" gorm:"type:uuid;uniqueIndex:idx_group_members_pair;index"`
+	Role      string    `json:"role" enums:"owner,member"`
+	CreatedAt time.Time `json:"createdAt"`
+}
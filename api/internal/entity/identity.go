@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// UserIdentity links user to the account at external OpenID Connect provider.
+type UserIdentity struct {
+	Id        string    `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId    string    `json:"userId" gorm:"type:uuid;index"`
+	Provider  string    `json:"provider" gorm:"uniqueIndex:idx_user_identity_subject"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_user_identity_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OIDCLoginState keeps parameters of started OpenID Connect login until the provider
+// redirects user back. It is consumed once, only hash of the state is stored.
+type OIDCLoginState struct {
+	Id           string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Provider     string     `json:"provider"`
+	StateHash    string     `json:"-" gorm:"uniqueIndex"`
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"`
+	ExpiresAt    time.Time  `json:"expiresAt" gorm:"index"`
+	UsedAt       *time.Time `json:"usedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
@@ -1,11 +1,28 @@
 package entity
 
+import "time"
+
+const (
+	NodeStatusDelivered = "delivered"
+	// NodeStatusHeld waits for receiver approval, receiver policy holds transfers from non-contacts.
+	NodeStatusHeld     = "held"
+	NodeStatusDeclined = "declined"
+	// NodeStatusBlocked is never shown to the receiver, sender sees it as delivered.
+	NodeStatusBlocked = "blocked"
+)
+
 type Node struct {
 	Id                 string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	SenderEmail        string `json:"senderEmail"`
 	SenderMacAddress   string `json:"senderMacAddress"`
 	ReceiverEmail      string `json:"receiverEmail"`
 	ReceiverMacAddress string `json:"ReceiverMacAddress"`
+	Status             string `json:"status" gorm:"default:delivered;index"`
+	// PayloadId identifies transferred content, transfer fanned out to a group shares it with all its receivers.
+	PayloadId string `json:"payloadId" gorm:"type:uuid;default:uuid_generate_v4();index"`
+	// GroupId references group the transfer was fanned out to, the group may have been changed or deleted since.
+	GroupId   *string   `json:"groupId" gorm:"type:uuid;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"default:now()"`
 }
 
 // Sender(Up server) - Backend(notification) - Receiver
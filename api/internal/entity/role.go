@@ -0,0 +1,39 @@
+package entity
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+const (
+	PermissionUsersRead     = "users.read"
+	PermissionUsersManage   = "users.manage"
+	PermissionDevicesRead   = "devices.read"
+	PermissionTransfersRead = "transfers.read"
+)
+
+// RolePermissions lists permissions granted to each role. Regular users have no
+// operator permissions, they can only access their own resources.
+var RolePermissions = map[string][]string{
+	RoleUser:    {},
+	RoleSupport: {PermissionUsersRead, PermissionDevicesRead, PermissionTransfersRead},
+	RoleAdmin:   {PermissionUsersRead, PermissionUsersManage, PermissionDevicesRead, PermissionTransfersRead},
+}
+
+// HasPermission reports whether the role is granted the permission.
+func HasPermission(role, permission string) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsKnownRole reports whether the role exists.
+func IsKnownRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
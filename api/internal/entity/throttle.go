@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// LoginThrottle counts failed sign in attempts per account or per client ip.
+// Key is prefixed with its kind, e.g. "email:user@example.com" or "ip:127.0.0.1".
+type LoginThrottle struct {
+	Key           string     `json:"key" gorm:"primaryKey"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil"`
+}
+
+// RateLimit counts requests of the key during fixed window which started at WindowStart.
+// Key is prefixed with limited action, e.g. "search:<user id>".
+type RateLimit struct {
+	Key         string    `json:"key" gorm:"primaryKey"`
+	Count       int       `json:"count"`
+	WindowStart time.Time `json:"windowStart"`
+}
@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+type RefreshToken struct {
+	Id        string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId    string     `json:"userId" gorm:"type:uuid;index"`
+	FamilyId  string     `json:"familyId" gorm:"type:uuid;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// RevokedToken is a denylist entry which rejects access token by its id or
+// every access token issued for the session. Entry is kept until ExpiresAt,
+// after which the tokens it covers are expired anyway.
+type RevokedToken struct {
+	Id        string    `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId    string    `json:"userId" gorm:"type:uuid;index"`
+	TokenId   string    `json:"tokenId" gorm:"index"`
+	SessionId string    `json:"sessionId" gorm:"index"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const (
+	UserTokenPurposePasswordReset     = "password_reset"
+	UserTokenPurposeEmailVerification = "email_verification"
+	UserTokenPurposeMFAChallenge      = "mfa_challenge"
+	UserTokenPurposeEmailChange       = "email_change"
+	UserTokenPurposeDeletionCancel    = "deletion_cancel"
+)
+
+// UserToken is a single-use expiring token which is sent to the user by mail
+// to confirm an action. Only hash of the token is stored. Payload keeps value
+// the action needs (e.g. new email), SessionId keeps session which requested it.
+type UserToken struct {
+	Id        string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId    string     `json:"userId" gorm:"type:uuid;index"`
+	Purpose   string     `json:"purpose" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	Payload   string     `json:"-"`
+	SessionId string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+const (
+	ScopeAccountRead    = "account:read"
+	ScopeAccountWrite   = "account:write"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+)
+
+// Scopes lists every scope personal access token may be granted.
+var Scopes = []string{ScopeAccountRead, ScopeAccountWrite, ScopeTransfersRead, ScopeTransfersWrite}
+
+// PersonalAccessToken is a long-lived token which user creates for scripts and CLI.
+// It authorizes only routes that accept one of its scopes, only hash of the token is stored.
+type PersonalAccessToken struct {
+	Id         string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId     string     `json:"userId" gorm:"type:uuid;index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
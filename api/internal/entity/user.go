@@ -1,8 +1,27 @@
 package entity
 
+import "time"
+
 type User struct {
-	Id       string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Id            string `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Username      string `json:"username" gorm:"index:idx_users_username_prefix,expression:lower(username) text_pattern_ops"`
+	Email         string `json:"email" gorm:"uniqueIndex:idx_users_email_lower,expression:lower(email)"`
+	Password      string `json:"password"`
+	EmailVerified bool   `json:"emailVerified" gorm:"default:false"`
+	TOTPSecret    string `json:"-"`
+	TOTPEnabled   bool   `json:"totpEnabled" gorm:"default:false"`
+	TOTPLastStep  int64  `json:"-"`
+	Role          string `json:"role" gorm:"default:user"`
+	Disabled      bool   `json:"disabled" gorm:"default:false"`
+	// DeletionScheduledFor is set while deletion is pending, the user is erased once it passes.
+	DeletionScheduledFor *time.Time `json:"deletionScheduledFor" gorm:"index"`
+}
+
+// RecoveryCode is a single-use code which replaces TOTP code when the authenticator is lost.
+type RecoveryCode struct {
+	Id        string     `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserId    string     `json:"userId" gorm:"type:uuid;index"`
+	CodeHash  string     `json:"-" gorm:"index"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
 }
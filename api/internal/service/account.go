@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/mail"
 )
 
 type accountService struct {
 	serviceContext
+	mailer mail.Mailer
 }
 
 var _ AccountService = (*accountService)(nil)
@@ -19,6 +21,7 @@ func NewAccountService(options *Options) AccountService {
 			config:   options.Config,
 			logger:   options.Logger.Named("AccountService"),
 		},
+		mailer: options.Mailer,
 	}
 }
 
@@ -39,19 +42,25 @@ func (a accountService) CreateAccount(ctx context.Context, options *CreateAccoun
 	}
 	logger = logger.With("user", user)
 
+	settings, err := newAccountSettings(options.AccountLanguage)
+	if err != nil {
+		logger.Info("invalid account settings", "err", err)
+		return nil, err
+	}
+
 	account := &entity.Account{
-		UserId: user.Id,
-		AccountDevices: []entity.AccountDevices{
-			{
-				Name:       options.DeviceName,
-				OS:         options.DeviceOS,
-				MacAddress: options.DeviceMacAddress,
-				Active:     options.Active,
-			},
-		},
-		AccountSettings: &entity.AccountSettings{
-			Language: options.AccountLanguage,
-		},
+		UserId:          user.Id,
+		AccountSettings: settings,
+	}
+
+	// account may be created before any device is known, devices are then registered separately
+	if options.DeviceName != "" || options.DeviceOS != "" || options.DeviceMacAddress != "" {
+		device, err := newDevice("", options.DeviceName, options.DeviceOS, options.DeviceMacAddress, options.Active)
+		if err != nil {
+			logger.Info("invalid device", "err", err)
+			return nil, err
+		}
+		account.AccountDevices = []entity.AccountDevices{*device}
 	}
 	logger = logger.With("account", account)
 
@@ -83,23 +92,99 @@ func (a accountService) GetAccount(ctx context.Context, options *GetAccountOptio
 	}
 	logger = logger.With("account", account)
 
+	if account.AccountSettings != nil {
+		upgradeAccountSettings(account.AccountSettings)
+	}
+
 	logger.Info("successfully got account")
 	return account, nil
 }
 
-func (a accountService) UpdateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error) {
+func (a accountService) UpdateAccount(ctx context.Context, options *UpdateAccountOptions) (*entity.Account, error) {
 	logger := a.logger.
 		Named("UpdateAccount").
 		WithContext(ctx).
-		With("account", account)
+		With("options", options)
 
-	updatedAccount, err := a.storages.AccountStorage.UpdateAccount(ctx, account)
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	// stale version is rejected before validation, so the client refetches instead of fixing outdated patch
+	if options.Version != 0 && options.Version != account.Version {
+		logger.Info("account version mismatch", "version", account.Version)
+		return nil, ErrUpdateAccountVersionMismatch
+	}
+
+	// devices are never added or removed here, patches only change names and activity of existing ones,
+	// so concurrent clients cannot overwrite each other's device lists with a stale copy
+	if options.AccountSettings != nil || options.MergePatch != nil || options.JSONPatch != nil {
+		if account.AccountSettings == nil {
+			account.AccountSettings = entity.DefaultAccountSettings()
+		}
+		upgradeAccountSettings(account.AccountSettings)
+
+		if options.AccountSettings != nil {
+			applyAccountSettingsPatch(account.AccountSettings, options.AccountSettings)
+		} else {
+			err = applyAccountPatch(account, options)
+			if err != nil {
+				logger.Info("failed to apply patch", "err", err)
+				return nil, err
+			}
+		}
+
+		err = validateAccountSettings(account.AccountSettings)
+		if err != nil {
+			logger.Info("invalid account settings", "err", err)
+			return nil, err
+		}
+	}
+
+	updatedAccount, err := a.storages.AccountStorage.UpdateAccount(ctx, account, account.Version)
 	if err != nil {
 		logger.Error("failed to update account: ", err)
 		return nil, fmt.Errorf("failed to update account: %w", err)
 	}
-	logger = logger.With("updatedAccount", updatedAccount)
+	if updatedAccount == nil {
+		logger.Info("account was updated concurrently")
+		return nil, ErrUpdateAccountVersionMismatch
+	}
 
-	logger.Info("successfully updated account")
+	logger.Info("successfully updated account", "version", updatedAccount.Version)
 	return updatedAccount, nil
 }
+
+// getOwnedAccount returns account only when it belongs to the user.
+func (a accountService) getOwnedAccount(ctx context.Context, accountId, userId string) (*entity.Account, error) {
+	account, err := a.storages.AccountStorage.GetAccount(ctx, &GetAccountFilter{AccountId: accountId, UserId: userId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		return nil, ErrGetAccountAccountNotFound
+	}
+
+	return account, nil
+}
+
+// findUserByEmail returns user other users may interact with, or nil when email is malformed,
+// unknown or belongs to disabled user. Malformed email is not passed on, as empty filter matches any user.
+func (s serviceContext) findUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, nil
+	}
+
+	user, err := s.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || checkUserActive(user) != nil {
+		return nil, nil
+	}
+
+	return user, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+)
+
+const _testAccountId = "account-1"
+
+// createVersionedTestAccount stores account of the user at its first version with default settings.
+func createVersionedTestAccount(t *testing.T, storages *testStorages, options *Options) *entity.User {
+	t.Helper()
+
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	storages.accounts.accounts = append(storages.accounts.accounts, entity.Account{
+		Id:              _testAccountId,
+		UserId:          user.Id,
+		Version:         1,
+		AccountSettings: entity.DefaultAccountSettings(),
+	})
+
+	return user
+}
+
+// concurrentAccountStorage bumps version of the stored account right after it is read,
+// as if other request updated it in the meantime.
+type concurrentAccountStorage struct {
+	*fakeAccountStorage
+}
+
+func (c *concurrentAccountStorage) GetAccount(ctx context.Context, filter *GetAccountFilter) (*entity.Account, error) {
+	account, err := c.fakeAccountStorage.GetAccount(ctx, filter)
+	for i := range c.accounts {
+		c.accounts[i].Version++
+	}
+
+	return account, err
+}
+
+func TestUpdateAccountVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		concurrent bool
+		// wantVersion is stored version after the update
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "current version", version: 1, wantVersion: 2},
+		{name: "any version", version: 0, wantVersion: 2},
+		{name: "stale version", version: 2, wantVersion: 1, wantErr: ErrUpdateAccountVersionMismatch},
+		{name: "concurrent update", version: 1, concurrent: true, wantVersion: 2, wantErr: ErrUpdateAccountVersionMismatch},
+		{name: "concurrent update of any version", version: 0, concurrent: true, wantVersion: 2, wantErr: ErrUpdateAccountVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := createVersionedTestAccount(t, storages, options)
+			if tt.concurrent {
+				options.Storages.AccountStorage = &concurrentAccountStorage{storages.accounts}
+			}
+
+			theme := entity.ThemeDark
+			account, err := NewAccountService(options).UpdateAccount(context.Background(), &UpdateAccountOptions{
+				AccountId:       _testAccountId,
+				UserId:          user.Id,
+				Version:         tt.version,
+				AccountSettings: &AccountSettingsPatch{Theme: &theme},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateAccount() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && account.Version != tt.wantVersion {
+				t.Errorf("UpdateAccount() version = %d, want %d", account.Version, tt.wantVersion)
+			}
+
+			stored := storages.accounts.accounts[0]
+			if stored.Version != tt.wantVersion {
+				t.Errorf("stored version = %d, want %d", stored.Version, tt.wantVersion)
+			}
+			if updated := stored.AccountSettings.Theme == entity.ThemeDark; updated != (tt.wantErr == nil) {
+				t.Errorf("stored theme = %s, updated %v, want %v", stored.AccountSettings.Theme, updated, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestDeviceChangesInvalidateAccountVersion(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createVersionedTestAccount(t, storages, options)
+	service := NewAccountService(options)
+
+	// version read before the device was registered no longer describes the account
+	_, err := service.RegisterDevice(context.Background(), &RegisterDeviceOptions{
+		AccountId:  _testAccountId,
+		UserId:     user.Id,
+		Name:       "laptop",
+		OS:         entity.DeviceOSLinux,
+		MacAddress: "00:1a:2b:3c:4d:5e",
+	})
+	if err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+
+	theme := entity.ThemeDark
+	_, err = service.UpdateAccount(context.Background(), &UpdateAccountOptions{
+		AccountId:       _testAccountId,
+		UserId:          user.Id,
+		Version:         1,
+		AccountSettings: &AccountSettingsPatch{Theme: &theme},
+	})
+	if !errors.Is(err, ErrUpdateAccountVersionMismatch) {
+		t.Fatalf("UpdateAccount() of version before device registration error = %v, want %v", err, ErrUpdateAccountVersionMismatch)
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+)
+
+const (
+	_listUsersDefaultLimit = 50
+	_listUsersMaxLimit     = 200
+)
+
+type adminService struct {
+	serviceContext
+}
+
+var _ AdminService = (*adminService)(nil)
+
+func NewAdminService(options *Options) AdminService {
+	return &adminService{
+		serviceContext: serviceContext{
+			storages: options.Storages,
+			config:   options.Config,
+			logger:   options.Logger.Named("AdminService"),
+		},
+	}
+}
+
+func (a adminService) ListUsers(ctx context.Context, options *ListUsersOptions) (*ListUsersOutput, error) {
+	logger := a.logger.
+		Named("ListUsers").
+		WithContext(ctx).
+		With("options", options)
+
+	limit := options.Limit
+	if limit <= 0 {
+		limit = _listUsersDefaultLimit
+	}
+	if limit > _listUsersMaxLimit {
+		limit = _listUsersMaxLimit
+	}
+	offset := options.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, total, err := a.storages.UserStorage.ListUsers(ctx, &ListUsersFilter{Limit: limit, Offset: offset})
+	if err != nil {
+		logger.Error("failed to list users: ", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	summaries := make([]UserSummary, 0, len(users))
+	for _, user := range users {
+		summaries = append(summaries, newUserSummary(&user))
+	}
+
+	logger.Info("successfully listed users", "count", len(summaries))
+	return &ListUsersOutput{Users: summaries, Total: total}, nil
+}
+
+func (a adminService) SetUserDisabled(ctx context.Context, options *SetUserDisabledOptions) error {
+	logger := a.logger.
+		Named("SetUserDisabled").
+		WithContext(ctx).
+		With("options", options)
+
+	if options.ActorUserId == options.UserId {
+		logger.Info("operator tried to change own status")
+		return ErrAdminSelfModification
+	}
+
+	user, err := a.getUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return err
+	}
+	user.Disabled = options.Disabled
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if options.Disabled {
+		err = a.revokeUserSessions(ctx, user.Id, "")
+		if err != nil {
+			logger.Error("failed to revoke user sessions: ", err)
+			return fmt.Errorf("failed to revoke user sessions: %w", err)
+		}
+	}
+
+	logger.Info("successfully changed user status")
+	return nil
+}
+
+func (a adminService) SetUserRole(ctx context.Context, options *SetUserRoleOptions) error {
+	logger := a.logger.
+		Named("SetUserRole").
+		WithContext(ctx).
+		With("options", options)
+
+	if !entity.IsKnownRole(options.Role) {
+		logger.Info("invalid role")
+		return ErrAdminInvalidRole
+	}
+	if options.ActorUserId == options.UserId {
+		logger.Info("operator tried to change own role")
+		return ErrAdminSelfModification
+	}
+
+	user, err := a.getUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return err
+	}
+	user.Role = options.Role
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// role is carried in access tokens, so already issued ones must not outlive the change
+	err = a.revokeUserSessions(ctx, user.Id, "")
+	if err != nil {
+		logger.Error("failed to revoke user sessions: ", err)
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	logger.Info("successfully changed user role")
+	return nil
+}
+
+func (a adminService) ListUserDevices(ctx context.Context, options *ListUserDevicesOptions) (*ListUserDevicesOutput, error) {
+	logger := a.logger.
+		Named("ListUserDevices").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := a.getUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return nil, err
+	}
+
+	account, err := a.storages.AccountStorage.GetAccount(ctx, &GetAccountFilter{UserId: user.Id})
+	if err != nil {
+		logger.Error("failed to get account: ", err)
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		logger.Info("user has no account")
+		return &ListUserDevicesOutput{Devices: []entity.AccountDevices{}}, nil
+	}
+
+	logger.Info("successfully listed user devices", "count", len(account.AccountDevices))
+	return &ListUserDevicesOutput{Devices: account.AccountDevices}, nil
+}
+
+func (a adminService) ListUserTransfers(ctx context.Context, options *ListUserTransfersOptions) (*ListUserTransfersOutput, error) {
+	logger := a.logger.
+		Named("ListUserTransfers").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := a.getUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return nil, err
+	}
+
+	nodes, err := a.storages.NodeStorage.ListNodes(ctx, &ListNodesFilter{Email: user.Email})
+	if err != nil {
+		logger.Error("failed to list nodes: ", err)
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	logger.Info("successfully listed user transfers", "count", len(nodes))
+	return &ListUserTransfersOutput{Transfers: nodes}, nil
+}
+
+// getUser returns ErrAdminUserNotFound when user does not exist.
+func (a adminService) getUser(ctx context.Context, userId string) (*entity.User, error) {
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: userId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrAdminUserNotFound
+	}
+
+	return user, nil
+}
+
+func newUserSummary(user *entity.User) UserSummary {
+	return UserSummary{
+		Id:            user.Id,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		TOTPEnabled:   user.TOTPEnabled,
+		Role:          user.Role,
+		Disabled:      user.Disabled,
+	}
+}
+
+func (a adminService) DeleteUser(ctx context.Context, options *DeleteUserOptions) error {
+	logger := a.logger.
+		Named("DeleteUser").
+		WithContext(ctx).
+		With("options", options)
+
+	if options.ActorUserId == options.UserId {
+		logger.Info("operator tried to delete self")
+		return ErrAdminSelfModification
+	}
+
+	user, err := a.getUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return err
+	}
+
+	// access tokens outlive erased refresh tokens, so sessions are denied first
+	err = a.revokeUserSessions(ctx, user.Id, "")
+	if err != nil {
+		logger.Error("failed to revoke user sessions: ", err)
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	err = a.storages.UserStorage.DeleteUser(ctx, user.Id)
+	if err != nil {
+		logger.Error("failed to delete user: ", err)
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	logger.Info("user successfully erased")
+	return nil
+}
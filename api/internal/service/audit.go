@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+)
+
+// recordAuditEvent stores audit record. Action has already happened by then,
+// so failure is only logged and does not fail the operation.
+func (s serviceContext) recordAuditEvent(ctx context.Context, event *entity.AuditEvent) {
+	if event.ActorUserId == "" {
+		event.ActorUserId = event.UserId
+	}
+
+	_, err := s.storages.AuditEventStorage.CreateAuditEvent(ctx, event)
+	if err != nil {
+		s.logger.
+			Named("recordAuditEvent").
+			WithContext(ctx).
+			With("userId", event.UserId, "action", event.Action).
+			Error("failed to create audit event: ", err)
+	}
+}
@@ -6,12 +6,24 @@ import (
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/pkg/auth"
 	"github.com/atlant1da-404/droplet/pkg/hash"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"github.com/atlant1da-404/droplet/pkg/oidc"
+	"github.com/atlant1da-404/droplet/pkg/password"
+	"github.com/google/uuid"
+	"strings"
+	"time"
 )
 
 type authService struct {
 	serviceContext
-	hash hash.Hash
-	auth auth.Authenticator
+	hash   hash.Hash
+	auth   auth.Authenticator
+	mailer mail.Mailer
+
+	oidcProviders map[string]oidc.Provider
+
+	passwordPolicy password.Policy
+	breachCorpus   password.BreachCorpus
 }
 
 var _ AuthService = (*authService)(nil)
@@ -23,8 +35,14 @@ func NewAuthService(options *Options) AuthService {
 			config:   options.Config,
 			logger:   options.Logger.Named("AuthService"),
 		},
-		hash: options.Hash,
-		auth: options.Auth,
+		hash:   options.Hash,
+		auth:   options.Auth,
+		mailer: options.Mailer,
+
+		oidcProviders: options.OIDCProviders,
+
+		passwordPolicy: options.PasswordPolicy,
+		breachCorpus:   options.BreachCorpus,
 	}
 }
 
@@ -32,7 +50,14 @@ func (a authService) SignIn(ctx context.Context, options *SignInOptions) (*SignI
 	logger := a.logger.
 		Named("SignIn").
 		WithContext(ctx).
-		With("options", options)
+		With("email", options.Email, "clientIP", options.ClientIP)
+
+	throttleKeys := newThrottleKeys(options.Email, options.ClientIP)
+	err := a.checkSignInThrottles(ctx, throttleKeys)
+	if err != nil {
+		logger.Info("sign in is locked", "err", err)
+		return nil, err
+	}
 
 	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: options.Email})
 	if err != nil {
@@ -41,24 +66,55 @@ func (a authService) SignIn(ctx context.Context, options *SignInOptions) (*SignI
 	}
 	if user == nil {
 		logger.Info("user not found")
-		return nil, ErrSignInUserNotFound
+		return nil, a.registerSignInFailure(ctx, throttleKeys, ErrSignInUserNotFound)
 	}
 	logger = logger.With("user", user)
 
 	err = a.hash.CompareHash([]byte(user.Password), []byte(options.Password))
 	if err != nil {
 		logger.Info(err.Error())
-		return nil, ErrSignInWrongPassword
+		return nil, a.registerSignInFailure(ctx, throttleKeys, ErrSignInWrongPassword)
 	}
+	err = checkUserActive(user)
+	if err != nil {
+		logger.Info("user is not active", "err", err)
+		return nil, err
+	}
+
+	if a.hash.NeedsRehash(user.Password) {
+		err = a.rehashPassword(ctx, user, options.Password)
+		if err != nil {
+			// user is already authenticated, so the old hash stays until the next sign in
+			logger.Error("failed to rehash user password: ", err)
+		}
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := a.createUserToken(ctx, user.Id, entity.UserTokenPurposeMFAChallenge, a.config.Auth.MFAChallengeTTL)
+		if err != nil {
+			logger.Error("failed to create mfa challenge: ", err)
+			return nil, fmt.Errorf("failed to create mfa challenge: %w", err)
+		}
 
-	accessToken, err := a.auth.GenerateToken(&auth.GenerateTokenClaimsOptions{UserName: user.Username, UserId: user.Id})
+		logger.Info("password accepted, second factor required")
+		return &SignInOutput{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	// counter is reset only once the user is fully authenticated, accounts with 2fa reset it in SignInMFA
+	err = a.storages.LoginThrottleStorage.DeleteLoginThrottle(ctx, throttleKeys.account)
 	if err != nil {
-		logger.Error("failed to generate token for user: ", err)
-		return nil, fmt.Errorf("failed to generate token for user: %w", err)
+		logger.Error("failed to reset sign in throttle: ", err)
+		return nil, fmt.Errorf("failed to reset sign in throttle: %w", err)
+	}
+
+	accessToken, refreshToken, err := a.generateTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		logger.Error("failed to generate tokens for user: ", err)
+		return nil, fmt.Errorf("failed to generate tokens for user: %w", err)
 	}
 
 	logger.Info("successfully signed user")
-	return &SignInOutput{AccessToken: accessToken}, nil
+	return &SignInOutput{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
 func (a authService) SignUp(ctx context.Context, options *SignUpOptions) (*SignUpOutput, error) {
@@ -77,6 +133,12 @@ func (a authService) SignUp(ctx context.Context, options *SignUpOptions) (*SignU
 		return nil, ErrSignUpUserAlreadyCreated
 	}
 
+	err = a.checkPassword("password", options.Password, options.Username, options.Email)
+	if err != nil {
+		logger.Info("password rejected", "err", err)
+		return nil, err
+	}
+
 	hashedPassword, err := a.hash.GenerateHash(options.Password)
 	if err != nil {
 		logger.Error("failed to hash user password: ", err)
@@ -88,15 +150,97 @@ func (a authService) SignUp(ctx context.Context, options *SignUpOptions) (*SignU
 		logger.Error("failed to create user: ", err)
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	if createdUser == nil {
+		logger.Info("user already created")
+		return nil, ErrSignUpUserAlreadyCreated
+	}
+
+	// user can request another verification mail, so sign up does not fail here
+	err = a.sendVerificationEmail(ctx, createdUser)
+	if err != nil {
+		logger.Error("failed to send verification email: ", err)
+	}
 
-	accessToken, err := a.auth.GenerateToken(&auth.GenerateTokenClaimsOptions{UserName: createdUser.Username, UserId: createdUser.Id})
+	accessToken, refreshToken, err := a.generateTokens(ctx, createdUser, uuid.NewString())
+	if err != nil {
+		logger.Error("failed to generate tokens for user: ", err)
+		return nil, fmt.Errorf("failed to generate tokens for user: %w", err)
+	}
+
+	logger.Info("successfully handled sign up")
+	return &SignUpOutput{
+		Id:           createdUser.Id,
+		Username:     createdUser.Username,
+		Email:        createdUser.Email,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (a authService) RefreshToken(ctx context.Context, options *RefreshTokenOptions) (*RefreshTokenOutput, error) {
+	logger := a.logger.
+		Named("RefreshToken").
+		WithContext(ctx)
+
+	refreshToken, err := a.storages.RefreshTokenStorage.GetRefreshToken(ctx, &GetRefreshTokenFilter{TokenHash: auth.HashOpaqueToken(options.RefreshToken)})
+	if err != nil {
+		logger.Error("failed to get refresh token: ", err)
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if refreshToken == nil {
+		logger.Info("refresh token not found")
+		return nil, ErrRefreshTokenInvalid
+	}
+	logger = logger.With("refreshTokenId", refreshToken.Id, "familyId", refreshToken.FamilyId)
+
+	if refreshToken.RevokedAt != nil {
+		logger.Warn("revoked refresh token reused, revoking whole family")
+		return nil, a.revokeRefreshTokenFamily(ctx, refreshToken.FamilyId)
+	}
+	if time.Now().After(refreshToken.ExpiresAt) {
+		logger.Info("refresh token expired")
+		return nil, ErrRefreshTokenExpired
+	}
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: refreshToken.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || checkUserActive(user) != nil {
+		logger.Info("user not found or disabled")
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	newRefreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		logger.Error("failed to generate refresh token: ", err)
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	rotatedRefreshToken, err := a.storages.RefreshTokenStorage.RotateRefreshToken(ctx, refreshToken.Id, &entity.RefreshToken{
+		UserId:    user.Id,
+		FamilyId:  refreshToken.FamilyId,
+		TokenHash: auth.HashOpaqueToken(newRefreshToken),
+		ExpiresAt: time.Now().Add(a.config.JWT.RefreshTokenTTL),
+	})
+	if err != nil {
+		logger.Error("failed to rotate refresh token: ", err)
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rotatedRefreshToken == nil {
+		logger.Warn("refresh token concurrently reused, revoking whole family")
+		return nil, a.revokeRefreshTokenFamily(ctx, refreshToken.FamilyId)
+	}
+
+	accessToken, err := a.auth.GenerateToken(&auth.GenerateTokenClaimsOptions{UserName: user.Username, UserId: user.Id, SessionId: refreshToken.FamilyId, Role: user.Role})
 	if err != nil {
 		logger.Error("failed to generate token for user: ", err)
 		return nil, fmt.Errorf("failed to generate token for user: %w", err)
 	}
 
-	logger.Info("successfully handled sign up")
-	return &SignUpOutput{Id: createdUser.Id, Username: createdUser.Username, Email: createdUser.Email, AccessToken: accessToken}, nil
+	logger.Info("successfully refreshed tokens")
+	return &RefreshTokenOutput{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
 }
 
 func (a authService) VerifyToken(ctx context.Context, options *VerifyTokenOptions) (*VerifyTokenOutput, error) {
@@ -104,12 +248,443 @@ func (a authService) VerifyToken(ctx context.Context, options *VerifyTokenOption
 		Named("VerifyToken").
 		WithContext(ctx)
 
+	if strings.HasPrefix(options.AccessToken, _personalAccessTokenPrefix) {
+		verified, err := a.verifyPersonalAccessToken(ctx, options.AccessToken)
+		if err != nil {
+			logger.Info("failed to verify personal access token: ", err)
+			return nil, err
+		}
+
+		logger.Info("successfully handled personal access token", "tokenId", verified.PersonalAccessTokenId)
+		return verified, nil
+	}
+
 	claims, err := a.auth.ParseToken(options.AccessToken)
 	if err != nil {
 		logger.Info("failed to parse token: ", err)
-		return nil, fmt.Errorf("invalid token")
+		return nil, ErrVerifyTokenInvalid
+	}
+	logger = logger.With("tokenId", claims.TokenId, "sessionId", claims.SessionId)
+
+	revoked, err := a.storages.RevokedTokenStorage.IsTokenRevoked(ctx, &IsTokenRevokedFilter{TokenId: claims.TokenId, SessionId: claims.SessionId})
+	if err != nil {
+		logger.Error("failed to check token revocation: ", err)
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		logger.Info("token revoked")
+		return nil, ErrVerifyTokenRevoked
 	}
 
 	logger.Info("successfully handled auth token")
-	return &VerifyTokenOutput{Username: claims.Username, UserId: claims.UserId}, nil
+	return &VerifyTokenOutput{
+		Username:  claims.Username,
+		UserId:    claims.UserId,
+		SessionId: claims.SessionId,
+		TokenId:   claims.TokenId,
+		Role:      claims.Role,
+	}, nil
+}
+
+func (a authService) SignOut(ctx context.Context, options *SignOutOptions) error {
+	logger := a.logger.
+		Named("SignOut").
+		WithContext(ctx)
+
+	claims, err := a.auth.ParseToken(options.AccessToken)
+	if err != nil {
+		logger.Info("failed to parse token: ", err)
+		return ErrSignOutInvalidToken
+	}
+	logger = logger.With("userId", claims.UserId, "tokenId", claims.TokenId, "sessionId", claims.SessionId)
+
+	if claims.SessionId != "" {
+		err = a.storages.RefreshTokenStorage.RevokeRefreshTokenFamily(ctx, claims.SessionId)
+		if err != nil {
+			logger.Error("failed to revoke refresh token family: ", err)
+			return fmt.Errorf("failed to revoke refresh token family: %w", err)
+		}
+	}
+
+	err = a.storages.RevokedTokenStorage.CreateRevokedTokens(ctx, []entity.RevokedToken{
+		{UserId: claims.UserId, TokenId: claims.TokenId, SessionId: claims.SessionId, ExpiresAt: claims.ExpiresAt},
+	})
+	if err != nil {
+		logger.Error("failed to revoke token: ", err)
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	logger.Info("successfully signed out")
+	return nil
+}
+
+func (a authService) SignOutAll(ctx context.Context, options *SignOutAllOptions) error {
+	logger := a.logger.
+		Named("SignOutAll").
+		WithContext(ctx)
+
+	claims, err := a.auth.ParseToken(options.AccessToken)
+	if err != nil {
+		logger.Info("failed to parse token: ", err)
+		return ErrSignOutInvalidToken
+	}
+	logger = logger.With("userId", claims.UserId)
+
+	err = a.revokeUserSessions(ctx, claims.UserId, "")
+	if err != nil {
+		logger.Error("failed to revoke user sessions: ", err)
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	// token might be issued before sessions were introduced
+	err = a.storages.RevokedTokenStorage.CreateRevokedTokens(ctx, []entity.RevokedToken{
+		{UserId: claims.UserId, TokenId: claims.TokenId, ExpiresAt: claims.ExpiresAt},
+	})
+	if err != nil {
+		logger.Error("failed to revoke token: ", err)
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	logger.Info("successfully signed out from all sessions")
+	return nil
+}
+
+func (a authService) PruneRevokedTokens(ctx context.Context) error {
+	logger := a.logger.
+		Named("PruneRevokedTokens").
+		WithContext(ctx)
+
+	deleted, err := a.storages.RevokedTokenStorage.DeleteExpiredRevokedTokens(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to delete expired revoked tokens: ", err)
+		return fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+
+	logger.Info("successfully pruned revoked tokens", "deleted", deleted)
+	return nil
+}
+
+func (a authService) GetJSONWebKeySet(ctx context.Context) (*GetJSONWebKeySetOutput, error) {
+	logger := a.logger.
+		Named("GetJSONWebKeySet").
+		WithContext(ctx)
+
+	keys := a.auth.PublicKeys()
+
+	logger.Debug("successfully got json web key set", "keys", len(keys))
+	return &GetJSONWebKeySetOutput{Keys: keys}, nil
+}
+
+func (a authService) ForgotPassword(ctx context.Context, options *ForgotPasswordOptions) error {
+	logger := a.logger.
+		Named("ForgotPassword").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: options.Email})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		// do not reveal whether the email is registered
+		logger.Info("user not found")
+		return nil
+	}
+	logger = logger.With("userId", user.Id)
+
+	resetToken, err := a.createUserToken(ctx, user.Id, entity.UserTokenPurposePasswordReset, a.config.Auth.PasswordResetTokenTTL)
+	if err != nil {
+		logger.Error("failed to create reset token: ", err)
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Reset your droplet password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to set a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not request a password reset, just ignore this email.\n",
+			user.Username, a.config.Auth.PasswordResetTokenTTL, a.config.App.BaseURL, resetToken,
+		),
+	})
+	if err != nil {
+		logger.Error("failed to send reset mail: ", err)
+		return fmt.Errorf("failed to send reset mail: %w", err)
+	}
+
+	logger.Info("successfully sent password reset mail")
+	return nil
+}
+
+func (a authService) ResetPassword(ctx context.Context, options *ResetPasswordOptions) error {
+	logger := a.logger.
+		Named("ResetPassword").
+		WithContext(ctx)
+
+	// checked before the token is consumed, so a rejected password does not burn the reset link;
+	// rules depending on the user are checked once the token resolves the user
+	err := a.checkPassword("password", options.Password)
+	if err != nil {
+		logger.Info("password rejected", "err", err)
+		return err
+	}
+
+	resetToken, err := a.storages.UserTokenStorage.ConsumeUserToken(ctx, &ConsumeUserTokenFilter{
+		TokenHash: auth.HashOpaqueToken(options.Token),
+		Purpose:   entity.UserTokenPurposePasswordReset,
+	})
+	if err != nil {
+		logger.Error("failed to consume reset token: ", err)
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+	if resetToken == nil {
+		logger.Info("reset token not found")
+		return ErrResetPasswordInvalidToken
+	}
+	logger = logger.With("userId", resetToken.UserId)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: resetToken.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return ErrResetPasswordInvalidToken
+	}
+
+	err = a.checkPassword("password", options.Password, user.Username, user.Email)
+	if err != nil {
+		logger.Info("password rejected", "err", err)
+		return err
+	}
+
+	hashedPassword, err := a.hash.GenerateHash(options.Password)
+	if err != nil {
+		logger.Error("failed to hash user password: ", err)
+		return fmt.Errorf("failed to hash user password: %w", err)
+	}
+	user.Password = hashedPassword
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	err = a.revokeUserSessions(ctx, user.Id, "")
+	if err != nil {
+		logger.Error("failed to revoke user sessions: ", err)
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	logger.Info("successfully reset password")
+	return nil
+}
+
+func (a authService) VerifyEmail(ctx context.Context, options *VerifyEmailOptions) error {
+	logger := a.logger.
+		Named("VerifyEmail").
+		WithContext(ctx)
+
+	verificationToken, err := a.storages.UserTokenStorage.ConsumeUserToken(ctx, &ConsumeUserTokenFilter{
+		TokenHash: auth.HashOpaqueToken(options.Token),
+		Purpose:   entity.UserTokenPurposeEmailVerification,
+	})
+	if err != nil {
+		logger.Error("failed to consume verification token: ", err)
+		return fmt.Errorf("failed to consume verification token: %w", err)
+	}
+	if verificationToken == nil {
+		logger.Info("verification token not found")
+		return ErrVerifyEmailInvalidToken
+	}
+	logger = logger.With("userId", verificationToken.UserId)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: verificationToken.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return ErrVerifyEmailInvalidToken
+	}
+	user.EmailVerified = true
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	logger.Info("successfully verified email")
+	return nil
+}
+
+func (a authService) ResendVerificationEmail(ctx context.Context, options *ResendVerificationEmailOptions) error {
+	logger := a.logger.
+		Named("ResendVerificationEmail").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return ErrResendVerificationEmailUserNotFound
+	}
+	if user.EmailVerified {
+		logger.Info("email already verified")
+		return ErrEmailAlreadyVerified
+	}
+
+	err = a.sendVerificationEmail(ctx, user)
+	if err != nil {
+		logger.Error("failed to send verification email: ", err)
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	logger.Info("successfully resent verification email")
+	return nil
+}
+
+// generateTokens issues access token and opaque refresh token which belongs to given refresh token family.
+func (a authService) generateTokens(ctx context.Context, user *entity.User, familyId string) (string, string, error) {
+	accessToken, err := a.auth.GenerateToken(&auth.GenerateTokenClaimsOptions{UserName: user.Username, UserId: user.Id, SessionId: familyId, Role: user.Role})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	_, err = a.storages.RefreshTokenStorage.CreateRefreshToken(ctx, &entity.RefreshToken{
+		UserId:    user.Id,
+		FamilyId:  familyId,
+		TokenHash: auth.HashOpaqueToken(refreshToken),
+		ExpiresAt: time.Now().Add(a.config.JWT.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// revokeRefreshTokenFamily invalidates all refresh tokens issued for the same sign in
+// and returns error which has to be handed to the client.
+func (a authService) revokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	err := a.storages.RefreshTokenStorage.RevokeRefreshTokenFamily(ctx, familyId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// revokeUserSessions revokes refresh tokens of every user session except the given one
+// and denylists access tokens which were already issued for them.
+func (s serviceContext) revokeUserSessions(ctx context.Context, userId, exceptSessionId string) error {
+	sessionIds, err := s.storages.RefreshTokenStorage.RevokeUserRefreshTokens(ctx, &RevokeUserRefreshTokensFilter{
+		UserId:         userId,
+		ExceptFamilyId: exceptSessionId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	revokedTokens := make([]entity.RevokedToken, 0, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		revokedTokens = append(revokedTokens, entity.RevokedToken{
+			UserId:    userId,
+			SessionId: sessionId,
+			ExpiresAt: time.Now().Add(s.config.JWT.AccessTokenTTL),
+		})
+	}
+
+	err = s.storages.RevokedTokenStorage.CreateRevokedTokens(ctx, revokedTokens)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	return nil
+}
+
+// createUserToken stores hash of new single-use token and returns the token itself.
+func (a authService) createUserToken(ctx context.Context, userId, purpose string, ttl time.Duration) (string, error) {
+	return a.storeUserToken(ctx, &entity.UserToken{UserId: userId, Purpose: purpose}, ttl)
+}
+
+// storeUserToken generates token for given user token template, stores its hash and returns the token itself.
+func (s serviceContext) storeUserToken(ctx context.Context, userToken *entity.UserToken, ttl time.Duration) (string, error) {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	userToken.TokenHash = auth.HashOpaqueToken(token)
+	userToken.ExpiresAt = time.Now().Add(ttl)
+
+	_, err = s.storages.UserTokenStorage.CreateUserToken(ctx, userToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return token, nil
+}
+
+// sendVerificationEmail creates email verification token and mails it to the user.
+func (a authService) sendVerificationEmail(ctx context.Context, user *entity.User) error {
+	verificationToken, err := a.createUserToken(ctx, user.Id, entity.UserTokenPurposeEmailVerification, a.config.Auth.EmailVerificationTokenTTL)
+	if err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your droplet email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address using the link below. It expires in %s.\n\n%s/verify-email?token=%s\n\nIf you did not sign up for droplet, just ignore this email.\n",
+			user.Username, a.config.Auth.EmailVerificationTokenTTL, a.config.App.BaseURL, verificationToken,
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// rehashPassword replaces outdated password hash with the hash of currently configured algorithm.
+func (a authService) rehashPassword(ctx context.Context, user *entity.User, password string) error {
+	hashedPassword, err := a.hash.GenerateHash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.Password = hashedPassword
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
+// checkUserActive rejects users which are disabled or scheduled for deletion.
+func checkUserActive(user *entity.User) error {
+	if user.Disabled {
+		return ErrSignInUserDisabled
+	}
+	if user.DeletionScheduledFor != nil {
+		return ErrSignInDeletionPending
+	}
+
+	return nil
 }
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+	"time"
+)
+
+// signIn signs in user without second factor and returns tokens of the new session.
+func signIn(t *testing.T, service AuthService) *SignInOutput {
+	t.Helper()
+
+	signed, err := service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+
+	return signed
+}
+
+func refresh(service AuthService, refreshToken string) (string, error) {
+	refreshed, err := service.RefreshToken(context.Background(), &RefreshTokenOptions{RefreshToken: refreshToken})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshed.RefreshToken, nil
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	stolen := signIn(t, service).RefreshToken
+	otherSession := signIn(t, service).RefreshToken
+
+	rotated, err := refresh(service, stolen)
+	if err != nil {
+		t.Fatalf("first RefreshToken() error = %v", err)
+	}
+
+	// attacker replays the token which was already rotated
+	_, err = refresh(service, stolen)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused RefreshToken() error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// the legitimate client loses the session as well, as nobody can tell which party is which
+	_, err = refresh(service, rotated)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RefreshToken() of revoked family error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	_, err = refresh(service, otherSession)
+	if err != nil {
+		t.Fatalf("RefreshToken() of other session error = %v", err)
+	}
+}
+
+func TestRefreshTokenRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup returns refresh token to use
+		setup   func(t *testing.T, storages *testStorages, service AuthService) string
+		wantErr error
+	}{
+		{
+			name: "unknown token",
+			setup: func(t *testing.T, storages *testStorages, service AuthService) string {
+				return "unknown"
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "expired token",
+			setup: func(t *testing.T, storages *testStorages, service AuthService) string {
+				refreshToken := signIn(t, service).RefreshToken
+				for _, token := range storages.refreshTokens.tokens {
+					token.ExpiresAt = time.Now().Add(-time.Second)
+				}
+				return refreshToken
+			},
+			wantErr: ErrRefreshTokenExpired,
+		},
+		{
+			name: "disabled user",
+			setup: func(t *testing.T, storages *testStorages, service AuthService) string {
+				refreshToken := signIn(t, service).RefreshToken
+				for _, user := range storages.users.users {
+					user.Disabled = true
+				}
+				return refreshToken
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "user pending deletion",
+			setup: func(t *testing.T, storages *testStorages, service AuthService) string {
+				refreshToken := signIn(t, service).RefreshToken
+				deletionScheduledFor := time.Now().Add(time.Hour)
+				for _, user := range storages.users.users {
+					user.DeletionScheduledFor = &deletionScheduledFor
+				}
+				return refreshToken
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			createTestUser(t, options, _testEmail, _testPassword)
+			service := NewAuthService(options)
+
+			_, err := refresh(service, tt.setup(t, storages, service))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefreshToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func verify(service AuthService, accessToken string) error {
+	_, err := service.VerifyToken(context.Background(), &VerifyTokenOptions{AccessToken: accessToken})
+	return err
+}
+
+func TestSignOut(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	session := signIn(t, service)
+	otherSession := signIn(t, service)
+
+	// access token issued by refresh belongs to the same session as the signed out one
+	refreshed, err := service.RefreshToken(context.Background(), &RefreshTokenOptions{RefreshToken: session.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err)
+	}
+
+	err = service.SignOut(context.Background(), &SignOutOptions{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("SignOut() error = %v", err)
+	}
+
+	for name, accessToken := range map[string]string{"signed out": session.AccessToken, "refreshed": refreshed.AccessToken} {
+		if err := verify(service, accessToken); !errors.Is(err, ErrVerifyTokenRevoked) {
+			t.Errorf("VerifyToken() of %s token error = %v, want %v", name, err, ErrVerifyTokenRevoked)
+		}
+	}
+	if _, err := refresh(service, refreshed.RefreshToken); err == nil {
+		t.Errorf("RefreshToken() of signed out session succeeded")
+	}
+
+	if err := verify(service, otherSession.AccessToken); err != nil {
+		t.Errorf("VerifyToken() of other session error = %v", err)
+	}
+	if _, err := refresh(service, otherSession.RefreshToken); err != nil {
+		t.Errorf("RefreshToken() of other session error = %v", err)
+	}
+}
+
+func TestSignOutAll(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	sessions := []*SignInOutput{signIn(t, service), signIn(t, service)}
+
+	err := service.SignOutAll(context.Background(), &SignOutAllOptions{AccessToken: sessions[0].AccessToken})
+	if err != nil {
+		t.Fatalf("SignOutAll() error = %v", err)
+	}
+
+	for i, session := range sessions {
+		if err := verify(service, session.AccessToken); !errors.Is(err, ErrVerifyTokenRevoked) {
+			t.Errorf("VerifyToken() of session %d error = %v, want %v", i, err, ErrVerifyTokenRevoked)
+		}
+		if _, err := refresh(service, session.RefreshToken); err == nil {
+			t.Errorf("RefreshToken() of session %d succeeded", i)
+		}
+	}
+}
+
+func TestSignOutRejectsInvalidToken(t *testing.T) {
+	service := NewAuthService(newTestOptions(t, newTestStorages()))
+
+	err := service.SignOut(context.Background(), &SignOutOptions{AccessToken: "invalid"})
+	if !errors.Is(err, ErrSignOutInvalidToken) {
+		t.Fatalf("SignOut() error = %v, want %v", err, ErrSignOutInvalidToken)
+	}
+}
+
+func TestPruneRevokedTokens(t *testing.T) {
+	storages := newTestStorages()
+	service := NewAuthService(newTestOptions(t, storages))
+
+	storages.revokedTokens.tokens = []entity.RevokedToken{
+		{TokenId: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+		{SessionId: "expired-session", ExpiresAt: time.Now().Add(-time.Second)},
+		{TokenId: "active", ExpiresAt: time.Now().Add(time.Minute)},
+	}
+
+	err := service.PruneRevokedTokens(context.Background())
+	if err != nil {
+		t.Fatalf("PruneRevokedTokens() error = %v", err)
+	}
+
+	if len(storages.revokedTokens.tokens) != 1 || storages.revokedTokens.tokens[0].TokenId != "active" {
+		t.Fatalf("revoked tokens after prune = %+v, want only active one", storages.revokedTokens.tokens)
+	}
+}
+
+func TestSignUpRejectsTakenEmail(t *testing.T) {
+	options := newTestOptions(t, newTestStorages())
+	service := NewAuthService(options)
+	createTestUser(t, options, "User@Droplet.local", _testPassword)
+
+	_, err := service.SignUp(context.Background(), &SignUpOptions{Email: _testEmail, Username: "user", Password: _testPassword})
+	if err != ErrSignUpUserAlreadyCreated {
+		t.Fatalf("SignUp() error = %v, want %v", err, ErrSignUpUserAlreadyCreated)
+	}
+}
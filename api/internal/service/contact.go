@@ -0,0 +1,548 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+type contactService struct {
+	serviceContext
+}
+
+var _ ContactService = (*contactService)(nil)
+
+func NewContactService(options *Options) ContactService {
+	return &contactService{
+		serviceContext: serviceContext{
+			storages: options.Storages,
+			config:   options.Config,
+			logger:   options.Logger.Named("ContactService"),
+		},
+	}
+}
+
+func (c contactService) SendContactRequest(ctx context.Context, options *SendContactRequestOptions) (*ContactOutput, error) {
+	logger := c.logger.
+		Named("SendContactRequest").
+		WithContext(ctx).
+		With("options", options)
+
+	target, err := c.findUserByEmail(ctx, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		// unknown email is answered like request to undiscoverable user, so it does not disclose who is registered
+		logger.Info("user not found")
+		return newUnknownContactOutput(options.UserId, options.Email), nil
+	}
+	if target.Id == options.UserId {
+		logger.Info("user cannot add themselves")
+		return nil, ErrContactSelf
+	}
+	logger = logger.With("targetUserId", target.Id)
+
+	blocking, err := c.isBlocked(ctx, options.UserId, target.Id)
+	if err != nil {
+		logger.Error("failed to check block: ", err)
+		return nil, fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocking {
+		logger.Info("target user is blocked by the user")
+		return nil, ErrContactUserBlocked
+	}
+
+	existing, err := c.storages.ContactStorage.GetContact(ctx, &GetContactFilter{UserId: options.UserId, OtherUserId: target.Id})
+	if err != nil {
+		logger.Error("failed to get contact: ", err)
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	if existing != nil {
+		switch {
+		case existing.Status == entity.ContactStatusAccepted:
+			logger.Info("users are already contacts")
+			return nil, ErrContactAlreadyExists
+
+		case existing.RequesterId == options.UserId && existing.Status == entity.ContactStatusDeclined:
+			logger.Info("contact request was declined")
+			return nil, ErrContactRequestDeclined
+
+		case existing.RequesterId == options.UserId:
+			// asking again changes nothing, ignored request keeps looking pending
+			logger.Info("contact request already sent")
+			return newContactOutput(existing, options.UserId, target), nil
+
+		default:
+			// target asked first, so asking back accepts the request
+			now := time.Now()
+			existing.Status = entity.ContactStatusAccepted
+			existing.RespondedAt = &now
+
+			accepted, err := c.storages.ContactStorage.UpdateContact(ctx, existing)
+			if err != nil {
+				logger.Error("failed to update contact: ", err)
+				return nil, fmt.Errorf("failed to update contact: %w", err)
+			}
+
+			logger.Info("mutual contact request accepted", "contactId", accepted.Id)
+			return newContactOutput(accepted, options.UserId, target), nil
+		}
+	}
+
+	blockedByTarget, err := c.isBlocked(ctx, target.Id, options.UserId)
+	if err != nil {
+		logger.Error("failed to check block: ", err)
+		return nil, fmt.Errorf("failed to check block: %w", err)
+	}
+
+	discoverable, err := c.getDiscoverableUserIds(ctx, options.UserId, []string{target.Id})
+	if err != nil {
+		logger.Error("failed to check discoverability: ", err)
+		return nil, fmt.Errorf("failed to check discoverability: %w", err)
+	}
+
+	// request to the user who blocked the requester or cannot be found by the requester is stored as ignored,
+	// so it never reaches the addressee while the requester sees it as pending like any other unanswered request
+	status := entity.ContactStatusPending
+	if blockedByTarget || !discoverable[target.Id] {
+		status = entity.ContactStatusIgnored
+	}
+
+	contact, err := c.storages.ContactStorage.CreateContact(ctx, &entity.Contact{
+		RequesterId: options.UserId,
+		AddresseeId: target.Id,
+		Status:      status,
+	})
+	if err != nil {
+		logger.Error("failed to create contact: ", err)
+		return nil, fmt.Errorf("failed to create contact: %w", err)
+	}
+
+	logger.Info("contact request successfully sent", "contactId", contact.Id)
+	return newContactOutput(contact, options.UserId, target), nil
+}
+
+func (c contactService) RespondContactRequest(ctx context.Context, options *RespondContactRequestOptions) (*ContactOutput, error) {
+	logger := c.logger.
+		Named("RespondContactRequest").
+		WithContext(ctx).
+		With("options", options)
+
+	var status string
+	switch options.Action {
+	case ContactActionAccept:
+		status = entity.ContactStatusAccepted
+	case ContactActionDecline:
+		status = entity.ContactStatusDeclined
+	case ContactActionIgnore:
+		status = entity.ContactStatusIgnored
+	default:
+		logger.Info("invalid action")
+		return nil, ErrContactInvalidAction
+	}
+
+	contact, err := c.storages.ContactStorage.GetContact(ctx, &GetContactFilter{Id: options.ContactId, UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get contact: ", err)
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	// only the addressee answers, ignored request can still be answered later
+	if contact == nil || contact.AddresseeId != options.UserId ||
+		(contact.Status != entity.ContactStatusPending && contact.Status != entity.ContactStatusIgnored) {
+		logger.Info("contact request not found")
+		return nil, ErrContactNotFound
+	}
+
+	requester, err := c.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: contact.RequesterId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if requester == nil {
+		logger.Info("requester not found")
+		return nil, ErrContactNotFound
+	}
+
+	contact.Status = status
+	if status != entity.ContactStatusIgnored {
+		now := time.Now()
+		contact.RespondedAt = &now
+	}
+
+	contact, err = c.storages.ContactStorage.UpdateContact(ctx, contact)
+	if err != nil {
+		logger.Error("failed to update contact: ", err)
+		return nil, fmt.Errorf("failed to update contact: %w", err)
+	}
+
+	logger.Info("contact request successfully answered")
+	return newContactOutput(contact, options.UserId, requester), nil
+}
+
+func (c contactService) ListContacts(ctx context.Context, options *ListContactsOptions) (*ListContactsOutput, error) {
+	logger := c.logger.
+		Named("ListContacts").
+		WithContext(ctx).
+		With("options", options)
+
+	contacts, err := c.storages.ContactStorage.ListContacts(ctx, &ListContactsFilter{
+		UserId:   options.UserId,
+		Statuses: []string{entity.ContactStatusAccepted},
+	})
+	if err != nil {
+		logger.Error("failed to list contacts: ", err)
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	output, err := c.newContactsOutput(ctx, options.UserId, contacts)
+	if err != nil {
+		logger.Error("failed to get contact users: ", err)
+		return nil, fmt.Errorf("failed to get contact users: %w", err)
+	}
+
+	logger.Info("successfully listed contacts", "count", len(output.Contacts))
+	return output, nil
+}
+
+func (c contactService) ListContactRequests(ctx context.Context, options *ListContactRequestsOptions) (*ListContactsOutput, error) {
+	logger := c.logger.
+		Named("ListContactRequests").
+		WithContext(ctx).
+		With("options", options)
+
+	filter := &ListContactsFilter{}
+	switch options.Direction {
+	case ContactDirectionIncoming:
+		// ignored requests are hidden from the addressee
+		filter.AddresseeId = options.UserId
+		filter.Statuses = []string{entity.ContactStatusPending}
+	case ContactDirectionOutgoing:
+		filter.RequesterId = options.UserId
+		filter.Statuses = []string{entity.ContactStatusPending, entity.ContactStatusIgnored, entity.ContactStatusDeclined}
+	default:
+		logger.Info("invalid direction")
+		return nil, ErrContactInvalidDirection
+	}
+
+	contacts, err := c.storages.ContactStorage.ListContacts(ctx, filter)
+	if err != nil {
+		logger.Error("failed to list contacts: ", err)
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	output, err := c.newContactsOutput(ctx, options.UserId, contacts)
+	if err != nil {
+		logger.Error("failed to get contact users: ", err)
+		return nil, fmt.Errorf("failed to get contact users: %w", err)
+	}
+
+	logger.Info("successfully listed contact requests", "count", len(output.Contacts))
+	return output, nil
+}
+
+func (c contactService) RemoveContact(ctx context.Context, options *RemoveContactOptions) error {
+	logger := c.logger.
+		Named("RemoveContact").
+		WithContext(ctx).
+		With("options", options)
+
+	contact, err := c.storages.ContactStorage.GetContact(ctx, &GetContactFilter{Id: options.ContactId, UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get contact: ", err)
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	// addressee answers requests instead of removing them, otherwise requester could ask again
+	if contact == nil || (contact.AddresseeId == options.UserId && contact.Status != entity.ContactStatusAccepted) {
+		logger.Info("contact not found")
+		return ErrContactNotFound
+	}
+
+	_, err = c.storages.ContactStorage.DeleteContact(ctx, &GetContactFilter{Id: contact.Id})
+	if err != nil {
+		logger.Error("failed to delete contact: ", err)
+		return fmt.Errorf("failed to delete contact: %w", err)
+	}
+
+	logger.Info("contact successfully removed")
+	return nil
+}
+
+func (c contactService) BlockUser(ctx context.Context, options *BlockUserOptions) (*BlockedUserOutput, error) {
+	logger := c.logger.
+		Named("BlockUser").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := c.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return nil, ErrContactUserNotFound
+	}
+
+	target, err := c.findUserByEmail(ctx, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		// blocking unknown email looks successful, so it does not disclose who is registered
+		logger.Info("target user not found")
+		return &BlockedUserOutput{Email: strings.TrimSpace(options.Email), BlockedAt: time.Now()}, nil
+	}
+	if target.Id == user.Id {
+		logger.Info("user cannot block themselves")
+		return nil, ErrContactSelf
+	}
+	logger = logger.With("targetUserId", target.Id)
+
+	err = c.storages.BlockStorage.CreateBlock(ctx, &entity.Block{UserId: user.Id, BlockedUserId: target.Id})
+	if err != nil {
+		logger.Error("failed to create block: ", err)
+		return nil, fmt.Errorf("failed to create block: %w", err)
+	}
+
+	// transfers which wait for approval are dropped the same way new ones are
+	heldTransfers, err := c.storages.NodeStorage.ListNodes(ctx, &ListNodesFilter{
+		SenderEmail:   target.Email,
+		ReceiverEmail: user.Email,
+		Status:        entity.NodeStatusHeld,
+	})
+	if err != nil {
+		logger.Error("failed to list held transfers: ", err)
+		return nil, fmt.Errorf("failed to list held transfers: %w", err)
+	}
+	for i := range heldTransfers {
+		heldTransfers[i].Status = entity.NodeStatusBlocked
+
+		_, err = c.storages.NodeStorage.UpdateNode(ctx, &heldTransfers[i])
+		if err != nil {
+			logger.Error("failed to update node: ", err)
+			return nil, fmt.Errorf("failed to update node: %w", err)
+		}
+	}
+
+	block, err := c.storages.BlockStorage.GetBlock(ctx, &GetBlockFilter{UserId: user.Id, BlockedUserId: target.Id})
+	if err != nil {
+		logger.Error("failed to get block: ", err)
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+	if block == nil {
+		// unblocked concurrently
+		logger.Info("block not found")
+		return nil, ErrBlockedUserNotFound
+	}
+
+	// blocked user is identified only once listed, so blocking registered and unknown emails looks the same
+	logger.Info("user successfully blocked")
+	return &BlockedUserOutput{Email: target.Email, BlockedAt: block.CreatedAt}, nil
+}
+
+func (c contactService) UnblockUser(ctx context.Context, options *UnblockUserOptions) error {
+	logger := c.logger.
+		Named("UnblockUser").
+		WithContext(ctx).
+		With("options", options)
+
+	deleted, err := c.storages.BlockStorage.DeleteBlock(ctx, &GetBlockFilter{UserId: options.UserId, BlockedUserId: options.BlockedUserId})
+	if err != nil {
+		logger.Error("failed to delete block: ", err)
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+	if !deleted {
+		logger.Info("block not found")
+		return ErrBlockedUserNotFound
+	}
+
+	logger.Info("user successfully unblocked")
+	return nil
+}
+
+func (c contactService) ListBlockedUsers(ctx context.Context, options *ListBlockedUsersOptions) (*ListBlockedUsersOutput, error) {
+	logger := c.logger.
+		Named("ListBlockedUsers").
+		WithContext(ctx).
+		With("options", options)
+
+	blocks, err := c.storages.BlockStorage.ListBlocks(ctx, options.UserId)
+	if err != nil {
+		logger.Error("failed to list blocks: ", err)
+		return nil, fmt.Errorf("failed to list blocks: %w", err)
+	}
+
+	userIds := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		userIds = append(userIds, block.BlockedUserId)
+	}
+
+	users, err := c.getUsersById(ctx, userIds)
+	if err != nil {
+		logger.Error("failed to get blocked users: ", err)
+		return nil, fmt.Errorf("failed to get blocked users: %w", err)
+	}
+
+	output := &ListBlockedUsersOutput{Users: make([]BlockedUserOutput, 0, len(blocks))}
+	for i := range blocks {
+		user, ok := users[blocks[i].BlockedUserId]
+		if !ok {
+			continue
+		}
+		output.Users = append(output.Users, *newBlockedUserOutput(&blocks[i], &user))
+	}
+
+	logger.Info("successfully listed blocked users", "count", len(output.Users))
+	return output, nil
+}
+
+// isBlocked reports whether userId blocked blockedUserId.
+func (s serviceContext) isBlocked(ctx context.Context, userId, blockedUserId string) (bool, error) {
+	block, err := s.storages.BlockStorage.GetBlock(ctx, &GetBlockFilter{UserId: userId, BlockedUserId: blockedUserId})
+	if err != nil {
+		return false, err
+	}
+
+	return block != nil, nil
+}
+
+// areContacts reports whether users accepted contact request of one another.
+func (s serviceContext) areContacts(ctx context.Context, userId, otherUserId string) (bool, error) {
+	contact, err := s.storages.ContactStorage.GetContact(ctx, &GetContactFilter{UserId: userId, OtherUserId: otherUserId})
+	if err != nil {
+		return false, err
+	}
+
+	return contact != nil && contact.Status == entity.ContactStatusAccepted, nil
+}
+
+// isTrustedContact reports whether users accepted contact request of one another
+// and neither of them blocked the other.
+func (s serviceContext) isTrustedContact(ctx context.Context, userId, otherUserId string) (bool, error) {
+	contacts, err := s.areContacts(ctx, userId, otherUserId)
+	if err != nil || !contacts {
+		return false, err
+	}
+
+	blocked, err := s.isBlocked(ctx, userId, otherUserId)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	blocked, err = s.isBlocked(ctx, otherUserId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return !blocked, nil
+}
+
+// getUsersById returns users mapped by id, users which no longer exist are left out.
+func (s serviceContext) getUsersById(ctx context.Context, userIds []string) (map[string]entity.User, error) {
+	users := make(map[string]entity.User, len(userIds))
+	if len(userIds) == 0 {
+		return users, nil
+	}
+
+	found, _, err := s.storages.UserStorage.ListUsers(ctx, &ListUsersFilter{UserIds: userIds, Limit: len(userIds)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	for _, user := range found {
+		users[user.Id] = user
+	}
+
+	return users, nil
+}
+
+func (c contactService) newContactsOutput(ctx context.Context, userId string, contacts []entity.Contact) (*ListContactsOutput, error) {
+	userIds := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		userIds = append(userIds, otherContactUserId(&contact, userId))
+	}
+
+	users, err := c.getUsersById(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ListContactsOutput{Contacts: make([]ContactOutput, 0, len(contacts))}
+	for i := range contacts {
+		user, ok := users[otherContactUserId(&contacts[i], userId)]
+		if !ok {
+			continue
+		}
+		output.Contacts = append(output.Contacts, *newContactOutput(&contacts[i], userId, &user))
+	}
+
+	return output, nil
+}
+
+func otherContactUserId(contact *entity.Contact, userId string) string {
+	if contact.RequesterId == userId {
+		return contact.AddresseeId
+	}
+
+	return contact.RequesterId
+}
+
+// newContactOutput shows contact from the point of view of userId, other is the other user.
+// Requester never learns the request was ignored, and other user is identified only once
+// the request is answered, so unanswered requests look the same as requests to unknown emails.
+func newContactOutput(contact *entity.Contact, userId string, other *entity.User) *ContactOutput {
+	output := &ContactOutput{
+		Id:          contact.Id,
+		UserId:      other.Id,
+		Username:    other.Username,
+		Email:       other.Email,
+		Status:      contact.Status,
+		Direction:   ContactDirectionIncoming,
+		RespondedAt: contact.RespondedAt,
+		CreatedAt:   contact.CreatedAt,
+	}
+
+	if contact.RequesterId == userId {
+		output.Direction = ContactDirectionOutgoing
+		if output.Status == entity.ContactStatusIgnored {
+			output.Status = entity.ContactStatusPending
+		}
+		if output.Status == entity.ContactStatusPending {
+			output.UserId = ""
+			output.Username = ""
+		}
+	}
+
+	return output
+}
+
+// _unknownContactNamespace derives ids of requests to unknown emails.
+var _unknownContactNamespace = uuid.MustParse("5b0f3c1e-8f2a-4d6b-9c3e-2a7d1e4f6b80")
+
+// newUnknownContactOutput returns pending request to email nobody registered. Nothing is stored,
+// its id is derived from the requester and email, so asking again returns the same request.
+func newUnknownContactOutput(userId, email string) *ContactOutput {
+	email = strings.TrimSpace(email)
+
+	return &ContactOutput{
+		Id:        uuid.NewSHA1(_unknownContactNamespace, []byte(userId+":"+strings.ToLower(email))).String(),
+		Email:     email,
+		Status:    entity.ContactStatusPending,
+		Direction: ContactDirectionOutgoing,
+		CreatedAt: time.Now(),
+	}
+}
+
+func newBlockedUserOutput(block *entity.Block, user *entity.User) *BlockedUserOutput {
+	return &BlockedUserOutput{
+		UserId:    user.Id,
+		Username:  user.Username,
+		Email:     user.Email,
+		BlockedAt: block.CreatedAt,
+	}
+}
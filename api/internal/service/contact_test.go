@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// jsonFields returns names of JSON fields present in the encoded value.
+func jsonFields(t *testing.T, value interface{}) []string {
+	t.Helper()
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode %T: %v", value, err)
+	}
+	var decoded map[string]interface{}
+	err = json.Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatalf("failed to decode %T: %v", value, err)
+	}
+
+	fields := make([]string, 0, len(decoded))
+	for field := range decoded {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func TestSendContactRequestDoesNotRevealRegistration(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		// setup prepares target user before the request is sent
+		setup func(storages *testStorages, requester, target *entity.User)
+		// wantDelivered is whether target sees the request
+		wantDelivered bool
+	}{
+		{
+			name:  "discoverable user",
+			email: "target@droplet.local",
+			setup: func(storages *testStorages, requester, target *entity.User) {
+				storages.users.discoverable[target.Id] = true
+			},
+			wantDelivered: true,
+		},
+		{
+			name:  "unknown email",
+			email: "nobody@droplet.local",
+		},
+		{
+			name:  "undiscoverable user",
+			email: "target@droplet.local",
+		},
+		{
+			name:  "blocked senders get an indistinguishable response",
+			email: "target@droplet.local",
+			setup: func(storages *testStorages, requester, target *entity.User) {
+				storages.users.discoverable[target.Id] = true
+				storages.blocks.blocks = append(storages.blocks.blocks, entity.Block{UserId: target.Id, BlockedUserId: requester.Id})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			requester := createTestUser(t, options, "requester@droplet.local", _testPassword)
+			target := createTestUser(t, options, "target@droplet.local", _testPassword)
+			if tt.setup != nil {
+				tt.setup(storages, requester, target)
+			}
+			service := NewContactService(options)
+			ctx := context.Background()
+
+			contact, err := service.SendContactRequest(ctx, &SendContactRequestOptions{UserId: requester.Id, Email: tt.email})
+			if err != nil {
+				t.Fatalf("SendContactRequest() error = %v", err)
+			}
+			want := &ContactOutput{
+				Id:        contact.Id,
+				Email:     tt.email,
+				Status:    entity.ContactStatusPending,
+				Direction: ContactDirectionOutgoing,
+				CreatedAt: contact.CreatedAt,
+			}
+			if contact.Id == "" || contact.CreatedAt.IsZero() || !reflect.DeepEqual(contact, want) {
+				t.Fatalf("SendContactRequest() = %+v, want %+v", contact, want)
+			}
+			wantFields := []string{"createdAt", "direction", "email", "id", "status"}
+			if fields := jsonFields(t, contact); !reflect.DeepEqual(fields, wantFields) {
+				t.Fatalf("SendContactRequest() fields = %v, want %v", fields, wantFields)
+			}
+
+			again, err := service.SendContactRequest(ctx, &SendContactRequestOptions{UserId: requester.Id, Email: tt.email})
+			if err != nil {
+				t.Fatalf("SendContactRequest() again error = %v", err)
+			}
+			if again.Id != contact.Id {
+				t.Errorf("SendContactRequest() again id = %s, want %s", again.Id, contact.Id)
+			}
+
+			incoming, err := service.ListContactRequests(ctx, &ListContactRequestsOptions{UserId: target.Id, Direction: ContactDirectionIncoming})
+			if err != nil {
+				t.Fatalf("ListContactRequests() error = %v", err)
+			}
+			if delivered := len(incoming.Contacts) > 0; delivered != tt.wantDelivered {
+				t.Errorf("request delivered = %t, want %t", delivered, tt.wantDelivered)
+			}
+		})
+	}
+}
+
+func TestBlockUserDoesNotRevealRegistration(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, "user@droplet.local", _testPassword)
+	target := createTestUser(t, options, "target@droplet.local", _testPassword)
+	storages.contacts.contacts = append(storages.contacts.contacts, entity.Contact{
+		Id:          "contact-1",
+		RequesterId: target.Id,
+		AddresseeId: user.Id,
+		Status:      entity.ContactStatusAccepted,
+	})
+	service := NewContactService(options)
+	ctx := context.Background()
+
+	for _, email := range []string{"target@droplet.local", "nobody@droplet.local"} {
+		blocked, err := service.BlockUser(ctx, &BlockUserOptions{UserId: user.Id, Email: email})
+		if err != nil {
+			t.Fatalf("BlockUser(%s) error = %v", email, err)
+		}
+		if blocked.Email != email || blocked.BlockedAt.IsZero() {
+			t.Errorf("BlockUser(%s) = %+v", email, blocked)
+		}
+		wantFields := []string{"blockedAt", "email"}
+		if fields := jsonFields(t, blocked); !reflect.DeepEqual(fields, wantFields) {
+			t.Errorf("BlockUser(%s) fields = %v, want %v", email, fields, wantFields)
+		}
+	}
+
+	if len(storages.blocks.blocks) != 1 || storages.blocks.blocks[0].BlockedUserId != target.Id {
+		t.Fatalf("blocks = %+v, want only block of the registered user", storages.blocks.blocks)
+	}
+	if len(storages.contacts.contacts) != 0 {
+		t.Errorf("contact with blocked user was kept")
+	}
+}
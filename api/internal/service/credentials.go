@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	netmail "net/mail"
+	"strings"
+)
+
+func (a authService) ChangePassword(ctx context.Context, options *ChangePasswordOptions) error {
+	logger := a.logger.
+		Named("ChangePassword").
+		WithContext(ctx).
+		With("userId", options.UserId, "sessionId", options.SessionId)
+
+	user, err := a.getCredentialsUser(ctx, options.UserId, options.CurrentPassword)
+	if err != nil {
+		logger.Info("failed to authenticate user", "err", err)
+		return err
+	}
+
+	err = a.checkPassword("newPassword", options.NewPassword, user.Username, user.Email)
+	if err != nil {
+		logger.Info("password rejected", "err", err)
+		return err
+	}
+
+	hashedPassword, err := a.hash.GenerateHash(options.NewPassword)
+	if err != nil {
+		logger.Error("failed to hash user password: ", err)
+		return fmt.Errorf("failed to hash user password: %w", err)
+	}
+	user.Password = hashedPassword
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	err = a.revokeUserSessions(ctx, user.Id, options.SessionId)
+	if err != nil {
+		logger.Error("failed to revoke other user sessions: ", err)
+		return fmt.Errorf("failed to revoke other user sessions: %w", err)
+	}
+
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId:   user.Id,
+		Action:   entity.AuditActionPasswordChanged,
+		ClientIP: options.ClientIP,
+	})
+
+	logger.Info("successfully changed password")
+	return nil
+}
+
+func (a authService) ChangeEmail(ctx context.Context, options *ChangeEmailOptions) error {
+	logger := a.logger.
+		Named("ChangeEmail").
+		WithContext(ctx).
+		With("userId", options.UserId, "newEmail", options.NewEmail)
+
+	newEmail, err := normalizeEmail(options.NewEmail)
+	if err != nil {
+		logger.Info("invalid email", "err", err)
+		return ErrChangeEmailInvalidEmail.WithFields(errs.FieldError{Field: "newEmail", Code: "invalid_email", Message: "invalid email address"})
+	}
+
+	user, err := a.getCredentialsUser(ctx, options.UserId, options.CurrentPassword)
+	if err != nil {
+		logger.Info("failed to authenticate user", "err", err)
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		logger.Info("new email is the same as current")
+		return ErrChangeEmailSameEmail
+	}
+
+	existingUser, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: newEmail})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if existingUser != nil {
+		logger.Info("email already taken")
+		return ErrChangeEmailAlreadyTaken
+	}
+
+	changeToken, err := a.storeUserToken(ctx, &entity.UserToken{
+		UserId:    user.Id,
+		Purpose:   entity.UserTokenPurposeEmailChange,
+		Payload:   newEmail,
+		SessionId: options.SessionId,
+	}, a.config.Auth.EmailChangeTokenTTL)
+	if err != nil {
+		logger.Error("failed to create email change token: ", err)
+		return fmt.Errorf("failed to create email change token: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new droplet email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your new email address using the link below. It expires in %s.\n\n%s/confirm-email-change?token=%s\n\nIf you did not request this change, just ignore this email.\n",
+			user.Username, a.config.Auth.EmailChangeTokenTTL, a.config.App.BaseURL, changeToken,
+		),
+	})
+	if err != nil {
+		logger.Error("failed to send confirmation mail: ", err)
+		return fmt.Errorf("failed to send confirmation mail: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Your droplet email is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomebody requested to change email of your droplet account to %s. The change takes effect once the new address is confirmed.\n\nIf it was not you, reset your password right away: %s/forgot-password\n",
+			user.Username, newEmail, a.config.App.BaseURL,
+		),
+	})
+	if err != nil {
+		// confirmation is already on its way, notice is best effort
+		logger.Error("failed to send notice to current email: ", err)
+	}
+
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId:   user.Id,
+		Action:   entity.AuditActionEmailChangeRequested,
+		ClientIP: options.ClientIP,
+		Details:  map[string]string{"oldEmail": user.Email, "newEmail": newEmail},
+	})
+
+	logger.Info("successfully requested email change")
+	return nil
+}
+
+func (a authService) ConfirmEmailChange(ctx context.Context, options *ConfirmEmailChangeOptions) error {
+	logger := a.logger.
+		Named("ConfirmEmailChange").
+		WithContext(ctx)
+
+	changeToken, err := a.storages.UserTokenStorage.ConsumeUserToken(ctx, &ConsumeUserTokenFilter{
+		TokenHash: auth.HashOpaqueToken(options.Token),
+		Purpose:   entity.UserTokenPurposeEmailChange,
+	})
+	if err != nil {
+		logger.Error("failed to consume email change token: ", err)
+		return fmt.Errorf("failed to consume email change token: %w", err)
+	}
+	if changeToken == nil {
+		logger.Info("email change token not found")
+		return ErrConfirmEmailChangeInvalidToken
+	}
+	logger = logger.With("userId", changeToken.UserId, "newEmail", changeToken.Payload)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: changeToken.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return ErrConfirmEmailChangeInvalidToken
+	}
+
+	// address could have been registered since the change was requested
+	existingUser, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: changeToken.Payload})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if existingUser != nil {
+		logger.Info("email already taken")
+		return ErrChangeEmailAlreadyTaken
+	}
+
+	oldEmail := user.Email
+	user.Email = changeToken.Payload
+	user.EmailVerified = true
+
+	// check above does not hold against concurrent confirmations and sign ups, unique index does
+	changedUser, err := a.storages.UserStorage.ChangeUserEmail(ctx, user, oldEmail)
+	if err != nil {
+		logger.Error("failed to change user email: ", err)
+		return fmt.Errorf("failed to change user email: %w", err)
+	}
+	if changedUser == nil {
+		logger.Info("email already taken")
+		return ErrChangeEmailAlreadyTaken
+	}
+
+	err = a.revokeUserSessions(ctx, user.Id, changeToken.SessionId)
+	if err != nil {
+		logger.Error("failed to revoke other user sessions: ", err)
+		return fmt.Errorf("failed to revoke other user sessions: %w", err)
+	}
+
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId:  user.Id,
+		Action:  entity.AuditActionEmailChanged,
+		Details: map[string]string{"oldEmail": oldEmail, "newEmail": user.Email},
+	})
+
+	logger.Info("successfully changed email")
+	return nil
+}
+
+// getCredentialsUser returns user after re-authenticating them with current password.
+func (a authService) getCredentialsUser(ctx context.Context, userId, currentPassword string) (*entity.User, error) {
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: userId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrChangeCredentialsUserNotFound
+	}
+
+	// users signed up via oidc provider have no password and have to set it through password reset
+	err = a.hash.CompareHash([]byte(user.Password), []byte(currentPassword))
+	if err != nil {
+		return nil, ErrChangeCredentialsWrongPassword
+	}
+
+	return user, nil
+}
+
+// normalizeEmail validates bare email address and returns it trimmed.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	address, err := netmail.ParseAddress(email)
+	if err != nil {
+		return "", err
+	}
+	if address.Address != email {
+		return "", fmt.Errorf("email must not contain display name")
+	}
+
+	return email, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"testing"
+	"time"
+)
+
+func TestConfirmEmailChangeMovesTransfers(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	service := NewAuthService(options).(*authService)
+	user := createTestUser(t, options, "old@droplet.local", _testPassword)
+
+	token, err := service.storeUserToken(context.Background(), &entity.UserToken{
+		UserId:  user.Id,
+		Purpose: entity.UserTokenPurposeEmailChange,
+		Payload: "new@droplet.local",
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to store token: %v", err)
+	}
+
+	err = service.ConfirmEmailChange(context.Background(), &ConfirmEmailChangeOptions{Token: token})
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+
+	changed, _ := storages.users.GetUser(context.Background(), &GetUserFilter{UserId: user.Id})
+	if changed.Email != "new@droplet.local" || !changed.EmailVerified {
+		t.Fatalf("user = %+v, want verified new email", changed)
+	}
+	if got := storages.users.emailChanges["old@droplet.local"]; got != "new@droplet.local" {
+		t.Fatalf("transfers of old email moved to %q, want new@droplet.local", got)
+	}
+
+	err = service.ConfirmEmailChange(context.Background(), &ConfirmEmailChangeOptions{Token: token})
+	if err != ErrConfirmEmailChangeInvalidToken {
+		t.Fatalf("replayed ConfirmEmailChange() error = %v, want %v", err, ErrConfirmEmailChangeInvalidToken)
+	}
+	if auth.HashOpaqueToken(token) != storages.userTokens.tokens[0].TokenHash {
+		t.Fatalf("token is not stored as hash")
+	}
+}
+
+func TestConfirmEmailChangeRejectsTakenEmail(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	service := NewAuthService(options).(*authService)
+	user := createTestUser(t, options, "old@droplet.local", _testPassword)
+
+	token, err := service.storeUserToken(context.Background(), &entity.UserToken{
+		UserId:  user.Id,
+		Purpose: entity.UserTokenPurposeEmailChange,
+		Payload: "new@droplet.local",
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to store token: %v", err)
+	}
+
+	// differently cased address passes the lookup by exact email and is rejected by unique index only
+	createTestUser(t, options, "New@Droplet.local", _testPassword)
+
+	err = service.ConfirmEmailChange(context.Background(), &ConfirmEmailChangeOptions{Token: token})
+	if err != ErrChangeEmailAlreadyTaken {
+		t.Fatalf("ConfirmEmailChange() error = %v, want %v", err, ErrChangeEmailAlreadyTaken)
+	}
+
+	unchanged, _ := storages.users.GetUser(context.Background(), &GetUserFilter{UserId: user.Id})
+	if unchanged.Email != "old@droplet.local" {
+		t.Fatalf("user email = %q, want old@droplet.local", unchanged.Email)
+	}
+	if len(storages.users.emailChanges) != 0 {
+		t.Fatalf("transfers moved %v, want none", storages.users.emailChanges)
+	}
+}
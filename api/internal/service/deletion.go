@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"time"
+)
+
+// _eraseBatchSize limits number of users erased by single sweep.
+const _eraseBatchSize = 100
+
+func (a accountService) DeleteAccount(ctx context.Context, options *DeleteAccountOptions) (*DeleteAccountOutput, error) {
+	logger := a.logger.
+		Named("DeleteAccount").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.storages.AccountStorage.GetAccount(ctx, &GetAccountFilter{AccountId: options.AccountId, UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get account: ", err)
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		logger.Info("account not found")
+		return nil, ErrGetAccountAccountNotFound
+	}
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: account.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return nil, ErrGetAccountAccountNotFound
+	}
+	if user.DeletionScheduledFor != nil {
+		logger.Info("deletion already scheduled")
+		return nil, ErrDeleteAccountAlreadyScheduled
+	}
+
+	gracePeriod := a.config.Account.DeletionGracePeriod
+	deletionScheduledFor := time.Now().Add(gracePeriod)
+	user.DeletionScheduledFor = &deletionScheduledFor
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	err = a.revokeUserSessions(ctx, user.Id, "")
+	if err != nil {
+		logger.Error("failed to revoke user sessions: ", err)
+		return nil, fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	cancelToken, err := a.storeUserToken(ctx, &entity.UserToken{
+		UserId:  user.Id,
+		Purpose: entity.UserTokenPurposeDeletionCancel,
+	}, gracePeriod)
+	if err != nil {
+		logger.Error("failed to create deletion cancel token: ", err)
+		return nil, fmt.Errorf("failed to create deletion cancel token: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Your droplet account is scheduled for deletion",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour droplet account and all its data will be erased on %s.\nIf you changed your mind, cancel the deletion using the link below.\n\n%s/cancel-deletion?token=%s\n",
+			user.Username, deletionScheduledFor.UTC().Format(time.RFC1123), a.config.App.BaseURL, cancelToken,
+		),
+	})
+	if err != nil {
+		// deletion is scheduled anyway, user can still ask support to cancel it
+		logger.Error("failed to send deletion mail: ", err)
+	}
+
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId:  user.Id,
+		Action:  entity.AuditActionDeletionScheduled,
+		Details: map[string]string{"deletionScheduledFor": deletionScheduledFor.UTC().Format(time.RFC3339)},
+	})
+
+	logger.Info("account deletion successfully scheduled")
+	return &DeleteAccountOutput{DeletionScheduledFor: deletionScheduledFor}, nil
+}
+
+func (a accountService) CancelAccountDeletion(ctx context.Context, options *CancelAccountDeletionOptions) error {
+	logger := a.logger.
+		Named("CancelAccountDeletion").
+		WithContext(ctx)
+
+	cancelToken, err := a.storages.UserTokenStorage.ConsumeUserToken(ctx, &ConsumeUserTokenFilter{
+		TokenHash: auth.HashOpaqueToken(options.Token),
+		Purpose:   entity.UserTokenPurposeDeletionCancel,
+	})
+	if err != nil {
+		logger.Error("failed to consume deletion cancel token: ", err)
+		return fmt.Errorf("failed to consume deletion cancel token: %w", err)
+	}
+	if cancelToken == nil {
+		logger.Info("deletion cancel token not found")
+		return ErrCancelAccountDeletionInvalidToken
+	}
+	logger = logger.With("userId", cancelToken.UserId)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: cancelToken.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.DeletionScheduledFor == nil {
+		logger.Info("deletion is not scheduled")
+		return ErrCancelAccountDeletionInvalidToken
+	}
+
+	user.DeletionScheduledFor = nil
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId: user.Id,
+		Action: entity.AuditActionDeletionCancelled,
+	})
+
+	logger.Info("account deletion successfully cancelled")
+	return nil
+}
+
+func (a accountService) EraseScheduledAccounts(ctx context.Context) error {
+	logger := a.logger.
+		Named("EraseScheduledAccounts").
+		WithContext(ctx)
+
+	now := time.Now()
+	users, _, err := a.storages.UserStorage.ListUsers(ctx, &ListUsersFilter{
+		Limit:             _eraseBatchSize,
+		DeletionDueBefore: &now,
+	})
+	if err != nil {
+		logger.Error("failed to list users due for deletion: ", err)
+		return fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+
+	// droplet does not keep transferred files on the server, so erasing
+	// database records removes everything stored about the user
+	for _, user := range users {
+		err = a.storages.UserStorage.DeleteUser(ctx, user.Id)
+		if err != nil {
+			logger.Error("failed to delete user: ", err)
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		logger.With("userId", user.Id).Info("user successfully erased")
+	}
+
+	return nil
+}
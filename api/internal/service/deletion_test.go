@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var _cancelTokenPattern = regexp.MustCompile(`cancel-deletion\?token=(\S+)`)
+
+// createTestAccount stores account of given user and returns its id.
+func createTestAccount(storages *testStorages, userId string) string {
+	accountId := fmt.Sprintf("account-%d", len(storages.accounts.accounts)+1)
+	storages.accounts.accounts = append(storages.accounts.accounts, entity.Account{Id: accountId, UserId: userId})
+
+	return accountId
+}
+
+// mailedCancelToken returns deletion cancel token from the last message sent to the user.
+func mailedCancelToken(t *testing.T, options *Options) string {
+	t.Helper()
+
+	messages := options.Mailer.(*fakeMailer).messages
+	if len(messages) == 0 {
+		t.Fatal("deletion mail was not sent")
+	}
+	match := _cancelTokenPattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		t.Fatalf("deletion mail does not contain cancel link: %q", messages[len(messages)-1].Body)
+	}
+
+	return match[1]
+}
+
+func TestDeleteAccountRevokesAccess(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	accountId := createTestAccount(storages, user.Id)
+	authService := NewAuthService(options)
+	accountService := NewAccountService(options)
+
+	session := signIn(t, authService)
+	personalAccessToken := createTestPersonalAccessToken(t, authService, user.Id, entity.ScopeAccountRead)
+
+	deleted, err := accountService.DeleteAccount(context.Background(), &DeleteAccountOptions{AccountId: accountId, UserId: user.Id})
+	if err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	wantScheduledFor := time.Now().Add(options.Config.Account.DeletionGracePeriod)
+	if deleted.DeletionScheduledFor.Sub(wantScheduledFor).Abs() > time.Minute {
+		t.Fatalf("DeleteAccount() scheduled for %v, want about %v", deleted.DeletionScheduledFor, wantScheduledFor)
+	}
+	if storages.users.users[user.Id].DeletionScheduledFor == nil {
+		t.Fatal("DeleteAccount() did not schedule deletion of the user")
+	}
+
+	err = verify(authService, session.AccessToken)
+	if !errors.Is(err, ErrVerifyTokenRevoked) {
+		t.Errorf("VerifyToken() of access token error = %v, want %v", err, ErrVerifyTokenRevoked)
+	}
+	err = verify(authService, personalAccessToken.Token)
+	if !errors.Is(err, ErrVerifyTokenInvalid) {
+		t.Errorf("VerifyToken() of personal access token error = %v, want %v", err, ErrVerifyTokenInvalid)
+	}
+	// revoked refresh token is treated as replayed one
+	_, err = refresh(authService, session.RefreshToken)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("RefreshToken() error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	_, err = authService.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if !errors.Is(err, ErrSignInDeletionPending) {
+		t.Errorf("SignIn() error = %v, want %v", err, ErrSignInDeletionPending)
+	}
+
+	_, err = accountService.DeleteAccount(context.Background(), &DeleteAccountOptions{AccountId: accountId, UserId: user.Id})
+	if !errors.Is(err, ErrDeleteAccountAlreadyScheduled) {
+		t.Errorf("repeated DeleteAccount() error = %v, want %v", err, ErrDeleteAccountAlreadyScheduled)
+	}
+}
+
+func TestDeleteAccountOfOtherUser(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	owner := createTestUser(t, options, "owner@droplet.local", _testPassword)
+	other := createTestUser(t, options, _testEmail, _testPassword)
+	accountId := createTestAccount(storages, owner.Id)
+
+	_, err := NewAccountService(options).DeleteAccount(context.Background(), &DeleteAccountOptions{AccountId: accountId, UserId: other.Id})
+	if !errors.Is(err, ErrGetAccountAccountNotFound) {
+		t.Fatalf("DeleteAccount() error = %v, want %v", err, ErrGetAccountAccountNotFound)
+	}
+	if storages.users.users[owner.Id].DeletionScheduledFor != nil {
+		t.Fatal("DeleteAccount() scheduled deletion of account owned by other user")
+	}
+}
+
+func TestCancelAccountDeletion(t *testing.T) {
+	tests := []struct {
+		name string
+		// expire moves the cancel token past its expiry before the cancellation
+		expire     bool
+		wantErr    error
+		wantActive bool
+	}{
+		{name: "within grace period", wantActive: true},
+		{name: "expired token", expire: true, wantErr: ErrCancelAccountDeletionInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := createTestUser(t, options, _testEmail, _testPassword)
+			accountId := createTestAccount(storages, user.Id)
+			authService := NewAuthService(options)
+			accountService := NewAccountService(options)
+
+			_, err := accountService.DeleteAccount(context.Background(), &DeleteAccountOptions{AccountId: accountId, UserId: user.Id})
+			if err != nil {
+				t.Fatalf("DeleteAccount() error = %v", err)
+			}
+			cancelToken := mailedCancelToken(t, options)
+			if tt.expire {
+				for _, token := range storages.userTokens.tokens {
+					token.ExpiresAt = time.Now().Add(-time.Second)
+				}
+			}
+
+			err = accountService.CancelAccountDeletion(context.Background(), &CancelAccountDeletionOptions{Token: cancelToken})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelAccountDeletion() error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, err = authService.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+			if tt.wantActive && err != nil {
+				t.Fatalf("SignIn() after cancellation error = %v", err)
+			}
+			if !tt.wantActive && !errors.Is(err, ErrSignInDeletionPending) {
+				t.Fatalf("SignIn() error = %v, want %v", err, ErrSignInDeletionPending)
+			}
+
+			// cancel token is single use
+			err = accountService.CancelAccountDeletion(context.Background(), &CancelAccountDeletionOptions{Token: cancelToken})
+			if !errors.Is(err, ErrCancelAccountDeletionInvalidToken) {
+				t.Fatalf("replayed CancelAccountDeletion() error = %v, want %v", err, ErrCancelAccountDeletionInvalidToken)
+			}
+		})
+	}
+}
+
+func TestEraseScheduledAccounts(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	accountService := NewAccountService(options)
+
+	schedule := func(email string, deletionScheduledFor time.Time) *entity.User {
+		user := createTestUser(t, options, email, _testPassword)
+		storages.users.users[user.Id].DeletionScheduledFor = &deletionScheduledFor
+		return user
+	}
+	due := schedule("due@droplet.local", time.Now().Add(-time.Minute))
+	pending := schedule("pending@droplet.local", time.Now().Add(time.Hour))
+	active := createTestUser(t, options, _testEmail, _testPassword)
+
+	err := accountService.EraseScheduledAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("EraseScheduledAccounts() error = %v", err)
+	}
+
+	if _, ok := storages.users.users[due.Id]; ok {
+		t.Error("user due for deletion was not erased")
+	}
+	for _, user := range []*entity.User{pending, active} {
+		if _, ok := storages.users.users[user.Id]; !ok {
+			t.Errorf("user %s was erased before deletion was due", user.Email)
+		}
+	}
+}
+
+func TestEraseScheduledAccountsInBatches(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	accountService := NewAccountService(options)
+
+	deletionScheduledFor := time.Now().Add(-time.Minute)
+	for i := 0; i < _eraseBatchSize+1; i++ {
+		user := createTestUser(t, options, fmt.Sprintf("user-%d@droplet.local", i), _testPassword)
+		storages.users.users[user.Id].DeletionScheduledFor = &deletionScheduledFor
+	}
+
+	for sweep, wantLeft := range []int{1, 0} {
+		err := accountService.EraseScheduledAccounts(context.Background())
+		if err != nil {
+			t.Fatalf("EraseScheduledAccounts() error = %v", err)
+		}
+		if len(storages.users.users) != wantLeft {
+			t.Fatalf("after sweep %d %d users left, want %d", sweep+1, len(storages.users.users), wantLeft)
+		}
+	}
+}
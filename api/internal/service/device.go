@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const _maxDeviceNameLength = 64
+
+func (a accountService) ListDevices(ctx context.Context, options *ListDevicesOptions) (*ListDevicesOutput, error) {
+	logger := a.logger.
+		Named("ListDevices").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	devices, err := a.storages.DeviceStorage.ListDevices(ctx, &ListDevicesFilter{AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to list devices: ", err)
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	now := time.Now()
+	for i := range devices {
+		devices[i].Online = a.isDeviceOnline(&devices[i], now)
+	}
+
+	logger.Info("successfully listed devices", "count", len(devices))
+	return &ListDevicesOutput{Devices: devices}, nil
+}
+
+func (a accountService) RegisterDevice(ctx context.Context, options *RegisterDeviceOptions) (*entity.AccountDevices, error) {
+	logger := a.logger.
+		Named("RegisterDevice").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	device, err := newDevice(account.Id, options.Name, options.OS, options.MacAddress, options.Active)
+	if err != nil {
+		logger.Info("invalid device", "err", err)
+		return nil, err
+	}
+
+	existingDevice, err := a.storages.DeviceStorage.GetDevice(ctx, &GetDeviceFilter{AccountId: account.Id, MacAddress: device.MacAddress})
+	if err != nil {
+		logger.Error("failed to get device: ", err)
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if existingDevice != nil {
+		logger.Info("device already registered", "deviceId", existingDevice.Id)
+		return nil, ErrDeviceAlreadyRegistered
+	}
+
+	createdDevice, err := a.storages.DeviceStorage.CreateDevice(ctx, device, a.config.Account.MaxDevices)
+	if err != nil {
+		logger.Error("failed to create device: ", err)
+		return nil, fmt.Errorf("failed to create device: %w", err)
+	}
+	if createdDevice == nil {
+		logger.Info("device limit reached")
+		return nil, ErrDeviceLimitReached
+	}
+
+	logger.Info("device successfully registered", "deviceId", createdDevice.Id)
+	return createdDevice, nil
+}
+
+func (a accountService) UpdateDevice(ctx context.Context, options *UpdateDeviceOptions) (*entity.AccountDevices, error) {
+	logger := a.logger.
+		Named("UpdateDevice").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	device, err := a.storages.DeviceStorage.GetDevice(ctx, &GetDeviceFilter{Id: options.DeviceId, AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to get device: ", err)
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		logger.Info("device not found")
+		return nil, ErrDeviceNotFound
+	}
+
+	if options.Name != nil {
+		name, err := normalizeDeviceName(*options.Name)
+		if err != nil {
+			logger.Info("invalid device name", "err", err)
+			return nil, err
+		}
+		device.Name = name
+	}
+
+	if options.Active != nil {
+		device.Active = *options.Active
+		if !device.Active {
+			device.Online = false
+		}
+	}
+
+	updatedDevice, err := a.storages.DeviceStorage.UpdateDevice(ctx, device)
+	if err != nil {
+		logger.Error("failed to update device: ", err)
+		return nil, fmt.Errorf("failed to update device: %w", err)
+	}
+
+	logger.Info("device successfully updated")
+	return updatedDevice, nil
+}
+
+func (a accountService) RemoveDevice(ctx context.Context, options *RemoveDeviceOptions) error {
+	logger := a.logger.
+		Named("RemoveDevice").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return err
+	}
+
+	deleted, err := a.storages.DeviceStorage.DeleteDevice(ctx, &DeleteDeviceFilter{Id: options.DeviceId, AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to delete device: ", err)
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+	if !deleted {
+		logger.Info("device not found")
+		return ErrDeviceNotFound
+	}
+
+	// revoked key stays in key history, so contacts can see that the device is gone
+	err = a.storages.DeviceKeyStorage.RevokeDeviceKeys(ctx, options.DeviceId, time.Now())
+	if err != nil {
+		logger.Error("failed to revoke device keys: ", err)
+		return fmt.Errorf("failed to revoke device keys: %w", err)
+	}
+
+	logger.Info("device successfully removed")
+	return nil
+}
+
+// newDevice validates and normalizes device fields coming from clients.
+func newDevice(accountId, name, os, macAddress string, active bool) (*entity.AccountDevices, error) {
+	name, err := normalizeDeviceName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	os = strings.ToLower(strings.TrimSpace(os))
+	if !entity.IsKnownDeviceOS(os) {
+		return nil, ErrDeviceInvalidOS
+	}
+
+	macAddress, err = normalizeMacAddress(macAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.AccountDevices{
+		AccountID:  accountId,
+		Name:       name,
+		OS:         os,
+		MacAddress: macAddress,
+		Active:     active,
+	}, nil
+}
+
+func normalizeDeviceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > _maxDeviceNameLength {
+		return "", ErrDeviceInvalidName
+	}
+
+	return name, nil
+}
+
+// normalizeMacAddress accepts EUI-48 address in any notation net.ParseMAC knows
+// (colons, hyphens or dots) and formats it as lower case colon separated octets.
+func normalizeMacAddress(macAddress string) (string, error) {
+	hardwareAddr, err := net.ParseMAC(strings.TrimSpace(macAddress))
+	if err != nil || len(hardwareAddr) != 6 {
+		return "", ErrDeviceInvalidMacAddress
+	}
+
+	return hardwareAddr.String(), nil
+}
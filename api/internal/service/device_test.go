@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+)
+
+func TestNormalizeMacAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		macAddress string
+		want       string
+		wantErr    error
+	}{
+		{name: "colons", macAddress: "00:1a:2b:3c:4d:5e", want: "00:1a:2b:3c:4d:5e"},
+		{name: "hyphens", macAddress: "00-1a-2b-3c-4d-5e", want: "00:1a:2b:3c:4d:5e"},
+		{name: "dots", macAddress: "001a.2b3c.4d5e", want: "00:1a:2b:3c:4d:5e"},
+		{name: "upper case", macAddress: "00:1A:2B:3C:4D:5E", want: "00:1a:2b:3c:4d:5e"},
+		{name: "surrounding spaces", macAddress: " 00:1a:2b:3c:4d:5e ", want: "00:1a:2b:3c:4d:5e"},
+		{name: "empty", macAddress: "", wantErr: ErrDeviceInvalidMacAddress},
+		{name: "garbage", macAddress: "not-a-mac", wantErr: ErrDeviceInvalidMacAddress},
+		{name: "EUI-64", macAddress: "00:1a:2b:3c:4d:5e:6f:70", wantErr: ErrDeviceInvalidMacAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeMacAddress(tt.macAddress)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeMacAddress() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("normalizeMacAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterDeviceNormalizesFields(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	accountId := createTestAccount(storages, user.Id)
+
+	device, err := NewAccountService(options).RegisterDevice(context.Background(), &RegisterDeviceOptions{
+		AccountId:  accountId,
+		UserId:     user.Id,
+		Name:       "  laptop ",
+		OS:         " Linux",
+		MacAddress: "00-1A-2B-3C-4D-5E",
+	})
+	if err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+	if device.Name != "laptop" || device.OS != entity.DeviceOSLinux || device.MacAddress != "00:1a:2b:3c:4d:5e" {
+		t.Fatalf("RegisterDevice() = %+v, want normalized name, os and mac address", device)
+	}
+}
+
+func TestRegisterDeviceRejectsInvalidFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		options RegisterDeviceOptions
+		wantErr error
+	}{
+		{name: "empty name", options: RegisterDeviceOptions{Name: " ", OS: entity.DeviceOSLinux, MacAddress: "00:1a:2b:3c:4d:5e"}, wantErr: ErrDeviceInvalidName},
+		{name: "unknown os", options: RegisterDeviceOptions{Name: "laptop", OS: "beos", MacAddress: "00:1a:2b:3c:4d:5e"}, wantErr: ErrDeviceInvalidOS},
+		{name: "invalid mac address", options: RegisterDeviceOptions{Name: "laptop", OS: entity.DeviceOSLinux, MacAddress: "00:1a"}, wantErr: ErrDeviceInvalidMacAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := createTestUser(t, options, _testEmail, _testPassword)
+			tt.options.AccountId = createTestAccount(storages, user.Id)
+			tt.options.UserId = user.Id
+
+			_, err := NewAccountService(options).RegisterDevice(context.Background(), &tt.options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RegisterDevice() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterDeviceLimit(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	accountId := createTestAccount(storages, user.Id)
+	service := NewAccountService(options)
+
+	register := func(macAddress string) (*entity.AccountDevices, error) {
+		return service.RegisterDevice(context.Background(), &RegisterDeviceOptions{
+			AccountId:  accountId,
+			UserId:     user.Id,
+			Name:       "device",
+			OS:         entity.DeviceOSAndroid,
+			MacAddress: macAddress,
+		})
+	}
+
+	first, err := register("00:1a:2b:3c:4d:01")
+	if err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+	// the same address in other notation is the same device
+	_, err = register("00-1A-2B-3C-4D-01")
+	if !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Fatalf("RegisterDevice() of registered mac address error = %v, want %v", err, ErrDeviceAlreadyRegistered)
+	}
+	_, err = register("00:1a:2b:3c:4d:02")
+	if err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+	_, err = register("00:1a:2b:3c:4d:03")
+	if !errors.Is(err, ErrDeviceLimitReached) {
+		t.Fatalf("RegisterDevice() over limit of %d error = %v, want %v", options.Config.Account.MaxDevices, err, ErrDeviceLimitReached)
+	}
+
+	// removing a device frees place for another one
+	err = service.RemoveDevice(context.Background(), &RemoveDeviceOptions{AccountId: accountId, UserId: user.Id, DeviceId: first.Id})
+	if err != nil {
+		t.Fatalf("RemoveDevice() error = %v", err)
+	}
+	_, err = register("00:1a:2b:3c:4d:03")
+	if err != nil {
+		t.Fatalf("RegisterDevice() after removal error = %v", err)
+	}
+}
+
+func TestRegisterDeviceToAccountOfOtherUser(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	owner := createTestUser(t, options, "owner@droplet.local", _testPassword)
+	other := createTestUser(t, options, _testEmail, _testPassword)
+	accountId := createTestAccount(storages, owner.Id)
+
+	_, err := NewAccountService(options).RegisterDevice(context.Background(), &RegisterDeviceOptions{
+		AccountId:  accountId,
+		UserId:     other.Id,
+		Name:       "device",
+		OS:         entity.DeviceOSIOS,
+		MacAddress: "00:1a:2b:3c:4d:5e",
+	})
+	if !errors.Is(err, ErrGetAccountAccountNotFound) {
+		t.Fatalf("RegisterDevice() error = %v, want %v", err, ErrGetAccountAccountNotFound)
+	}
+	if len(storages.accounts.accounts[0].AccountDevices) != 0 {
+		t.Fatal("RegisterDevice() added device to account of other user")
+	}
+}
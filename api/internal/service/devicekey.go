@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/devicekey"
+)
+
+func (a accountService) RegisterDeviceKey(ctx context.Context, options *RegisterDeviceKeyOptions) (*PublicDeviceKey, error) {
+	logger := a.logger.
+		Named("RegisterDeviceKey").
+		WithContext(ctx).
+		With("accountId", options.AccountId, "deviceId", options.DeviceId, "signerDeviceId", options.SignerDeviceId)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	device, err := a.storages.DeviceStorage.GetDevice(ctx, &GetDeviceFilter{Id: options.DeviceId, AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to get device: ", err)
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		logger.Info("device not found")
+		return nil, ErrDeviceNotFound
+	}
+	if !device.Active {
+		logger.Info("device is deactivated")
+		return nil, ErrDeviceInactive
+	}
+
+	publicKeys, err := devicekey.Parse(options.EncryptionKey, options.SigningKey)
+	if err != nil {
+		logger.Info("invalid device key", "err", err)
+		return nil, ErrDeviceKeyInvalid
+	}
+	fingerprint := publicKeys.Fingerprint()
+	logger = logger.With("fingerprint", fingerprint)
+
+	accountKeys, err := a.storages.DeviceKeyStorage.ListDeviceKeys(ctx, &ListDeviceKeysFilter{AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to list device keys: ", err)
+		return nil, fmt.Errorf("failed to list device keys: %w", err)
+	}
+
+	// every key which is not revoked stays trusted, keys are revoked only by removing the device,
+	// so deactivating all devices does not let anyone register unsigned key as if the account was new
+	var currentKey *entity.DeviceKey
+	trustedKeys := make(map[string]*entity.DeviceKey, len(accountKeys))
+	for i := range accountKeys {
+		if accountKeys[i].DeviceId == device.Id {
+			currentKey = &accountKeys[i]
+		}
+		trustedKeys[accountKeys[i].DeviceId] = &accountKeys[i]
+	}
+	if currentKey != nil && currentKey.Fingerprint == fingerprint {
+		logger.Info("device key is unchanged")
+		return newPublicDeviceKey(currentKey, device.Name), nil
+	}
+
+	// the very first key of the account is trusted on first use, any later key
+	// has to be vouched for by a device which already holds a trusted key
+	signedByDeviceId := ""
+	if len(trustedKeys) > 0 {
+		if options.SignerDeviceId == "" || options.Signature == "" {
+			logger.Info("device key is not signed")
+			return nil, ErrDeviceKeySignatureRequired
+		}
+
+		signerKey, ok := trustedKeys[options.SignerDeviceId]
+		if !ok {
+			logger.Info("signer device has no trusted key")
+			return nil, ErrDeviceKeySignatureRequired
+		}
+
+		signerPublicKeys, err := devicekey.Parse(signerKey.EncryptionKey, signerKey.SigningKey)
+		if err != nil {
+			logger.Error("failed to parse signer key: ", err)
+			return nil, fmt.Errorf("failed to parse signer key: %w", err)
+		}
+
+		message := devicekey.RegistrationMessage(account.Id, device.Id, options.EncryptionKey, options.SigningKey)
+		if !devicekey.Verify(signerPublicKeys.Signing, message, options.Signature) {
+			logger.Info("invalid device key signature")
+			return nil, ErrDeviceKeyInvalidSignature
+		}
+		signedByDeviceId = signerKey.DeviceId
+	}
+
+	key, err := a.storages.DeviceKeyStorage.RotateDeviceKey(ctx, &entity.DeviceKey{
+		DeviceId:         device.Id,
+		AccountId:        account.Id,
+		UserId:           account.UserId,
+		EncryptionKey:    options.EncryptionKey,
+		SigningKey:       options.SigningKey,
+		Fingerprint:      fingerprint,
+		SignedByDeviceId: signedByDeviceId,
+	})
+	if err != nil {
+		logger.Error("failed to rotate device key: ", err)
+		return nil, fmt.Errorf("failed to rotate device key: %w", err)
+	}
+
+	details := map[string]string{"deviceId": device.Id, "fingerprint": fingerprint}
+	if currentKey != nil {
+		details["previousFingerprint"] = currentKey.Fingerprint
+	}
+	a.recordAuditEvent(ctx, &entity.AuditEvent{
+		UserId:  account.UserId,
+		Action:  entity.AuditActionDeviceKeyChanged,
+		Details: details,
+	})
+
+	logger.Info("device key successfully registered")
+	return newPublicDeviceKey(key, device.Name), nil
+}
+
+func (a accountService) LookupDeviceKeys(ctx context.Context, options *LookupDeviceKeysOptions) (*LookupDeviceKeysOutput, error) {
+	logger := a.logger.
+		Named("LookupDeviceKeys").
+		WithContext(ctx).
+		With("options", options)
+
+	// unknown and undiscoverable users look the same as users without keys
+	user, err := a.findDiscoverableUser(ctx, options.UserId, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return &LookupDeviceKeysOutput{Keys: []PublicDeviceKey{}}, nil
+	}
+
+	keys, err := a.storages.DeviceKeyStorage.ListDeviceKeys(ctx, &ListDeviceKeysFilter{UserId: user.Id})
+	if err != nil {
+		logger.Error("failed to list device keys: ", err)
+		return nil, fmt.Errorf("failed to list device keys: %w", err)
+	}
+
+	accounts, err := a.storages.AccountStorage.ListAccounts(ctx, &ListAccountsFilter{UserId: user.Id})
+	if err != nil {
+		logger.Error("failed to list accounts: ", err)
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	activeDevices := make(map[string]string)
+	for _, account := range accounts {
+		for _, device := range account.AccountDevices {
+			if device.Active {
+				activeDevices[device.Id] = device.Name
+			}
+		}
+	}
+
+	publicKeys := make([]PublicDeviceKey, 0, len(keys))
+	for i := range keys {
+		name, ok := activeDevices[keys[i].DeviceId]
+		if !ok {
+			continue
+		}
+		publicKeys = append(publicKeys, *newPublicDeviceKey(&keys[i], name))
+	}
+
+	logger.Info("successfully looked up device keys", "count", len(publicKeys))
+	return &LookupDeviceKeysOutput{Keys: publicKeys}, nil
+}
+
+func (a accountService) ListDeviceKeyChanges(ctx context.Context, options *LookupDeviceKeysOptions) (*LookupDeviceKeysOutput, error) {
+	logger := a.logger.
+		Named("ListDeviceKeyChanges").
+		WithContext(ctx).
+		With("options", options)
+
+	// unknown and undiscoverable users look the same as users without keys
+	user, err := a.findDiscoverableUser(ctx, options.UserId, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return &LookupDeviceKeysOutput{Keys: []PublicDeviceKey{}}, nil
+	}
+
+	keys, err := a.storages.DeviceKeyStorage.ListDeviceKeys(ctx, &ListDeviceKeysFilter{UserId: user.Id, IncludeRevoked: true})
+	if err != nil {
+		logger.Error("failed to list device keys: ", err)
+		return nil, fmt.Errorf("failed to list device keys: %w", err)
+	}
+
+	publicKeys := make([]PublicDeviceKey, 0, len(keys))
+	for i := range keys {
+		publicKeys = append(publicKeys, *newPublicDeviceKey(&keys[i], ""))
+	}
+
+	logger.Info("successfully listed device key changes", "count", len(publicKeys))
+	return &LookupDeviceKeysOutput{Keys: publicKeys}, nil
+}
+
+func newPublicDeviceKey(key *entity.DeviceKey, deviceName string) *PublicDeviceKey {
+	return &PublicDeviceKey{
+		DeviceId:         key.DeviceId,
+		DeviceName:       deviceName,
+		EncryptionKey:    key.EncryptionKey,
+		SigningKey:       key.SigningKey,
+		Fingerprint:      key.Fingerprint,
+		SignedByDeviceId: key.SignedByDeviceId,
+		CreatedAt:        key.CreatedAt,
+		RevokedAt:        key.RevokedAt,
+	}
+}
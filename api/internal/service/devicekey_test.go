@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/devicekey"
+	"testing"
+	"time"
+)
+
+type testDeviceKey struct {
+	encryptionKey string
+	signingKey    string
+	private       ed25519.PrivateKey
+}
+
+func newTestDeviceKey(t *testing.T) *testDeviceKey {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	encryption := make([]byte, 32)
+	_, err = rand.Read(encryption)
+	if err != nil {
+		t.Fatalf("failed to generate encryption key: %v", err)
+	}
+
+	return &testDeviceKey{
+		encryptionKey: base64.StdEncoding.EncodeToString(encryption),
+		signingKey:    base64.StdEncoding.EncodeToString(public),
+		private:       private,
+	}
+}
+
+// sign vouches for key of the device as RegisterDeviceKey expects.
+func (k *testDeviceKey) sign(accountId, deviceId string, key *testDeviceKey) string {
+	message := devicekey.RegistrationMessage(accountId, deviceId, key.encryptionKey, key.signingKey)
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(k.private, message))
+}
+
+func TestRegisterDeviceKey(t *testing.T) {
+	const (
+		_accountId = "account-1"
+		_newDevice = "device-new"
+		_oldDevice = "device-old"
+	)
+
+	tests := []struct {
+		name string
+		// existing adds key of the old device, otherwise the account has no keys yet
+		existing         bool
+		oldDeviceActive  bool
+		oldKeyRevoked    bool
+		signed           bool
+		signedByStranger bool
+		wantErr          error
+	}{
+		{name: "first key of the account"},
+		{name: "unsigned key next to active device", existing: true, oldDeviceActive: true, wantErr: ErrDeviceKeySignatureRequired},
+		{name: "unsigned key next to deactivated device", existing: true, wantErr: ErrDeviceKeySignatureRequired},
+		{name: "signed by active device", existing: true, oldDeviceActive: true, signed: true},
+		{name: "signed by deactivated device", existing: true, signed: true},
+		{name: "signed by stranger", existing: true, oldDeviceActive: true, signed: true, signedByStranger: true, wantErr: ErrDeviceKeyInvalidSignature},
+		{name: "unsigned key after every key was revoked", existing: true, oldKeyRevoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := createTestUser(t, options, _testEmail, _testPassword)
+
+			storages.accounts.accounts = append(storages.accounts.accounts, entity.Account{
+				Id:     _accountId,
+				UserId: user.Id,
+				AccountDevices: []entity.AccountDevices{
+					{Id: _newDevice, AccountID: _accountId, Name: "new", Active: true},
+					{Id: _oldDevice, AccountID: _accountId, Name: "old", Active: tt.oldDeviceActive},
+				},
+			})
+
+			oldKey, newKey := newTestDeviceKey(t), newTestDeviceKey(t)
+			if tt.existing {
+				key := entity.DeviceKey{
+					Id:            "key-old",
+					DeviceId:      _oldDevice,
+					AccountId:     _accountId,
+					UserId:        user.Id,
+					EncryptionKey: oldKey.encryptionKey,
+					SigningKey:    oldKey.signingKey,
+					Fingerprint:   "old",
+				}
+				if tt.oldKeyRevoked {
+					revokedAt := time.Now()
+					key.RevokedAt = &revokedAt
+				}
+				storages.deviceKeys.keys = append(storages.deviceKeys.keys, key)
+			}
+
+			registerOptions := &RegisterDeviceKeyOptions{
+				AccountId:     _accountId,
+				DeviceId:      _newDevice,
+				UserId:        user.Id,
+				EncryptionKey: newKey.encryptionKey,
+				SigningKey:    newKey.signingKey,
+			}
+			if tt.signed {
+				signer := oldKey
+				if tt.signedByStranger {
+					signer = newTestDeviceKey(t)
+				}
+				registerOptions.SignerDeviceId = _oldDevice
+				registerOptions.Signature = signer.sign(_accountId, _newDevice, newKey)
+			}
+
+			key, err := NewAccountService(options).RegisterDeviceKey(context.Background(), registerOptions)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RegisterDeviceKey() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RegisterDeviceKey() error = %v", err)
+			}
+
+			wantSigner := ""
+			if tt.signed {
+				wantSigner = _oldDevice
+			}
+			if key.SignedByDeviceId != wantSigner {
+				t.Errorf("key signed by %q, want %q", key.SignedByDeviceId, wantSigner)
+			}
+		})
+	}
+}
+
+func TestLookupDeviceKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		email        string
+		discoverable bool
+		contact      bool
+		wantKeys     int
+	}{
+		{name: "discoverable user", email: "owner@droplet.local", discoverable: true, wantKeys: 1},
+		{name: "undiscoverable contact", email: "owner@droplet.local", contact: true, wantKeys: 1},
+		{name: "self", email: "viewer@droplet.local", wantKeys: 1},
+		{name: "undiscoverable user", email: "owner@droplet.local"},
+		{name: "unknown user", email: "nobody@droplet.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+
+			viewer := createTestUser(t, options, "viewer@droplet.local", _testPassword)
+			owner := createTestUser(t, options, "owner@droplet.local", _testPassword)
+			for _, user := range []*entity.User{viewer, owner} {
+				storages.accounts.accounts = append(storages.accounts.accounts, entity.Account{
+					Id:             user.Id,
+					UserId:         user.Id,
+					AccountDevices: []entity.AccountDevices{{Id: user.Id, AccountID: user.Id, Active: true}},
+				})
+				storages.deviceKeys.keys = append(storages.deviceKeys.keys, entity.DeviceKey{
+					DeviceId:  user.Id,
+					AccountId: user.Id,
+					UserId:    user.Id,
+				})
+			}
+			if tt.contact {
+				storages.contacts.contacts = append(storages.contacts.contacts, entity.Contact{
+					RequesterId: owner.Id,
+					AddresseeId: viewer.Id,
+					Status:      entity.ContactStatusAccepted,
+				})
+			}
+			storages.users.discoverable[owner.Id] = tt.discoverable
+
+			service := NewAccountService(options)
+			lookupOptions := &LookupDeviceKeysOptions{UserId: viewer.Id, Email: tt.email}
+
+			keys, err := service.LookupDeviceKeys(context.Background(), lookupOptions)
+			if err != nil {
+				t.Fatalf("LookupDeviceKeys() error = %v", err)
+			}
+			if len(keys.Keys) != tt.wantKeys {
+				t.Errorf("LookupDeviceKeys() returned %d keys, want %d", len(keys.Keys), tt.wantKeys)
+			}
+
+			changes, err := service.ListDeviceKeyChanges(context.Background(), lookupOptions)
+			if err != nil {
+				t.Fatalf("ListDeviceKeyChanges() error = %v", err)
+			}
+			if len(changes.Keys) != tt.wantKeys {
+				t.Errorf("ListDeviceKeyChanges() returned %d keys, want %d", len(changes.Keys), tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"time"
+)
+
+// exportProfile is user profile as it appears in the archive, credentials are never exported.
+type exportProfile struct {
+	Id                   string     `json:"id"`
+	Username             string     `json:"username"`
+	Email                string     `json:"email"`
+	EmailVerified        bool       `json:"emailVerified"`
+	TOTPEnabled          bool       `json:"totpEnabled"`
+	Role                 string     `json:"role"`
+	DeletionScheduledFor *time.Time `json:"deletionScheduledFor,omitempty"`
+}
+
+func (a accountService) RequestDataExport(ctx context.Context, options *RequestDataExportOptions) (*DataExportOutput, error) {
+	logger := a.logger.
+		Named("RequestDataExport").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.storages.AccountStorage.GetAccount(ctx, &GetAccountFilter{AccountId: options.AccountId, UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get account: ", err)
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		logger.Info("account not found")
+		return nil, ErrGetAccountAccountNotFound
+	}
+
+	inProgress, err := a.storages.DataExportStorage.GetDataExport(ctx, &GetDataExportFilter{
+		UserId:   account.UserId,
+		Statuses: []string{entity.DataExportStatusPending, entity.DataExportStatusProcessing},
+	})
+	if err != nil {
+		logger.Error("failed to get data export: ", err)
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if inProgress != nil {
+		logger.Info("data export already in progress", "exportId", inProgress.Id)
+		return nil, ErrDataExportInProgress
+	}
+
+	export, err := a.storages.DataExportStorage.CreateDataExport(ctx, &entity.DataExport{
+		UserId: account.UserId,
+		Status: entity.DataExportStatusPending,
+	})
+	if err != nil {
+		logger.Error("failed to create data export: ", err)
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	logger.Info("data export successfully requested", "exportId", export.Id)
+	return newDataExportOutput(export), nil
+}
+
+func (a accountService) GetDataExport(ctx context.Context, options *GetDataExportOptions) (*DataExportOutput, error) {
+	logger := a.logger.
+		Named("GetDataExport").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.storages.AccountStorage.GetAccount(ctx, &GetAccountFilter{AccountId: options.AccountId, UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get account: ", err)
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		logger.Info("account not found")
+		return nil, ErrGetAccountAccountNotFound
+	}
+
+	export, err := a.storages.DataExportStorage.GetDataExport(ctx, &GetDataExportFilter{Id: options.ExportId, UserId: account.UserId})
+	if err != nil {
+		logger.Error("failed to get data export: ", err)
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil {
+		logger.Info("data export not found")
+		return nil, ErrDataExportNotFound
+	}
+
+	logger.Info("successfully got data export")
+	return newDataExportOutput(export), nil
+}
+
+func (a accountService) DownloadDataExport(ctx context.Context, options *DownloadDataExportOptions) (*DownloadDataExportOutput, error) {
+	logger := a.logger.
+		Named("DownloadDataExport").
+		WithContext(ctx)
+
+	export, err := a.storages.DataExportStorage.GetDataExport(ctx, &GetDataExportFilter{
+		TokenHash: auth.HashOpaqueToken(options.Token),
+		Statuses:  []string{entity.DataExportStatusReady},
+	})
+	if err != nil {
+		logger.Error("failed to get data export: ", err)
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil {
+		logger.Info("data export not found")
+		return nil, ErrDownloadDataExportInvalidToken
+	}
+
+	logger.Info("data export successfully downloaded", "exportId", export.Id, "userId", export.UserId)
+	return &DownloadDataExportOutput{
+		FileName: fmt.Sprintf("droplet-export-%s.zip", export.CreatedAt.UTC().Format("20060102")),
+		Archive:  export.Archive,
+	}, nil
+}
+
+func (a accountService) ProcessDataExports(ctx context.Context) error {
+	logger := a.logger.
+		Named("ProcessDataExports").
+		WithContext(ctx)
+
+	stale, err := a.storages.DataExportStorage.FailStaleDataExports(
+		ctx,
+		time.Now().Add(-a.config.Account.ExportProcessingTimeout),
+		time.Now().Add(a.config.Account.ExportArchiveTTL),
+	)
+	if err != nil {
+		logger.Error("failed to fail stale data exports: ", err)
+		return fmt.Errorf("failed to fail stale data exports: %w", err)
+	}
+
+	failed := 0
+	for {
+		export, err := a.storages.DataExportStorage.ClaimPendingDataExport(ctx)
+		if err != nil {
+			logger.Error("failed to claim pending data export: ", err)
+			return fmt.Errorf("failed to claim pending data export: %w", err)
+		}
+		if export == nil {
+			break
+		}
+
+		// one broken export must not block the others, it is failed and the user may request a new one
+		err = a.completeDataExport(ctx, export)
+		if err != nil {
+			logger.With("exportId", export.Id).Error("failed to complete data export: ", err)
+			a.failDataExport(ctx, export)
+			failed++
+		}
+	}
+
+	deleted, err := a.storages.DataExportStorage.DeleteExpiredDataExports(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to delete expired data exports: ", err)
+		return fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+
+	logger.Info("successfully processed data exports", "stale", stale, "failed", failed, "deleted", deleted)
+	return nil
+}
+
+// failDataExport marks export as failed. Export which cannot be updated now is failed
+// later as stale, so the error is only logged.
+func (a accountService) failDataExport(ctx context.Context, export *entity.DataExport) {
+	now := time.Now()
+	expiresAt := now.Add(a.config.Account.ExportArchiveTTL)
+	export.Status = entity.DataExportStatusFailed
+	export.Archive = nil
+	export.TokenHash = ""
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+
+	_, err := a.storages.DataExportStorage.UpdateDataExport(ctx, export)
+	if err != nil {
+		a.logger.
+			Named("failDataExport").
+			WithContext(ctx).
+			With("exportId", export.Id).
+			Error("failed to mark data export as failed: ", err)
+	}
+}
+
+// completeDataExport builds archive of claimed export and mails download link to the user.
+// Export is marked as failed when the archive cannot be built, so it is not retried forever.
+func (a accountService) completeDataExport(ctx context.Context, export *entity.DataExport) error {
+	logger := a.logger.
+		Named("completeDataExport").
+		WithContext(ctx).
+		With("exportId", export.Id, "userId", export.UserId)
+
+	now := time.Now()
+	expiresAt := now.Add(a.config.Account.ExportArchiveTTL)
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: export.UserId})
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var archive []byte
+	if user != nil {
+		archive, err = a.buildDataExportArchive(ctx, user)
+	}
+	if user == nil || err != nil {
+		logger.Info("failed to build archive", "err", err)
+		export.Status = entity.DataExportStatusFailed
+
+		_, err = a.storages.DataExportStorage.UpdateDataExport(ctx, export)
+		if err != nil {
+			return fmt.Errorf("failed to update data export: %w", err)
+		}
+		return nil
+	}
+
+	downloadToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	export.Status = entity.DataExportStatusReady
+	export.Archive = archive
+	export.TokenHash = auth.HashOpaqueToken(downloadToken)
+
+	_, err = a.storages.DataExportStorage.UpdateDataExport(ctx, export)
+	if err != nil {
+		return fmt.Errorf("failed to update data export: %w", err)
+	}
+
+	err = a.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Your droplet data export is ready",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe archive with your droplet data is ready. Download it using the link below, it expires in %s.\n\n%s/api/v1/account/export/download?token=%s\n",
+			user.Username, a.config.Account.ExportArchiveTTL, a.config.App.BaseURL, downloadToken,
+		),
+	})
+	if err != nil {
+		// archive is built anyway, user can request another export to get new link
+		logger.Error("failed to send export mail: ", err)
+	}
+
+	logger.Info("data export successfully completed", "size", len(archive))
+	return nil
+}
+
+// buildDataExportArchive collects everything stored about the user into zip of JSON documents.
+func (a accountService) buildDataExportArchive(ctx context.Context, user *entity.User) ([]byte, error) {
+	accounts, err := a.storages.AccountStorage.ListAccounts(ctx, &ListAccountsFilter{UserId: user.Id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	transfers, err := a.storages.NodeStorage.ListNodes(ctx, &ListNodesFilter{Email: user.Email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range transfers {
+		// sender never learns the receiver blocked them
+		if transfers[i].SenderEmail == user.Email && transfers[i].Status == entity.NodeStatusBlocked {
+			transfers[i].Status = entity.NodeStatusDelivered
+		}
+	}
+
+	contacts, err := a.storages.ContactStorage.ListContacts(ctx, &ListContactsFilter{UserId: user.Id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+	for i := range contacts {
+		// requester never learns the request was ignored
+		if contacts[i].RequesterId == user.Id && contacts[i].Status == entity.ContactStatusIgnored {
+			contacts[i].Status = entity.ContactStatusPending
+		}
+	}
+
+	blocks, err := a.storages.BlockStorage.ListBlocks(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocks: %w", err)
+	}
+
+	groups, err := a.storages.GroupStorage.ListGroups(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	auditEvents, err := a.storages.AuditEventStorage.ListAuditEvents(ctx, &ListAuditEventsFilter{UserId: user.Id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	documents := []struct {
+		name    string
+		content interface{}
+	}{
+		{name: "profile.json", content: exportProfile{
+			Id:                   user.Id,
+			Username:             user.Username,
+			Email:                user.Email,
+			EmailVerified:        user.EmailVerified,
+			TOTPEnabled:          user.TOTPEnabled,
+			Role:                 user.Role,
+			DeletionScheduledFor: user.DeletionScheduledFor,
+		}},
+		{name: "accounts.json", content: accounts},
+		{name: "transfers.json", content: transfers},
+		{name: "contacts.json", content: contacts},
+		{name: "blocked_users.json", content: blocks},
+		{name: "groups.json", content: groups},
+		{name: "audit_events.json", content: auditEvents},
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, document := range documents {
+		file, err := archive.Create(document.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", document.name, err)
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(document.content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", document.name, err)
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func newDataExportOutput(export *entity.DataExport) *DataExportOutput {
+	return &DataExportOutput{
+		Id:          export.Id,
+		Status:      export.Status,
+		ExpiresAt:   export.ExpiresAt,
+		CompletedAt: export.CompletedAt,
+		CreatedAt:   export.CreatedAt,
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+	"time"
+)
+
+type fakeDataExportStorage struct {
+	DataExportStorage
+	exports     []*entity.DataExport
+	staleBefore time.Time
+}
+
+func (f *fakeDataExportStorage) ClaimPendingDataExport(ctx context.Context) (*entity.DataExport, error) {
+	for _, export := range f.exports {
+		if export.Status == entity.DataExportStatusPending {
+			now := time.Now()
+			export.Status = entity.DataExportStatusProcessing
+			export.ClaimedAt = &now
+
+			copied := *export
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakeDataExportStorage) UpdateDataExport(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error) {
+	for i := range f.exports {
+		if f.exports[i].Id == export.Id {
+			copied := *export
+			f.exports[i] = &copied
+		}
+	}
+
+	return export, nil
+}
+
+func (f *fakeDataExportStorage) FailStaleDataExports(ctx context.Context, claimedBefore, expiresAt time.Time) (int64, error) {
+	f.staleBefore = claimedBefore
+
+	var failed int64
+	for _, export := range f.exports {
+		if export.Status == entity.DataExportStatusProcessing && export.ClaimedAt.Before(claimedBefore) {
+			export.Status = entity.DataExportStatusFailed
+			export.ExpiresAt = &expiresAt
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+func (f *fakeDataExportStorage) DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// brokenUserStorage fails to get the user with given id.
+type brokenUserStorage struct {
+	UserStorage
+	userId string
+}
+
+func (b brokenUserStorage) GetUser(ctx context.Context, filter *GetUserFilter) (*entity.User, error) {
+	if filter.UserId == b.userId {
+		return nil, errors.New("connection reset")
+	}
+
+	return b.UserStorage.GetUser(ctx, filter)
+}
+
+func TestProcessDataExportsContinuesAfterFailure(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	options.Config.Account.ExportProcessingTimeout = 15 * time.Minute
+	options.Config.Account.ExportArchiveTTL = time.Hour
+
+	abandonedAt := time.Now().Add(-time.Hour)
+	exports := &fakeDataExportStorage{exports: []*entity.DataExport{
+		{Id: "abandoned", UserId: "user-1", Status: entity.DataExportStatusProcessing, ClaimedAt: &abandonedAt},
+		{Id: "broken", UserId: "user-2", Status: entity.DataExportStatusPending},
+		{Id: "deleted user", UserId: "user-3", Status: entity.DataExportStatusPending},
+	}}
+	storages.DataExportStorage = exports
+	storages.UserStorage = brokenUserStorage{UserStorage: storages.users, userId: "user-2"}
+
+	err := NewAccountService(options).ProcessDataExports(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessDataExports() error = %v", err)
+	}
+
+	for _, export := range exports.exports {
+		if export.Status != entity.DataExportStatusFailed {
+			t.Errorf("export %q status = %s, want %s", export.Id, export.Status, entity.DataExportStatusFailed)
+		}
+		if export.ExpiresAt == nil {
+			t.Errorf("failed export %q never expires", export.Id)
+		}
+	}
+	if want := time.Now().Add(-15 * time.Minute); exports.staleBefore.Sub(want).Abs() > time.Minute {
+		t.Errorf("stale exports claimed before %v, want about %v", exports.staleBefore, want)
+	}
+}
@@ -0,0 +1,482 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"strings"
+	"unicode/utf8"
+)
+
+const _maxGroupNameLength = 64
+
+type groupService struct {
+	serviceContext
+}
+
+var _ GroupService = (*groupService)(nil)
+
+func NewGroupService(options *Options) GroupService {
+	return &groupService{
+		serviceContext: serviceContext{
+			storages: options.Storages,
+			config:   options.Config,
+			logger:   options.Logger.Named("GroupService"),
+		},
+	}
+}
+
+func (g groupService) CreateGroup(ctx context.Context, options *CreateGroupOptions) (*GroupOutput, error) {
+	logger := g.logger.
+		Named("CreateGroup").
+		WithContext(ctx).
+		With("options", options)
+
+	name, err := normalizeGroupName(options.Name)
+	if err != nil {
+		logger.Info(err.Error())
+		return nil, err
+	}
+
+	user, err := g.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return nil, ErrGroupUserNotFound
+	}
+
+	group, err := g.storages.GroupStorage.CreateGroup(ctx, &entity.Group{
+		Name:    name,
+		Members: []entity.GroupMember{{UserId: user.Id, Role: entity.GroupRoleOwner}},
+	})
+	if err != nil {
+		logger.Error("failed to create group: ", err)
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	logger.Info("group successfully created", "groupId", group.Id)
+	return newGroupOutput(group, user.Id, map[string]entity.User{user.Id: *user}), nil
+}
+
+func (g groupService) GetGroup(ctx context.Context, options *GetGroupOptions) (*GroupOutput, error) {
+	logger := g.logger.
+		Named("GetGroup").
+		WithContext(ctx).
+		With("options", options)
+
+	group, _, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return nil, ErrGroupNotFound
+	}
+
+	output, err := g.newGroupOutput(ctx, group, options.UserId)
+	if err != nil {
+		logger.Error("failed to get group members: ", err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	logger.Info("successfully got group")
+	return output, nil
+}
+
+func (g groupService) ListGroups(ctx context.Context, options *ListGroupsOptions) (*ListGroupsOutput, error) {
+	logger := g.logger.
+		Named("ListGroups").
+		WithContext(ctx).
+		With("options", options)
+
+	groups, err := g.storages.GroupStorage.ListGroups(ctx, options.UserId)
+	if err != nil {
+		logger.Error("failed to list groups: ", err)
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	users, err := g.getGroupUsers(ctx, options.UserId, groups...)
+	if err != nil {
+		logger.Error("failed to get group members: ", err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	output := &ListGroupsOutput{Groups: make([]GroupOutput, 0, len(groups))}
+	for i := range groups {
+		output.Groups = append(output.Groups, *newGroupOutput(&groups[i], options.UserId, users))
+	}
+
+	logger.Info("successfully listed groups", "count", len(output.Groups))
+	return output, nil
+}
+
+func (g groupService) RenameGroup(ctx context.Context, options *RenameGroupOptions) (*GroupOutput, error) {
+	logger := g.logger.
+		Named("RenameGroup").
+		WithContext(ctx).
+		With("options", options)
+
+	name, err := normalizeGroupName(options.Name)
+	if err != nil {
+		logger.Info(err.Error())
+		return nil, err
+	}
+
+	group, member, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return nil, ErrGroupNotFound
+	}
+	if member.Role != entity.GroupRoleOwner {
+		logger.Info("user is not group owner")
+		return nil, ErrGroupOwnerRequired
+	}
+
+	group.Name = name
+	group, err = g.storages.GroupStorage.UpdateGroup(ctx, group)
+	if err != nil {
+		logger.Error("failed to update group: ", err)
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	output, err := g.newGroupOutput(ctx, group, options.UserId)
+	if err != nil {
+		logger.Error("failed to get group members: ", err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	logger.Info("group successfully renamed")
+	return output, nil
+}
+
+func (g groupService) DeleteGroup(ctx context.Context, options *DeleteGroupOptions) error {
+	logger := g.logger.
+		Named("DeleteGroup").
+		WithContext(ctx).
+		With("options", options)
+
+	group, member, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return ErrGroupNotFound
+	}
+	if member.Role != entity.GroupRoleOwner {
+		logger.Info("user is not group owner")
+		return ErrGroupOwnerRequired
+	}
+
+	err = g.storages.GroupStorage.DeleteGroup(ctx, group.Id)
+	if err != nil {
+		logger.Error("failed to delete group: ", err)
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	logger.Info("group successfully deleted")
+	return nil
+}
+
+func (g groupService) AddGroupMember(ctx context.Context, options *AddGroupMemberOptions) (*GroupOutput, error) {
+	logger := g.logger.
+		Named("AddGroupMember").
+		WithContext(ctx).
+		With("options", options)
+
+	role := options.Role
+	if role == "" {
+		role = entity.GroupRoleMember
+	}
+	if role != entity.GroupRoleOwner && role != entity.GroupRoleMember {
+		logger.Info("invalid role")
+		return nil, ErrGroupInvalidRole
+	}
+
+	group, member, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return nil, ErrGroupNotFound
+	}
+	if member.Role != entity.GroupRoleOwner {
+		logger.Info("user is not group owner")
+		return nil, ErrGroupOwnerRequired
+	}
+
+	target, err := g.findUserByEmail(ctx, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		logger.Info("user not found")
+		return nil, ErrGroupContactNotFound
+	}
+	if findGroupMember(group, target.Id) != nil {
+		logger.Info("user is already a member")
+		return nil, ErrGroupMemberExists
+	}
+
+	// users are added only by their contacts, so nobody can be put into groups of strangers or of users
+	// they blocked. Other users get the same answer as unknown emails, which does not reveal accounts or blocks
+	trusted, err := g.isTrustedContact(ctx, options.UserId, target.Id)
+	if err != nil {
+		logger.Error("failed to check contact: ", err)
+		return nil, fmt.Errorf("failed to check contact: %w", err)
+	}
+	if !trusted {
+		logger.Info("user is not trusted contact")
+		return nil, ErrGroupContactNotFound
+	}
+
+	created, err := g.storages.GroupStorage.CreateGroupMember(ctx, &entity.GroupMember{
+		GroupId: group.Id,
+		UserId:  target.Id,
+		Role:    role,
+	}, g.config.Group.MaxMembers)
+	if err != nil {
+		logger.Error("failed to create group member: ", err)
+		return nil, fmt.Errorf("failed to create group member: %w", err)
+	}
+	if created == nil {
+		logger.Info("group is full")
+		return nil, ErrGroupFull
+	}
+	group.Members = append(group.Members, *created)
+
+	output, err := g.newGroupOutput(ctx, group, options.UserId)
+	if err != nil {
+		logger.Error("failed to get group members: ", err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	logger.Info("group member successfully added", "memberUserId", target.Id)
+	return output, nil
+}
+
+func (g groupService) UpdateGroupMember(ctx context.Context, options *UpdateGroupMemberOptions) (*GroupOutput, error) {
+	logger := g.logger.
+		Named("UpdateGroupMember").
+		WithContext(ctx).
+		With("options", options)
+
+	if options.Role != entity.GroupRoleOwner && options.Role != entity.GroupRoleMember {
+		logger.Info("invalid role")
+		return nil, ErrGroupInvalidRole
+	}
+
+	group, member, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return nil, ErrGroupNotFound
+	}
+	if member.Role != entity.GroupRoleOwner {
+		logger.Info("user is not group owner")
+		return nil, ErrGroupOwnerRequired
+	}
+
+	target := findGroupMember(group, options.MemberUserId)
+	if target == nil {
+		logger.Info("member not found")
+		return nil, ErrGroupMemberNotFound
+	}
+	if target.Role == entity.GroupRoleOwner && options.Role != entity.GroupRoleOwner && countGroupOwners(group) == 1 {
+		logger.Info("last owner cannot be demoted")
+		return nil, ErrGroupLastOwner
+	}
+
+	target.Role = options.Role
+	_, err = g.storages.GroupStorage.UpdateGroupMember(ctx, target)
+	if err != nil {
+		logger.Error("failed to update group member: ", err)
+		return nil, fmt.Errorf("failed to update group member: %w", err)
+	}
+
+	output, err := g.newGroupOutput(ctx, group, options.UserId)
+	if err != nil {
+		logger.Error("failed to get group members: ", err)
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	logger.Info("group member successfully updated")
+	return output, nil
+}
+
+func (g groupService) RemoveGroupMember(ctx context.Context, options *RemoveGroupMemberOptions) error {
+	logger := g.logger.
+		Named("RemoveGroupMember").
+		WithContext(ctx).
+		With("options", options)
+
+	group, member, err := g.getMemberGroup(ctx, options.UserId, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return ErrGroupNotFound
+	}
+	if options.MemberUserId != options.UserId && member.Role != entity.GroupRoleOwner {
+		logger.Info("user is not group owner")
+		return ErrGroupOwnerRequired
+	}
+
+	target := findGroupMember(group, options.MemberUserId)
+	if target == nil {
+		logger.Info("member not found")
+		return ErrGroupMemberNotFound
+	}
+	if target.Role == entity.GroupRoleOwner && countGroupOwners(group) == 1 {
+		logger.Info("last owner cannot leave")
+		return ErrGroupLastOwner
+	}
+
+	_, err = g.storages.GroupStorage.DeleteGroupMember(ctx, &GetGroupMemberFilter{GroupId: group.Id, UserId: target.UserId})
+	if err != nil {
+		logger.Error("failed to delete group member: ", err)
+		return fmt.Errorf("failed to delete group member: %w", err)
+	}
+
+	logger.Info("group member successfully removed")
+	return nil
+}
+
+// getMemberGroup returns group together with membership of the user, group is nil when it does not exist
+// or the user is not its member.
+func (s serviceContext) getMemberGroup(ctx context.Context, userId, groupId string) (*entity.Group, *entity.GroupMember, error) {
+	group, err := s.storages.GroupStorage.GetGroup(ctx, &GetGroupFilter{Id: groupId, MemberId: userId})
+	if err != nil {
+		return nil, nil, err
+	}
+	if group == nil {
+		return nil, nil, nil
+	}
+
+	member := findGroupMember(group, userId)
+	if member == nil {
+		// left the group concurrently
+		return nil, nil, nil
+	}
+
+	return group, member, nil
+}
+
+// getGroupUsers returns users of all members of the groups mapped by id. Emails of members whose
+// discoverability does not allow viewerId to find them are cleared.
+func (g groupService) getGroupUsers(ctx context.Context, viewerId string, groups ...entity.Group) (map[string]entity.User, error) {
+	seen := make(map[string]bool)
+	var userIds []string
+	for _, group := range groups {
+		for _, member := range group.Members {
+			if !seen[member.UserId] {
+				seen[member.UserId] = true
+				userIds = append(userIds, member.UserId)
+			}
+		}
+	}
+
+	users, err := g.getUsersById(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	discoverable, err := g.getDiscoverableUserIds(ctx, viewerId, userIds)
+	if err != nil {
+		return nil, err
+	}
+	for id, user := range users {
+		if id != viewerId && !discoverable[id] {
+			user.Email = ""
+			users[id] = user
+		}
+	}
+
+	return users, nil
+}
+
+func (g groupService) newGroupOutput(ctx context.Context, group *entity.Group, userId string) (*GroupOutput, error) {
+	users, err := g.getGroupUsers(ctx, userId, *group)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGroupOutput(group, userId, users), nil
+}
+
+// newGroupOutput shows group to userId, members whose users no longer exist are left out.
+func newGroupOutput(group *entity.Group, userId string, users map[string]entity.User) *GroupOutput {
+	output := &GroupOutput{
+		Id:        group.Id,
+		Name:      group.Name,
+		Members:   make([]GroupMemberOutput, 0, len(group.Members)),
+		CreatedAt: group.CreatedAt,
+	}
+
+	for _, member := range group.Members {
+		if member.UserId == userId {
+			output.Role = member.Role
+		}
+
+		user, ok := users[member.UserId]
+		if !ok {
+			continue
+		}
+		output.Members = append(output.Members, GroupMemberOutput{
+			UserId:   user.Id,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     member.Role,
+			JoinedAt: member.CreatedAt,
+		})
+	}
+
+	return output
+}
+
+func findGroupMember(group *entity.Group, userId string) *entity.GroupMember {
+	for i := range group.Members {
+		if group.Members[i].UserId == userId {
+			return &group.Members[i]
+		}
+	}
+
+	return nil
+}
+
+func countGroupOwners(group *entity.Group) int {
+	count := 0
+	for _, member := range group.Members {
+		if member.Role == entity.GroupRoleOwner {
+			count++
+		}
+	}
+
+	return count
+}
+
+func normalizeGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > _maxGroupNameLength {
+		return "", ErrGroupInvalidName
+	}
+
+	return name, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+type fakeGroupStorage struct {
+	GroupStorage
+	group entity.Group
+}
+
+func (f *fakeGroupStorage) GetGroup(ctx context.Context, filter *GetGroupFilter) (*entity.Group, error) {
+	if f.group.Id != filter.Id || findGroupMember(&f.group, filter.MemberId) == nil {
+		return nil, nil
+	}
+
+	copied := f.group
+	copied.Members = append([]entity.GroupMember{}, f.group.Members...)
+	return &copied, nil
+}
+
+func (f *fakeGroupStorage) CreateGroupMember(ctx context.Context, member *entity.GroupMember, maxMembers int) (*entity.GroupMember, error) {
+	if len(f.group.Members) >= maxMembers {
+		return nil, nil
+	}
+
+	member.Id = uuid.NewString()
+	member.CreatedAt = time.Now()
+	f.group.Members = append(f.group.Members, *member)
+
+	return member, nil
+}
+
+type groupTestContext struct {
+	storages *testStorages
+	service  GroupService
+	owner    *entity.User
+	target   *entity.User
+	groups   *fakeGroupStorage
+}
+
+func newGroupTestContext(t *testing.T) *groupTestContext {
+	t.Helper()
+
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	options.Config.Group.MaxMembers = 10
+
+	owner := createTestUser(t, options, "owner@example.com", _testPassword)
+	target := createTestUser(t, options, "target@example.com", _testPassword)
+
+	groups := &fakeGroupStorage{group: entity.Group{
+		Id:      "group-1",
+		Name:    "family",
+		Members: []entity.GroupMember{{Id: "member-1", GroupId: "group-1", UserId: owner.Id, Role: entity.GroupRoleOwner}},
+	}}
+	storages.GroupStorage = groups
+
+	return &groupTestContext{
+		storages: storages,
+		service:  NewGroupService(options),
+		owner:    owner,
+		target:   target,
+		groups:   groups,
+	}
+}
+
+func TestAddGroupMemberRequiresTrustedContact(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		contact   string
+		blockedBy string
+		wantErr   error
+	}{
+		{name: "accepted contact", email: "target@example.com", contact: entity.ContactStatusAccepted},
+		{name: "stranger", email: "target@example.com", wantErr: ErrGroupContactNotFound},
+		{name: "pending contact", email: "target@example.com", contact: entity.ContactStatusPending, wantErr: ErrGroupContactNotFound},
+		{name: "contact blocked owner", email: "target@example.com", contact: entity.ContactStatusAccepted, blockedBy: "target", wantErr: ErrGroupContactNotFound},
+		{name: "owner blocked contact", email: "target@example.com", contact: entity.ContactStatusAccepted, blockedBy: "owner", wantErr: ErrGroupContactNotFound},
+		{name: "unknown user", email: "nobody@example.com", wantErr: ErrGroupContactNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newGroupTestContext(t)
+			if tt.contact != "" {
+				c.storages.contacts.contacts = append(c.storages.contacts.contacts, entity.Contact{
+					RequesterId: c.owner.Id,
+					AddresseeId: c.target.Id,
+					Status:      tt.contact,
+				})
+			}
+			switch tt.blockedBy {
+			case "owner":
+				c.storages.blocks.blocks = append(c.storages.blocks.blocks, entity.Block{UserId: c.owner.Id, BlockedUserId: c.target.Id})
+			case "target":
+				c.storages.blocks.blocks = append(c.storages.blocks.blocks, entity.Block{UserId: c.target.Id, BlockedUserId: c.owner.Id})
+			}
+
+			_, err := c.service.AddGroupMember(context.Background(), &AddGroupMemberOptions{
+				UserId:  c.owner.Id,
+				GroupId: c.groups.group.Id,
+				Email:   tt.email,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddGroupMember() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantMembers := 1
+			if tt.wantErr == nil {
+				wantMembers = 2
+			}
+			if len(c.groups.group.Members) != wantMembers {
+				t.Errorf("group has %d members, want %d", len(c.groups.group.Members), wantMembers)
+			}
+		})
+	}
+}
+
+func TestGetGroupHidesUndiscoverableEmails(t *testing.T) {
+	tests := []struct {
+		name         string
+		discoverable bool
+		wantEmail    string
+	}{
+		{name: "discoverable member", discoverable: true, wantEmail: "target@example.com"},
+		{name: "undiscoverable member"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newGroupTestContext(t)
+			c.groups.group.Members = append(c.groups.group.Members, entity.GroupMember{
+				Id:      "member-2",
+				GroupId: c.groups.group.Id,
+				UserId:  c.target.Id,
+				Role:    entity.GroupRoleMember,
+			})
+			c.storages.users.discoverable[c.target.Id] = tt.discoverable
+
+			// the owner is never discoverable by search, yet always sees own email
+			for _, viewer := range []*entity.User{c.owner, c.target} {
+				group, err := c.service.GetGroup(context.Background(), &GetGroupOptions{UserId: viewer.Id, GroupId: c.groups.group.Id})
+				if err != nil {
+					t.Fatalf("GetGroup() error = %v", err)
+				}
+
+				emails := make(map[string]string, len(group.Members))
+				for _, member := range group.Members {
+					emails[member.UserId] = member.Email
+				}
+
+				wantEmails := map[string]string{c.owner.Id: "", c.target.Id: tt.wantEmail}
+				wantEmails[viewer.Id] = viewer.Email
+				for userId, want := range wantEmails {
+					if emails[userId] != want {
+						t.Errorf("viewer %s sees email of %s = %q, want %q", viewer.Email, userId, emails[userId], want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/totp"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const _recoveryCodesCount = 10
+
+func (a authService) SignInMFA(ctx context.Context, options *SignInMFAOptions) (*SignInOutput, error) {
+	logger := a.logger.
+		Named("SignInMFA").
+		WithContext(ctx).
+		With("clientIP", options.ClientIP)
+
+	// challenge is consumed before the code is checked, so a wrong code requires signing in again
+	// and codes cannot be brute forced within one challenge.
+	challenge, err := a.storages.UserTokenStorage.ConsumeUserToken(ctx, &ConsumeUserTokenFilter{
+		TokenHash: auth.HashOpaqueToken(options.MFAToken),
+		Purpose:   entity.UserTokenPurposeMFAChallenge,
+	})
+	if err != nil {
+		logger.Error("failed to consume mfa challenge: ", err)
+		return nil, fmt.Errorf("failed to consume mfa challenge: %w", err)
+	}
+	if challenge == nil {
+		logger.Info("mfa challenge not found")
+		return nil, ErrSignInMFAInvalidToken
+	}
+	logger = logger.With("userId", challenge.UserId)
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: challenge.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.TOTPEnabled {
+		logger.Info("user not found or 2fa disabled")
+		return nil, ErrSignInMFAInvalidToken
+	}
+	err = checkUserActive(user)
+	if err != nil {
+		logger.Info("user is not active", "err", err)
+		return nil, err
+	}
+
+	// second factor failures share counters with password failures, so codes cannot be guessed
+	// by signing in again with a known password
+	throttleKeys := newThrottleKeys(user.Email, options.ClientIP)
+	err = a.checkSignInThrottles(ctx, throttleKeys)
+	if err != nil {
+		logger.Info("sign in is locked", "err", err)
+		return nil, err
+	}
+
+	err = a.verifySecondFactor(ctx, user, options.Code)
+	if errors.Is(err, ErrMFAInvalidCode) {
+		logger.Info("second factor rejected")
+		return nil, a.registerSignInFailure(ctx, throttleKeys, err)
+	}
+	if err != nil {
+		logger.Error("failed to verify second factor: ", err)
+		return nil, fmt.Errorf("failed to verify second factor: %w", err)
+	}
+
+	// per-ip counter is kept, otherwise one valid account would let an attacker reset it
+	err = a.storages.LoginThrottleStorage.DeleteLoginThrottle(ctx, throttleKeys.account)
+	if err != nil {
+		logger.Error("failed to reset sign in throttle: ", err)
+		return nil, fmt.Errorf("failed to reset sign in throttle: %w", err)
+	}
+
+	accessToken, refreshToken, err := a.generateTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		logger.Error("failed to generate tokens for user: ", err)
+		return nil, fmt.Errorf("failed to generate tokens for user: %w", err)
+	}
+
+	logger.Info("successfully signed user with second factor")
+	return &SignInOutput{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (a authService) EnrollTOTP(ctx context.Context, options *EnrollTOTPOptions) (*EnrollTOTPOutput, error) {
+	logger := a.logger.
+		Named("EnrollTOTP").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := a.getMFAUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		logger.Info("2fa already enabled")
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Error("failed to generate totp secret: ", err)
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	logger.Info("successfully enrolled totp")
+	return &EnrollTOTPOutput{Secret: secret, URI: totp.URI(a.config.Auth.TOTPIssuer, user.Email, secret)}, nil
+}
+
+func (a authService) ConfirmTOTP(ctx context.Context, options *ConfirmTOTPOptions) (*ConfirmTOTPOutput, error) {
+	logger := a.logger.
+		Named("ConfirmTOTP").
+		WithContext(ctx).
+		With("userId", options.UserId)
+
+	user, err := a.getMFAUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		logger.Info("2fa already enabled")
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		logger.Info("2fa enrollment not started")
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, options.Code, time.Now())
+	if !ok {
+		logger.Info("invalid totp code")
+		return nil, ErrMFAInvalidCode
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+
+	recoveryCodes, err := a.replaceRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		logger.Error("failed to create recovery codes: ", err)
+		return nil, fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	logger.Info("successfully enabled 2fa")
+	return &ConfirmTOTPOutput{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (a authService) DisableTOTP(ctx context.Context, options *DisableTOTPOptions) error {
+	logger := a.logger.
+		Named("DisableTOTP").
+		WithContext(ctx).
+		With("userId", options.UserId)
+
+	user, err := a.getMFAUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return err
+	}
+	if !user.TOTPEnabled {
+		logger.Info("2fa not enabled")
+		return ErrTOTPNotEnabled
+	}
+
+	err = a.verifySecondFactor(ctx, user, options.Code)
+	if err != nil {
+		logger.Info("second factor rejected", "err", err)
+		return err
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+
+	_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+	if err != nil {
+		logger.Error("failed to update user: ", err)
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	err = a.storages.RecoveryCodeStorage.ReplaceRecoveryCodes(ctx, user.Id, nil)
+	if err != nil {
+		logger.Error("failed to delete recovery codes: ", err)
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	logger.Info("successfully disabled 2fa")
+	return nil
+}
+
+func (a authService) RegenerateRecoveryCodes(ctx context.Context, options *RegenerateRecoveryCodesOptions) (*RegenerateRecoveryCodesOutput, error) {
+	logger := a.logger.
+		Named("RegenerateRecoveryCodes").
+		WithContext(ctx).
+		With("userId", options.UserId)
+
+	user, err := a.getMFAUser(ctx, options.UserId)
+	if err != nil {
+		logger.Info("failed to get user", "err", err)
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		logger.Info("2fa not enabled")
+		return nil, ErrTOTPNotEnabled
+	}
+
+	err = a.verifySecondFactor(ctx, user, options.Code)
+	if err != nil {
+		logger.Info("second factor rejected", "err", err)
+		return nil, err
+	}
+
+	recoveryCodes, err := a.replaceRecoveryCodes(ctx, user.Id)
+	if err != nil {
+		logger.Error("failed to create recovery codes: ", err)
+		return nil, fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+
+	logger.Info("successfully regenerated recovery codes")
+	return &RegenerateRecoveryCodesOutput{RecoveryCodes: recoveryCodes}, nil
+}
+
+// getMFAUser returns user or expected error if the user does not exist.
+func (a authService) getMFAUser(ctx context.Context, userId string) (*entity.User, error) {
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: userId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrMFAUserNotFound
+	}
+
+	return user, nil
+}
+
+// verifySecondFactor accepts TOTP code newer than the last accepted one or unused recovery code.
+func (a authService) verifySecondFactor(ctx context.Context, user *entity.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if ok {
+		if step <= user.TOTPLastStep {
+			return ErrMFAInvalidCode
+		}
+		user.TOTPLastStep = step
+
+		_, err := a.storages.UserStorage.UpdateUser(ctx, user)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		return nil
+	}
+
+	consumed, err := a.storages.RecoveryCodeStorage.ConsumeRecoveryCode(ctx, user.Id, auth.HashOpaqueToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	if !consumed {
+		return ErrMFAInvalidCode
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes generates new recovery codes, stores their hashes and returns codes to show to the user.
+func (a authService) replaceRecoveryCodes(ctx context.Context, userId string) ([]string, error) {
+	codes := make([]string, 0, _recoveryCodesCount)
+	recoveryCodes := make([]entity.RecoveryCode, 0, _recoveryCodesCount)
+	for i := 0; i < _recoveryCodesCount; i++ {
+		b := make([]byte, 7)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		// 10 base32 characters, shown as "xxxxx-xxxxx"
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		recoveryCodes = append(recoveryCodes, entity.RecoveryCode{UserId: userId, CodeHash: auth.HashOpaqueToken(raw)})
+	}
+
+	err := a.storages.RecoveryCodeStorage.ReplaceRecoveryCodes(ctx, userId, recoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/atlant1da-404/droplet/pkg/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMFAService(t *testing.T) (*authService, *testStorages, string) {
+	t.Helper()
+
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	user.TOTPSecret = secret
+	user.TOTPEnabled = true
+	_, _ = storages.users.UpdateUser(context.Background(), user)
+
+	return NewAuthService(options).(*authService), storages, secret
+}
+
+func signInWithPassword(t *testing.T, service *authService) string {
+	t.Helper()
+
+	signed, err := service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	if !signed.MFARequired {
+		t.Fatalf("SignIn() did not require second factor")
+	}
+
+	return signed.MFAToken
+}
+
+func TestSignInMFA(t *testing.T) {
+	service, _, secret := newTestMFAService(t)
+
+	// wrong code consumes the challenge, so the next attempt needs a new sign in
+	mfaToken := signInWithPassword(t, service)
+	_, err := service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: mfaToken, Code: "000000", ClientIP: _testClientIP})
+	if !errors.Is(err, ErrMFAInvalidCode) {
+		t.Fatalf("SignInMFA() error = %v, want %v", err, ErrMFAInvalidCode)
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	_, err = service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: mfaToken, Code: code, ClientIP: _testClientIP})
+	if !errors.Is(err, ErrSignInMFAInvalidToken) {
+		t.Fatalf("SignInMFA() with consumed challenge error = %v, want %v", err, ErrSignInMFAInvalidToken)
+	}
+
+	signed, err := service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: signInWithPassword(t, service), Code: code, ClientIP: _testClientIP})
+	if err != nil {
+		t.Fatalf("SignInMFA() error = %v", err)
+	}
+	if signed.AccessToken == "" || signed.RefreshToken == "" {
+		t.Fatalf("SignInMFA() returned no tokens")
+	}
+}
+func TestSignInMFAThrottlesWrongCodes(t *testing.T) {
+	service, storages, _ := newTestMFAService(t)
+	threshold := service.config.Auth.LockoutThreshold
+
+	for i := 1; i <= threshold; i++ {
+		mfaToken := signInWithPassword(t, service)
+
+		_, err := service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: mfaToken, Code: "000000", ClientIP: _testClientIP})
+		wantCode := "invalid_mfa_code"
+		if i == threshold {
+			wantCode = "account_locked"
+		}
+		if errs.GetCode(err) != wantCode {
+			t.Fatalf("attempt %d: SignInMFA() error = %v, want %s", i, err, wantCode)
+		}
+
+		// correct password must not reset failures of the second factor
+		if failures := storages.loginThrottles.throttles["email:"+_testEmail].Failures; failures != i {
+			t.Fatalf("attempt %d: account failures = %d, want %d", i, failures, i)
+		}
+	}
+
+	_, err := service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if errs.GetCode(err) != "account_locked" {
+		t.Fatalf("SignIn() after lockout error = %v, want account_locked", err)
+	}
+}
+
+func TestSignInMFAResetsThrottleOnSuccess(t *testing.T) {
+	service, storages, secret := newTestMFAService(t)
+
+	_, err := service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: signInWithPassword(t, service), Code: "000000", ClientIP: _testClientIP})
+	if errs.GetCode(err) != "invalid_mfa_code" {
+		t.Fatalf("SignInMFA() error = %v, want invalid_mfa_code", err)
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	signed, err := service.SignInMFA(context.Background(), &SignInMFAOptions{MFAToken: signInWithPassword(t, service), Code: code, ClientIP: _testClientIP})
+	if err != nil {
+		t.Fatalf("SignInMFA() error = %v", err)
+	}
+	if signed.AccessToken == "" || signed.RefreshToken == "" {
+		t.Fatalf("SignInMFA() returned no tokens")
+	}
+
+	if _, ok := storages.loginThrottles.throttles["email:"+_testEmail]; ok {
+		t.Fatalf("account throttle was not reset after full authentication")
+	}
+	if _, ok := storages.loginThrottles.throttles["ip:"+_testClientIP]; !ok {
+		t.Fatalf("ip throttle was reset after successful sign in")
+	}
+}
+
+func TestVerifySecondFactor(t *testing.T) {
+	service, storages, secret := newTestMFAService(t)
+	now := time.Now()
+
+	codeAt := func(t *testing.T, at time.Time) string {
+		code, err := totp.Code(secret, at)
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		return code
+	}
+
+	recoveryCodes, err := service.replaceRecoveryCodes(context.Background(), firstUserId(storages))
+	if err != nil {
+		t.Fatalf("failed to create recovery codes: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		code    func(t *testing.T) string
+		wantErr bool
+	}{
+		{name: "current code", code: func(t *testing.T) string { return codeAt(t, now) }},
+		{name: "replayed code", code: func(t *testing.T) string { return codeAt(t, now) }, wantErr: true},
+		{name: "previous step after newer one", code: func(t *testing.T) string { return codeAt(t, now.Add(-30*time.Second)) }, wantErr: true},
+		{name: "next step within skew", code: func(t *testing.T) string { return codeAt(t, now.Add(30*time.Second)) }},
+		{name: "outside of window", code: func(t *testing.T) string { return codeAt(t, now.Add(5*time.Minute)) }, wantErr: true},
+		{name: "recovery code", code: func(t *testing.T) string { return recoveryCodes[0] }},
+		{name: "recovery code in upper case without dash", code: func(t *testing.T) string {
+			return "  " + strings.ToUpper(strings.ReplaceAll(recoveryCodes[1], "-", ""))
+		}},
+		{name: "used recovery code", code: func(t *testing.T) string { return recoveryCodes[0] }, wantErr: true},
+		{name: "garbage", code: func(t *testing.T) string { return "not a code" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, _ := storages.users.GetUser(context.Background(), &GetUserFilter{Email: _testEmail})
+
+			err := service.verifySecondFactor(context.Background(), user, tt.code(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifySecondFactor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err != ErrMFAInvalidCode {
+				t.Fatalf("verifySecondFactor() error = %v, want ErrMFAInvalidCode", err)
+			}
+		})
+	}
+}
+
+func firstUserId(storages *testStorages) string {
+	for id := range storages.users.users {
+		return id
+	}
+
+	return ""
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/google/uuid"
 )
 
 type nodeService struct {
@@ -28,9 +30,58 @@ func (n nodeService) CreateNode(ctx context.Context, options *CreateNodeOptions)
 		WithContext(ctx).
 		With("options", options)
 
+	if (options.ReceiverEmail == "") == (options.ReceiverUserId == "") {
+		logger.Info("receiver is required")
+		return nil, ErrCreateNodeReceiverRequired
+	}
+
+	sender, err := n.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.SenderUserId})
+	if err != nil {
+		logger.Error("failed to get sender: ", err)
+		return nil, fmt.Errorf("failed to get sender: %w", err)
+	}
+	if sender == nil {
+		logger.Info("sender not found")
+		return nil, ErrCreateNodeSenderNotFound
+	}
+	if n.config.Auth.RequireVerifiedEmail && !sender.EmailVerified {
+		logger.Info("sender email is not verified")
+		return nil, ErrCreateNodeEmailNotVerified
+	}
+
 	node := &entity.Node{
-		SenderEmail:   options.SenderEmail,
+		SenderEmail:   sender.Email,
 		ReceiverEmail: options.ReceiverEmail,
+		Status:        entity.NodeStatusDelivered,
+	}
+
+	var receiver *entity.User
+	if options.ReceiverUserId != "" {
+		receiver, err = n.findUserById(ctx, options.ReceiverUserId)
+	} else {
+		receiver, err = n.findUserByEmail(ctx, options.ReceiverEmail)
+	}
+	if err != nil {
+		logger.Error("failed to find receiver: ", err)
+		return nil, fmt.Errorf("failed to find receiver: %w", err)
+	}
+	if receiver == nil && options.ReceiverUserId != "" {
+		// unlike email, id cannot belong to user who registers later
+		logger.Info("receiver not found")
+		return nil, ErrCreateNodeReceiverNotFound
+	}
+	if receiver != nil {
+		node.ReceiverEmail = receiver.Email
+
+		err = n.applyReceiverPolicy(ctx, sender, receiver, node)
+		if errors.Is(err, ErrCreateNodeRejected) {
+			logger.Info("receiver rejects transfers from non-contacts")
+			return nil, err
+		}
+		if err != nil {
+			logger.Error("failed to apply receiver policy: ", err)
+			return nil, fmt.Errorf("failed to apply receiver policy: %w", err)
+		}
 	}
 	logger = logger.With("node", node)
 
@@ -44,3 +95,248 @@ func (n nodeService) CreateNode(ctx context.Context, options *CreateNodeOptions)
 	logger.Info("successfully created node")
 	return &CreateNodeOutput{Id: createdNode.Id}, nil
 }
+
+func (n nodeService) ListHeldTransfers(ctx context.Context, options *ListHeldTransfersOptions) (*ListHeldTransfersOutput, error) {
+	logger := n.logger.
+		Named("ListHeldTransfers").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := n.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return &ListHeldTransfersOutput{Transfers: []HeldTransfer{}}, nil
+	}
+
+	nodes, err := n.storages.NodeStorage.ListNodes(ctx, &ListNodesFilter{ReceiverEmail: user.Email, Status: entity.NodeStatusHeld})
+	if err != nil {
+		logger.Error("failed to list nodes: ", err)
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	output := &ListHeldTransfersOutput{Transfers: make([]HeldTransfer, 0, len(nodes))}
+	for _, node := range nodes {
+		output.Transfers = append(output.Transfers, HeldTransfer{
+			Id:          node.Id,
+			SenderEmail: node.SenderEmail,
+			CreatedAt:   node.CreatedAt,
+		})
+	}
+
+	logger.Info("successfully listed held transfers", "count", len(output.Transfers))
+	return output, nil
+}
+
+func (n nodeService) ReviewHeldTransfer(ctx context.Context, options *ReviewHeldTransferOptions) error {
+	logger := n.logger.
+		Named("ReviewHeldTransfer").
+		WithContext(ctx).
+		With("options", options)
+
+	user, err := n.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to get user: ", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return ErrHeldTransferNotFound
+	}
+
+	node, err := n.storages.NodeStorage.GetNode(ctx, &GetNodeFilter{
+		Id:            options.NodeId,
+		ReceiverEmail: user.Email,
+		Status:        entity.NodeStatusHeld,
+	})
+	if err != nil {
+		logger.Error("failed to get node: ", err)
+		return fmt.Errorf("failed to get node: %w", err)
+	}
+	if node == nil {
+		logger.Info("held transfer not found")
+		return ErrHeldTransferNotFound
+	}
+
+	node.Status = entity.NodeStatusDeclined
+	if options.Approve {
+		node.Status = entity.NodeStatusDelivered
+	}
+
+	_, err = n.storages.NodeStorage.UpdateNode(ctx, node)
+	if err != nil {
+		logger.Error("failed to update node: ", err)
+		return fmt.Errorf("failed to update node: %w", err)
+	}
+
+	logger.Info("held transfer successfully reviewed", "status", node.Status)
+	return nil
+}
+
+func (n nodeService) CreateGroupNode(ctx context.Context, options *CreateGroupNodeOptions) (*CreateGroupNodeOutput, error) {
+	logger := n.logger.
+		Named("CreateGroupNode").
+		WithContext(ctx).
+		With("options", options)
+
+	sender, err := n.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: options.SenderUserId})
+	if err != nil {
+		logger.Error("failed to get sender: ", err)
+		return nil, fmt.Errorf("failed to get sender: %w", err)
+	}
+	if sender == nil {
+		logger.Info("sender not found")
+		return nil, ErrCreateNodeSenderNotFound
+	}
+	if n.config.Auth.RequireVerifiedEmail && !sender.EmailVerified {
+		logger.Info("sender email is not verified")
+		return nil, ErrCreateNodeEmailNotVerified
+	}
+
+	group, _, err := n.getMemberGroup(ctx, sender.Id, options.GroupId)
+	if err != nil {
+		logger.Error("failed to get group: ", err)
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		logger.Info("group not found")
+		return nil, ErrCreateGroupNodeNotFound
+	}
+
+	var receiverIds []string
+	for _, member := range group.Members {
+		if member.UserId != sender.Id {
+			receiverIds = append(receiverIds, member.UserId)
+		}
+	}
+
+	receivers, err := n.getUsersById(ctx, receiverIds)
+	if err != nil {
+		logger.Error("failed to get receivers: ", err)
+		return nil, fmt.Errorf("failed to get receivers: %w", err)
+	}
+
+	// receivers are resolved now, so later membership changes do not touch created nodes
+	output := &CreateGroupNodeOutput{PayloadId: uuid.NewString(), Transfers: []GroupNodeTransfer{}, Rejected: []string{}}
+	var nodes []entity.Node
+	for _, receiverId := range receiverIds {
+		receiver, ok := receivers[receiverId]
+		if !ok || checkUserActive(&receiver) != nil {
+			continue
+		}
+
+		node := &entity.Node{
+			SenderEmail:   sender.Email,
+			ReceiverEmail: receiver.Email,
+			Status:        entity.NodeStatusDelivered,
+			PayloadId:     output.PayloadId,
+			GroupId:       &group.Id,
+		}
+		err = n.applyReceiverPolicy(ctx, sender, &receiver, node)
+		if errors.Is(err, ErrCreateNodeRejected) {
+			output.Rejected = append(output.Rejected, receiver.Email)
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to apply receiver policy: ", err)
+			return nil, fmt.Errorf("failed to apply receiver policy: %w", err)
+		}
+		nodes = append(nodes, *node)
+	}
+	if len(nodes) == 0 && len(output.Rejected) == 0 {
+		logger.Info("group has no receivers")
+		return nil, ErrCreateGroupNodeNoReceivers
+	}
+
+	createdNodes, err := n.storages.NodeStorage.CreateNodes(ctx, nodes)
+	if err != nil {
+		logger.Error("failed to create nodes: ", err)
+		return nil, fmt.Errorf("failed to create nodes: %w", err)
+	}
+	for _, node := range createdNodes {
+		output.Transfers = append(output.Transfers, GroupNodeTransfer{Id: node.Id, ReceiverEmail: node.ReceiverEmail})
+	}
+
+	logger.Info("successfully created group nodes", "payloadId", output.PayloadId, "count", len(createdNodes))
+	return output, nil
+}
+
+// findUserById returns active user with given id, or nil when there is no such user.
+func (n nodeService) findUserById(ctx context.Context, userId string) (*entity.User, error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return nil, nil
+	}
+
+	user, err := n.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: userId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || checkUserActive(user) != nil {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// applyReceiverPolicy sets status of the node from sender to receiver. Transfers from non-contacts follow
+// the receiver policy, ErrCreateNodeRejected is returned when the policy rejects them.
+func (n nodeService) applyReceiverPolicy(ctx context.Context, sender, receiver *entity.User, node *entity.Node) error {
+	if receiver.Id == sender.Id {
+		return nil
+	}
+
+	contacts, err := n.areContacts(ctx, sender.Id, receiver.Id)
+	if err != nil {
+		return fmt.Errorf("failed to check contact: %w", err)
+	}
+	if !contacts {
+		policy, err := n.getNonContactTransfersPolicy(ctx, receiver.Id)
+		if err != nil {
+			return fmt.Errorf("failed to get receiver policy: %w", err)
+		}
+
+		switch policy {
+		case entity.NonContactTransfersReject:
+			return ErrCreateNodeRejected
+		case entity.NonContactTransfersHold:
+			node.Status = entity.NodeStatusHeld
+		}
+	}
+
+	// sender gets the same answer as for delivered transfer, so blocking is not revealed
+	blocked, err := n.isBlocked(ctx, receiver.Id, sender.Id)
+	if err != nil {
+		return fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocked {
+		node.Status = entity.NodeStatusBlocked
+	}
+
+	return nil
+}
+
+// getNonContactTransfersPolicy returns the strictest policy among accounts of the user,
+// user without accounts accepts transfers from anyone.
+func (n nodeService) getNonContactTransfersPolicy(ctx context.Context, userId string) (string, error) {
+	accounts, err := n.storages.AccountStorage.ListAccounts(ctx, &ListAccountsFilter{UserId: userId})
+	if err != nil {
+		return "", fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	strictest := 0
+	for _, account := range accounts {
+		if account.AccountSettings == nil {
+			continue
+		}
+		for i, policy := range entity.NonContactTransferPolicies {
+			if policy == account.AccountSettings.NonContactTransfers && i > strictest {
+				strictest = i
+			}
+		}
+	}
+
+	return entity.NonContactTransferPolicies[strictest], nil
+}
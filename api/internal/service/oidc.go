@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/oidc"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+func (a authService) StartOIDCLogin(ctx context.Context, options *StartOIDCLoginOptions) (*StartOIDCLoginOutput, error) {
+	logger := a.logger.
+		Named("StartOIDCLogin").
+		WithContext(ctx).
+		With("provider", options.Provider)
+
+	provider, ok := a.oidcProviders[options.Provider]
+	if !ok {
+		logger.Info("oidc provider not found")
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := oidc.GenerateRandom()
+	if err != nil {
+		logger.Error("failed to generate state: ", err)
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := oidc.GenerateRandom()
+	if err != nil {
+		logger.Error("failed to generate nonce: ", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	codeVerifier, err := oidc.GenerateRandom()
+	if err != nil {
+		logger.Error("failed to generate code verifier: ", err)
+		return nil, fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authorizationURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		logger.Error("failed to build authorization url: ", err)
+		return nil, fmt.Errorf("failed to build authorization url: %w", err)
+	}
+
+	_, err = a.storages.OIDCLoginStateStorage.CreateOIDCLoginState(ctx, &entity.OIDCLoginState{
+		Provider:     options.Provider,
+		StateHash:    auth.HashOpaqueToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(a.config.OIDC.LoginTTL),
+	})
+	if err != nil {
+		logger.Error("failed to create login state: ", err)
+		return nil, fmt.Errorf("failed to create login state: %w", err)
+	}
+
+	logger.Info("successfully started oidc login")
+	return &StartOIDCLoginOutput{AuthorizationURL: authorizationURL}, nil
+}
+
+func (a authService) CompleteOIDCLogin(ctx context.Context, options *CompleteOIDCLoginOptions) (*SignInOutput, error) {
+	logger := a.logger.
+		Named("CompleteOIDCLogin").
+		WithContext(ctx).
+		With("provider", options.Provider)
+
+	provider, ok := a.oidcProviders[options.Provider]
+	if !ok {
+		logger.Info("oidc provider not found")
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	loginState, err := a.storages.OIDCLoginStateStorage.ConsumeOIDCLoginState(ctx, &ConsumeOIDCLoginStateFilter{
+		StateHash: auth.HashOpaqueToken(options.State),
+		Provider:  options.Provider,
+	})
+	if err != nil {
+		logger.Error("failed to consume login state: ", err)
+		return nil, fmt.Errorf("failed to consume login state: %w", err)
+	}
+	if loginState == nil {
+		logger.Info("login state not found")
+		return nil, ErrOIDCInvalidState
+	}
+
+	claims, err := provider.Exchange(ctx, options.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		logger.Info("failed to exchange authorization code: ", err)
+		return nil, ErrOIDCLoginFailed
+	}
+	logger = logger.With("subject", claims.Subject)
+
+	user, err := a.getOIDCUser(ctx, options.Provider, claims)
+	if err != nil {
+		logger.Info("failed to get oidc user: ", err)
+		return nil, err
+	}
+	logger = logger.With("userId", user.Id)
+	err = checkUserActive(user)
+	if err != nil {
+		logger.Info("user is not active", "err", err)
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := a.createUserToken(ctx, user.Id, entity.UserTokenPurposeMFAChallenge, a.config.Auth.MFAChallengeTTL)
+		if err != nil {
+			logger.Error("failed to create mfa challenge: ", err)
+			return nil, fmt.Errorf("failed to create mfa challenge: %w", err)
+		}
+
+		logger.Info("identity accepted, second factor required")
+		return &SignInOutput{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	accessToken, refreshToken, err := a.generateTokens(ctx, user, uuid.NewString())
+	if err != nil {
+		logger.Error("failed to generate tokens for user: ", err)
+		return nil, fmt.Errorf("failed to generate tokens for user: %w", err)
+	}
+
+	logger.Info("successfully signed user via oidc")
+	return &SignInOutput{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (a authService) PruneOIDCLoginStates(ctx context.Context) error {
+	logger := a.logger.
+		Named("PruneOIDCLoginStates").
+		WithContext(ctx)
+
+	deleted, err := a.storages.OIDCLoginStateStorage.DeleteExpiredOIDCLoginStates(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to delete expired oidc login states: ", err)
+		return fmt.Errorf("failed to delete expired oidc login states: %w", err)
+	}
+
+	logger.Info("successfully pruned oidc login states", "deleted", deleted)
+	return nil
+}
+
+// getOIDCUser resolves user of external identity. Unknown identity is linked to the user
+// with the same email when provider has verified it, otherwise new user is created.
+func (a authService) getOIDCUser(ctx context.Context, provider string, claims *oidc.Claims) (*entity.User, error) {
+	identity, err := a.storages.UserIdentityStorage.GetUserIdentity(ctx, &GetUserIdentityFilter{Provider: provider, Subject: claims.Subject})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	if identity != nil {
+		user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: identity.UserId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, ErrOIDCLoginFailed
+		}
+
+		return user, nil
+	}
+
+	// unverified email could belong to anybody, so it is never used for linking or sign up
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{Email: claims.Email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	switch {
+	case user == nil:
+		// user signs in only via provider until password is set through password reset
+		user, err = a.storages.UserStorage.CreateUser(ctx, &entity.User{
+			Username:      oidcUsername(claims),
+			Email:         claims.Email,
+			EmailVerified: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		// the email was registered concurrently, retried login links to that user
+		if user == nil {
+			return nil, ErrOIDCLoginFailed
+		}
+	case !user.EmailVerified:
+		// whoever signed up with this email has not proven owning it, so the password
+		// they have set and their sessions must not survive linking
+		user.EmailVerified = true
+		user.Password = ""
+		_, err = a.storages.UserStorage.UpdateUser(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+
+		err = a.revokeUserSessions(ctx, user.Id, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke user sessions: %w", err)
+		}
+	}
+
+	_, err = a.storages.UserIdentityStorage.CreateUserIdentity(ctx, &entity.UserIdentity{
+		UserId:   user.Id,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user identity: %w", err)
+	}
+
+	return user, nil
+}
+
+// oidcUsername picks username for the user created from external identity.
+func oidcUsername(claims *oidc.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if claims.Name != "" {
+		return claims.Name
+	}
+
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
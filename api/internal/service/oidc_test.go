@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/oidc"
+	"github.com/atlant1da-404/droplet/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+const _testOIDCProvider = "test"
+
+type oidcTestContext struct {
+	service  *authService
+	options  *Options
+	storages *testStorages
+	server   *oidctest.Server
+}
+
+func newOIDCTestContext(t *testing.T) *oidcTestContext {
+	t.Helper()
+
+	server, err := oidctest.NewServer("droplet", "secret")
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	options.OIDCProviders = map[string]oidc.Provider{
+		_testOIDCProvider: oidc.NewProvider(oidc.ProviderConfig{
+			IssuerURL:    server.Issuer(),
+			ClientID:     "droplet",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost:8082/api/v1/auth/oidc/test/callback",
+		}),
+	}
+
+	return &oidcTestContext{
+		service:  NewAuthService(options).(*authService),
+		options:  options,
+		storages: storages,
+		server:   server,
+	}
+}
+
+// authorize starts login and lets the user sign in at provider, it returns code and state of the callback.
+func (c *oidcTestContext) authorize(t *testing.T, identity oidctest.Identity) (string, string) {
+	t.Helper()
+
+	started, err := c.service.StartOIDCLogin(context.Background(), &StartOIDCLoginOptions{Provider: _testOIDCProvider})
+	if err != nil {
+		t.Fatalf("StartOIDCLogin() error = %v", err)
+	}
+	code, state, err := c.server.Authorize(started.AuthorizationURL, identity)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	return code, state
+}
+
+func (c *oidcTestContext) login(t *testing.T, identity oidctest.Identity) (*SignInOutput, error) {
+	t.Helper()
+
+	code, state := c.authorize(t, identity)
+	return c.service.CompleteOIDCLogin(context.Background(), &CompleteOIDCLoginOptions{Provider: _testOIDCProvider, Code: code, State: state})
+}
+
+func (c *oidcTestContext) signedUserId(t *testing.T, signed *SignInOutput) string {
+	t.Helper()
+
+	claims, err := c.options.Auth.ParseToken(signed.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to parse access token: %v", err)
+	}
+
+	return claims.UserId
+}
+
+func TestCompleteOIDCLoginResolvesUser(t *testing.T) {
+	identity := oidctest.Identity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, PreferredUsername: "oidc-user"}
+
+	tests := []struct {
+		name string
+		// existing is stored before login, nil when there is no user with identity email
+		existing *entity.User
+		identity oidctest.Identity
+		wantErr  error
+		check    func(t *testing.T, c *oidcTestContext, user *entity.User)
+	}{
+		{
+			name:     "creates new user",
+			identity: identity,
+			check: func(t *testing.T, c *oidcTestContext, user *entity.User) {
+				if user.Username != "oidc-user" || !user.EmailVerified || user.Password != "" {
+					t.Fatalf("created user = %+v", user)
+				}
+			},
+		},
+		{
+			name:     "links existing user with verified email",
+			existing: &entity.User{Email: identity.Email, Username: "existing", Password: "hash", EmailVerified: true},
+			identity: identity,
+			check: func(t *testing.T, c *oidcTestContext, user *entity.User) {
+				if user.Username != "existing" || user.Password != "hash" {
+					t.Fatalf("linked user was modified: %+v", user)
+				}
+				if len(c.storages.revokedTokens.tokens) != 0 {
+					t.Fatalf("sessions of verified user were revoked")
+				}
+			},
+		},
+		{
+			name:     "takes over existing user with unverified email",
+			existing: &entity.User{Email: identity.Email, Username: "squatter", Password: "hash"},
+			identity: identity,
+			check: func(t *testing.T, c *oidcTestContext, user *entity.User) {
+				if !user.EmailVerified || user.Password != "" {
+					t.Fatalf("password of unverified user survived linking: %+v", user)
+				}
+				for _, token := range c.storages.refreshTokens.tokens {
+					if token.UserId == user.Id && token.FamilyId == "squatter-session" && token.RevokedAt == nil {
+						t.Fatalf("session of unverified user survived linking")
+					}
+				}
+				if len(c.storages.revokedTokens.tokens) != 1 {
+					t.Fatalf("revoked access tokens = %d, want 1", len(c.storages.revokedTokens.tokens))
+				}
+			},
+		},
+		{
+			name:     "rejects email not verified by provider",
+			existing: &entity.User{Email: identity.Email, Username: "existing", Password: "hash", EmailVerified: true},
+			identity: oidctest.Identity{Subject: "subject-1", Email: identity.Email},
+			wantErr:  ErrOIDCEmailNotVerified,
+		},
+		{
+			name:     "rejects missing email",
+			identity: oidctest.Identity{Subject: "subject-1", EmailVerified: true},
+			wantErr:  ErrOIDCEmailNotVerified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newOIDCTestContext(t)
+			if tt.existing != nil {
+				existing, _ := c.storages.users.CreateUser(context.Background(), tt.existing)
+				_, _ = c.storages.refreshTokens.CreateRefreshToken(context.Background(), &entity.RefreshToken{
+					UserId:    existing.Id,
+					FamilyId:  "squatter-session",
+					ExpiresAt: time.Now().Add(time.Hour),
+				})
+			}
+
+			signed, err := c.login(t, tt.identity)
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("CompleteOIDCLogin() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(c.storages.identities.identities) != 0 {
+					t.Fatalf("identity was linked")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteOIDCLogin() error = %v", err)
+			}
+
+			if len(c.storages.users.users) != 1 {
+				t.Fatalf("users = %d, want 1", len(c.storages.users.users))
+			}
+			user, _ := c.storages.users.GetUser(context.Background(), &GetUserFilter{UserId: c.signedUserId(t, signed)})
+			if user == nil || user.Email != tt.identity.Email {
+				t.Fatalf("signed in user = %+v", user)
+			}
+			if tt.existing != nil && user.Id != tt.existing.Id {
+				t.Fatalf("signed in as %s, want existing user %s", user.Id, tt.existing.Id)
+			}
+
+			linked, _ := c.storages.identities.GetUserIdentity(context.Background(), &GetUserIdentityFilter{Provider: _testOIDCProvider, Subject: tt.identity.Subject})
+			if linked == nil || linked.UserId != user.Id {
+				t.Fatalf("identity = %+v, want linked to %s", linked, user.Id)
+			}
+
+			tt.check(t, c, user)
+		})
+	}
+}
+
+func TestCompleteOIDCLoginUsesLinkedIdentity(t *testing.T) {
+	c := newOIDCTestContext(t)
+
+	first, err := c.login(t, oidctest.Identity{Subject: "subject-1", Email: "old@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("first CompleteOIDCLogin() error = %v", err)
+	}
+
+	// provider reports changed email, the user is still found by subject
+	second, err := c.login(t, oidctest.Identity{Subject: "subject-1", Email: "new@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("second CompleteOIDCLogin() error = %v", err)
+	}
+
+	if c.signedUserId(t, first) != c.signedUserId(t, second) {
+		t.Fatalf("same subject signed in as different users")
+	}
+	if len(c.storages.users.users) != 1 || len(c.storages.identities.identities) != 1 {
+		t.Fatalf("users = %d, identities = %d, want 1 and 1", len(c.storages.users.users), len(c.storages.identities.identities))
+	}
+}
+
+func TestCompleteOIDCLoginRejects(t *testing.T) {
+	identity := oidctest.Identity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name string
+		// tamper changes the callback or stored login state before login is completed
+		tamper  func(c *oidcTestContext, options *CompleteOIDCLoginOptions)
+		wantErr error
+	}{
+		{
+			name: "unknown state",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				options.State = "forged"
+			},
+			wantErr: ErrOIDCInvalidState,
+		},
+		{
+			name: "state of other provider",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.storages.oidcStates.states[0].Provider = "other"
+			},
+			wantErr: ErrOIDCInvalidState,
+		},
+		{
+			name: "expired state",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.storages.oidcStates.states[0].ExpiresAt = time.Now().Add(-time.Second)
+			},
+			wantErr: ErrOIDCInvalidState,
+		},
+		{
+			name: "unknown provider",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				options.Provider = "other"
+			},
+			wantErr: ErrOIDCProviderNotFound,
+		},
+		{
+			name: "pkce verifier mismatch",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.storages.oidcStates.states[0].CodeVerifier = "other verifier"
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "nonce mismatch",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.storages.oidcStates.states[0].Nonce = "other nonce"
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "wrong issuer",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.server.Tamper = func(claims jwt.MapClaims) { claims["iss"] = "https://attacker.example.com" }
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "wrong audience",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.server.Tamper = func(claims jwt.MapClaims) { claims["aud"] = "other client" }
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "expired id token",
+			tamper: func(c *oidcTestContext, options *CompleteOIDCLoginOptions) {
+				c.server.Tamper = func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newOIDCTestContext(t)
+			code, state := c.authorize(t, identity)
+
+			options := &CompleteOIDCLoginOptions{Provider: _testOIDCProvider, Code: code, State: state}
+			tt.tamper(c, options)
+
+			_, err := c.service.CompleteOIDCLogin(context.Background(), options)
+			if err != tt.wantErr {
+				t.Fatalf("CompleteOIDCLogin() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(c.storages.users.users) != 0 {
+				t.Fatalf("user was created")
+			}
+		})
+	}
+}
+
+func TestCompleteOIDCLoginConsumesState(t *testing.T) {
+	c := newOIDCTestContext(t)
+	code, state := c.authorize(t, oidctest.Identity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true})
+
+	stored := c.storages.oidcStates.states[0]
+	if stored.StateHash != auth.HashOpaqueToken(state) {
+		t.Fatalf("state is not stored as hash")
+	}
+
+	options := &CompleteOIDCLoginOptions{Provider: _testOIDCProvider, Code: code, State: state}
+	_, err := c.service.CompleteOIDCLogin(context.Background(), options)
+	if err != nil {
+		t.Fatalf("first CompleteOIDCLogin() error = %v", err)
+	}
+	_, err = c.service.CompleteOIDCLogin(context.Background(), options)
+	if err != ErrOIDCInvalidState {
+		t.Fatalf("replayed CompleteOIDCLogin() error = %v, want %v", err, ErrOIDCInvalidState)
+	}
+}
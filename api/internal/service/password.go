@@ -0,0 +1,37 @@
+package service
+
+import (
+	"fmt"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/atlant1da-404/droplet/pkg/password"
+)
+
+// checkPassword validates new password against configured policy and breach corpus.
+// Violations are reported as invalid fields of ErrPasswordPolicyViolation.
+func (a authService) checkPassword(field, newPassword string, personal ...string) error {
+	violations := a.passwordPolicy.Check(newPassword, personal...)
+
+	if a.breachCorpus != nil {
+		breached, err := a.breachCorpus.IsBreached(newPassword)
+		if err != nil {
+			return fmt.Errorf("failed to check breach corpus: %w", err)
+		}
+		if breached {
+			violations = append(violations, password.Violation{
+				Code:    password.ViolationBreached,
+				Message: "password has appeared in a data breach, choose another one",
+			})
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]errs.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, errs.FieldError{Field: field, Code: violation.Code, Message: violation.Message})
+	}
+
+	return ErrPasswordPolicyViolation.WithFields(fields...)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/atlant1da-404/droplet/pkg/password"
+	"reflect"
+	"testing"
+)
+
+type fakeBreachCorpus map[string]bool
+
+func (f fakeBreachCorpus) IsBreached(password string) (bool, error) {
+	return f[password], nil
+}
+
+func newTestPasswordService(t *testing.T) AuthService {
+	t.Helper()
+
+	options := newTestOptions(t, newTestStorages())
+	options.PasswordPolicy = password.Policy{MinLength: 10, MaxLength: 128, MinCharacterClasses: 2}
+	options.BreachCorpus = fakeBreachCorpus{"Password123": true}
+
+	return NewAuthService(options)
+}
+
+func TestCheckPassword(t *testing.T) {
+	service := newTestPasswordService(t).(*authService)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "valid", password: "river-stone42"},
+		{name: "too short", password: "rs-42", want: []string{password.ViolationTooShort}},
+		{name: "single character class", password: "riverstones", want: []string{password.ViolationTooWeak}},
+		{name: "contains username", password: "alice-river-42", want: []string{password.ViolationPersonalInfo}},
+		{name: "contains email local part", password: "river-alice.b", want: []string{password.ViolationPersonalInfo}},
+		{name: "breached", password: "Password123", want: []string{password.ViolationBreached}},
+		{name: "every violation", password: "alice", want: []string{password.ViolationTooShort, password.ViolationTooWeak, password.ViolationPersonalInfo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.checkPassword("password", tt.password, "alice", "alice.b@droplet.local")
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("checkPassword() error = %v", err)
+				}
+				return
+			}
+			// violations are attached to a copy of the shared error, so it is matched by code
+			if errs.GetCode(err) != ErrPasswordPolicyViolation.Code {
+				t.Fatalf("checkPassword() error = %v, want %v", err, ErrPasswordPolicyViolation)
+			}
+
+			var codes []string
+			for _, field := range errs.GetFields(err) {
+				if field.Field != "password" {
+					t.Errorf("invalid field = %q, want %q", field.Field, "password")
+				}
+				codes = append(codes, field.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Fatalf("invalid field codes = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignUpRejectsWeakPassword(t *testing.T) {
+	service := newTestPasswordService(t)
+
+	_, err := service.SignUp(context.Background(), &SignUpOptions{Email: "alice@droplet.local", Username: "alice", Password: "alice-2024"})
+	if errs.GetCode(err) != ErrPasswordPolicyViolation.Code {
+		t.Fatalf("SignUp() error = %v, want %v", err, ErrPasswordPolicyViolation)
+	}
+
+	fields := errs.GetFields(err)
+	if len(fields) != 1 || fields[0].Code != password.ViolationPersonalInfo {
+		t.Fatalf("SignUp() invalid fields = %+v, want single %s", fields, password.ViolationPersonalInfo)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"strings"
+	"time"
+)
+
+// _personalAccessTokenPrefix tells personal access tokens apart from JWTs
+// and makes them easy to find by secret scanners.
+const _personalAccessTokenPrefix = "dpat_"
+
+// _personalAccessTokenNameMaxLength limits name user gives to personal access token.
+const _personalAccessTokenNameMaxLength = 100
+
+func (a authService) CreatePersonalAccessToken(ctx context.Context, options *CreatePersonalAccessTokenOptions) (*CreatePersonalAccessTokenOutput, error) {
+	logger := a.logger.
+		Named("CreatePersonalAccessToken").
+		WithContext(ctx).
+		With("options", options)
+
+	name := strings.TrimSpace(options.Name)
+	if name == "" || len(name) > _personalAccessTokenNameMaxLength {
+		logger.Info("invalid token name")
+		return nil, ErrPersonalAccessTokenInvalidName
+	}
+
+	scopes, err := normalizeScopes(options.Scopes)
+	if err != nil {
+		logger.Info("invalid scopes: ", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	if !options.ExpiresAt.After(now) || options.ExpiresAt.After(now.Add(a.config.Auth.PersonalAccessTokenMaxTTL)) {
+		logger.Info("invalid token expiration")
+		return nil, ErrPersonalAccessTokenInvalidExpiration
+	}
+
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		logger.Error("failed to generate token: ", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = _personalAccessTokenPrefix + token
+
+	createdToken, err := a.storages.PersonalAccessTokenStorage.CreatePersonalAccessToken(ctx, &entity.PersonalAccessToken{
+		UserId:    options.UserId,
+		Name:      name,
+		TokenHash: auth.HashOpaqueToken(token),
+		Scopes:    scopes,
+		ExpiresAt: options.ExpiresAt,
+	})
+	if err != nil {
+		logger.Error("failed to create personal access token: ", err)
+		return nil, fmt.Errorf("failed to create personal access token: %w", err)
+	}
+
+	logger.Info("successfully created personal access token", "tokenId", createdToken.Id)
+	return &CreatePersonalAccessTokenOutput{
+		Id:        createdToken.Id,
+		Name:      createdToken.Name,
+		Scopes:    createdToken.Scopes,
+		ExpiresAt: createdToken.ExpiresAt,
+		Token:     token,
+	}, nil
+}
+
+func (a authService) ListPersonalAccessTokens(ctx context.Context, options *ListPersonalAccessTokensOptions) (*ListPersonalAccessTokensOutput, error) {
+	logger := a.logger.
+		Named("ListPersonalAccessTokens").
+		WithContext(ctx).
+		With("options", options)
+
+	tokens, err := a.storages.PersonalAccessTokenStorage.ListPersonalAccessTokens(ctx, &ListPersonalAccessTokensFilter{UserId: options.UserId})
+	if err != nil {
+		logger.Error("failed to list personal access tokens: ", err)
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+
+	logger.Info("successfully listed personal access tokens", "count", len(tokens))
+	return &ListPersonalAccessTokensOutput{Tokens: tokens}, nil
+}
+
+func (a authService) RevokePersonalAccessToken(ctx context.Context, options *RevokePersonalAccessTokenOptions) error {
+	logger := a.logger.
+		Named("RevokePersonalAccessToken").
+		WithContext(ctx).
+		With("options", options)
+
+	revoked, err := a.storages.PersonalAccessTokenStorage.RevokePersonalAccessToken(ctx, &RevokePersonalAccessTokenFilter{
+		Id:     options.TokenId,
+		UserId: options.UserId,
+	})
+	if err != nil {
+		logger.Error("failed to revoke personal access token: ", err)
+		return fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+	if !revoked {
+		logger.Info("personal access token not found")
+		return ErrPersonalAccessTokenNotFound
+	}
+
+	logger.Info("successfully revoked personal access token")
+	return nil
+}
+
+// verifyPersonalAccessToken resolves owner and scopes of personal access token.
+func (a authService) verifyPersonalAccessToken(ctx context.Context, token string) (*VerifyTokenOutput, error) {
+	personalAccessToken, err := a.storages.PersonalAccessTokenStorage.GetPersonalAccessToken(ctx, &GetPersonalAccessTokenFilter{TokenHash: auth.HashOpaqueToken(token)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+	if personalAccessToken == nil || time.Now().After(personalAccessToken.ExpiresAt) {
+		return nil, ErrVerifyTokenInvalid
+	}
+	if personalAccessToken.RevokedAt != nil {
+		return nil, ErrVerifyTokenRevoked
+	}
+
+	user, err := a.storages.UserStorage.GetUser(ctx, &GetUserFilter{UserId: personalAccessToken.UserId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || checkUserActive(user) != nil {
+		return nil, ErrVerifyTokenInvalid
+	}
+
+	err = a.storages.PersonalAccessTokenStorage.TouchPersonalAccessToken(ctx, personalAccessToken.Id, time.Now())
+	if err != nil {
+		// last usage is informational, so it does not block the request
+		a.logger.Named("verifyPersonalAccessToken").WithContext(ctx).Error("failed to touch personal access token: ", err)
+	}
+
+	return &VerifyTokenOutput{
+		Username:              user.Username,
+		UserId:                user.Id,
+		Role:                  user.Role,
+		PersonalAccessTokenId: personalAccessToken.Id,
+		Scopes:                personalAccessToken.Scopes,
+	}, nil
+}
+
+// normalizeScopes validates requested scopes and removes duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrPersonalAccessTokenInvalidScope
+	}
+
+	normalized := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			return nil, ErrPersonalAccessTokenInvalidScope.WithDetails(map[string]string{"scope": scope})
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+
+	return normalized, nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range entity.Scopes {
+		if scope == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// createTestPersonalAccessToken creates token of the test user with given scopes and returns its value.
+func createTestPersonalAccessToken(t *testing.T, service AuthService, userId string, scopes ...string) *CreatePersonalAccessTokenOutput {
+	t.Helper()
+
+	created, err := service.CreatePersonalAccessToken(context.Background(), &CreatePersonalAccessTokenOptions{
+		UserId:    userId,
+		Name:      "ci",
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken() error = %v", err)
+	}
+
+	return created
+}
+
+func TestVerifyPersonalAccessToken(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead, entity.ScopeAccountRead, entity.ScopeTransfersRead)
+
+	verified, err := service.VerifyToken(context.Background(), &VerifyTokenOptions{AccessToken: created.Token})
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if verified.UserId != user.Id || verified.PersonalAccessTokenId != created.Id {
+		t.Fatalf("VerifyToken() = %+v, want user %s and token %s", verified, user.Id, created.Id)
+	}
+	wantScopes := []string{entity.ScopeAccountRead, entity.ScopeTransfersRead}
+	if !reflect.DeepEqual(verified.Scopes, wantScopes) {
+		t.Fatalf("VerifyToken() scopes = %v, want %v", verified.Scopes, wantScopes)
+	}
+	if storages.accessTokens.tokens[0].LastUsedAt == nil {
+		t.Fatal("VerifyToken() did not record token usage")
+	}
+}
+
+func TestVerifyPersonalAccessTokenRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup returns personal access token to verify
+		setup   func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string
+		wantErr error
+	}{
+		{
+			name: "unknown token",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				return _personalAccessTokenPrefix + "unknown"
+			},
+			wantErr: ErrVerifyTokenInvalid,
+		},
+		{
+			name: "expired token",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+				storages.accessTokens.tokens[0].ExpiresAt = time.Now().Add(-time.Minute)
+				return created.Token
+			},
+			wantErr: ErrVerifyTokenInvalid,
+		},
+		{
+			name: "revoked token",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+				err := service.RevokePersonalAccessToken(context.Background(), &RevokePersonalAccessTokenOptions{UserId: user.Id, TokenId: created.Id})
+				if err != nil {
+					t.Fatalf("RevokePersonalAccessToken() error = %v", err)
+				}
+				return created.Token
+			},
+			wantErr: ErrVerifyTokenRevoked,
+		},
+		{
+			name: "deleted owner",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+				delete(storages.users.users, user.Id)
+				return created.Token
+			},
+			wantErr: ErrVerifyTokenInvalid,
+		},
+		{
+			name: "disabled owner",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+				storages.users.users[user.Id].Disabled = true
+				return created.Token
+			},
+			wantErr: ErrVerifyTokenInvalid,
+		},
+		{
+			name: "owner pending deletion",
+			setup: func(t *testing.T, storages *testStorages, service AuthService, user *entity.User) string {
+				created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+				deletionScheduledFor := time.Now().Add(time.Hour)
+				storages.users.users[user.Id].DeletionScheduledFor = &deletionScheduledFor
+				return created.Token
+			},
+			wantErr: ErrVerifyTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := createTestUser(t, options, _testEmail, _testPassword)
+			service := NewAuthService(options)
+
+			token := tt.setup(t, storages, service, user)
+
+			_, err := service.VerifyToken(context.Background(), &VerifyTokenOptions{AccessToken: token})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRevokePersonalAccessTokenOfOtherUser(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	user := createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	created := createTestPersonalAccessToken(t, service, user.Id, entity.ScopeAccountRead)
+
+	err := service.RevokePersonalAccessToken(context.Background(), &RevokePersonalAccessTokenOptions{UserId: "other", TokenId: created.Id})
+	if !errors.Is(err, ErrPersonalAccessTokenNotFound) {
+		t.Fatalf("RevokePersonalAccessToken() error = %v, want %v", err, ErrPersonalAccessTokenNotFound)
+	}
+
+	err = verify(service, created.Token)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+}
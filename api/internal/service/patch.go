@@ -0,0 +1,199 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"github.com/atlant1da-404/droplet/pkg/jsonpatch"
+	"strconv"
+	"strings"
+)
+
+// _patchableAccountPaths lists pointers which patches may change, "*" matches device index.
+// Devices cannot be added or removed by patch, it is done by device endpoints.
+var _patchableAccountPaths = [][]string{
+	{"accountSettings", "language"},
+	{"accountSettings", "theme"},
+	{"accountSettings", "discoverability"},
+	{"accountSettings", "sharePresence"},
+	{"accountSettings", "notifications", "incomingTransfers"},
+	{"accountSettings", "notifications", "completedTransfers"},
+	{"accountSettings", "notifications", "securityEvents"},
+	{"accountSettings", "autoAccept"},
+	{"accountSettings", "downloadAction"},
+	{"accountSettings", "maxIncomingFileSize"},
+	{"accountSettings", "nonContactTransfers"},
+	{"accountDevices", "*", "name"},
+	{"accountDevices", "*", "active"},
+}
+
+// _immutableAccountPaths lists pointers which are reported as immutable rather than just not patchable.
+var _immutableAccountPaths = [][]string{
+	{"id"},
+	{"userId"},
+	{"version"},
+	{"accountSettings", "id"},
+	{"accountSettings", "AccountID"},
+	{"accountSettings", "version"},
+	{"accountDevices", "*", "id"},
+	{"accountDevices", "*", "AccountID"},
+	{"accountDevices", "*", "os"},
+	{"accountDevices", "*", "macAddress"},
+	{"accountDevices", "*", "lastSeenAt"},
+	{"accountDevices", "*", "online"},
+}
+
+// applyAccountPatch applies merge or JSON patch of the options to JSON form of the account.
+// Only whitelisted fields are copied back from the patched document, so a path the check
+// misses still cannot change anything else.
+func applyAccountPatch(account *entity.Account, options *UpdateAccountOptions) error {
+	var paths []string
+	if options.MergePatch != nil {
+		mergePaths, err := jsonpatch.MergePatchPaths(options.MergePatch)
+		if err != nil {
+			return ErrAccountPatchFailed.WithDetails(map[string]string{"reason": err.Error()})
+		}
+		paths = mergePaths
+	} else {
+		err := options.JSONPatch.Validate()
+		if err != nil {
+			return ErrAccountPatchFailed.WithDetails(map[string]string{"reason": err.Error()})
+		}
+		for _, operation := range options.JSONPatch {
+			switch operation.Op {
+			case jsonpatch.OpTest:
+				// test only reads the value, so it may guard any path, e.g. id of patched device
+			case jsonpatch.OpMove:
+				paths = append(paths, operation.From, operation.Path)
+			default:
+				paths = append(paths, operation.Path)
+			}
+		}
+	}
+
+	var fields []errs.FieldError
+	for _, path := range paths {
+		field := checkAccountPatchPath(path)
+		if field != nil {
+			fields = append(fields, *field)
+		}
+	}
+	if len(fields) > 0 {
+		return ErrAccountPatchInvalid.WithFields(fields...)
+	}
+
+	document, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account: %w", err)
+	}
+
+	if options.MergePatch != nil {
+		document, err = jsonpatch.MergePatch(document, options.MergePatch)
+	} else {
+		document, err = options.JSONPatch.Apply(document)
+	}
+	if err != nil {
+		return ErrAccountPatchFailed.WithDetails(map[string]string{"reason": err.Error()})
+	}
+
+	var patched entity.Account
+	err = json.Unmarshal(document, &patched)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			// decoder reports dotted path, it is turned into pointer like paths of other errors
+			path := jsonpatch.FormatPointer(strings.Split(typeErr.Field, ".")...)
+			return ErrAccountPatchInvalid.WithFields(errs.FieldError{
+				Field:   path,
+				Code:    "invalid_type",
+				Message: fmt.Sprintf("%s must be %s", path, typeErr.Type),
+			})
+		}
+		return ErrAccountPatchFailed.WithDetails(map[string]string{"reason": err.Error()})
+	}
+
+	if patched.AccountSettings != nil {
+		settings := account.AccountSettings
+		settings.Language = patched.AccountSettings.Language
+		settings.Theme = patched.AccountSettings.Theme
+		settings.Discoverability = patched.AccountSettings.Discoverability
+		settings.SharePresence = patched.AccountSettings.SharePresence
+		settings.Notifications = patched.AccountSettings.Notifications
+		settings.AutoAccept = patched.AccountSettings.AutoAccept
+		settings.DownloadAction = patched.AccountSettings.DownloadAction
+		settings.MaxIncomingFileSize = patched.AccountSettings.MaxIncomingFileSize
+		settings.NonContactTransfers = patched.AccountSettings.NonContactTransfers
+	}
+
+	// patchable paths never change number of devices, so devices are matched by index
+	for i := range account.AccountDevices {
+		if i >= len(patched.AccountDevices) {
+			break
+		}
+		device := &account.AccountDevices[i]
+
+		name, err := normalizeDeviceName(patched.AccountDevices[i].Name)
+		if err != nil {
+			fields = append(fields, errs.FieldError{
+				Field:   jsonpatch.FormatPointer("accountDevices", strconv.Itoa(i), "name"),
+				Code:    errs.GetCode(err),
+				Message: err.Error(),
+			})
+			continue
+		}
+		device.Name = name
+
+		device.Active = patched.AccountDevices[i].Active
+		if !device.Active {
+			device.Online = false
+		}
+	}
+	if len(fields) > 0 {
+		return ErrAccountPatchInvalid.WithFields(fields...)
+	}
+
+	return nil
+}
+
+// checkAccountPatchPath returns field error when patch may not change value at the path.
+func checkAccountPatchPath(path string) *errs.FieldError {
+	tokens, err := jsonpatch.ParsePointer(path)
+	if err != nil {
+		return &errs.FieldError{Field: path, Code: "invalid_path", Message: err.Error()}
+	}
+
+	if matchAccountPath(_patchableAccountPaths, tokens) {
+		return nil
+	}
+	if matchAccountPath(_immutableAccountPaths, tokens) {
+		return &errs.FieldError{Field: path, Code: "immutable_field", Message: fmt.Sprintf("%s cannot be changed", path)}
+	}
+
+	return &errs.FieldError{Field: path, Code: "not_patchable", Message: fmt.Sprintf("%s cannot be patched", path)}
+}
+
+func matchAccountPath(patterns [][]string, tokens []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) != len(tokens) {
+			continue
+		}
+
+		matched := true
+		for i := range pattern {
+			if pattern[i] == "*" {
+				// index must reference existing device, "-" would append new one
+				index, err := strconv.Atoi(tokens[i])
+				matched = matched && err == nil && index >= 0 && strconv.Itoa(index) == tokens[i]
+				continue
+			}
+			matched = matched && pattern[i] == tokens[i]
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
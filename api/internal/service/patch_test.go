@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"reflect"
+	"testing"
+)
+
+// newPatchTestAccount stores account of the user with default settings and single device.
+func newPatchTestAccount(t *testing.T, storages *testStorages, options *Options) *entity.User {
+	t.Helper()
+
+	user := createVersionedTestAccount(t, storages, options)
+	storages.accounts.accounts[0].AccountDevices = []entity.AccountDevices{{
+		Id:         "device-1",
+		AccountID:  _testAccountId,
+		Name:       "laptop",
+		OS:         "linux",
+		MacAddress: "00:11:22:33:44:55",
+		Active:     true,
+		Online:     true,
+	}}
+
+	return user
+}
+
+func TestUpdateAccountPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		mergePatch string
+		jsonPatch  string
+		// check inspects updated account
+		check      func(t *testing.T, account *entity.Account)
+		wantCode   string
+		wantFields map[string]string
+	}{
+		{
+			name:       "merge patch of settings",
+			mergePatch: `{"accountSettings":{"theme":"dark","notifications":{"securityEvents":false}}}`,
+			check: func(t *testing.T, account *entity.Account) {
+				if account.AccountSettings.Theme != "dark" || account.AccountSettings.Notifications.SecurityEvents {
+					t.Errorf("settings = %+v, want dark theme without security notifications", *account.AccountSettings)
+				}
+			},
+		},
+		{
+			name:      "json patch deactivates device",
+			jsonPatch: `[{"op":"test","path":"/accountDevices/0/id","value":"device-1"},{"op":"replace","path":"/accountDevices/0/active","value":false}]`,
+			check: func(t *testing.T, account *entity.Account) {
+				device := account.AccountDevices[0]
+				if device.Active || device.Online {
+					t.Errorf("device = %+v, want inactive and offline", device)
+				}
+			},
+		},
+		{
+			name:       "merge patch of immutable fields",
+			mergePatch: `{"id":"other","version":7,"accountSettings":{"version":1}}`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/id": "immutable_field", "/version": "immutable_field", "/accountSettings/version": "immutable_field"},
+		},
+		{
+			name:       "json patch of immutable device fields",
+			jsonPatch:  `[{"op":"replace","path":"/accountDevices/0/macAddress","value":"ff:ff:ff:ff:ff:ff"},{"op":"replace","path":"/userId","value":"other"}]`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountDevices/0/macAddress": "immutable_field", "/userId": "immutable_field"},
+		},
+		{
+			name:       "move reads from immutable field",
+			jsonPatch:  `[{"op":"move","from":"/accountDevices/0/os","path":"/accountDevices/0/name"}]`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountDevices/0/os": "immutable_field"},
+		},
+		{
+			name:       "device cannot be appended",
+			jsonPatch:  `[{"op":"add","path":"/accountDevices/-","value":{"name":"phone"}}]`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountDevices/-": "not_patchable"},
+		},
+		{
+			name:       "device index with leading zero",
+			jsonPatch:  `[{"op":"replace","path":"/accountDevices/00/name","value":"phone"}]`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountDevices/00/name": "not_patchable"},
+		},
+		{
+			name:       "whole settings replaced",
+			mergePatch: `{"accountSettings":null}`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountSettings": "not_patchable"},
+		},
+		{
+			name:       "value of wrong type",
+			mergePatch: `{"accountSettings":{"sharePresence":"yes"}}`,
+			wantCode:   "invalid_patch",
+			wantFields: map[string]string{"/accountSettings/sharePresence": "invalid_type"},
+		},
+		{
+			name:      "failed test",
+			jsonPatch: `[{"op":"test","path":"/accountDevices/0/id","value":"device-2"},{"op":"replace","path":"/accountDevices/0/name","value":"phone"}]`,
+			wantCode:  "patch_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			user := newPatchTestAccount(t, storages, options)
+
+			updateOptions := &UpdateAccountOptions{AccountId: _testAccountId, UserId: user.Id, Version: 1}
+			if tt.mergePatch != "" {
+				updateOptions.MergePatch = json.RawMessage(tt.mergePatch)
+			} else if err := json.Unmarshal([]byte(tt.jsonPatch), &updateOptions.JSONPatch); err != nil {
+				t.Fatalf("failed to decode patch: %v", err)
+			}
+
+			account, err := NewAccountService(options).UpdateAccount(context.Background(), updateOptions)
+			if tt.wantCode != "" {
+				if errs.GetCode(err) != tt.wantCode {
+					t.Fatalf("UpdateAccount() error = %v, want %s", err, tt.wantCode)
+				}
+
+				fields := make(map[string]string)
+				for _, field := range errs.GetFields(err) {
+					fields[field.Field] = field.Code
+				}
+				if len(fields) == 0 {
+					fields = nil
+				}
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Errorf("invalid fields = %v, want %v", fields, tt.wantFields)
+				}
+				if storages.accounts.accounts[0].Version != 1 {
+					t.Errorf("rejected patch changed version to %d", storages.accounts.accounts[0].Version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateAccount() error = %v", err)
+			}
+			if account.Id != _testAccountId || account.UserId != user.Id {
+				t.Errorf("patch changed identity of the account to %s of %s", account.Id, account.UserId)
+			}
+			tt.check(t, account)
+		})
+	}
+}
+
+func TestCheckAccountPatchPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/accountSettings/language", want: ""},
+		{path: "/accountDevices/3/name", want: ""},
+		{path: "/accountDevices/3/active", want: ""},
+		{path: "/id", want: "immutable_field"},
+		{path: "/accountSettings/AccountID", want: "immutable_field"},
+		{path: "/accountDevices/0/lastSeenAt", want: "immutable_field"},
+		{path: "/accountDevices/0/online", want: "immutable_field"},
+		{path: "/accountDevices/-1/name", want: "not_patchable"},
+		{path: "/accountDevices/0", want: "not_patchable"},
+		{path: "/accountDevices", want: "not_patchable"},
+		{path: "", want: "not_patchable"},
+		{path: "/accountSettings/unknown", want: "not_patchable"},
+		{path: "accountSettings/theme", want: "invalid_path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := ""
+			if field := checkAccountPatchPath(tt.path); field != nil {
+				got = field.Code
+			}
+			if got != tt.want {
+				t.Fatalf("checkAccountPatchPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"time"
+)
+
+func (a accountService) DeviceHeartbeat(ctx context.Context, options *DeviceHeartbeatOptions) (*DeviceHeartbeatOutput, error) {
+	logger := a.logger.
+		Named("DeviceHeartbeat").
+		WithContext(ctx).
+		With("options", options)
+
+	account, err := a.getOwnedAccount(ctx, options.AccountId, options.UserId)
+	if err != nil {
+		logger.Info("failed to get account", "err", err)
+		return nil, err
+	}
+
+	device, err := a.storages.DeviceStorage.GetDevice(ctx, &GetDeviceFilter{Id: options.DeviceId, AccountId: account.Id})
+	if err != nil {
+		logger.Error("failed to get device: ", err)
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	if device == nil {
+		logger.Info("device not found")
+		return nil, ErrDeviceNotFound
+	}
+	if !device.Active {
+		logger.Info("device is deactivated")
+		return nil, ErrDeviceInactive
+	}
+
+	now := time.Now()
+	err = a.storages.DeviceStorage.TouchDevice(ctx, device.Id, now)
+	if err != nil {
+		logger.Error("failed to touch device: ", err)
+		return nil, fmt.Errorf("failed to touch device: %w", err)
+	}
+
+	logger.Debug("device heartbeat recorded")
+	return &DeviceHeartbeatOutput{
+		LastSeenAt: now,
+		TTLSeconds: int64(a.config.Account.PresenceTTL / time.Second),
+	}, nil
+}
+
+func (a accountService) GetPresence(ctx context.Context, options *GetPresenceOptions) (*GetPresenceOutput, error) {
+	logger := a.logger.
+		Named("GetPresence").
+		WithContext(ctx).
+		With("options", options)
+
+	// unknown users, users who are not contacts and blocked users look the same as having no devices,
+	// so presence neither discloses whether the email is registered nor who blocked whom
+	devices := make([]DevicePresence, 0)
+
+	user, err := a.findUserByEmail(ctx, options.Email)
+	if err != nil {
+		logger.Error("failed to find user: ", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return &GetPresenceOutput{Devices: devices}, nil
+	}
+
+	if user.Id != options.UserId {
+		trusted, err := a.isTrustedContact(ctx, options.UserId, user.Id)
+		if err != nil {
+			logger.Error("failed to check contact: ", err)
+			return nil, fmt.Errorf("failed to check contact: %w", err)
+		}
+		if !trusted {
+			logger.Info("user is not trusted contact")
+			return &GetPresenceOutput{Devices: devices}, nil
+		}
+	}
+
+	accounts, err := a.storages.AccountStorage.ListAccounts(ctx, &ListAccountsFilter{UserId: user.Id})
+	if err != nil {
+		logger.Error("failed to list accounts: ", err)
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	now := time.Now()
+	for _, account := range accounts {
+		// hidden presence looks the same as having no devices, so it is not disclosed either
+		if account.AccountSettings != nil && !account.AccountSettings.SharePresence {
+			continue
+		}
+
+		for _, device := range account.AccountDevices {
+			if !device.Active {
+				continue
+			}
+
+			devices = append(devices, DevicePresence{
+				Id:         device.Id,
+				Name:       device.Name,
+				OS:         device.OS,
+				Online:     a.isDeviceOnline(&device, now),
+				LastSeenAt: device.LastSeenAt,
+			})
+		}
+	}
+
+	logger.Info("successfully got presence", "count", len(devices))
+	return &GetPresenceOutput{Devices: devices}, nil
+}
+
+func (a accountService) MarkStaleDevicesOffline(ctx context.Context) error {
+	logger := a.logger.
+		Named("MarkStaleDevicesOffline").
+		WithContext(ctx)
+
+	updated, err := a.storages.DeviceStorage.MarkStaleDevicesOffline(ctx, time.Now().Add(-a.config.Account.PresenceTTL))
+	if err != nil {
+		logger.Error("failed to mark stale devices offline: ", err)
+		return fmt.Errorf("failed to mark stale devices offline: %w", err)
+	}
+
+	logger.Info("successfully marked stale devices offline", "updated", updated)
+	return nil
+}
+
+// isDeviceOnline derives presence from last heartbeat, so devices which went
+// stale since the last sweep are not reported online either.
+func (a accountService) isDeviceOnline(device *entity.AccountDevices, now time.Time) bool {
+	return device.Active &&
+		device.Online &&
+		device.LastSeenAt != nil &&
+		now.Sub(*device.LastSeenAt) < a.config.Account.PresenceTTL
+}
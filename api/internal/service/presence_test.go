@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestGetPresenceOnlyForTrustedContacts(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		contact string
+		// blockedBy is "viewer" or "owner" when one of them blocked the other
+		blockedBy     string
+		sharePresence bool
+		wantDevices   int
+	}{
+		{name: "accepted contact", email: "owner@droplet.local", contact: entity.ContactStatusAccepted, sharePresence: true, wantDevices: 1},
+		{name: "self", email: "viewer@droplet.local", sharePresence: true, wantDevices: 1},
+		{name: "not contact", email: "owner@droplet.local", sharePresence: true},
+		{name: "pending contact", email: "owner@droplet.local", contact: entity.ContactStatusPending, sharePresence: true},
+		{name: "contact blocked viewer", email: "owner@droplet.local", contact: entity.ContactStatusAccepted, blockedBy: "owner", sharePresence: true},
+		{name: "viewer blocked contact", email: "owner@droplet.local", contact: entity.ContactStatusAccepted, blockedBy: "viewer", sharePresence: true},
+		{name: "presence hidden", email: "owner@droplet.local", contact: entity.ContactStatusAccepted},
+		{name: "unknown user", email: "nobody@droplet.local", sharePresence: true},
+		{name: "invalid email", email: "not an email", sharePresence: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storages := newTestStorages()
+			options := newTestOptions(t, storages)
+			options.Config.Account.PresenceTTL = time.Minute
+
+			viewer := createTestUser(t, options, "viewer@droplet.local", _testPassword)
+			owner := createTestUser(t, options, "owner@droplet.local", _testPassword)
+			users := map[string]*entity.User{"viewer": viewer, "owner": owner}
+
+			now := time.Now()
+			for _, user := range users {
+				storages.accounts.accounts = append(storages.accounts.accounts, entity.Account{
+					Id:              user.Id,
+					UserId:          user.Id,
+					AccountSettings: &entity.AccountSettings{SharePresence: tt.sharePresence},
+					AccountDevices:  []entity.AccountDevices{{Id: user.Id, Active: true, Online: true, LastSeenAt: &now}},
+				})
+			}
+			if tt.contact != "" {
+				storages.contacts.contacts = append(storages.contacts.contacts, entity.Contact{
+					RequesterId: viewer.Id,
+					AddresseeId: owner.Id,
+					Status:      tt.contact,
+				})
+			}
+			if blocker, ok := users[tt.blockedBy]; ok {
+				blocked := owner
+				if blocker == owner {
+					blocked = viewer
+				}
+				storages.blocks.blocks = append(storages.blocks.blocks, entity.Block{UserId: blocker.Id, BlockedUserId: blocked.Id})
+			}
+
+			presence, err := NewAccountService(options).GetPresence(context.Background(), &GetPresenceOptions{
+				UserId: viewer.Id,
+				Email:  tt.email,
+			})
+			if err != nil {
+				t.Fatalf("GetPresence() error = %v", err)
+			}
+			if len(presence.Devices) != tt.wantDevices {
+				t.Fatalf("GetPresence() returned %d devices, want %d", len(presence.Devices), tt.wantDevices)
+			}
+			if tt.wantDevices > 0 && !presence.Devices[0].Online {
+				t.Errorf("device is reported offline")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func (a accountService) SearchUsers(ctx context.Context, options *SearchUsersOptions) (*SearchUsersOutput, error) {
+	logger := a.logger.
+		Named("SearchUsers").
+		WithContext(ctx).
+		With("options", options)
+
+	query := strings.TrimSpace(options.Query)
+	byEmail := strings.Contains(query, "@")
+	if !byEmail && utf8.RuneCountInString(query) < a.config.Search.MinQueryLength {
+		logger.Info("search query is too short")
+		return nil, ErrSearchUsersQueryTooShort
+	}
+
+	err := a.hitSearchRateLimit(ctx, options.UserId)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info("search rate limit exceeded")
+			return nil, err
+		}
+		logger.Error("failed to hit search rate limit: ", err)
+		return nil, fmt.Errorf("failed to hit search rate limit: %w", err)
+	}
+
+	filter := newDiscoverableUsersFilter(options.UserId)
+	filter.Limit = a.config.Search.MaxResults
+	if byEmail {
+		email, err := normalizeEmail(query)
+		if err != nil {
+			// malformed email matches nobody
+			logger.Info("invalid email query")
+			return &SearchUsersOutput{Users: []PublicUserProfile{}}, nil
+		}
+		filter.Email = email
+	} else {
+		filter.UsernamePrefix = query
+	}
+
+	users, err := a.storages.UserStorage.SearchUsers(ctx, filter)
+	if err != nil {
+		logger.Error("failed to search users: ", err)
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	output := &SearchUsersOutput{Users: make([]PublicUserProfile, 0, len(users))}
+	for _, user := range users {
+		output.Users = append(output.Users, PublicUserProfile{Id: user.Id, Username: user.Username})
+	}
+
+	logger.Info("successfully searched users", "count", len(output.Users))
+	return output, nil
+}
+
+// newDiscoverableUsersFilter returns filter of users whose discoverability allows the searcher to find them.
+func newDiscoverableUsersFilter(searcherId string) *SearchUsersFilter {
+	return &SearchUsersFilter{
+		SearcherId:         searcherId,
+		Hidden:             []string{entity.DiscoverabilityNobody},
+		ContactsOfContacts: []string{entity.DiscoverabilityContactsOfContacts},
+	}
+}
+
+// findDiscoverableUser returns user with given email when the viewer may find them: the viewer
+// themselves, accepted contacts who did not block one another and users whose discoverability
+// allows the viewer. Returns nil for anyone else, the same as for unknown email.
+func (s serviceContext) findDiscoverableUser(ctx context.Context, viewerId, email string) (*entity.User, error) {
+	user, err := s.findUserByEmail(ctx, email)
+	if err != nil || user == nil || user.Id == viewerId {
+		return user, err
+	}
+
+	trusted, err := s.isTrustedContact(ctx, viewerId, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check contact: %w", err)
+	}
+	if trusted {
+		return user, nil
+	}
+
+	discoverable, err := s.getDiscoverableUserIds(ctx, viewerId, []string{user.Id})
+	if err != nil || !discoverable[user.Id] {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// getDiscoverableUserIds returns which of the users the searcher is allowed to find.
+func (s serviceContext) getDiscoverableUserIds(ctx context.Context, searcherId string, userIds []string) (map[string]bool, error) {
+	discoverable := make(map[string]bool, len(userIds))
+	if len(userIds) == 0 {
+		return discoverable, nil
+	}
+
+	filter := newDiscoverableUsersFilter(searcherId)
+	filter.UserIds = userIds
+	filter.Limit = len(userIds)
+	users, err := s.storages.UserStorage.SearchUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	for _, user := range users {
+		discoverable[user.Id] = true
+	}
+
+	return discoverable, nil
+}
+
+// hitSearchRateLimit counts the search in current fixed window and returns ErrSearchUsersRateLimited
+// with retry after value once the user exceeded the limit.
+func (a accountService) hitSearchRateLimit(ctx context.Context, userId string) error {
+	window := a.config.Search.RateLimitWindow
+	rateLimit, err := a.storages.RateLimitStorage.HitRateLimit(ctx, "search:"+userId, time.Now().Truncate(window))
+	if err != nil {
+		return err
+	}
+	if rateLimit.Count <= a.config.Search.RateLimit {
+		return nil
+	}
+
+	retryAfter := int(math.Ceil(time.Until(rateLimit.WindowStart.Add(window)).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	return ErrSearchUsersRateLimited.WithDetails(map[string]string{"retryAfter": fmt.Sprint(retryAfter)})
+}
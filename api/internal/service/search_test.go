@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"strconv"
+	"testing"
+)
+
+func TestSearchUsersReturnsOnlyDiscoverableProfiles(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	options.Config.Search.RateLimit = 10
+	searcher := createTestUser(t, options, "searcher@droplet.local", _testPassword)
+	found := createTestUser(t, options, "found@droplet.local", _testPassword)
+	createTestUser(t, options, "hidden@droplet.local", _testPassword)
+	storages.users.discoverable[found.Id] = true
+	service := NewAccountService(options)
+
+	for _, query := range []string{"found@droplet.local", "hidden@droplet.local", "not an email@"} {
+		output, err := service.SearchUsers(context.Background(), &SearchUsersOptions{UserId: searcher.Id, Query: query})
+		if err != nil {
+			t.Fatalf("SearchUsers(%q) error = %v", query, err)
+		}
+
+		want := 0
+		if query == found.Email {
+			want = 1
+		}
+		if len(output.Users) != want {
+			t.Fatalf("SearchUsers(%q) returned %d users, want %d", query, len(output.Users), want)
+		}
+		if want == 1 && (output.Users[0] != PublicUserProfile{Id: found.Id, Username: found.Username}) {
+			t.Errorf("SearchUsers(%q) = %+v, want public profile of %s", query, output.Users[0], found.Id)
+		}
+	}
+}
+
+func TestSearchUsersQueryTooShort(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	searcher := createTestUser(t, options, "searcher@droplet.local", _testPassword)
+
+	_, err := NewAccountService(options).SearchUsers(context.Background(), &SearchUsersOptions{UserId: searcher.Id, Query: " ab "})
+	if !errors.Is(err, ErrSearchUsersQueryTooShort) {
+		t.Fatalf("SearchUsers() error = %v, want %v", err, ErrSearchUsersQueryTooShort)
+	}
+	if len(storages.rateLimits.limits) != 0 {
+		t.Errorf("rejected query counted towards rate limit")
+	}
+}
+
+func TestSearchUsersRateLimit(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	searcher := createTestUser(t, options, "searcher@droplet.local", _testPassword)
+	other := createTestUser(t, options, "other@droplet.local", _testPassword)
+	service := NewAccountService(options)
+	search := func(userId string) error {
+		_, err := service.SearchUsers(context.Background(), &SearchUsersOptions{UserId: userId, Query: "user"})
+		return err
+	}
+
+	for i := 0; i < options.Config.Search.RateLimit; i++ {
+		err := search(searcher.Id)
+		if err != nil {
+			t.Fatalf("search %d error = %v", i+1, err)
+		}
+	}
+
+	err := search(searcher.Id)
+	if errs.GetCode(err) != ErrSearchUsersRateLimited.Code {
+		t.Fatalf("SearchUsers() over limit error = %v, want %v", err, ErrSearchUsersRateLimited)
+	}
+	retryAfter, convErr := strconv.Atoi(errs.GetDetails(err)["retryAfter"])
+	if convErr != nil || retryAfter < 1 || retryAfter > int(options.Config.Search.RateLimitWindow.Seconds()) {
+		t.Errorf("retryAfter = %q, want seconds until the window ends", errs.GetDetails(err)["retryAfter"])
+	}
+
+	// limit is counted per user
+	err = search(other.Id)
+	if err != nil {
+		t.Fatalf("SearchUsers() of other user error = %v", err)
+	}
+}
@@ -2,18 +2,27 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/atlant1da-404/droplet/config"
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/pkg/auth"
 	"github.com/atlant1da-404/droplet/pkg/errs"
 	"github.com/atlant1da-404/droplet/pkg/hash"
+	"github.com/atlant1da-404/droplet/pkg/jsonpatch"
 	"github.com/atlant1da-404/droplet/pkg/logger"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"github.com/atlant1da-404/droplet/pkg/oidc"
+	"github.com/atlant1da-404/droplet/pkg/password"
+	"time"
 )
 
 type Services struct {
 	AuthService    AuthService
 	AccountService AccountService
 	NodeService    NodeService
+	AdminService   AdminService
+	ContactService ContactService
+	GroupService   GroupService
 }
 
 type Options struct {
@@ -22,6 +31,12 @@ type Options struct {
 	Logger   logger.Logger
 	Hash     hash.Hash
 	Auth     auth.Authenticator
+	Mailer   mail.Mailer
+	// OIDCProviders maps provider name to its client.
+	OIDCProviders map[string]oidc.Provider
+	// BreachCorpus is nil when breached password screening is off.
+	PasswordPolicy password.Policy
+	BreachCorpus   password.BreachCorpus
 }
 
 type serviceContext struct {
@@ -35,17 +50,76 @@ type AuthService interface {
 	SignIn(ctx context.Context, options *SignInOptions) (*SignInOutput, error)
 	// SignUp provides logic of creating the clients and returns access and refresh tokens.
 	SignUp(ctx context.Context, options *SignUpOptions) (*SignUpOutput, error)
+	// RefreshToken provides logic of rotating refresh token and returns new access and refresh tokens.
+	RefreshToken(ctx context.Context, options *RefreshTokenOptions) (*RefreshTokenOutput, error)
 	// VerifyToken provides logic of validating provided authorization token.
 	VerifyToken(ctx context.Context, options *VerifyTokenOptions) (*VerifyTokenOutput, error)
+	// SignOut provides logic of revoking provided access token together with its session.
+	SignOut(ctx context.Context, options *SignOutOptions) error
+	// SignOutAll provides logic of revoking every session of the token owner.
+	SignOutAll(ctx context.Context, options *SignOutAllOptions) error
+	// PruneRevokedTokens provides logic of removing expired entries from access token denylist.
+	PruneRevokedTokens(ctx context.Context) error
+	// GetJSONWebKeySet provides public keys which can be used to verify issued access tokens.
+	GetJSONWebKeySet(ctx context.Context) (*GetJSONWebKeySetOutput, error)
+	// ForgotPassword provides logic of sending password reset token to the user email.
+	ForgotPassword(ctx context.Context, options *ForgotPasswordOptions) error
+	// ResetPassword provides logic of setting new password via reset token and revoking all user sessions.
+	ResetPassword(ctx context.Context, options *ResetPasswordOptions) error
+	// VerifyEmail provides logic of confirming user email via verification token.
+	VerifyEmail(ctx context.Context, options *VerifyEmailOptions) error
+	// ResendVerificationEmail provides logic of sending new verification token to the user email.
+	ResendVerificationEmail(ctx context.Context, options *ResendVerificationEmailOptions) error
+	// SignInMFA provides logic of exchanging mfa challenge token and second factor code for access and refresh tokens.
+	SignInMFA(ctx context.Context, options *SignInMFAOptions) (*SignInOutput, error)
+	// EnrollTOTP provides logic of generating TOTP secret which has to be confirmed before 2FA is enabled.
+	EnrollTOTP(ctx context.Context, options *EnrollTOTPOptions) (*EnrollTOTPOutput, error)
+	// ConfirmTOTP provides logic of enabling 2FA with enrolled secret and returns recovery codes.
+	ConfirmTOTP(ctx context.Context, options *ConfirmTOTPOptions) (*ConfirmTOTPOutput, error)
+	// DisableTOTP provides logic of disabling 2FA.
+	DisableTOTP(ctx context.Context, options *DisableTOTPOptions) error
+	// RegenerateRecoveryCodes provides logic of replacing recovery codes with new ones.
+	RegenerateRecoveryCodes(ctx context.Context, options *RegenerateRecoveryCodesOptions) (*RegenerateRecoveryCodesOutput, error)
+	// UnlockAccount provides logic of resetting failed sign in attempts of the account.
+	UnlockAccount(ctx context.Context, options *UnlockAccountOptions) error
+	// StartOIDCLogin provides logic of starting sign in via external OpenID Connect provider
+	// and returns url the user has to be redirected to.
+	StartOIDCLogin(ctx context.Context, options *StartOIDCLoginOptions) (*StartOIDCLoginOutput, error)
+	// CompleteOIDCLogin provides logic of handling provider callback, linking external identity
+	// to the user and returns access and refresh tokens.
+	CompleteOIDCLogin(ctx context.Context, options *CompleteOIDCLoginOptions) (*SignInOutput, error)
+	// PruneOIDCLoginStates provides logic of removing expired OpenID Connect login states.
+	PruneOIDCLoginStates(ctx context.Context) error
+	// CreatePersonalAccessToken provides logic of creating scoped long-lived token for scripts and CLI.
+	// The token itself is returned only once.
+	CreatePersonalAccessToken(ctx context.Context, options *CreatePersonalAccessTokenOptions) (*CreatePersonalAccessTokenOutput, error)
+	// ListPersonalAccessTokens provides logic of getting personal access tokens of the user.
+	ListPersonalAccessTokens(ctx context.Context, options *ListPersonalAccessTokensOptions) (*ListPersonalAccessTokensOutput, error)
+	// RevokePersonalAccessToken provides logic of revoking personal access token of the user.
+	RevokePersonalAccessToken(ctx context.Context, options *RevokePersonalAccessTokenOptions) error
+	// ChangePassword provides logic of replacing password after checking the current one,
+	// other user sessions are revoked.
+	ChangePassword(ctx context.Context, options *ChangePasswordOptions) error
+	// ChangeEmail provides logic of sending confirmation link to the new email and notice to the current one.
+	ChangeEmail(ctx context.Context, options *ChangeEmailOptions) error
+	// ConfirmEmailChange provides logic of applying requested email change via confirmation token,
+	// other user sessions are revoked.
+	ConfirmEmailChange(ctx context.Context, options *ConfirmEmailChangeOptions) error
 }
 
 type SignInOptions struct {
 	Email    string
 	Password string
+	ClientIP string `json:"-"`
 }
 
 type SignInOutput struct {
-	AccessToken string
+	AccessToken  string
+	RefreshToken string
+	// MFARequired is set instead of tokens when user has 2FA enabled,
+	// MFAToken then has to be exchanged via SignInMFA.
+	MFARequired bool
+	MFAToken    string
 }
 
 type SignUpOptions struct {
@@ -56,10 +130,20 @@ type SignUpOptions struct {
 }
 
 type SignUpOutput struct {
-	Id          string
-	Username    string
-	Email       string
-	AccessToken string
+	Id           string
+	Username     string
+	Email        string
+	AccessToken  string
+	RefreshToken string
+}
+
+type RefreshTokenOptions struct {
+	RefreshToken string
+}
+
+type RefreshTokenOutput struct {
+	AccessToken  string
+	RefreshToken string
 }
 
 type VerifyTokenOptions struct {
@@ -67,14 +151,194 @@ type VerifyTokenOptions struct {
 }
 
 type VerifyTokenOutput struct {
-	Username string
-	UserId   string
+	Username  string
+	UserId    string
+	SessionId string
+	TokenId   string
+	Role      string
+	// PersonalAccessTokenId and Scopes are set only when request is authorized by personal
+	// access token, such request is allowed only on routes that accept one of the scopes.
+	PersonalAccessTokenId string
+	Scopes                []string
+}
+
+type SignOutOptions struct {
+	AccessToken string
+}
+
+type SignOutAllOptions struct {
+	AccessToken string
+}
+
+type GetJSONWebKeySetOutput struct {
+	Keys []auth.JSONWebKey `json:"keys"`
+}
+
+type ForgotPasswordOptions struct {
+	Email string
+}
+
+type ResetPasswordOptions struct {
+	Token    string
+	Password string
+}
+
+type VerifyEmailOptions struct {
+	Token string
+}
+
+type ResendVerificationEmailOptions struct {
+	UserId string
+}
+
+type SignInMFAOptions struct {
+	MFAToken string
+	// Code is either TOTP code or one of recovery codes.
+	Code     string
+	ClientIP string `json:"-"`
+}
+
+type EnrollTOTPOptions struct {
+	UserId string `json:"-"`
+}
+
+type EnrollTOTPOutput struct {
+	Secret string
+	URI    string
+}
+
+type ConfirmTOTPOptions struct {
+	UserId string `json:"-"`
+	Code   string
+}
+
+type ConfirmTOTPOutput struct {
+	RecoveryCodes []string
+}
+
+type DisableTOTPOptions struct {
+	UserId string `json:"-"`
+	Code   string
+}
+
+type RegenerateRecoveryCodesOptions struct {
+	UserId string `json:"-"`
+	Code   string
+}
+
+type RegenerateRecoveryCodesOutput struct {
+	RecoveryCodes []string
+}
+
+type UnlockAccountOptions struct {
+	Email string
+}
+
+type StartOIDCLoginOptions struct {
+	Provider string
+}
+
+type StartOIDCLoginOutput struct {
+	AuthorizationURL string
+}
+
+type CompleteOIDCLoginOptions struct {
+	Provider string
+	Code     string
+	State    string
+}
+
+type CreatePersonalAccessTokenOptions struct {
+	UserId    string `json:"-"`
+	Name      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+type CreatePersonalAccessTokenOutput struct {
+	Id        string
+	Name      string
+	Scopes    []string
+	ExpiresAt time.Time
+	Token     string
+}
+
+type ListPersonalAccessTokensOptions struct {
+	UserId string
+}
+
+type ListPersonalAccessTokensOutput struct {
+	Tokens []entity.PersonalAccessToken
+}
+
+type RevokePersonalAccessTokenOptions struct {
+	UserId  string
+	TokenId string
+}
+
+type ChangePasswordOptions struct {
+	UserId          string `json:"-"`
+	SessionId       string `json:"-"`
+	ClientIP        string `json:"-"`
+	CurrentPassword string
+	NewPassword     string
+}
+
+type ChangeEmailOptions struct {
+	UserId          string `json:"-"`
+	SessionId       string `json:"-"`
+	ClientIP        string `json:"-"`
+	CurrentPassword string
+	NewEmail        string
+}
+
+type ConfirmEmailChangeOptions struct {
+	Token string
 }
 
 var (
-	ErrSignUpUserAlreadyCreated = errs.New("user already created", "user_already_created")
-	ErrSignInUserNotFound       = errs.New("user not found", "user_not_found")
-	ErrSignInWrongPassword      = errs.New("wrong password", "wrong_password")
+	ErrSignUpUserAlreadyCreated  = errs.New("user already created", "user_already_created")
+	ErrPasswordPolicyViolation   = errs.New("password does not meet requirements", "invalid_password")
+	ErrSignInUserNotFound        = errs.New("user not found", "user_not_found")
+	ErrSignInWrongPassword       = errs.New("wrong password", "wrong_password")
+	ErrSignInAccountLocked       = errs.New("too many failed sign in attempts", "account_locked")
+	ErrSignInUserDisabled        = errs.New("user is disabled", "user_disabled")
+	ErrSignInDeletionPending     = errs.New("account is scheduled for deletion", "account_deletion_pending")
+	ErrRefreshTokenInvalid       = errs.New("invalid refresh token", "invalid_refresh_token")
+	ErrRefreshTokenExpired       = errs.New("refresh token expired", "refresh_token_expired")
+	ErrRefreshTokenReused        = errs.New("refresh token reuse detected", "refresh_token_reused")
+	ErrVerifyTokenRevoked        = errs.New("token revoked", "token_revoked")
+	ErrVerifyTokenInvalid        = errs.New("invalid token", "invalid_token")
+	ErrSignOutInvalidToken       = errs.New("invalid token", "invalid_token")
+	ErrResetPasswordInvalidToken = errs.New("invalid or expired reset token", "invalid_reset_token")
+	ErrVerifyEmailInvalidToken   = errs.New("invalid or expired verification token", "invalid_verification_token")
+	ErrEmailAlreadyVerified      = errs.New("email already verified", "email_already_verified")
+
+	ErrResendVerificationEmailUserNotFound = errs.New("user not found", "user_not_found")
+
+	ErrSignInMFAInvalidToken = errs.New("invalid or expired mfa token", "invalid_mfa_token")
+	ErrMFAUserNotFound       = errs.New("user not found", "user_not_found")
+	ErrMFAInvalidCode        = errs.New("invalid code", "invalid_mfa_code")
+	ErrTOTPAlreadyEnabled    = errs.New("2fa already enabled", "mfa_already_enabled")
+	ErrTOTPNotEnrolled       = errs.New("2fa enrollment not started", "mfa_not_enrolled")
+	ErrTOTPNotEnabled        = errs.New("2fa not enabled", "mfa_not_enabled")
+
+	ErrOIDCProviderNotFound = errs.New("oidc provider not found", "oidc_provider_not_found")
+	ErrOIDCInvalidState     = errs.New("invalid or expired oidc login state", "invalid_oidc_state")
+	ErrOIDCLoginFailed      = errs.New("oidc login failed", "oidc_login_failed")
+	ErrOIDCEmailNotVerified = errs.New("email is not verified by oidc provider", "oidc_email_not_verified")
+
+	ErrPersonalAccessTokenInvalidName       = errs.New("token name is required and must be at most 100 characters", "invalid_token_name")
+	ErrPersonalAccessTokenInvalidScope      = errs.New("invalid token scope", "invalid_scope")
+	ErrPersonalAccessTokenInvalidExpiration = errs.New("token expiration must be in the future and within allowed lifetime", "invalid_token_expiration")
+	ErrPersonalAccessTokenNotFound          = errs.New("token not found", "token_not_found")
+
+	ErrChangeCredentialsUserNotFound  = errs.New("user not found", "user_not_found")
+	ErrChangeCredentialsWrongPassword = errs.New("wrong password", "wrong_password")
+	ErrChangeEmailInvalidEmail        = errs.New("invalid email", "invalid_email")
+	ErrChangeEmailSameEmail           = errs.New("new email is the same as current one", "same_email")
+	ErrChangeEmailAlreadyTaken        = errs.New("email already taken", "email_already_taken")
+	ErrConfirmEmailChangeInvalidToken = errs.New("invalid or expired email change token", "invalid_email_change_token")
 )
 
 type AccountService interface {
@@ -82,12 +346,52 @@ type AccountService interface {
 	CreateAccount(ctx context.Context, options *CreateAccountOptions) (*CreateAccountOutput, error)
 	// GetAccount provides logic of getting account via accountId.
 	GetAccount(ctx context.Context, options *GetAccountOptions) (*entity.Account, error)
-	// UpdateAccount provides logic of updating existing account.
-	UpdateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error)
+	// UpdateAccount provides logic of updating settings of existing account, only given settings are changed.
+	UpdateAccount(ctx context.Context, options *UpdateAccountOptions) (*entity.Account, error)
+	// DeleteAccount provides logic of scheduling erase of the account owner after grace period, sessions are revoked immediately.
+	DeleteAccount(ctx context.Context, options *DeleteAccountOptions) (*DeleteAccountOutput, error)
+	// CancelAccountDeletion provides logic of cancelling scheduled deletion via emailed token.
+	CancelAccountDeletion(ctx context.Context, options *CancelAccountDeletionOptions) error
+	// EraseScheduledAccounts provides logic of erasing users whose grace period has passed.
+	EraseScheduledAccounts(ctx context.Context) error
+	// RequestDataExport provides logic of queueing export of all user data, archive is built in background.
+	RequestDataExport(ctx context.Context, options *RequestDataExportOptions) (*DataExportOutput, error)
+	// GetDataExport provides logic of getting status of user data export.
+	GetDataExport(ctx context.Context, options *GetDataExportOptions) (*DataExportOutput, error)
+	// DownloadDataExport provides logic of getting export archive via emailed token.
+	DownloadDataExport(ctx context.Context, options *DownloadDataExportOptions) (*DownloadDataExportOutput, error)
+	// ProcessDataExports provides logic of building queued archives and pruning expired ones.
+	ProcessDataExports(ctx context.Context) error
+	// ListDevices provides logic of getting devices of the account.
+	ListDevices(ctx context.Context, options *ListDevicesOptions) (*ListDevicesOutput, error)
+	// RegisterDevice provides logic of adding device to the account within configured device limit.
+	RegisterDevice(ctx context.Context, options *RegisterDeviceOptions) (*entity.AccountDevices, error)
+	// UpdateDevice provides logic of renaming, activating or deactivating device.
+	UpdateDevice(ctx context.Context, options *UpdateDeviceOptions) (*entity.AccountDevices, error)
+	// RemoveDevice provides logic of removing device from the account.
+	RemoveDevice(ctx context.Context, options *RemoveDeviceOptions) error
+	// DeviceHeartbeat provides logic of marking device online, devices call it periodically.
+	DeviceHeartbeat(ctx context.Context, options *DeviceHeartbeatOptions) (*DeviceHeartbeatOutput, error)
+	// GetPresence provides logic of getting which devices of the user with given email are online,
+	// presence is shared only between accepted contacts who did not block one another.
+	GetPresence(ctx context.Context, options *GetPresenceOptions) (*GetPresenceOutput, error)
+	// MarkStaleDevicesOffline provides logic of flipping devices without recent heartbeat offline.
+	MarkStaleDevicesOffline(ctx context.Context) error
+	// RegisterDeviceKey provides logic of publishing device public keys, new keys must be signed
+	// by device of the account holding key which is not revoked, unless the account has no such keys.
+	RegisterDeviceKey(ctx context.Context, options *RegisterDeviceKeyOptions) (*PublicDeviceKey, error)
+	// LookupDeviceKeys provides logic of getting current keys of active devices of the user with given email,
+	// users who are not discoverable by the caller look the same as users without keys.
+	LookupDeviceKeys(ctx context.Context, options *LookupDeviceKeysOptions) (*LookupDeviceKeysOutput, error)
+	// ListDeviceKeyChanges provides logic of getting history of key changes of the user with given email.
+	ListDeviceKeyChanges(ctx context.Context, options *LookupDeviceKeysOptions) (*LookupDeviceKeysOutput, error)
+	// SearchUsers provides logic of finding users by username prefix or exact email. Users are found only
+	// when their discoverability allows the searcher, every searcher is rate limited.
+	SearchUsers(ctx context.Context, options *SearchUsersOptions) (*SearchUsersOutput, error)
 }
 
 type CreateAccountOptions struct {
-	UserId           string `json:"userId"`
+	UserId           string `json:"-"`
 	DeviceName       string `json:"deviceName"`
 	DeviceOS         string `json:"deviceOs"`
 	DeviceMacAddress string `json:"deviceMacAddress"`
@@ -101,25 +405,578 @@ type CreateAccountOutput struct {
 }
 
 type GetAccountOptions struct {
-	AccountId string `json:"accountId"`
-	UserId    string `json:"userId"`
+	AccountId string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+// UpdateAccountOptions - Version is account version the client has seen, zero skips the check.
+// Account is changed either by AccountSettings, by MergePatch (RFC 7396) or by JSONPatch (RFC 6902),
+// patches are applied to JSON form of the account returned by GetAccount.
+type UpdateAccountOptions struct {
+	AccountId       string                `json:"-"`
+	UserId          string                `json:"-"`
+	Version         int                   `json:"-"`
+	AccountSettings *AccountSettingsPatch `json:"accountSettings"`
+	MergePatch      json.RawMessage       `json:"-"`
+	JSONPatch       jsonpatch.Patch       `json:"-"`
+}
+
+// AccountSettingsPatch - represents partial update of account settings, nil fields are left unchanged.
+type AccountSettingsPatch struct {
+	Language            *string                    `json:"language,omitempty" example:"en-GB"`
+	Theme               *string                    `json:"theme,omitempty" enums:"system,light,dark"`
+	Discoverability     *string                    `json:"discoverability,omitempty" enums:"everyone,contacts_of_contacts,nobody"`
+	SharePresence       *bool                      `json:"sharePresence,omitempty"`
+	Notifications       *NotificationSettingsPatch `json:"notifications,omitempty"`
+	AutoAccept          *string                    `json:"autoAccept,omitempty" enums:"never,contacts,own_devices"`
+	DownloadAction      *string                    `json:"downloadAction,omitempty" enums:"ask,save,open"`
+	MaxIncomingFileSize *int64                     `json:"maxIncomingFileSize,omitempty"`
+	NonContactTransfers *string                    `json:"nonContactTransfers,omitempty" enums:"allow,hold,reject"`
+}
+
+type NotificationSettingsPatch struct {
+	IncomingTransfers  *bool `json:"incomingTransfers,omitempty"`
+	CompletedTransfers *bool `json:"completedTransfers,omitempty"`
+	SecurityEvents     *bool `json:"securityEvents,omitempty"`
+}
+
+type DeleteAccountOptions struct {
+	AccountId string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type DeleteAccountOutput struct {
+	DeletionScheduledFor time.Time `json:"deletionScheduledFor"`
+}
+
+type CancelAccountDeletionOptions struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RequestDataExportOptions struct {
+	AccountId string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type GetDataExportOptions struct {
+	AccountId string `json:"-"`
+	ExportId  string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type DataExportOutput struct {
+	Id          string     `json:"id"`
+	Status      string     `json:"status" enums:"pending,processing,ready,failed"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type ListDevicesOptions struct {
+	AccountId string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type ListDevicesOutput struct {
+	Devices []entity.AccountDevices `json:"devices"`
+}
+
+type RegisterDeviceOptions struct {
+	AccountId  string `json:"-"`
+	UserId     string `json:"-"`
+	Name       string `json:"name" binding:"required"`
+	OS         string `json:"os" binding:"required" enums:"windows,macos,linux,android,ios"`
+	MacAddress string `json:"macAddress" binding:"required" example:"a4:83:e7:1c:2b:90"`
+	Active     bool   `json:"active"`
+}
+
+// UpdateDeviceOptions - only fields which are set are changed.
+type UpdateDeviceOptions struct {
+	AccountId string  `json:"-"`
+	DeviceId  string  `json:"-"`
+	UserId    string  `json:"-"`
+	Name      *string `json:"name"`
+	Active    *bool   `json:"active"`
+}
+
+type RemoveDeviceOptions struct {
+	AccountId string `json:"-"`
+	DeviceId  string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type DeviceHeartbeatOptions struct {
+	AccountId string `json:"-"`
+	DeviceId  string `json:"-"`
+	UserId    string `json:"-"`
+}
+
+type DeviceHeartbeatOutput struct {
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	// TTLSeconds is how long device stays online without another heartbeat.
+	TTLSeconds int64 `json:"ttlSeconds"`
+}
+
+type GetPresenceOptions struct {
+	UserId string `json:"-"`
+	Email  string `json:"-"`
+}
+
+type GetPresenceOutput struct {
+	Devices []DevicePresence `json:"devices"`
+}
+
+// DevicePresence - represents device as other users see it, addresses are not exposed.
+type DevicePresence struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	OS         string     `json:"os"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+}
+
+// SearchUsersOptions - Query containing @ is matched as exact email, otherwise as username prefix.
+type SearchUsersOptions struct {
+	UserId string `json:"-"`
+	Query  string `json:"-"`
+}
+
+type SearchUsersOutput struct {
+	Users []PublicUserProfile `json:"users"`
+}
+
+// PublicUserProfile - represents user as search shows them to others, email is never exposed.
+type PublicUserProfile struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// RegisterDeviceKeyOptions - keys are base64 encoded, Signature is Ed25519 signature of
+// "droplet-device-key-v1\n<accountId>\n<deviceId>\n<encryptionKey>\n<signingKey>" made by SignerDeviceId.
+type RegisterDeviceKeyOptions struct {
+	AccountId      string `json:"-"`
+	DeviceId       string `json:"-"`
+	UserId         string `json:"-"`
+	EncryptionKey  string `json:"encryptionKey" binding:"required"`
+	SigningKey     string `json:"signingKey" binding:"required"`
+	SignerDeviceId string `json:"signerDeviceId"`
+	Signature      string `json:"signature"`
+}
+
+type LookupDeviceKeysOptions struct {
+	UserId string `json:"-"`
+	Email  string `json:"-"`
+}
+
+type LookupDeviceKeysOutput struct {
+	Keys []PublicDeviceKey `json:"keys"`
+}
+
+// PublicDeviceKey - represents device key as senders see it.
+type PublicDeviceKey struct {
+	DeviceId         string     `json:"deviceId"`
+	DeviceName       string     `json:"deviceName,omitempty"`
+	EncryptionKey    string     `json:"encryptionKey"`
+	SigningKey       string     `json:"signingKey"`
+	Fingerprint      string     `json:"fingerprint"`
+	SignedByDeviceId string     `json:"signedByDeviceId,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+}
+
+type DownloadDataExportOptions struct {
+	Token string
+}
+
+type DownloadDataExportOutput struct {
+	FileName string
+	Archive  []byte
 }
 
 var (
-	ErrCreateAccountUserNotFound = errs.New("user not found", "user_not_found")
-	ErrGetAccountAccountNotFound = errs.New("account not found", "account_not_found")
+	ErrCreateAccountUserNotFound         = errs.New("user not found", "user_not_found")
+	ErrGetAccountAccountNotFound         = errs.New("account not found", "account_not_found")
+	ErrUpdateAccountVersionMismatch      = errs.New("account was modified by another client", "version_mismatch")
+	ErrAccountSettingsInvalid            = errs.New("invalid account settings", "invalid_settings")
+	ErrAccountPatchInvalid               = errs.New("patch changes fields which cannot be patched", "invalid_patch")
+	ErrAccountPatchFailed                = errs.New("patch cannot be applied to the account", "patch_failed")
+	ErrDeleteAccountAlreadyScheduled     = errs.New("account deletion is already scheduled", "deletion_already_scheduled")
+	ErrCancelAccountDeletionInvalidToken = errs.New("invalid or expired deletion cancel token", "invalid_deletion_cancel_token")
+	ErrDataExportInProgress              = errs.New("data export is already in progress", "export_in_progress")
+	ErrDataExportNotFound                = errs.New("data export not found", "export_not_found")
+	ErrDeviceInvalidName                 = errs.New("device name must be from 1 to 64 characters", "invalid_device_name")
+	ErrDeviceInvalidOS                   = errs.New("unknown device os", "invalid_device_os")
+	ErrDeviceInvalidMacAddress           = errs.New("invalid mac address", "invalid_mac_address")
+	ErrDeviceAlreadyRegistered           = errs.New("device with this mac address is already registered", "device_already_registered")
+	ErrDeviceLimitReached                = errs.New("account has reached device limit", "device_limit_reached")
+	ErrDeviceNotFound                    = errs.New("device not found", "device_not_found")
+	ErrDeviceInactive                    = errs.New("device is deactivated", "device_inactive")
+	ErrDeviceKeyInvalid                  = errs.New("invalid device key", "invalid_device_key")
+	ErrDeviceKeySignatureRequired        = errs.New("key must be signed by trusted device of the account", "device_key_signature_required")
+	ErrDeviceKeyInvalidSignature         = errs.New("invalid device key signature", "invalid_device_key_signature")
+	ErrSearchUsersQueryTooShort          = errs.New("search query is too short", "query_too_short")
+	ErrSearchUsersRateLimited            = errs.New("too many searches, try again later", "rate_limited")
+	ErrDownloadDataExportInvalidToken    = errs.New("invalid or expired export download token", "invalid_export_token")
 )
 
 type NodeService interface {
-	// CreateNode provides logic of creating new node.
+	// CreateNode provides logic of creating new node. Transfers from non-contacts follow receiver policy,
+	// transfers from blocked senders are answered as if they were delivered.
 	CreateNode(ctx context.Context, options *CreateNodeOptions) (*CreateNodeOutput, error)
+	// ListHeldTransfers provides logic of getting transfers from non-contacts which wait for the user approval.
+	ListHeldTransfers(ctx context.Context, options *ListHeldTransfersOptions) (*ListHeldTransfersOutput, error)
+	// ReviewHeldTransfer provides logic of approving or declining held transfer.
+	ReviewHeldTransfer(ctx context.Context, options *ReviewHeldTransferOptions) error
+	// CreateGroupNode provides logic of fanning transfer out to members of the group except the sender.
+	// Every receiver gets own node, all of them share the same payload and follow policy of their receiver.
+	CreateGroupNode(ctx context.Context, options *CreateGroupNodeOptions) (*CreateGroupNodeOutput, error)
 }
 
+// CreateNodeOptions - receiver is given either by email or by id, e.g. of user found by search.
 type CreateNodeOptions struct {
-	SenderEmail   string `json:"senderEmail"`
-	ReceiverEmail string `json:"receiverEmail"`
+	SenderUserId   string `json:"-"`
+	ReceiverEmail  string `json:"receiverEmail"`
+	ReceiverUserId string `json:"receiverUserId"`
 }
 
 type CreateNodeOutput struct {
 	Id string
 }
+
+type ListHeldTransfersOptions struct {
+	UserId string `json:"-"`
+}
+
+type ListHeldTransfersOutput struct {
+	Transfers []HeldTransfer `json:"transfers"`
+}
+
+// HeldTransfer - represents transfer as its receiver sees it while it waits for approval.
+type HeldTransfer struct {
+	Id          string    `json:"id"`
+	SenderEmail string    `json:"senderEmail"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ReviewHeldTransferOptions struct {
+	UserId  string `json:"-"`
+	NodeId  string `json:"-"`
+	Approve bool   `json:"-"`
+}
+
+type CreateGroupNodeOptions struct {
+	SenderUserId string `json:"-"`
+	GroupId      string `json:"-"`
+}
+
+// CreateGroupNodeOutput - Rejected lists receivers whose policy rejects transfers from the sender.
+type CreateGroupNodeOutput struct {
+	PayloadId string              `json:"payloadId"`
+	Transfers []GroupNodeTransfer `json:"transfers"`
+	Rejected  []string            `json:"rejected"`
+}
+
+type GroupNodeTransfer struct {
+	Id            string `json:"id"`
+	ReceiverEmail string `json:"receiverEmail"`
+}
+
+var (
+	ErrCreateNodeSenderNotFound   = errs.New("sender not found", "user_not_found")
+	ErrCreateNodeEmailNotVerified = errs.New("email is not verified", "email_not_verified")
+	ErrCreateNodeRejected         = errs.New("receiver accepts transfers only from contacts", "transfer_rejected")
+	ErrCreateNodeReceiverRequired = errs.New("either receiver email or receiver user id is required", "receiver_required")
+	ErrCreateNodeReceiverNotFound = errs.New("receiver not found", "receiver_not_found")
+	ErrHeldTransferNotFound       = errs.New("held transfer not found", "transfer_not_found")
+	ErrCreateGroupNodeNotFound    = errs.New("group not found", "group_not_found")
+	ErrCreateGroupNodeNoReceivers = errs.New("group has no other members", "group_has_no_receivers")
+)
+
+type ContactService interface {
+	// SendContactRequest provides logic of asking another user to become a contact, asking user who
+	// already asked the user accepts the request. Requests to unknown emails and to users who blocked the user
+	// or cannot be found by the user look as pending, so they disclose neither registration nor blocking.
+	SendContactRequest(ctx context.Context, options *SendContactRequestOptions) (*ContactOutput, error)
+	// RespondContactRequest provides logic of accepting, declining or ignoring incoming contact request.
+	RespondContactRequest(ctx context.Context, options *RespondContactRequestOptions) (*ContactOutput, error)
+	// ListContacts provides logic of getting accepted contacts of the user.
+	ListContacts(ctx context.Context, options *ListContactsOptions) (*ListContactsOutput, error)
+	// ListContactRequests provides logic of getting pending contact requests the user sent or received.
+	ListContactRequests(ctx context.Context, options *ListContactRequestsOptions) (*ListContactsOutput, error)
+	// RemoveContact provides logic of removing contact or withdrawing sent contact request.
+	RemoveContact(ctx context.Context, options *RemoveContactOptions) error
+	// BlockUser provides logic of blocking user, contact and contact requests between both users are removed.
+	// Blocking unknown email looks successful as well.
+	BlockUser(ctx context.Context, options *BlockUserOptions) (*BlockedUserOutput, error)
+	// UnblockUser provides logic of unblocking user.
+	UnblockUser(ctx context.Context, options *UnblockUserOptions) error
+	// ListBlockedUsers provides logic of getting users the user blocked.
+	ListBlockedUsers(ctx context.Context, options *ListBlockedUsersOptions) (*ListBlockedUsersOutput, error)
+}
+
+type SendContactRequestOptions struct {
+	UserId string `json:"-"`
+	Email  string `json:"email" binding:"required"`
+}
+
+const (
+	ContactActionAccept  = "accept"
+	ContactActionDecline = "decline"
+	ContactActionIgnore  = "ignore"
+)
+
+type RespondContactRequestOptions struct {
+	UserId    string `json:"-"`
+	ContactId string `json:"-"`
+	Action    string `json:"action" binding:"required" enums:"accept,decline,ignore"`
+}
+
+type ListContactsOptions struct {
+	UserId string `json:"-"`
+}
+
+const (
+	ContactDirectionIncoming = "incoming"
+	ContactDirectionOutgoing = "outgoing"
+)
+
+type ListContactRequestsOptions struct {
+	UserId    string `json:"-"`
+	Direction string `json:"-"`
+}
+
+type ListContactsOutput struct {
+	Contacts []ContactOutput `json:"contacts"`
+}
+
+// ContactOutput - represents contact or contact request from the user point of view,
+// UserId, Username and Email belong to the other user.
+type ContactOutput struct {
+	Id          string     `json:"id"`
+	UserId      string     `json:"userId,omitempty"`
+	Username    string     `json:"username,omitempty"`
+	Email       string     `json:"email"`
+	Status      string     `json:"status" enums:"pending,accepted,declined,ignored"`
+	Direction   string     `json:"direction" enums:"incoming,outgoing"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type RemoveContactOptions struct {
+	UserId    string `json:"-"`
+	ContactId string `json:"-"`
+}
+
+type BlockUserOptions struct {
+	UserId string `json:"-"`
+	Email  string `json:"email" binding:"required"`
+}
+
+type UnblockUserOptions struct {
+	UserId        string `json:"-"`
+	BlockedUserId string `json:"-"`
+}
+
+type ListBlockedUsersOptions struct {
+	UserId string `json:"-"`
+}
+
+type ListBlockedUsersOutput struct {
+	Users []BlockedUserOutput `json:"users"`
+}
+
+type BlockedUserOutput struct {
+	UserId    string    `json:"userId,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Email     string    `json:"email"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+var (
+	ErrContactUserNotFound     = errs.New("user not found", "user_not_found")
+	ErrContactSelf             = errs.New("users cannot add or block themselves", "cannot_contact_self")
+	ErrContactAlreadyExists    = errs.New("user is already a contact", "contact_already_exists")
+	ErrContactRequestDeclined  = errs.New("user declined the contact request", "contact_request_declined")
+	ErrContactUserBlocked      = errs.New("user is blocked, unblock the user first", "user_blocked")
+	ErrContactNotFound         = errs.New("contact not found", "contact_not_found")
+	ErrContactInvalidAction    = errs.New("action must be one of: accept, decline, ignore", "invalid_action")
+	ErrContactInvalidDirection = errs.New("direction must be one of: incoming, outgoing", "invalid_direction")
+	ErrBlockedUserNotFound     = errs.New("user is not blocked", "block_not_found")
+)
+
+type GroupService interface {
+	// CreateGroup provides logic of creating group, the user becomes its owner.
+	CreateGroup(ctx context.Context, options *CreateGroupOptions) (*GroupOutput, error)
+	// GetGroup provides logic of getting group the user is member of.
+	GetGroup(ctx context.Context, options *GetGroupOptions) (*GroupOutput, error)
+	// ListGroups provides logic of getting groups the user is member of.
+	ListGroups(ctx context.Context, options *ListGroupsOptions) (*ListGroupsOutput, error)
+	// RenameGroup provides logic of renaming group, only owners can rename it.
+	RenameGroup(ctx context.Context, options *RenameGroupOptions) (*GroupOutput, error)
+	// DeleteGroup provides logic of deleting group, only owners can delete it. Transfers sent to the group are kept.
+	DeleteGroup(ctx context.Context, options *DeleteGroupOptions) error
+	// AddGroupMember provides logic of adding user to the group, only owners can add members
+	// and only their accepted contacts who did not block one another can be added.
+	AddGroupMember(ctx context.Context, options *AddGroupMemberOptions) (*GroupOutput, error)
+	// UpdateGroupMember provides logic of changing role of the member, only owners can change roles.
+	UpdateGroupMember(ctx context.Context, options *UpdateGroupMemberOptions) (*GroupOutput, error)
+	// RemoveGroupMember provides logic of removing member from the group. Owners can remove anyone,
+	// members can only leave. Group always keeps at least one owner.
+	RemoveGroupMember(ctx context.Context, options *RemoveGroupMemberOptions) error
+}
+
+type CreateGroupOptions struct {
+	UserId string `json:"-"`
+	Name   string `json:"name" binding:"required"`
+}
+
+type GetGroupOptions struct {
+	UserId  string `json:"-"`
+	GroupId string `json:"-"`
+}
+
+type ListGroupsOptions struct {
+	UserId string `json:"-"`
+}
+
+type ListGroupsOutput struct {
+	Groups []GroupOutput `json:"groups"`
+}
+
+// GroupOutput - Role is role of the user who requested the group.
+type GroupOutput struct {
+	Id        string              `json:"id"`
+	Name      string              `json:"name"`
+	Role      string              `json:"role" enums:"owner,member"`
+	Members   []GroupMemberOutput `json:"members"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+// GroupMemberOutput - Email is shown only when discoverability of the member allows the viewer to find them.
+type GroupMemberOutput struct {
+	UserId   string    `json:"userId"`
+	Username string    `json:"username"`
+	Email    string    `json:"email,omitempty"`
+	Role     string    `json:"role" enums:"owner,member"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type RenameGroupOptions struct {
+	UserId  string `json:"-"`
+	GroupId string `json:"-"`
+	Name    string `json:"name" binding:"required"`
+}
+
+type DeleteGroupOptions struct {
+	UserId  string `json:"-"`
+	GroupId string `json:"-"`
+}
+
+type AddGroupMemberOptions struct {
+	UserId  string `json:"-"`
+	GroupId string `json:"-"`
+	Email   string `json:"email" binding:"required"`
+	// Role defaults to member.
+	Role string `json:"role" enums:"owner,member"`
+}
+
+type UpdateGroupMemberOptions struct {
+	UserId       string `json:"-"`
+	GroupId      string `json:"-"`
+	MemberUserId string `json:"-"`
+	Role         string `json:"role" binding:"required" enums:"owner,member"`
+}
+
+type RemoveGroupMemberOptions struct {
+	UserId       string `json:"-"`
+	GroupId      string `json:"-"`
+	MemberUserId string `json:"-"`
+}
+
+var (
+	ErrGroupNotFound        = errs.New("group not found", "group_not_found")
+	ErrGroupOwnerRequired   = errs.New("only group owners can do this", "group_owner_required")
+	ErrGroupInvalidName     = errs.New("group name must be 1-64 characters long", "invalid_group_name")
+	ErrGroupInvalidRole     = errs.New("role must be one of: owner, member", "invalid_group_role")
+	ErrGroupUserNotFound    = errs.New("user not found", "user_not_found")
+	ErrGroupContactNotFound = errs.New("user not found among your contacts", "user_not_found")
+	ErrGroupMemberExists    = errs.New("user is already a member", "member_already_exists")
+	ErrGroupMemberNotFound  = errs.New("member not found", "member_not_found")
+	ErrGroupFull            = errs.New("group reached maximum number of members", "group_full")
+	ErrGroupLastOwner       = errs.New("group must keep at least one owner", "last_group_owner")
+)
+
+type AdminService interface {
+	// ListUsers provides logic of getting page of users for operators.
+	ListUsers(ctx context.Context, options *ListUsersOptions) (*ListUsersOutput, error)
+	// SetUserDisabled provides logic of disabling or enabling user, disabling also revokes all user sessions.
+	SetUserDisabled(ctx context.Context, options *SetUserDisabledOptions) error
+	// SetUserRole provides logic of changing user role, user sessions are revoked so the role applies immediately.
+	SetUserRole(ctx context.Context, options *SetUserRoleOptions) error
+	// ListUserDevices provides logic of getting devices of user account.
+	ListUserDevices(ctx context.Context, options *ListUserDevicesOptions) (*ListUserDevicesOutput, error)
+	// ListUserTransfers provides logic of getting nodes user sends or receives.
+	ListUserTransfers(ctx context.Context, options *ListUserTransfersOptions) (*ListUserTransfersOutput, error)
+	// DeleteUser provides logic of erasing user immediately, without grace period.
+	DeleteUser(ctx context.Context, options *DeleteUserOptions) error
+}
+
+type ListUsersOptions struct {
+	Limit  int
+	Offset int
+}
+
+type ListUsersOutput struct {
+	Users []UserSummary
+	Total int64
+}
+
+// UserSummary - represents user as operators see it, credentials are never exposed.
+type UserSummary struct {
+	Id            string
+	Username      string
+	Email         string
+	EmailVerified bool
+	TOTPEnabled   bool
+	Role          string
+	Disabled      bool
+}
+
+type SetUserDisabledOptions struct {
+	ActorUserId string `json:"-"`
+	UserId      string `json:"-"`
+	Disabled    bool   `json:"-"`
+}
+
+type SetUserRoleOptions struct {
+	ActorUserId string `json:"-"`
+	UserId      string `json:"-"`
+	Role        string
+}
+
+type ListUserDevicesOptions struct {
+	UserId string
+}
+
+type ListUserDevicesOutput struct {
+	Devices []entity.AccountDevices
+}
+
+type ListUserTransfersOptions struct {
+	UserId string
+}
+
+type ListUserTransfersOutput struct {
+	Transfers []entity.Node
+}
+
+type DeleteUserOptions struct {
+	ActorUserId string `json:"-"`
+	UserId      string `json:"-"`
+}
+
+var (
+	ErrAdminUserNotFound     = errs.New("user not found", "user_not_found")
+	ErrAdminInvalidRole      = errs.New("invalid role", "invalid_role")
+	ErrAdminSelfModification = errs.New("operators cannot change their own role or status", "cannot_modify_self")
+)
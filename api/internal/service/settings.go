@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// newAccountSettings returns default settings in given language, or in default language when it is empty.
+func newAccountSettings(lang string) (*entity.AccountSettings, error) {
+	settings := entity.DefaultAccountSettings()
+	if strings.TrimSpace(lang) != "" {
+		settings.Language = lang
+	}
+
+	err := validateAccountSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// upgradeAccountSettings brings settings stored by older schema versions to the current one.
+// Every step upgrades by exactly one version, so clients can rely on the same migrations.
+func upgradeAccountSettings(settings *entity.AccountSettings) {
+	if settings.Version < 2 {
+		// version 1 kept language as free-form string
+		tag, err := language.Parse(settings.Language)
+		if err != nil {
+			settings.Language = entity.DefaultLanguage
+		} else {
+			settings.Language = tag.String()
+		}
+		settings.Version = 2
+	}
+	if settings.Version < 3 {
+		// discoverability renamed by version 3 is rewritten in stored rows on startup
+		settings.Version = 3
+	}
+}
+
+// applyAccountSettingsPatch changes only fields which are set in the patch.
+func applyAccountSettingsPatch(settings *entity.AccountSettings, patch *AccountSettingsPatch) {
+	if patch.Language != nil {
+		settings.Language = *patch.Language
+	}
+	if patch.Theme != nil {
+		settings.Theme = *patch.Theme
+	}
+	if patch.Discoverability != nil {
+		settings.Discoverability = *patch.Discoverability
+	}
+	if patch.SharePresence != nil {
+		settings.SharePresence = *patch.SharePresence
+	}
+	if patch.Notifications != nil {
+		if patch.Notifications.IncomingTransfers != nil {
+			settings.Notifications.IncomingTransfers = *patch.Notifications.IncomingTransfers
+		}
+		if patch.Notifications.CompletedTransfers != nil {
+			settings.Notifications.CompletedTransfers = *patch.Notifications.CompletedTransfers
+		}
+		if patch.Notifications.SecurityEvents != nil {
+			settings.Notifications.SecurityEvents = *patch.Notifications.SecurityEvents
+		}
+	}
+	if patch.AutoAccept != nil {
+		settings.AutoAccept = *patch.AutoAccept
+	}
+	if patch.DownloadAction != nil {
+		settings.DownloadAction = *patch.DownloadAction
+	}
+	if patch.MaxIncomingFileSize != nil {
+		settings.MaxIncomingFileSize = *patch.MaxIncomingFileSize
+	}
+	if patch.NonContactTransfers != nil {
+		settings.NonContactTransfers = *patch.NonContactTransfers
+	}
+}
+
+// validateAccountSettings normalizes language tag and reports every invalid setting
+// as invalid field of ErrAccountSettingsInvalid.
+func validateAccountSettings(settings *entity.AccountSettings) error {
+	var fields []errs.FieldError
+
+	tag, err := language.Parse(settings.Language)
+	if err != nil {
+		fields = append(fields, errs.FieldError{Field: "language", Code: "invalid_language", Message: "language must be BCP 47 language tag"})
+	} else {
+		settings.Language = tag.String()
+	}
+
+	enums := []struct {
+		field   string
+		value   string
+		allowed []string
+	}{
+		{field: "theme", value: settings.Theme, allowed: entity.Themes},
+		{field: "discoverability", value: settings.Discoverability, allowed: entity.Discoverabilities},
+		{field: "autoAccept", value: settings.AutoAccept, allowed: entity.AutoAcceptRules},
+		{field: "downloadAction", value: settings.DownloadAction, allowed: entity.DownloadActions},
+		{field: "nonContactTransfers", value: settings.NonContactTransfers, allowed: entity.NonContactTransferPolicies},
+	}
+	for _, enum := range enums {
+		if !contains(enum.allowed, enum.value) {
+			fields = append(fields, errs.FieldError{
+				Field:   enum.field,
+				Code:    "invalid_value",
+				Message: fmt.Sprintf("%s must be one of: %s", enum.field, strings.Join(enum.allowed, ", ")),
+			})
+		}
+	}
+
+	if settings.MaxIncomingFileSize < 0 {
+		fields = append(fields, errs.FieldError{Field: "maxIncomingFileSize", Code: "invalid_value", Message: "maxIncomingFileSize must not be negative"})
+	}
+
+	if len(fields) > 0 {
+		return ErrAccountSettingsInvalid.WithFields(fields...)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
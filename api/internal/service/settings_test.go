@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"testing"
+)
+
+func TestUpgradeAccountSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     entity.AccountSettings
+		wantLanguage string
+	}{
+		{name: "version 1 with valid language", settings: entity.AccountSettings{Version: 1, Language: "en-us"}, wantLanguage: "en-US"},
+		{name: "version 1 with free-form language", settings: entity.AccountSettings{Version: 1, Language: "english"}, wantLanguage: entity.DefaultLanguage},
+		{name: "version 2", settings: entity.AccountSettings{Version: 2, Language: "uk"}, wantLanguage: "uk"},
+		{name: "current version", settings: entity.AccountSettings{Version: entity.AccountSettingsVersion, Language: "de"}, wantLanguage: "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := tt.settings
+			upgradeAccountSettings(&settings)
+
+			if settings.Version != entity.AccountSettingsVersion {
+				t.Errorf("version = %d, want %d", settings.Version, entity.AccountSettingsVersion)
+			}
+			if settings.Language != tt.wantLanguage {
+				t.Errorf("language = %q, want %q", settings.Language, tt.wantLanguage)
+			}
+		})
+	}
+}
@@ -3,19 +3,49 @@ package service
 import (
 	"context"
 	"github.com/atlant1da-404/droplet/internal/entity"
+	"time"
 )
 
 type Storages struct {
-	UserStorage    UserStorage
-	AccountStorage AccountStorage
-	NodeStorage    NodeStorage
+	UserStorage           UserStorage
+	AccountStorage        AccountStorage
+	NodeStorage           NodeStorage
+	RefreshTokenStorage   RefreshTokenStorage
+	RevokedTokenStorage   RevokedTokenStorage
+	UserTokenStorage      UserTokenStorage
+	RecoveryCodeStorage   RecoveryCodeStorage
+	LoginThrottleStorage  LoginThrottleStorage
+	UserIdentityStorage   UserIdentityStorage
+	OIDCLoginStateStorage OIDCLoginStateStorage
+
+	PersonalAccessTokenStorage PersonalAccessTokenStorage
+	AuditEventStorage          AuditEventStorage
+	DataExportStorage          DataExportStorage
+	DeviceStorage              DeviceStorage
+	DeviceKeyStorage           DeviceKeyStorage
+	ContactStorage             ContactStorage
+	BlockStorage               BlockStorage
+	GroupStorage               GroupStorage
+	RateLimitStorage           RateLimitStorage
 }
 
 type UserStorage interface {
 	// GetUser provides getting user from storage via requested filters.
 	GetUser(ctx context.Context, filter *GetUserFilter) (*entity.User, error)
-	// CreateUser provides creating user in the system.
+	// CreateUser provides creating user in the system. Returns nil when email is already taken.
 	CreateUser(ctx context.Context, user *entity.User) (*entity.User, error)
+	// UpdateUser provides saving all fields of existing user.
+	UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error)
+	// ChangeUserEmail provides saving user with changed email and moving transfers of oldEmail
+	// to the new one in single transaction, so whoever registers oldEmail later does not receive them.
+	// Returns nil when the new email is already taken.
+	ChangeUserEmail(ctx context.Context, user *entity.User, oldEmail string) (*entity.User, error)
+	// ListUsers provides getting page of users ordered by email and total count of users.
+	ListUsers(ctx context.Context, filter *ListUsersFilter) ([]entity.User, int64, error)
+	// DeleteUser provides erasing user together with every record which belongs to the user.
+	DeleteUser(ctx context.Context, userId string) error
+	// SearchUsers provides getting active users the searcher may find, ordered by username.
+	SearchUsers(ctx context.Context, filter *SearchUsersFilter) ([]entity.User, error)
 }
 
 type GetUserFilter struct {
@@ -23,13 +53,38 @@ type GetUserFilter struct {
 	UserId string
 }
 
+// SearchUsersFilter - either UsernamePrefix, Email or UserIds is set. Users who blocked SearcherId never match.
+// Users with any account whose discoverability is one of Hidden never match, users with any account
+// whose discoverability is one of ContactsOfContacts match only contacts of SearcherId or of their contacts.
+type SearchUsersFilter struct {
+	SearcherId         string
+	UsernamePrefix     string
+	Email              string
+	UserIds            []string
+	Hidden             []string
+	ContactsOfContacts []string
+	Limit              int
+}
+
+type ListUsersFilter struct {
+	Limit  int
+	Offset int
+	// DeletionDueBefore selects only users whose scheduled deletion is due before given time.
+	DeletionDueBefore *time.Time
+	// UserIds selects only users with given ids.
+	UserIds []string
+}
+
 type AccountStorage interface {
 	// CreateAccount provides creating account in the system.
 	CreateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error)
 	// GetAccount provides logic of getting account from storage.
 	GetAccount(ctx context.Context, filter *GetAccountFilter) (*entity.Account, error)
-	// UpdateAccount provides logic of updating account in storage.
-	UpdateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error)
+	// UpdateAccount provides updating account settings and bumping account version, unless expectedVersion
+	// is zero it is done only when stored version equals expectedVersion. Returns nil on version mismatch.
+	UpdateAccount(ctx context.Context, account *entity.Account, expectedVersion int) (*entity.Account, error)
+	// ListAccounts provides getting accounts of the user with their devices and settings.
+	ListAccounts(ctx context.Context, filter *ListAccountsFilter) ([]entity.Account, error)
 }
 
 type GetAccountFilter struct {
@@ -37,7 +92,317 @@ type GetAccountFilter struct {
 	UserId    string
 }
 
+type ListAccountsFilter struct {
+	UserId string
+}
+
+type DeviceStorage interface {
+	// CreateDevice provides registering device of the account. Returns nil if account already has maxDevices devices.
+	CreateDevice(ctx context.Context, device *entity.AccountDevices, maxDevices int) (*entity.AccountDevices, error)
+	// GetDevice provides getting device of the account. Returns nil if device is not found.
+	GetDevice(ctx context.Context, filter *GetDeviceFilter) (*entity.AccountDevices, error)
+	// ListDevices provides getting devices of the account.
+	ListDevices(ctx context.Context, filter *ListDevicesFilter) ([]entity.AccountDevices, error)
+	// UpdateDevice provides updating device.
+	UpdateDevice(ctx context.Context, device *entity.AccountDevices) (*entity.AccountDevices, error)
+	// DeleteDevice provides removing device of the account and reports whether it was found.
+	DeleteDevice(ctx context.Context, filter *DeleteDeviceFilter) (bool, error)
+	// TouchDevice provides marking device online as of seenAt.
+	TouchDevice(ctx context.Context, id string, seenAt time.Time) error
+	// MarkStaleDevicesOffline provides marking devices offline when they were last seen before given time.
+	MarkStaleDevicesOffline(ctx context.Context, before time.Time) (int64, error)
+}
+
+type GetDeviceFilter struct {
+	Id         string
+	AccountId  string
+	MacAddress string
+}
+
+type ListDevicesFilter struct {
+	AccountId string
+}
+
+type DeleteDeviceFilter struct {
+	Id        string
+	AccountId string
+}
+
+type DeviceKeyStorage interface {
+	// RotateDeviceKey provides revoking current key of the device and storing new one in single transaction.
+	RotateDeviceKey(ctx context.Context, key *entity.DeviceKey) (*entity.DeviceKey, error)
+	// ListDeviceKeys provides getting device keys, newest first.
+	ListDeviceKeys(ctx context.Context, filter *ListDeviceKeysFilter) ([]entity.DeviceKey, error)
+	// RevokeDeviceKeys provides revoking current key of the device.
+	RevokeDeviceKeys(ctx context.Context, deviceId string, revokedAt time.Time) error
+}
+
+type ListDeviceKeysFilter struct {
+	UserId    string
+	AccountId string
+	DeviceId  string
+	// IncludeRevoked adds revoked keys, so the result is the history of key changes.
+	IncludeRevoked bool
+}
+
 type NodeStorage interface {
 	// CreateNode provides creating new node in system.
 	CreateNode(ctx context.Context, node *entity.Node) (*entity.Node, error)
+	// CreateNodes provides creating several nodes in single transaction.
+	CreateNodes(ctx context.Context, nodes []entity.Node) ([]entity.Node, error)
+	// GetNode provides getting node via requested filters.
+	GetNode(ctx context.Context, filter *GetNodeFilter) (*entity.Node, error)
+	// UpdateNode provides saving all fields of existing node.
+	UpdateNode(ctx context.Context, node *entity.Node) (*entity.Node, error)
+	// ListNodes provides getting nodes via requested filters, newest first.
+	ListNodes(ctx context.Context, filter *ListNodesFilter) ([]entity.Node, error)
+}
+
+type GetNodeFilter struct {
+	Id            string
+	ReceiverEmail string
+	Status        string
+}
+
+// ListNodesFilter - Email matches nodes where the user is either sender or receiver.
+type ListNodesFilter struct {
+	Email         string
+	SenderEmail   string
+	ReceiverEmail string
+	Status        string
+}
+
+type ContactStorage interface {
+	// CreateContact provides creating contact request.
+	CreateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error)
+	// GetContact provides getting contact request via requested filters.
+	GetContact(ctx context.Context, filter *GetContactFilter) (*entity.Contact, error)
+	// ListContacts provides getting contact requests via requested filters, newest first.
+	ListContacts(ctx context.Context, filter *ListContactsFilter) ([]entity.Contact, error)
+	// UpdateContact provides saving all fields of existing contact request.
+	UpdateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error)
+	// DeleteContact provides deleting contact requests via requested filters and reports whether any was deleted.
+	DeleteContact(ctx context.Context, filter *GetContactFilter) (bool, error)
+}
+
+// GetContactFilter - UserId matches requests where the user is either requester or addressee,
+// together with OtherUserId it matches request between the two users regardless of its direction.
+type GetContactFilter struct {
+	Id          string
+	UserId      string
+	OtherUserId string
+}
+
+type ListContactsFilter struct {
+	UserId      string
+	RequesterId string
+	AddresseeId string
+	Statuses    []string
+}
+
+type BlockStorage interface {
+	// CreateBlock provides blocking user and deleting contact requests between both users in single transaction,
+	// blocking already blocked user changes nothing.
+	CreateBlock(ctx context.Context, block *entity.Block) error
+	// GetBlock provides getting block of BlockedUserId by UserId.
+	GetBlock(ctx context.Context, filter *GetBlockFilter) (*entity.Block, error)
+	// ListBlocks provides getting users blocked by the user, newest first.
+	ListBlocks(ctx context.Context, userId string) ([]entity.Block, error)
+	// DeleteBlock provides unblocking user and reports whether the user was blocked.
+	DeleteBlock(ctx context.Context, filter *GetBlockFilter) (bool, error)
+}
+
+type GetBlockFilter struct {
+	UserId        string
+	BlockedUserId string
+}
+
+type GroupStorage interface {
+	// CreateGroup provides creating group together with its members.
+	CreateGroup(ctx context.Context, group *entity.Group) (*entity.Group, error)
+	// GetGroup provides getting group with its members via requested filters.
+	GetGroup(ctx context.Context, filter *GetGroupFilter) (*entity.Group, error)
+	// ListGroups provides getting groups the user is member of with their members, newest first.
+	ListGroups(ctx context.Context, userId string) ([]entity.Group, error)
+	// UpdateGroup provides saving name of existing group.
+	UpdateGroup(ctx context.Context, group *entity.Group) (*entity.Group, error)
+	// DeleteGroup provides deleting group together with its members.
+	DeleteGroup(ctx context.Context, groupId string) error
+	// CreateGroupMember provides adding member to the group. Returns nil if group already has maxMembers members.
+	CreateGroupMember(ctx context.Context, member *entity.GroupMember, maxMembers int) (*entity.GroupMember, error)
+	// UpdateGroupMember provides saving role of existing member.
+	UpdateGroupMember(ctx context.Context, member *entity.GroupMember) (*entity.GroupMember, error)
+	// DeleteGroupMember provides removing member from the group and reports whether the user was a member.
+	DeleteGroupMember(ctx context.Context, filter *GetGroupMemberFilter) (bool, error)
+}
+
+// GetGroupFilter - MemberId matches the group only if the user is its member.
+type GetGroupFilter struct {
+	Id       string
+	MemberId string
+}
+
+type GetGroupMemberFilter struct {
+	GroupId string
+	UserId  string
+}
+
+type RefreshTokenStorage interface {
+	// CreateRefreshToken provides creating refresh token in storage.
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) (*entity.RefreshToken, error)
+	// GetRefreshToken provides getting refresh token from storage via requested filters.
+	GetRefreshToken(ctx context.Context, filter *GetRefreshTokenFilter) (*entity.RefreshToken, error)
+	// RotateRefreshToken atomically revokes refresh token with given id and stores its successor.
+	// Returns nil if the token has already been revoked.
+	RotateRefreshToken(ctx context.Context, id string, token *entity.RefreshToken) (*entity.RefreshToken, error)
+	// RevokeRefreshTokenFamily revokes all refresh tokens that descend from the same sign in.
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	// RevokeUserRefreshTokens revokes all active refresh tokens of the user and returns ids of revoked families.
+	RevokeUserRefreshTokens(ctx context.Context, filter *RevokeUserRefreshTokensFilter) ([]string, error)
+}
+
+type GetRefreshTokenFilter struct {
+	TokenHash string
+}
+
+type RevokeUserRefreshTokensFilter struct {
+	UserId         string
+	ExceptFamilyId string
+}
+
+type RevokedTokenStorage interface {
+	// CreateRevokedTokens provides adding entries to access token denylist.
+	CreateRevokedTokens(ctx context.Context, tokens []entity.RevokedToken) error
+	// IsTokenRevoked checks whether access token or its session is in denylist.
+	IsTokenRevoked(ctx context.Context, filter *IsTokenRevokedFilter) (bool, error)
+	// DeleteExpiredRevokedTokens removes denylist entries which expired before given time.
+	DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+type IsTokenRevokedFilter struct {
+	TokenId   string
+	SessionId string
+}
+
+type UserTokenStorage interface {
+	// CreateUserToken provides creating single-use token and invalidates previous tokens of the same purpose.
+	CreateUserToken(ctx context.Context, token *entity.UserToken) (*entity.UserToken, error)
+	// ConsumeUserToken marks valid token as used and returns it. Returns nil if token is unknown, used or expired.
+	ConsumeUserToken(ctx context.Context, filter *ConsumeUserTokenFilter) (*entity.UserToken, error)
+}
+
+type ConsumeUserTokenFilter struct {
+	TokenHash string
+	Purpose   string
+}
+
+type RecoveryCodeStorage interface {
+	// ReplaceRecoveryCodes removes existing recovery codes of the user and stores given ones.
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codes []entity.RecoveryCode) error
+	// ConsumeRecoveryCode marks unused recovery code as used and reports whether it was found.
+	ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error)
+}
+
+type LoginThrottleStorage interface {
+	// GetLoginThrottles provides getting failed sign in counters by their keys.
+	GetLoginThrottles(ctx context.Context, keys []string) ([]entity.LoginThrottle, error)
+	// RegisterLoginFailure atomically increments failures counter of the key. Counter starts over
+	// when the previous failure happened before resetBefore.
+	RegisterLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*entity.LoginThrottle, error)
+	// LockLoginThrottle provides locking sign in for the key until given time.
+	LockLoginThrottle(ctx context.Context, key string, until time.Time) error
+	// DeleteLoginThrottle provides resetting failures counter and lock of the key.
+	DeleteLoginThrottle(ctx context.Context, key string) error
+}
+
+type RateLimitStorage interface {
+	// HitRateLimit atomically counts request of the key in window which started at windowStart.
+	// Counter starts over when the previous request belongs to an older window.
+	HitRateLimit(ctx context.Context, key string, windowStart time.Time) (*entity.RateLimit, error)
+}
+
+type UserIdentityStorage interface {
+	// GetUserIdentity provides getting external identity by provider and subject.
+	GetUserIdentity(ctx context.Context, filter *GetUserIdentityFilter) (*entity.UserIdentity, error)
+	// CreateUserIdentity provides linking external identity to the user.
+	CreateUserIdentity(ctx context.Context, identity *entity.UserIdentity) (*entity.UserIdentity, error)
+}
+
+type GetUserIdentityFilter struct {
+	Provider string
+	Subject  string
+}
+
+type OIDCLoginStateStorage interface {
+	// CreateOIDCLoginState provides storing parameters of started OpenID Connect login.
+	CreateOIDCLoginState(ctx context.Context, state *entity.OIDCLoginState) (*entity.OIDCLoginState, error)
+	// ConsumeOIDCLoginState marks valid login state as used and returns it. Returns nil if state is unknown, used or expired.
+	ConsumeOIDCLoginState(ctx context.Context, filter *ConsumeOIDCLoginStateFilter) (*entity.OIDCLoginState, error)
+	// DeleteExpiredOIDCLoginStates removes login states which expired before given time.
+	DeleteExpiredOIDCLoginStates(ctx context.Context, before time.Time) (int64, error)
+}
+
+type ConsumeOIDCLoginStateFilter struct {
+	StateHash string
+	Provider  string
+}
+
+type PersonalAccessTokenStorage interface {
+	// CreatePersonalAccessToken provides creating personal access token in storage.
+	CreatePersonalAccessToken(ctx context.Context, token *entity.PersonalAccessToken) (*entity.PersonalAccessToken, error)
+	// GetPersonalAccessToken provides getting personal access token from storage via requested filters.
+	GetPersonalAccessToken(ctx context.Context, filter *GetPersonalAccessTokenFilter) (*entity.PersonalAccessToken, error)
+	// ListPersonalAccessTokens provides getting personal access tokens of the user, newest first.
+	ListPersonalAccessTokens(ctx context.Context, filter *ListPersonalAccessTokensFilter) ([]entity.PersonalAccessToken, error)
+	// RevokePersonalAccessToken revokes active personal access token and reports whether it was found.
+	RevokePersonalAccessToken(ctx context.Context, filter *RevokePersonalAccessTokenFilter) (bool, error)
+	// TouchPersonalAccessToken provides updating time when personal access token was last used.
+	TouchPersonalAccessToken(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type GetPersonalAccessTokenFilter struct {
+	TokenHash string
+}
+
+type ListPersonalAccessTokensFilter struct {
+	UserId string
+}
+
+type RevokePersonalAccessTokenFilter struct {
+	Id     string
+	UserId string
+}
+
+type AuditEventStorage interface {
+	// CreateAuditEvent provides storing audit record.
+	CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) (*entity.AuditEvent, error)
+	// ListAuditEvents provides getting audit records of the user, oldest first.
+	ListAuditEvents(ctx context.Context, filter *ListAuditEventsFilter) ([]entity.AuditEvent, error)
+}
+
+type ListAuditEventsFilter struct {
+	UserId string
+}
+
+type DataExportStorage interface {
+	// CreateDataExport provides storing export request.
+	CreateDataExport(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error)
+	// GetDataExport provides getting export. Returns nil if export is not found or its archive has expired.
+	GetDataExport(ctx context.Context, filter *GetDataExportFilter) (*entity.DataExport, error)
+	// ClaimPendingDataExport marks the oldest pending export as processing and returns it. Returns nil if nothing is pending.
+	ClaimPendingDataExport(ctx context.Context) (*entity.DataExport, error)
+	// UpdateDataExport provides updating export.
+	UpdateDataExport(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error)
+	// FailStaleDataExports marks exports which have been processing since before claimedBefore as failed,
+	// so exports abandoned by crashed instance do not block new requests of their users.
+	FailStaleDataExports(ctx context.Context, claimedBefore, expiresAt time.Time) (int64, error)
+	// DeleteExpiredDataExports provides deleting exports whose archive expired before given time.
+	DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error)
+}
+
+type GetDataExportFilter struct {
+	Id        string
+	UserId    string
+	TokenHash string
+	Statuses  []string
 }
@@ -0,0 +1,919 @@
+package service
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/config"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/pkg/auth"
+	"github.com/atlant1da-404/droplet/pkg/hash"
+	"github.com/atlant1da-404/droplet/pkg/logger"
+	"github.com/atlant1da-404/droplet/pkg/mail"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	_testEmail    = "user@droplet.local"
+	_testPassword = "correct horse battery"
+	_testClientIP = "10.0.0.1"
+)
+
+// In-memory storages used by service tests. Every fake embeds its interface,
+// so a test calling a method which is not faked fails with nil pointer panic.
+
+type fakeUserStorage struct {
+	UserStorage
+	users map[string]*entity.User
+	// emailChanges maps old email to the new one for every ChangeUserEmail call.
+	emailChanges map[string]string
+	// discoverable holds ids of users SearchUsers finds, discoverability itself is tested with storage.
+	discoverable map[string]bool
+}
+
+func newFakeUserStorage() *fakeUserStorage {
+	return &fakeUserStorage{
+		users:        map[string]*entity.User{},
+		emailChanges: map[string]string{},
+		discoverable: map[string]bool{},
+	}
+}
+
+func (f *fakeUserStorage) GetUser(ctx context.Context, filter *GetUserFilter) (*entity.User, error) {
+	for _, user := range f.users {
+		if filter.UserId != "" && user.Id != filter.UserId {
+			continue
+		}
+		if filter.Email != "" && user.Email != filter.Email {
+			continue
+		}
+		copied := *user
+		return &copied, nil
+	}
+
+	return nil, nil
+}
+
+func (f *fakeUserStorage) SearchUsers(ctx context.Context, filter *SearchUsersFilter) ([]entity.User, error) {
+	userIds := make(map[string]bool, len(filter.UserIds))
+	for _, userId := range filter.UserIds {
+		userIds[userId] = true
+	}
+
+	users := make([]entity.User, 0)
+	for _, user := range f.users {
+		if user.Id == filter.SearcherId || !f.discoverable[user.Id] {
+			continue
+		}
+		if filter.Email != "" && user.Email != filter.Email {
+			continue
+		}
+		if len(userIds) > 0 && !userIds[user.Id] {
+			continue
+		}
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+
+	return users, nil
+}
+
+func (f *fakeUserStorage) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	if f.emailTaken(user.Id, user.Email) {
+		return nil, nil
+	}
+	if user.Id == "" {
+		user.Id = uuid.NewString()
+	}
+	copied := *user
+	f.users[user.Id] = &copied
+
+	return user, nil
+}
+
+func (f *fakeUserStorage) UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	copied := *user
+	f.users[user.Id] = &copied
+
+	return user, nil
+}
+
+func (f *fakeUserStorage) ListUsers(ctx context.Context, filter *ListUsersFilter) ([]entity.User, int64, error) {
+	var users []entity.User
+	for _, user := range f.users {
+		if filter.DeletionDueBefore != nil && (user.DeletionScheduledFor == nil || !user.DeletionScheduledFor.Before(*filter.DeletionDueBefore)) {
+			continue
+		}
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	total := int64(len(users))
+	if filter.Offset > len(users) {
+		filter.Offset = len(users)
+	}
+	users = users[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(users) {
+		users = users[:filter.Limit]
+	}
+
+	return users, total, nil
+}
+
+func (f *fakeUserStorage) DeleteUser(ctx context.Context, userId string) error {
+	delete(f.users, userId)
+	return nil
+}
+
+func (f *fakeUserStorage) ChangeUserEmail(ctx context.Context, user *entity.User, oldEmail string) (*entity.User, error) {
+	if f.emailTaken(user.Id, user.Email) {
+		return nil, nil
+	}
+	f.emailChanges[oldEmail] = user.Email
+	return f.UpdateUser(ctx, user)
+}
+
+// emailTaken mirrors unique index on lower(email), which GetUser filter by exact email does not see.
+func (f *fakeUserStorage) emailTaken(userId, email string) bool {
+	for _, user := range f.users {
+		if user.Id != userId && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeAccountStorage struct {
+	AccountStorage
+	accounts []entity.Account
+}
+
+func (f *fakeAccountStorage) GetAccount(ctx context.Context, filter *GetAccountFilter) (*entity.Account, error) {
+	for _, account := range f.accounts {
+		if account.Id == filter.AccountId && account.UserId == filter.UserId {
+			return copyAccount(account), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// UpdateAccount compares and bumps version like the storage does, expectedVersion zero skips the comparison.
+func (f *fakeAccountStorage) UpdateAccount(ctx context.Context, account *entity.Account, expectedVersion int) (*entity.Account, error) {
+	for i := range f.accounts {
+		if f.accounts[i].Id != account.Id {
+			continue
+		}
+		if expectedVersion != 0 && f.accounts[i].Version != expectedVersion {
+			return nil, nil
+		}
+
+		updated := *copyAccount(*account)
+		updated.Version = f.accounts[i].Version + 1
+		f.accounts[i] = updated
+		return copyAccount(updated), nil
+	}
+
+	return nil, nil
+}
+
+func (f *fakeAccountStorage) ListAccounts(ctx context.Context, filter *ListAccountsFilter) ([]entity.Account, error) {
+	accounts := make([]entity.Account, 0)
+	for _, account := range f.accounts {
+		if account.UserId == filter.UserId {
+			accounts = append(accounts, *copyAccount(account))
+		}
+	}
+
+	return accounts, nil
+}
+
+// copyAccount copies settings and devices as well, so changes of the service are stored only by update.
+func copyAccount(account entity.Account) *entity.Account {
+	if account.AccountSettings != nil {
+		settings := *account.AccountSettings
+		account.AccountSettings = &settings
+	}
+	account.AccountDevices = append([]entity.AccountDevices(nil), account.AccountDevices...)
+
+	return &account
+}
+
+// fakeDeviceStorage keeps devices inside accounts of fakeAccountStorage, like the database does,
+// and bumps account version on every change of devices.
+type fakeDeviceStorage struct {
+	DeviceStorage
+	accounts *fakeAccountStorage
+}
+
+func (f *fakeDeviceStorage) account(accountId string) *entity.Account {
+	for i := range f.accounts.accounts {
+		if f.accounts.accounts[i].Id == accountId {
+			return &f.accounts.accounts[i]
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeDeviceStorage) CreateDevice(ctx context.Context, device *entity.AccountDevices, maxDevices int) (*entity.AccountDevices, error) {
+	account := f.account(device.AccountID)
+	if len(account.AccountDevices) >= maxDevices {
+		return nil, nil
+	}
+	device.Id = uuid.NewString()
+	account.AccountDevices = append(account.AccountDevices, *device)
+	account.Version++
+
+	return device, nil
+}
+
+func (f *fakeDeviceStorage) GetDevice(ctx context.Context, filter *GetDeviceFilter) (*entity.AccountDevices, error) {
+	account := f.account(filter.AccountId)
+	if account == nil {
+		return nil, nil
+	}
+	for _, device := range account.AccountDevices {
+		if (filter.Id == "" || device.Id == filter.Id) && (filter.MacAddress == "" || device.MacAddress == filter.MacAddress) {
+			copied := device
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakeDeviceStorage) ListDevices(ctx context.Context, filter *ListDevicesFilter) ([]entity.AccountDevices, error) {
+	return append([]entity.AccountDevices{}, f.account(filter.AccountId).AccountDevices...), nil
+}
+
+func (f *fakeDeviceStorage) UpdateDevice(ctx context.Context, device *entity.AccountDevices) (*entity.AccountDevices, error) {
+	account := f.account(device.AccountID)
+	for i := range account.AccountDevices {
+		if account.AccountDevices[i].Id == device.Id {
+			// only fields clients may change are written, like the storage does
+			account.AccountDevices[i].Name = device.Name
+			account.AccountDevices[i].Active = device.Active
+			account.Version++
+		}
+	}
+
+	return device, nil
+}
+
+func (f *fakeDeviceStorage) DeleteDevice(ctx context.Context, filter *DeleteDeviceFilter) (bool, error) {
+	account := f.account(filter.AccountId)
+	for i, device := range account.AccountDevices {
+		if device.Id == filter.Id {
+			account.AccountDevices = append(account.AccountDevices[:i], account.AccountDevices[i+1:]...)
+			account.Version++
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type fakeDeviceKeyStorage struct {
+	DeviceKeyStorage
+	keys []entity.DeviceKey
+}
+
+func (f *fakeDeviceKeyStorage) ListDeviceKeys(ctx context.Context, filter *ListDeviceKeysFilter) ([]entity.DeviceKey, error) {
+	keys := make([]entity.DeviceKey, 0)
+	for _, key := range f.keys {
+		if (filter.UserId != "" && key.UserId != filter.UserId) ||
+			(filter.AccountId != "" && key.AccountId != filter.AccountId) ||
+			(filter.DeviceId != "" && key.DeviceId != filter.DeviceId) ||
+			(!filter.IncludeRevoked && key.RevokedAt != nil) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (f *fakeDeviceKeyStorage) RotateDeviceKey(ctx context.Context, key *entity.DeviceKey) (*entity.DeviceKey, error) {
+	err := f.RevokeDeviceKeys(ctx, key.DeviceId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	key.Id = uuid.NewString()
+	key.CreatedAt = time.Now()
+	f.keys = append(f.keys, *key)
+
+	return key, nil
+}
+
+func (f *fakeDeviceKeyStorage) RevokeDeviceKeys(ctx context.Context, deviceId string, revokedAt time.Time) error {
+	for i := range f.keys {
+		if f.keys[i].DeviceId == deviceId && f.keys[i].RevokedAt == nil {
+			f.keys[i].RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+type fakeNodeStorage struct {
+	NodeStorage
+	nodes []entity.Node
+}
+
+func (f *fakeNodeStorage) ListNodes(ctx context.Context, filter *ListNodesFilter) ([]entity.Node, error) {
+	var nodes []entity.Node
+	for _, node := range f.nodes {
+		if filter.Email != "" && node.SenderEmail != filter.Email && node.ReceiverEmail != filter.Email {
+			continue
+		}
+		if (filter.SenderEmail != "" && node.SenderEmail != filter.SenderEmail) ||
+			(filter.ReceiverEmail != "" && node.ReceiverEmail != filter.ReceiverEmail) ||
+			(filter.Status != "" && node.Status != filter.Status) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+type fakeContactStorage struct {
+	ContactStorage
+	contacts []entity.Contact
+}
+
+func (f *fakeContactStorage) CreateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error) {
+	contact.Id = uuid.NewString()
+	contact.CreatedAt = time.Now()
+	f.contacts = append(f.contacts, *contact)
+
+	return contact, nil
+}
+
+func (f *fakeContactStorage) GetContact(ctx context.Context, filter *GetContactFilter) (*entity.Contact, error) {
+	for _, contact := range f.contacts {
+		if filter.Id != "" && contact.Id != filter.Id {
+			continue
+		}
+		if filter.UserId != "" && contact.RequesterId != filter.UserId && contact.AddresseeId != filter.UserId {
+			continue
+		}
+		if filter.OtherUserId != "" && contact.RequesterId != filter.OtherUserId && contact.AddresseeId != filter.OtherUserId {
+			continue
+		}
+		copied := contact
+		return &copied, nil
+	}
+
+	return nil, nil
+}
+
+func (f *fakeContactStorage) ListContacts(ctx context.Context, filter *ListContactsFilter) ([]entity.Contact, error) {
+	contacts := make([]entity.Contact, 0)
+	for _, contact := range f.contacts {
+		if (filter.UserId != "" && contact.RequesterId != filter.UserId && contact.AddresseeId != filter.UserId) ||
+			(filter.RequesterId != "" && contact.RequesterId != filter.RequesterId) ||
+			(filter.AddresseeId != "" && contact.AddresseeId != filter.AddresseeId) {
+			continue
+		}
+		for _, status := range filter.Statuses {
+			if contact.Status == status {
+				contacts = append(contacts, contact)
+				break
+			}
+		}
+	}
+
+	return contacts, nil
+}
+
+func (f *fakeContactStorage) UpdateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error) {
+	for i := range f.contacts {
+		if f.contacts[i].Id == contact.Id {
+			f.contacts[i] = *contact
+		}
+	}
+
+	return contact, nil
+}
+
+type fakeBlockStorage struct {
+	BlockStorage
+	blocks   []entity.Block
+	contacts *fakeContactStorage
+}
+
+func (f *fakeBlockStorage) CreateBlock(ctx context.Context, block *entity.Block) error {
+	existing, _ := f.GetBlock(ctx, &GetBlockFilter{UserId: block.UserId, BlockedUserId: block.BlockedUserId})
+	if existing != nil {
+		return nil
+	}
+
+	block.Id = uuid.NewString()
+	block.CreatedAt = time.Now()
+	f.blocks = append(f.blocks, *block)
+
+	contacts := f.contacts.contacts[:0]
+	for _, contact := range f.contacts.contacts {
+		if otherContactUserId(&contact, block.UserId) != block.BlockedUserId {
+			contacts = append(contacts, contact)
+		}
+	}
+	f.contacts.contacts = contacts
+
+	return nil
+}
+
+func (f *fakeBlockStorage) GetBlock(ctx context.Context, filter *GetBlockFilter) (*entity.Block, error) {
+	for _, block := range f.blocks {
+		if block.UserId == filter.UserId && block.BlockedUserId == filter.BlockedUserId {
+			copied := block
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+type fakeRefreshTokenStorage struct {
+	RefreshTokenStorage
+	tokens map[string]*entity.RefreshToken
+}
+
+func newFakeRefreshTokenStorage() *fakeRefreshTokenStorage {
+	return &fakeRefreshTokenStorage{tokens: map[string]*entity.RefreshToken{}}
+}
+
+func (f *fakeRefreshTokenStorage) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) (*entity.RefreshToken, error) {
+	token.Id = uuid.NewString()
+	copied := *token
+	f.tokens[token.Id] = &copied
+
+	return token, nil
+}
+
+func (f *fakeRefreshTokenStorage) GetRefreshToken(ctx context.Context, filter *GetRefreshTokenFilter) (*entity.RefreshToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == filter.TokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakeRefreshTokenStorage) RotateRefreshToken(ctx context.Context, id string, token *entity.RefreshToken) (*entity.RefreshToken, error) {
+	old := f.tokens[id]
+	if old == nil || old.RevokedAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+
+	return f.CreateRefreshToken(ctx, token)
+}
+
+func (f *fakeRefreshTokenStorage) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	now := time.Now()
+	for _, token := range f.tokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeRefreshTokenStorage) RevokeUserRefreshTokens(ctx context.Context, filter *RevokeUserRefreshTokensFilter) ([]string, error) {
+	now := time.Now()
+	revoked := map[string]struct{}{}
+	for _, token := range f.tokens {
+		if token.UserId != filter.UserId || token.FamilyId == filter.ExceptFamilyId || token.RevokedAt != nil {
+			continue
+		}
+		token.RevokedAt = &now
+		revoked[token.FamilyId] = struct{}{}
+	}
+
+	familyIds := make([]string, 0, len(revoked))
+	for familyId := range revoked {
+		familyIds = append(familyIds, familyId)
+	}
+
+	return familyIds, nil
+}
+
+type fakeLoginThrottleStorage struct {
+	LoginThrottleStorage
+	throttles map[string]*entity.LoginThrottle
+}
+
+func newFakeLoginThrottleStorage() *fakeLoginThrottleStorage {
+	return &fakeLoginThrottleStorage{throttles: map[string]*entity.LoginThrottle{}}
+}
+
+func (f *fakeLoginThrottleStorage) GetLoginThrottles(ctx context.Context, keys []string) ([]entity.LoginThrottle, error) {
+	var throttles []entity.LoginThrottle
+	for _, key := range keys {
+		if throttle, ok := f.throttles[key]; ok {
+			throttles = append(throttles, *throttle)
+		}
+	}
+
+	return throttles, nil
+}
+
+func (f *fakeLoginThrottleStorage) RegisterLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*entity.LoginThrottle, error) {
+	throttle, ok := f.throttles[key]
+	if !ok || throttle.LastFailureAt.Before(resetBefore) {
+		throttle = &entity.LoginThrottle{Key: key}
+		f.throttles[key] = throttle
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = time.Now()
+
+	copied := *throttle
+	return &copied, nil
+}
+
+func (f *fakeLoginThrottleStorage) LockLoginThrottle(ctx context.Context, key string, until time.Time) error {
+	f.throttles[key].LockedUntil = &until
+	return nil
+}
+
+func (f *fakeLoginThrottleStorage) DeleteLoginThrottle(ctx context.Context, key string) error {
+	delete(f.throttles, key)
+	return nil
+}
+
+type fakeRateLimitStorage struct {
+	RateLimitStorage
+	limits map[string]*entity.RateLimit
+}
+
+func (f *fakeRateLimitStorage) HitRateLimit(ctx context.Context, key string, windowStart time.Time) (*entity.RateLimit, error) {
+	limit, ok := f.limits[key]
+	if !ok || limit.WindowStart.Before(windowStart) {
+		limit = &entity.RateLimit{Key: key, WindowStart: windowStart}
+		f.limits[key] = limit
+	}
+	limit.Count++
+
+	copied := *limit
+	return &copied, nil
+}
+
+type fakeUserIdentityStorage struct {
+	UserIdentityStorage
+	identities []entity.UserIdentity
+}
+
+func (f *fakeUserIdentityStorage) GetUserIdentity(ctx context.Context, filter *GetUserIdentityFilter) (*entity.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == filter.Provider && identity.Subject == filter.Subject {
+			return &identity, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakeUserIdentityStorage) CreateUserIdentity(ctx context.Context, identity *entity.UserIdentity) (*entity.UserIdentity, error) {
+	identity.Id = uuid.NewString()
+	f.identities = append(f.identities, *identity)
+
+	return identity, nil
+}
+
+type fakeOIDCLoginStateStorage struct {
+	OIDCLoginStateStorage
+	states []*entity.OIDCLoginState
+}
+
+func (f *fakeOIDCLoginStateStorage) CreateOIDCLoginState(ctx context.Context, state *entity.OIDCLoginState) (*entity.OIDCLoginState, error) {
+	state.Id = uuid.NewString()
+	copied := *state
+	f.states = append(f.states, &copied)
+
+	return state, nil
+}
+
+func (f *fakeOIDCLoginStateStorage) ConsumeOIDCLoginState(ctx context.Context, filter *ConsumeOIDCLoginStateFilter) (*entity.OIDCLoginState, error) {
+	for _, state := range f.states {
+		if state.StateHash != filter.StateHash || state.Provider != filter.Provider {
+			continue
+		}
+		if state.UsedAt != nil || time.Now().After(state.ExpiresAt) {
+			return nil, nil
+		}
+		now := time.Now()
+		state.UsedAt = &now
+
+		copied := *state
+		return &copied, nil
+	}
+
+	return nil, nil
+}
+
+type fakeAuditEventStorage struct {
+	AuditEventStorage
+	events []entity.AuditEvent
+}
+
+func (f *fakeAuditEventStorage) CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) (*entity.AuditEvent, error) {
+	event.Id = uuid.NewString()
+	event.CreatedAt = time.Now()
+	f.events = append(f.events, *event)
+
+	return event, nil
+}
+
+type fakeRevokedTokenStorage struct {
+	RevokedTokenStorage
+	tokens []entity.RevokedToken
+}
+
+func (f *fakeRevokedTokenStorage) CreateRevokedTokens(ctx context.Context, tokens []entity.RevokedToken) error {
+	f.tokens = append(f.tokens, tokens...)
+	return nil
+}
+
+func (f *fakeRevokedTokenStorage) IsTokenRevoked(ctx context.Context, filter *IsTokenRevokedFilter) (bool, error) {
+	for _, token := range f.tokens {
+		if token.TokenId == filter.TokenId || (filter.SessionId != "" && token.SessionId == filter.SessionId) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (f *fakeRevokedTokenStorage) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	kept := f.tokens[:0]
+	for _, token := range f.tokens {
+		if !token.ExpiresAt.Before(before) {
+			kept = append(kept, token)
+		}
+	}
+	deleted := int64(len(f.tokens) - len(kept))
+	f.tokens = kept
+
+	return deleted, nil
+}
+
+type fakeUserTokenStorage struct {
+	UserTokenStorage
+	tokens []*entity.UserToken
+}
+
+func (f *fakeUserTokenStorage) CreateUserToken(ctx context.Context, token *entity.UserToken) (*entity.UserToken, error) {
+	token.Id = uuid.NewString()
+	copied := *token
+	f.tokens = append(f.tokens, &copied)
+
+	return token, nil
+}
+
+func (f *fakeUserTokenStorage) ConsumeUserToken(ctx context.Context, filter *ConsumeUserTokenFilter) (*entity.UserToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash != filter.TokenHash || token.Purpose != filter.Purpose {
+			continue
+		}
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return nil, nil
+		}
+		now := time.Now()
+		token.UsedAt = &now
+
+		copied := *token
+		return &copied, nil
+	}
+
+	return nil, nil
+}
+
+type fakeRecoveryCodeStorage struct {
+	RecoveryCodeStorage
+	codes map[string][]entity.RecoveryCode
+}
+
+func newFakeRecoveryCodeStorage() *fakeRecoveryCodeStorage {
+	return &fakeRecoveryCodeStorage{codes: map[string][]entity.RecoveryCode{}}
+}
+
+func (f *fakeRecoveryCodeStorage) ReplaceRecoveryCodes(ctx context.Context, userId string, codes []entity.RecoveryCode) error {
+	f.codes[userId] = codes
+	return nil
+}
+
+func (f *fakeRecoveryCodeStorage) ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error) {
+	codes := f.codes[userId]
+	for i := range codes {
+		if codes[i].CodeHash == codeHash && codes[i].UsedAt == nil {
+			now := time.Now()
+			codes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type fakePersonalAccessTokenStorage struct {
+	PersonalAccessTokenStorage
+	tokens []*entity.PersonalAccessToken
+}
+
+func (f *fakePersonalAccessTokenStorage) CreatePersonalAccessToken(ctx context.Context, token *entity.PersonalAccessToken) (*entity.PersonalAccessToken, error) {
+	token.Id = uuid.NewString()
+	token.CreatedAt = time.Now()
+	copied := *token
+	f.tokens = append(f.tokens, &copied)
+
+	return token, nil
+}
+
+func (f *fakePersonalAccessTokenStorage) GetPersonalAccessToken(ctx context.Context, filter *GetPersonalAccessTokenFilter) (*entity.PersonalAccessToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == filter.TokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakePersonalAccessTokenStorage) RevokePersonalAccessToken(ctx context.Context, filter *RevokePersonalAccessTokenFilter) (bool, error) {
+	for _, token := range f.tokens {
+		if token.Id == filter.Id && token.UserId == filter.UserId && token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (f *fakePersonalAccessTokenStorage) TouchPersonalAccessToken(ctx context.Context, id string, usedAt time.Time) error {
+	for _, token := range f.tokens {
+		if token.Id == id {
+			token.LastUsedAt = &usedAt
+		}
+	}
+
+	return nil
+}
+
+// fakeMailer keeps sent messages instead of delivering them.
+type fakeMailer struct {
+	messages []mail.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, message *mail.Message) error {
+	f.messages = append(f.messages, *message)
+	return nil
+}
+
+// testStorages keeps typed fakes next to Storages handed to services, so tests can inspect them.
+type testStorages struct {
+	*Storages
+	users          *fakeUserStorage
+	accounts       *fakeAccountStorage
+	devices        *fakeDeviceStorage
+	deviceKeys     *fakeDeviceKeyStorage
+	nodes          *fakeNodeStorage
+	refreshTokens  *fakeRefreshTokenStorage
+	userTokens     *fakeUserTokenStorage
+	recoveryCodes  *fakeRecoveryCodeStorage
+	loginThrottles *fakeLoginThrottleStorage
+	rateLimits     *fakeRateLimitStorage
+	revokedTokens  *fakeRevokedTokenStorage
+	identities     *fakeUserIdentityStorage
+	oidcStates     *fakeOIDCLoginStateStorage
+	accessTokens   *fakePersonalAccessTokenStorage
+	auditEvents    *fakeAuditEventStorage
+	contacts       *fakeContactStorage
+	blocks         *fakeBlockStorage
+}
+
+func newTestStorages() *testStorages {
+	s := &testStorages{
+		users:          newFakeUserStorage(),
+		accounts:       &fakeAccountStorage{},
+		deviceKeys:     &fakeDeviceKeyStorage{},
+		nodes:          &fakeNodeStorage{},
+		refreshTokens:  newFakeRefreshTokenStorage(),
+		userTokens:     &fakeUserTokenStorage{},
+		recoveryCodes:  newFakeRecoveryCodeStorage(),
+		loginThrottles: newFakeLoginThrottleStorage(),
+		rateLimits:     &fakeRateLimitStorage{limits: map[string]*entity.RateLimit{}},
+		revokedTokens:  &fakeRevokedTokenStorage{},
+		identities:     &fakeUserIdentityStorage{},
+		oidcStates:     &fakeOIDCLoginStateStorage{},
+		accessTokens:   &fakePersonalAccessTokenStorage{},
+		auditEvents:    &fakeAuditEventStorage{},
+		contacts:       &fakeContactStorage{},
+	}
+	s.devices = &fakeDeviceStorage{accounts: s.accounts}
+	s.blocks = &fakeBlockStorage{contacts: s.contacts}
+	s.Storages = &Storages{
+		UserStorage:                s.users,
+		AccountStorage:             s.accounts,
+		DeviceStorage:              s.devices,
+		DeviceKeyStorage:           s.deviceKeys,
+		NodeStorage:                s.nodes,
+		RefreshTokenStorage:        s.refreshTokens,
+		UserTokenStorage:           s.userTokens,
+		RecoveryCodeStorage:        s.recoveryCodes,
+		LoginThrottleStorage:       s.loginThrottles,
+		RateLimitStorage:           s.rateLimits,
+		RevokedTokenStorage:        s.revokedTokens,
+		UserIdentityStorage:        s.identities,
+		OIDCLoginStateStorage:      s.oidcStates,
+		PersonalAccessTokenStorage: s.accessTokens,
+		AuditEventStorage:          s.auditEvents,
+		ContactStorage:             s.contacts,
+		BlockStorage:               s.blocks,
+	}
+
+	return s
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		JWT: config.JWT{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+		OIDC: config.OIDC{
+			LoginTTL: 10 * time.Minute,
+		},
+		Account: config.Account{
+			DeletionGracePeriod: 720 * time.Hour,
+			MaxDevices:          2,
+		},
+		Auth: config.Auth{
+			MFAChallengeTTL:           5 * time.Minute,
+			LockoutThreshold:          3,
+			IPLockoutThreshold:        10,
+			LockoutBaseDuration:       time.Minute,
+			LockoutMaxDuration:        time.Hour,
+			LockoutResetAfter:         24 * time.Hour,
+			PersonalAccessTokenMaxTTL: 24 * time.Hour,
+		},
+		Search: config.Search{
+			RateLimit:       2,
+			RateLimitWindow: time.Minute,
+			MinQueryLength:  3,
+			MaxResults:      20,
+		},
+	}
+}
+
+func newTestOptions(t *testing.T, storages *testStorages) *Options {
+	t.Helper()
+
+	authenticator, err := auth.NewAuth(auth.SignKey("test"), auth.AccessTokenTTL(15*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	return &Options{
+		Storages: storages.Storages,
+		Config:   newTestConfig(),
+		Logger:   logger.New("fatal"),
+		Hash:     hash.NewArgon2idHash(hash.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		Auth:     authenticator,
+		Mailer:   &fakeMailer{},
+	}
+}
+
+// createTestUser stores verified user with given password hashed by options hasher.
+func createTestUser(t *testing.T, options *Options, email, password string) *entity.User {
+	t.Helper()
+
+	hashedPassword, err := options.Hash.GenerateHash(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	user, err := options.Storages.UserStorage.CreateUser(context.Background(), &entity.User{
+		Email:         email,
+		Username:      email,
+		Password:      hashedPassword,
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return user
+}
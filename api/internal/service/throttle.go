@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"math"
+	"strings"
+	"time"
+)
+
+type throttleKeys struct {
+	account string
+	ip      string
+}
+
+func newThrottleKeys(email, clientIP string) throttleKeys {
+	keys := throttleKeys{account: "email:" + strings.ToLower(strings.TrimSpace(email))}
+	if clientIP != "" {
+		keys.ip = "ip:" + clientIP
+	}
+
+	return keys
+}
+
+func (k throttleKeys) list() []string {
+	if k.ip == "" {
+		return []string{k.account}
+	}
+
+	return []string{k.account, k.ip}
+}
+
+func (a authService) UnlockAccount(ctx context.Context, options *UnlockAccountOptions) error {
+	logger := a.logger.
+		Named("UnlockAccount").
+		WithContext(ctx).
+		With("options", options)
+
+	err := a.storages.LoginThrottleStorage.DeleteLoginThrottle(ctx, newThrottleKeys(options.Email, "").account)
+	if err != nil {
+		logger.Error("failed to delete sign in throttle: ", err)
+		return fmt.Errorf("failed to delete sign in throttle: %w", err)
+	}
+
+	logger.Info("successfully unlocked account")
+	return nil
+}
+
+// checkSignInThrottles returns ErrSignInAccountLocked with retry after value if any of the keys is locked.
+func (a authService) checkSignInThrottles(ctx context.Context, keys throttleKeys) error {
+	throttles, err := a.storages.LoginThrottleStorage.GetLoginThrottles(ctx, keys.list())
+	if err != nil {
+		return fmt.Errorf("failed to get sign in throttles: %w", err)
+	}
+
+	var lockedUntil time.Time
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(lockedUntil) {
+			lockedUntil = *throttle.LockedUntil
+		}
+	}
+	if time.Now().Before(lockedUntil) {
+		return lockedError(lockedUntil)
+	}
+
+	return nil
+}
+
+// registerSignInFailure counts the failure for every key, locks keys which reached their threshold
+// and returns either cause or ErrSignInAccountLocked.
+func (a authService) registerSignInFailure(ctx context.Context, keys throttleKeys, cause error) error {
+	thresholds := map[string]int{keys.account: a.config.Auth.LockoutThreshold}
+	if keys.ip != "" {
+		thresholds[keys.ip] = a.config.Auth.IPLockoutThreshold
+	}
+
+	var lockedUntil time.Time
+	for key, threshold := range thresholds {
+		throttle, err := a.storages.LoginThrottleStorage.RegisterLoginFailure(ctx, key, time.Now().Add(-a.config.Auth.LockoutResetAfter))
+		if err != nil {
+			return fmt.Errorf("failed to register sign in failure: %w", err)
+		}
+		if throttle.Failures < threshold {
+			continue
+		}
+
+		until := time.Now().Add(a.lockoutDuration(throttle.Failures - threshold))
+		err = a.storages.LoginThrottleStorage.LockLoginThrottle(ctx, key, until)
+		if err != nil {
+			return fmt.Errorf("failed to lock sign in: %w", err)
+		}
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+
+	if !lockedUntil.IsZero() {
+		return lockedError(lockedUntil)
+	}
+
+	return cause
+}
+
+// lockoutDuration doubles base lockout duration for every failure above threshold.
+func (a authService) lockoutDuration(failuresAboveThreshold int) time.Duration {
+	multiplier := math.Pow(2, float64(failuresAboveThreshold))
+	duration := time.Duration(float64(a.config.Auth.LockoutBaseDuration) * multiplier)
+	if duration <= 0 || duration > a.config.Auth.LockoutMaxDuration {
+		return a.config.Auth.LockoutMaxDuration
+	}
+
+	return duration
+}
+
+func lockedError(lockedUntil time.Time) *errs.Err {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	return ErrSignInAccountLocked.WithDetails(map[string]string{"retryAfter": fmt.Sprint(retryAfter)})
+}
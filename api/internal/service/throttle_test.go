@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/atlant1da-404/droplet/pkg/errs"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	// base duration is one minute and maximum one hour
+	tests := []struct {
+		failuresAboveThreshold int
+		want                   time.Duration
+	}{
+		{failuresAboveThreshold: 0, want: time.Minute},
+		{failuresAboveThreshold: 1, want: 2 * time.Minute},
+		{failuresAboveThreshold: 2, want: 4 * time.Minute},
+		{failuresAboveThreshold: 5, want: 32 * time.Minute},
+		{failuresAboveThreshold: 6, want: time.Hour},
+		{failuresAboveThreshold: 100, want: time.Hour},
+		{failuresAboveThreshold: 10000, want: time.Hour},
+	}
+
+	service := NewAuthService(newTestOptions(t, newTestStorages())).(*authService)
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.failuresAboveThreshold), func(t *testing.T) {
+			if got := service.lockoutDuration(tt.failuresAboveThreshold); got != tt.want {
+				t.Fatalf("lockoutDuration(%d) = %v, want %v", tt.failuresAboveThreshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterSignInFailure(t *testing.T) {
+	cause := errors.New("wrong password")
+
+	tests := []struct {
+		name string
+		// emails are used for failures in order, all of them from the same ip
+		emails []string
+		// wantRetryAfter is retry after of the last failure in seconds, zero when it must not lock
+		wantRetryAfter int
+	}{
+		{name: "below account threshold", emails: repeatEmail("a@example.com", 2)},
+		{name: "account threshold", emails: repeatEmail("a@example.com", 3), wantRetryAfter: 60},
+		{name: "lockout doubles", emails: repeatEmail("a@example.com", 5), wantRetryAfter: 240},
+		{name: "failures of other accounts do not lock account", emails: []string{"a@example.com", "b@example.com", "a@example.com"}},
+		{name: "ip threshold across accounts", emails: distinctEmails(10), wantRetryAfter: 60},
+		{name: "below ip threshold", emails: distinctEmails(9)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAuthService(newTestOptions(t, newTestStorages())).(*authService)
+
+			var err error
+			for _, email := range tt.emails {
+				err = service.registerSignInFailure(context.Background(), newThrottleKeys(email, _testClientIP), cause)
+			}
+
+			if tt.wantRetryAfter == 0 {
+				if !errors.Is(err, cause) {
+					t.Fatalf("registerSignInFailure() error = %v, want %v", err, cause)
+				}
+				return
+			}
+			if errs.GetCode(err) != "account_locked" {
+				t.Fatalf("registerSignInFailure() error = %v, want account_locked", err)
+			}
+			if got := errs.GetDetails(err)["retryAfter"]; got != fmt.Sprint(tt.wantRetryAfter) {
+				t.Errorf("retryAfter = %s, want %d", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestSignInRejectsCorrectPasswordWhileLocked(t *testing.T) {
+	storages := newTestStorages()
+	options := newTestOptions(t, storages)
+	createTestUser(t, options, _testEmail, _testPassword)
+	service := NewAuthService(options)
+
+	for i := 0; i < options.Config.Auth.LockoutThreshold; i++ {
+		_, _ = service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: "wrong", ClientIP: _testClientIP})
+	}
+
+	_, err := service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if errs.GetCode(err) != "account_locked" {
+		t.Fatalf("SignIn() error = %v, want account_locked", err)
+	}
+
+	// unlocking resets the counter, so the user can sign in right away
+	err = service.UnlockAccount(context.Background(), &UnlockAccountOptions{Email: _testEmail})
+	if err != nil {
+		t.Fatalf("UnlockAccount() error = %v", err)
+	}
+	_, err = service.SignIn(context.Background(), &SignInOptions{Email: _testEmail, Password: _testPassword, ClientIP: _testClientIP})
+	if err != nil {
+		t.Fatalf("SignIn() after unlock error = %v", err)
+	}
+}
+
+func repeatEmail(email string, count int) []string {
+	emails := make([]string, count)
+	for i := range emails {
+		emails[i] = email
+	}
+
+	return emails
+}
+
+func distinctEmails(count int) []string {
+	emails := make([]string, count)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user-%d@example.com", i)
+	}
+
+	return emails
+}
@@ -2,10 +2,10 @@ package storage
 
 import (
 	"context"
-	"github.com/a631807682/zerofield"
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/database"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -20,6 +20,17 @@ func NewAccountStorage(postgresql *database.PostgreSQL) service.AccountStorage {
 	return &accountStorage{postgresql}
 }
 
+// MigrateAccountSettings rewrites stored settings values which older settings versions named differently,
+// so queries over stored settings match only current values. Other upgrades are applied when settings are read.
+func MigrateAccountSettings(postgresql *database.PostgreSQL) error {
+	// version 2 called contacts of contacts discoverability just contacts
+	return postgresql.DB.
+		Model(&entity.AccountSettings{}).
+		Where("discoverability = ?", "contacts").
+		Update("discoverability", entity.DiscoverabilityContactsOfContacts).
+		Error
+}
+
 func (u *accountStorage) CreateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error) {
 	err := u.DB.WithContext(ctx).Create(account).Error
 	if err != nil {
@@ -30,7 +41,7 @@ func (u *accountStorage) CreateAccount(ctx context.Context, account *entity.Acco
 }
 
 func (u *accountStorage) GetAccount(ctx context.Context, filter *service.GetAccountFilter) (*entity.Account, error) {
-	stmt := u.DB.Preload(clause.Associations)
+	stmt := u.DB.Preload(clause.Associations).Preload("AccountDevices", orderAccountDevices)
 
 	if filter.AccountId != "" {
 		stmt = stmt.Where(entity.Account{Id: filter.AccountId})
@@ -55,20 +66,68 @@ func (u *accountStorage) GetAccount(ctx context.Context, filter *service.GetAcco
 	return &account, nil
 }
 
-func (u *accountStorage) UpdateAccount(ctx context.Context, account *entity.Account) (*entity.Account, error) {
-	err := u.DB.
-		Session(&gorm.Session{FullSaveAssociations: true}).
-		Scopes(zerofield.UpdateScopes()).
-		Where(&entity.Account{Id: account.Id}).
-		Updates(account).
-		Error
+func (u *accountStorage) UpdateAccount(ctx context.Context, account *entity.Account, expectedVersion int) (*entity.Account, error) {
+	updated := false
+	err := u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// version is compared and bumped by single statement, so only one of concurrent writers succeeds
+		stmt := tx.
+			Model(&entity.Account{}).
+			Where("id = ?", account.Id)
+		if expectedVersion != 0 {
+			stmt = stmt.Where("version = ?", expectedVersion)
+		}
+
+		result := stmt.Update("version", gorm.Expr("version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+
+		// only fields patches may change are written, so heartbeats are not overwritten by stale copy
+		for _, device := range account.AccountDevices {
+			fields := map[string]interface{}{"name": device.Name, "active": device.Active}
+			if !device.Active {
+				fields["online"] = false
+			}
+
+			err := tx.
+				Model(&entity.AccountDevices{}).
+				Where("id = ? AND account_id = ?", device.Id, account.Id).
+				Updates(fields).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if account.AccountSettings == nil {
+			return nil
+		}
+
+		settings := account.AccountSettings
+		settings.AccountID = account.Id
+		if settings.Id != "" {
+			return tx.Save(settings).Error
+		}
+
+		// columns have defaults, so all fields are selected explicitly, otherwise false and empty values would be replaced
+		settings.Id = uuid.NewString()
+		return tx.Select("*").Create(settings).Error
+	})
 	if err != nil {
 		return nil, err
 	}
+	if !updated {
+		return nil, nil
+	}
 
 	var updatedAccount entity.Account
 	err = u.DB.
 		Preload(clause.Associations).
+		Preload("AccountDevices", orderAccountDevices).
 		WithContext(ctx).
 		Take(&updatedAccount, "id = ?", account.Id).
 		Error
@@ -78,3 +137,24 @@ func (u *accountStorage) UpdateAccount(ctx context.Context, account *entity.Acco
 
 	return &updatedAccount, nil
 }
+
+func (u *accountStorage) ListAccounts(ctx context.Context, filter *service.ListAccountsFilter) ([]entity.Account, error) {
+	var accounts []entity.Account
+	err := u.DB.
+		Preload(clause.Associations).
+		Preload("AccountDevices", orderAccountDevices).
+		WithContext(ctx).
+		Where(entity.Account{UserId: filter.UserId}).
+		Find(&accounts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// orderAccountDevices keeps devices in stable order, patches address them by index.
+func orderAccountDevices(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+)
+
+type auditEventStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.AuditEventStorage = (*auditEventStorage)(nil)
+
+func NewAuditEventStorage(postgresql *database.PostgreSQL) service.AuditEventStorage {
+	return &auditEventStorage{postgresql}
+}
+
+func (a auditEventStorage) CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) (*entity.AuditEvent, error) {
+	err := a.DB.WithContext(ctx).Create(event).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (a auditEventStorage) ListAuditEvents(ctx context.Context, filter *service.ListAuditEventsFilter) ([]entity.AuditEvent, error) {
+	var events []entity.AuditEvent
+	err := a.DB.
+		WithContext(ctx).
+		Where("user_id = ?", filter.UserId).
+		Order("created_at").
+		Find(&events).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type contactStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.ContactStorage = (*contactStorage)(nil)
+
+func NewContactStorage(postgresql *database.PostgreSQL) service.ContactStorage {
+	return &contactStorage{postgresql}
+}
+
+func (c contactStorage) CreateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error) {
+	err := c.DB.WithContext(ctx).Create(contact).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func (c contactStorage) GetContact(ctx context.Context, filter *service.GetContactFilter) (*entity.Contact, error) {
+	var contact entity.Contact
+	err := whereContact(c.DB.Model(&entity.Contact{}), filter).
+		WithContext(ctx).
+		First(&contact).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+func (c contactStorage) ListContacts(ctx context.Context, filter *service.ListContactsFilter) ([]entity.Contact, error) {
+	stmt := c.DB.Model(&entity.Contact{})
+
+	if filter.UserId != "" {
+		stmt = stmt.Where("requester_id = ? OR addressee_id = ?", filter.UserId, filter.UserId)
+	}
+
+	if filter.RequesterId != "" {
+		stmt = stmt.Where("requester_id = ?", filter.RequesterId)
+	}
+
+	if filter.AddresseeId != "" {
+		stmt = stmt.Where("addressee_id = ?", filter.AddresseeId)
+	}
+
+	if len(filter.Statuses) > 0 {
+		stmt = stmt.Where("status IN ?", filter.Statuses)
+	}
+
+	var contacts []entity.Contact
+	err := stmt.
+		WithContext(ctx).
+		Order("created_at DESC").
+		Find(&contacts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+func (c contactStorage) UpdateContact(ctx context.Context, contact *entity.Contact) (*entity.Contact, error) {
+	err := c.DB.WithContext(ctx).Save(contact).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func (c contactStorage) DeleteContact(ctx context.Context, filter *service.GetContactFilter) (bool, error) {
+	result := whereContact(c.DB.WithContext(ctx), filter).Delete(&entity.Contact{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// whereContact applies contact filter, request between two users is matched in both directions.
+func whereContact(stmt *gorm.DB, filter *service.GetContactFilter) *gorm.DB {
+	if filter.Id != "" {
+		stmt = stmt.Where("id = ?", filter.Id)
+	}
+
+	if filter.UserId != "" && filter.OtherUserId != "" {
+		stmt = stmt.Where(
+			"(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)",
+			filter.UserId, filter.OtherUserId, filter.OtherUserId, filter.UserId,
+		)
+	} else if filter.UserId != "" {
+		stmt = stmt.Where("requester_id = ? OR addressee_id = ?", filter.UserId, filter.UserId)
+	}
+
+	return stmt
+}
+
+type blockStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.BlockStorage = (*blockStorage)(nil)
+
+func NewBlockStorage(postgresql *database.PostgreSQL) service.BlockStorage {
+	return &blockStorage{postgresql}
+}
+
+func (b blockStorage) CreateBlock(ctx context.Context, block *entity.Block) error {
+	return b.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(block).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return whereContact(tx, &service.GetContactFilter{UserId: block.UserId, OtherUserId: block.BlockedUserId}).
+			Delete(&entity.Contact{}).
+			Error
+	})
+}
+
+func (b blockStorage) GetBlock(ctx context.Context, filter *service.GetBlockFilter) (*entity.Block, error) {
+	var block entity.Block
+	err := b.DB.
+		WithContext(ctx).
+		Where("user_id = ? AND blocked_user_id = ?", filter.UserId, filter.BlockedUserId).
+		First(&block).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+func (b blockStorage) ListBlocks(ctx context.Context, userId string) ([]entity.Block, error) {
+	var blocks []entity.Block
+	err := b.DB.
+		WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Find(&blocks).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+func (b blockStorage) DeleteBlock(ctx context.Context, filter *service.GetBlockFilter) (bool, error) {
+	result := b.DB.
+		WithContext(ctx).
+		Where("user_id = ? AND blocked_user_id = ?", filter.UserId, filter.BlockedUserId).
+		Delete(&entity.Block{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
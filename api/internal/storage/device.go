@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type deviceStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.DeviceStorage = (*deviceStorage)(nil)
+
+func NewDeviceStorage(postgresql *database.PostgreSQL) service.DeviceStorage {
+	return &deviceStorage{postgresql}
+}
+
+func (d deviceStorage) CreateDevice(ctx context.Context, device *entity.AccountDevices, maxDevices int) (*entity.AccountDevices, error) {
+	created := false
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// account row serializes concurrent registrations, so the limit cannot be exceeded
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(&entity.Account{}, "id = ?", device.AccountID).
+			Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.
+			Model(&entity.AccountDevices{}).
+			Where("account_id = ?", device.AccountID).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count >= int64(maxDevices) {
+			return nil
+		}
+
+		err = tx.Create(device).Error
+		if err != nil {
+			return err
+		}
+		created = true
+
+		return bumpAccountVersion(tx, device.AccountID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, nil
+	}
+
+	return device, nil
+}
+
+func (d deviceStorage) GetDevice(ctx context.Context, filter *service.GetDeviceFilter) (*entity.AccountDevices, error) {
+	stmt := d.DB.Where("account_id = ?", filter.AccountId)
+
+	if filter.Id != "" {
+		stmt = stmt.Where("id = ?", filter.Id)
+	}
+
+	if filter.MacAddress != "" {
+		stmt = stmt.Where("mac_address = ?", filter.MacAddress)
+	}
+
+	var device entity.AccountDevices
+	err := stmt.
+		WithContext(ctx).
+		First(&device).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (d deviceStorage) ListDevices(ctx context.Context, filter *service.ListDevicesFilter) ([]entity.AccountDevices, error) {
+	var devices []entity.AccountDevices
+	err := d.DB.
+		WithContext(ctx).
+		Where("account_id = ?", filter.AccountId).
+		Order("name").
+		Find(&devices).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// UpdateDevice writes only fields clients may change, so it does not overwrite presence
+// recorded by heartbeats since the device was read.
+func (d deviceStorage) UpdateDevice(ctx context.Context, device *entity.AccountDevices) (*entity.AccountDevices, error) {
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&entity.AccountDevices{}).
+			Where("id = ? AND account_id = ?", device.Id, device.AccountID).
+			Updates(map[string]interface{}{"name": device.Name, "active": device.Active})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return bumpAccountVersion(tx, device.AccountID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (d deviceStorage) DeleteDevice(ctx context.Context, filter *service.DeleteDeviceFilter) (bool, error) {
+	deleted := false
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("id = ? AND account_id = ?", filter.Id, filter.AccountId).
+			Delete(&entity.AccountDevices{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+
+		return bumpAccountVersion(tx, filter.AccountId)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+func (d deviceStorage) TouchDevice(ctx context.Context, id string, seenAt time.Time) error {
+	return d.DB.
+		WithContext(ctx).
+		Model(&entity.AccountDevices{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "online": true}).
+		Error
+}
+
+func (d deviceStorage) MarkStaleDevicesOffline(ctx context.Context, before time.Time) (int64, error) {
+	result := d.DB.
+		WithContext(ctx).
+		Model(&entity.AccountDevices{}).
+		Where("online AND (last_seen_at IS NULL OR last_seen_at < ?)", before).
+		Update("online", false)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// bumpAccountVersion increments version of the account whose devices were changed, devices are part of
+// account representation, so ETags and JSON patch device indexes of clients have to be invalidated as well.
+// Presence is left out, as heartbeats would otherwise invalidate every ETag each minute.
+func bumpAccountVersion(tx *gorm.DB, accountId string) error {
+	return tx.
+		Model(&entity.Account{}).
+		Where("id = ?", accountId).
+		Update("version", gorm.Expr("version + 1")).
+		Error
+}
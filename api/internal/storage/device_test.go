@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"reflect"
+	"testing"
+)
+
+const _bumpAccountVersionQuery = `UPDATE "accounts" SET "version"=version + 1 WHERE id = $1`
+
+func TestUpdateDeviceKeepsPresence(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, nil)
+
+	_, err := NewDeviceStorage(postgresql).UpdateDevice(context.Background(), &entity.AccountDevices{
+		Id:        "device-1",
+		AccountID: "account-1",
+		Name:      "laptop",
+		Active:    false,
+		Online:    false,
+	})
+	if err != nil {
+		t.Fatalf("UpdateDevice() error = %v", err)
+	}
+
+	// online and last_seen_at stay as the latest heartbeat left them
+	wantQuery := `UPDATE "account_devices" SET "active"=$1,"name"=$2 WHERE id = $3 AND account_id = $4`
+	if got := connector.queries(`UPDATE "account_devices"`); !reflect.DeepEqual(got, []string{wantQuery}) {
+		t.Fatalf("UpdateDevice() queries = %q, want %q", got, wantQuery)
+	}
+	wantArgs := []interface{}{false, "laptop", "device-1", "account-1"}
+	if got := connector.args(wantQuery); !reflect.DeepEqual(got, wantArgs) {
+		t.Fatalf("UpdateDevice() args = %v, want %v", got, wantArgs)
+	}
+}
+
+func TestDeviceChangesBumpAccountVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(storage service.DeviceStorage) error
+		// devices is number of devices the account already has
+		devices     int
+		wantVersion bool
+	}{
+		{
+			name: "create",
+			change: func(storage service.DeviceStorage) error {
+				_, err := storage.CreateDevice(context.Background(), &entity.AccountDevices{AccountID: "account-1", Name: "laptop"}, 2)
+				return err
+			},
+			devices:     1,
+			wantVersion: true,
+		},
+		{
+			name: "create over limit",
+			change: func(storage service.DeviceStorage) error {
+				_, err := storage.CreateDevice(context.Background(), &entity.AccountDevices{AccountID: "account-1", Name: "laptop"}, 2)
+				return err
+			},
+			devices: 2,
+		},
+		{
+			name: "update",
+			change: func(storage service.DeviceStorage) error {
+				_, err := storage.UpdateDevice(context.Background(), &entity.AccountDevices{Id: "device-1", AccountID: "account-1", Name: "laptop"})
+				return err
+			},
+			wantVersion: true,
+		},
+		{
+			name: "delete",
+			change: func(storage service.DeviceStorage) error {
+				_, err := storage.DeleteDevice(context.Background(), &service.DeleteDeviceFilter{Id: "device-1", AccountId: "account-1"})
+				return err
+			},
+			wantVersion: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postgresql, connector := newRecordingPostgreSQL(t, map[string]recordedRows{
+				"accounts":        {columns: []string{"id", "version"}, values: [][]driver.Value{{"account-1", int64(3)}}},
+				"account_devices": {columns: []string{"count"}, values: [][]driver.Value{{int64(tt.devices)}}},
+			})
+
+			err := tt.change(NewDeviceStorage(postgresql))
+			if err != nil {
+				t.Fatalf("%s error = %v", tt.name, err)
+			}
+
+			queries := connector.queries("")
+			bumped := -1
+			for i, query := range queries {
+				if query == _bumpAccountVersionQuery {
+					bumped = i
+				}
+			}
+			if !tt.wantVersion {
+				if bumped != -1 {
+					t.Fatalf("account version was bumped, queries: %q", queries)
+				}
+				return
+			}
+			// version is bumped by the same transaction which changes the device
+			if bumped == -1 || queries[0] != "BEGIN" || queries[len(queries)-1] != "COMMIT" || bumped != len(queries)-2 {
+				t.Fatalf("account version was not bumped within the transaction, queries: %q", queries)
+			}
+			if got := connector.args(_bumpAccountVersionQuery); !reflect.DeepEqual(got, []interface{}{"account-1"}) {
+				t.Fatalf("account version bumped for %v, want account-1", got)
+			}
+		})
+	}
+}
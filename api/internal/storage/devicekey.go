@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"time"
+)
+
+type deviceKeyStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.DeviceKeyStorage = (*deviceKeyStorage)(nil)
+
+func NewDeviceKeyStorage(postgresql *database.PostgreSQL) service.DeviceKeyStorage {
+	return &deviceKeyStorage{postgresql}
+}
+
+func (d deviceKeyStorage) RotateDeviceKey(ctx context.Context, key *entity.DeviceKey) (*entity.DeviceKey, error) {
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&entity.DeviceKey{}).
+			Where("device_id = ? AND revoked_at IS NULL", key.DeviceId).
+			Update("revoked_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(key).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (d deviceKeyStorage) ListDeviceKeys(ctx context.Context, filter *service.ListDeviceKeysFilter) ([]entity.DeviceKey, error) {
+	stmt := d.DB.Model(&entity.DeviceKey{})
+
+	if filter.UserId != "" {
+		stmt = stmt.Where("user_id = ?", filter.UserId)
+	}
+
+	if filter.AccountId != "" {
+		stmt = stmt.Where("account_id = ?", filter.AccountId)
+	}
+
+	if filter.DeviceId != "" {
+		stmt = stmt.Where("device_id = ?", filter.DeviceId)
+	}
+
+	if !filter.IncludeRevoked {
+		stmt = stmt.Where("revoked_at IS NULL")
+	}
+
+	var keys []entity.DeviceKey
+	err := stmt.
+		WithContext(ctx).
+		Order("created_at DESC").
+		Find(&keys).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (d deviceKeyStorage) RevokeDeviceKeys(ctx context.Context, deviceId string, revokedAt time.Time) error {
+	return d.DB.
+		WithContext(ctx).
+		Model(&entity.DeviceKey{}).
+		Where("device_id = ? AND revoked_at IS NULL", deviceId).
+		Update("revoked_at", revokedAt).
+		Error
+}
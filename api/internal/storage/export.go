@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type dataExportStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.DataExportStorage = (*dataExportStorage)(nil)
+
+func NewDataExportStorage(postgresql *database.PostgreSQL) service.DataExportStorage {
+	return &dataExportStorage{postgresql}
+}
+
+func (d dataExportStorage) CreateDataExport(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error) {
+	err := d.DB.WithContext(ctx).Create(export).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (d dataExportStorage) GetDataExport(ctx context.Context, filter *service.GetDataExportFilter) (*entity.DataExport, error) {
+	stmt := d.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+
+	if filter.Id != "" {
+		stmt = stmt.Where("id = ?", filter.Id)
+	}
+
+	if filter.UserId != "" {
+		stmt = stmt.Where("user_id = ?", filter.UserId)
+	}
+
+	if filter.TokenHash != "" {
+		stmt = stmt.Where("token_hash = ?", filter.TokenHash)
+	}
+
+	if len(filter.Statuses) > 0 {
+		stmt = stmt.Where("status IN ?", filter.Statuses)
+	}
+
+	var export entity.DataExport
+	err := stmt.
+		WithContext(ctx).
+		Order("created_at DESC").
+		First(&export).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (d dataExportStorage) ClaimPendingDataExport(ctx context.Context) (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// other instances skip locked export and claim the next one
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", entity.DataExportStatusPending).
+			Order("created_at").
+			First(&export).
+			Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		export.Status = entity.DataExportStatusProcessing
+		export.ClaimedAt = &now
+		return tx.Model(&export).Updates(map[string]interface{}{"status": export.Status, "claimed_at": export.ClaimedAt}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (d dataExportStorage) UpdateDataExport(ctx context.Context, export *entity.DataExport) (*entity.DataExport, error) {
+	err := d.DB.WithContext(ctx).Save(export).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (d dataExportStorage) FailStaleDataExports(ctx context.Context, claimedBefore, expiresAt time.Time) (int64, error) {
+	result := d.DB.
+		WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("status = ? AND (claimed_at IS NULL OR claimed_at < ?)", entity.DataExportStatusProcessing, claimedBefore).
+		Updates(map[string]interface{}{
+			"status":       entity.DataExportStatusFailed,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (d dataExportStorage) DeleteExpiredDataExports(ctx context.Context, before time.Time) (int64, error) {
+	result := d.DB.
+		WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&entity.DataExport{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type groupStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.GroupStorage = (*groupStorage)(nil)
+
+func NewGroupStorage(postgresql *database.PostgreSQL) service.GroupStorage {
+	return &groupStorage{postgresql}
+}
+
+func (g groupStorage) CreateGroup(ctx context.Context, group *entity.Group) (*entity.Group, error) {
+	err := g.DB.WithContext(ctx).Create(group).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (g groupStorage) GetGroup(ctx context.Context, filter *service.GetGroupFilter) (*entity.Group, error) {
+	stmt := g.DB.Model(&entity.Group{}).Preload("Members", orderGroupMembers)
+
+	if filter.Id != "" {
+		stmt = stmt.Where("id = ?", filter.Id)
+	}
+
+	if filter.MemberId != "" {
+		stmt = stmt.Where("id IN (?)", g.DB.Model(&entity.GroupMember{}).Select("group_id").Where("user_id = ?", filter.MemberId))
+	}
+
+	var group entity.Group
+	err := stmt.
+		WithContext(ctx).
+		First(&group).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (g groupStorage) ListGroups(ctx context.Context, userId string) ([]entity.Group, error) {
+	var groups []entity.Group
+	err := g.DB.
+		WithContext(ctx).
+		Preload("Members", orderGroupMembers).
+		Where("id IN (?)", g.DB.Model(&entity.GroupMember{}).Select("group_id").Where("user_id = ?", userId)).
+		Order("created_at DESC").
+		Find(&groups).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (g groupStorage) UpdateGroup(ctx context.Context, group *entity.Group) (*entity.Group, error) {
+	err := g.DB.
+		WithContext(ctx).
+		Model(&entity.Group{Id: group.Id}).
+		Update("name", group.Name).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (g groupStorage) DeleteGroup(ctx context.Context, groupId string) error {
+	return g.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("group_id = ?", groupId).Delete(&entity.GroupMember{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", groupId).Delete(&entity.Group{}).Error
+	})
+}
+
+func (g groupStorage) CreateGroupMember(ctx context.Context, member *entity.GroupMember, maxMembers int) (*entity.GroupMember, error) {
+	created := false
+	err := g.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// group row serializes concurrent additions, so the limit cannot be exceeded
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(&entity.Group{}, "id = ?", member.GroupId).
+			Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.
+			Model(&entity.GroupMember{}).
+			Where("group_id = ?", member.GroupId).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count >= int64(maxMembers) {
+			return nil
+		}
+
+		created = true
+		return tx.Create(member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, nil
+	}
+
+	return member, nil
+}
+
+func (g groupStorage) UpdateGroupMember(ctx context.Context, member *entity.GroupMember) (*entity.GroupMember, error) {
+	err := g.DB.
+		WithContext(ctx).
+		Model(&entity.GroupMember{Id: member.Id}).
+		Update("role", member.Role).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (g groupStorage) DeleteGroupMember(ctx context.Context, filter *service.GetGroupMemberFilter) (bool, error) {
+	result := g.DB.
+		WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", filter.GroupId, filter.UserId).
+		Delete(&entity.GroupMember{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// orderGroupMembers lists members in order they joined the group.
+func orderGroupMembers(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, id")
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type userIdentityStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.UserIdentityStorage = (*userIdentityStorage)(nil)
+
+func NewUserIdentityStorage(postgresql *database.PostgreSQL) service.UserIdentityStorage {
+	return &userIdentityStorage{postgresql}
+}
+
+func (u userIdentityStorage) GetUserIdentity(ctx context.Context, filter *service.GetUserIdentityFilter) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	err := u.DB.
+		WithContext(ctx).
+		Where(entity.UserIdentity{Provider: filter.Provider, Subject: filter.Subject}).
+		First(&identity).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (u userIdentityStorage) CreateUserIdentity(ctx context.Context, identity *entity.UserIdentity) (*entity.UserIdentity, error) {
+	err := u.DB.WithContext(ctx).Create(identity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+type oidcLoginStateStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.OIDCLoginStateStorage = (*oidcLoginStateStorage)(nil)
+
+func NewOIDCLoginStateStorage(postgresql *database.PostgreSQL) service.OIDCLoginStateStorage {
+	return &oidcLoginStateStorage{postgresql}
+}
+
+func (o oidcLoginStateStorage) CreateOIDCLoginState(ctx context.Context, state *entity.OIDCLoginState) (*entity.OIDCLoginState, error) {
+	err := o.DB.WithContext(ctx).Create(state).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (o oidcLoginStateStorage) ConsumeOIDCLoginState(ctx context.Context, filter *service.ConsumeOIDCLoginStateFilter) (*entity.OIDCLoginState, error) {
+	var state entity.OIDCLoginState
+	err := o.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND provider = ? AND used_at IS NULL AND expires_at > ?", filter.StateHash, filter.Provider, time.Now()).
+			First(&state).
+			Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		state.UsedAt = &now
+		return tx.Model(&state).Update("used_at", now).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (o oidcLoginStateStorage) DeleteExpiredOIDCLoginStates(ctx context.Context, before time.Time) (int64, error) {
+	result := o.DB.
+		WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&entity.OIDCLoginState{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
 )
 
 type nodeStorage struct {
@@ -25,3 +26,87 @@ func (n nodeStorage) CreateNode(ctx context.Context, node *entity.Node) (*entity
 
 	return node, nil
 }
+
+func (n nodeStorage) CreateNodes(ctx context.Context, nodes []entity.Node) ([]entity.Node, error) {
+	if len(nodes) == 0 {
+		return nodes, nil
+	}
+
+	err := n.DB.WithContext(ctx).Create(&nodes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (n nodeStorage) GetNode(ctx context.Context, filter *service.GetNodeFilter) (*entity.Node, error) {
+	stmt := n.DB.Model(&entity.Node{})
+
+	if filter.Id != "" {
+		stmt = stmt.Where("id = ?", filter.Id)
+	}
+
+	if filter.ReceiverEmail != "" {
+		stmt = stmt.Where("receiver_email = ?", filter.ReceiverEmail)
+	}
+
+	if filter.Status != "" {
+		stmt = stmt.Where("status = ?", filter.Status)
+	}
+
+	var node entity.Node
+	err := stmt.
+		WithContext(ctx).
+		First(&node).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (n nodeStorage) UpdateNode(ctx context.Context, node *entity.Node) (*entity.Node, error) {
+	err := n.DB.WithContext(ctx).Save(node).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (n nodeStorage) ListNodes(ctx context.Context, filter *service.ListNodesFilter) ([]entity.Node, error) {
+	stmt := n.DB.Model(&entity.Node{})
+
+	if filter.Email != "" {
+		stmt = stmt.Where("sender_email = ? OR receiver_email = ?", filter.Email, filter.Email)
+	}
+
+	if filter.SenderEmail != "" {
+		stmt = stmt.Where("sender_email = ?", filter.SenderEmail)
+	}
+
+	if filter.ReceiverEmail != "" {
+		stmt = stmt.Where("receiver_email = ?", filter.ReceiverEmail)
+	}
+
+	if filter.Status != "" {
+		stmt = stmt.Where("status = ?", filter.Status)
+	}
+
+	var nodes []entity.Node
+	err := stmt.
+		WithContext(ctx).
+		Order("created_at DESC").
+		Find(&nodes).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Storage tests run against recording connection instead of PostgreSQL: statements are
+// recorded in order and SELECTs are answered with rows registered for the queried table.
+
+// recordedRows - represents result of SELECT from a table.
+type recordedRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// recordedStatement - represents executed SQL with its arguments.
+type recordedStatement struct {
+	query string
+	args  []interface{}
+}
+
+type recordingConnector struct {
+	// statements holds executed SQL, transactions are recorded as BEGIN, COMMIT and ROLLBACK.
+	statements []recordedStatement
+	// rows maps table name to rows returned by SELECT from it, other tables return no rows.
+	rows map[string]recordedRows
+	// failures maps query prefix to error returned by matching statements after they are recorded.
+	failures map[string]error
+}
+
+var _selectTablePattern = regexp.MustCompile(`FROM "(\w+)"`)
+
+// newRecordingPostgreSQL returns storage connection which records statements to returned connector.
+func newRecordingPostgreSQL(t *testing.T, rows map[string]recordedRows) (*database.PostgreSQL, *recordingConnector) {
+	t.Helper()
+
+	connector := &recordingConnector{rows: rows}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open recording database: %v", err)
+	}
+
+	return &database.PostgreSQL{DB: db}, connector
+}
+
+// queries returns recorded SQL which starts with given prefix.
+func (c *recordingConnector) queries(prefix string) []string {
+	var queries []string
+	for _, statement := range c.statements {
+		if strings.HasPrefix(statement.query, prefix) {
+			queries = append(queries, statement.query)
+		}
+	}
+
+	return queries
+}
+
+// args returns arguments of the first recorded statement with given query.
+func (c *recordingConnector) args(query string) []interface{} {
+	for _, statement := range c.statements {
+		if statement.query == query {
+			return statement.args
+		}
+	}
+
+	return nil
+}
+
+func (c *recordingConnector) record(query string, args []driver.NamedValue) error {
+	statement := recordedStatement{query: query}
+	for _, arg := range args {
+		statement.args = append(statement.args, arg.Value)
+	}
+	c.statements = append(c.statements, statement)
+
+	for prefix, err := range c.failures {
+		if strings.HasPrefix(query, prefix) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recordingConn{connector: c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+// Prepare is never used, as queries are run by ExecContext and QueryContext directly.
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	err := c.connector.record("BEGIN", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	return c.connector.record("COMMIT", nil)
+}
+
+func (c *recordingConn) Rollback() error {
+	return c.connector.record("ROLLBACK", nil)
+}
+
+// CheckNamedValue accepts arguments of any type, they are only recorded.
+func (c *recordingConn) CheckNamedValue(value *driver.NamedValue) error {
+	return nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	err := c.connector.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	err := c.connector.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows recordedRows
+	if match := _selectTablePattern.FindStringSubmatch(query); match != nil {
+		rows = c.connector.rows[match[1]]
+	}
+
+	return &recordingRows{recordedRows: rows}, nil
+}
+
+type recordingRows struct {
+	recordedRows
+	next int
+}
+
+func (r *recordingRows) Columns() []string {
+	return r.columns
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+
+	return nil
+}
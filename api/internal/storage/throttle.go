@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type loginThrottleStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.LoginThrottleStorage = (*loginThrottleStorage)(nil)
+
+func NewLoginThrottleStorage(postgresql *database.PostgreSQL) service.LoginThrottleStorage {
+	return &loginThrottleStorage{postgresql}
+}
+
+func (l loginThrottleStorage) GetLoginThrottles(ctx context.Context, keys []string) ([]entity.LoginThrottle, error) {
+	var throttles []entity.LoginThrottle
+	err := l.DB.
+		WithContext(ctx).
+		Where("key IN ?", keys).
+		Find(&throttles).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return throttles, nil
+}
+
+func (l loginThrottleStorage) RegisterLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*entity.LoginThrottle, error) {
+	now := time.Now()
+	throttle := &entity.LoginThrottle{Key: key, Failures: 1, LastFailureAt: now}
+
+	// counter is incremented in a single statement, so concurrent instances never lose a failure
+	err := l.DB.
+		WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"failures": gorm.Expr(
+						"CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END",
+						resetBefore,
+					),
+					"last_failure_at": now,
+				}),
+			},
+			clause.Returning{},
+		).
+		Create(throttle).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return throttle, nil
+}
+
+func (l loginThrottleStorage) LockLoginThrottle(ctx context.Context, key string, until time.Time) error {
+	return l.DB.
+		WithContext(ctx).
+		Model(&entity.LoginThrottle{}).
+		Where("key = ?", key).
+		Update("locked_until", until).
+		Error
+}
+
+func (l loginThrottleStorage) DeleteLoginThrottle(ctx context.Context, key string) error {
+	return l.DB.
+		WithContext(ctx).
+		Where("key = ?", key).
+		Delete(&entity.LoginThrottle{}).
+		Error
+}
+
+type rateLimitStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.RateLimitStorage = (*rateLimitStorage)(nil)
+
+func NewRateLimitStorage(postgresql *database.PostgreSQL) service.RateLimitStorage {
+	return &rateLimitStorage{postgresql}
+}
+
+func (r rateLimitStorage) HitRateLimit(ctx context.Context, key string, windowStart time.Time) (*entity.RateLimit, error) {
+	rateLimit := &entity.RateLimit{Key: key, Count: 1, WindowStart: windowStart}
+
+	// counter is incremented in a single statement, so concurrent instances never lose a request
+	err := r.DB.
+		WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"count": gorm.Expr(
+						"CASE WHEN rate_limits.window_start < ? THEN 1 ELSE rate_limits.count + 1 END",
+						windowStart,
+					),
+					"window_start": gorm.Expr("GREATEST(rate_limits.window_start, ?)", windowStart),
+				}),
+			},
+			clause.Returning{},
+		).
+		Create(rateLimit).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rateLimit, nil
+}
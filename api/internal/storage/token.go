@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"context"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/atlant1da-404/droplet/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type refreshTokenStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.RefreshTokenStorage = (*refreshTokenStorage)(nil)
+
+func NewRefreshTokenStorage(postgresql *database.PostgreSQL) service.RefreshTokenStorage {
+	return &refreshTokenStorage{postgresql}
+}
+
+func (r refreshTokenStorage) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) (*entity.RefreshToken, error) {
+	err := r.DB.WithContext(ctx).Create(token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r refreshTokenStorage) GetRefreshToken(ctx context.Context, filter *service.GetRefreshTokenFilter) (*entity.RefreshToken, error) {
+	stmt := r.DB
+
+	if filter.TokenHash != "" {
+		stmt = stmt.Where(entity.RefreshToken{TokenHash: filter.TokenHash})
+	}
+
+	var token entity.RefreshToken
+	err := stmt.
+		WithContext(ctx).
+		First(&token).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r refreshTokenStorage) RotateRefreshToken(ctx context.Context, id string, token *entity.RefreshToken) (*entity.RefreshToken, error) {
+	var rotated bool
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// revoke old token only if nobody has done it yet
+		result := tx.
+			Model(&entity.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		err := tx.Create(token).Error
+		if err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, nil
+	}
+
+	return token, nil
+}
+
+func (r refreshTokenStorage) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	return r.DB.
+		WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).
+		Error
+}
+
+func (r refreshTokenStorage) RevokeUserRefreshTokens(ctx context.Context, filter *service.RevokeUserRefreshTokensFilter) ([]string, error) {
+	var familyIds []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stmt := tx.
+			Model(&entity.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", filter.UserId)
+
+		if filter.ExceptFamilyId != "" {
+			stmt = stmt.Where("family_id <> ?", filter.ExceptFamilyId)
+		}
+
+		err := stmt.Distinct().Pluck("family_id", &familyIds).Error
+		if err != nil {
+			return err
+		}
+		if len(familyIds) == 0 {
+			return nil
+		}
+
+		return tx.
+			Model(&entity.RefreshToken{}).
+			Where("family_id IN ? AND revoked_at IS NULL", familyIds).
+			Update("revoked_at", time.Now()).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return familyIds, nil
+}
+
+type revokedTokenStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.RevokedTokenStorage = (*revokedTokenStorage)(nil)
+
+func NewRevokedTokenStorage(postgresql *database.PostgreSQL) service.RevokedTokenStorage {
+	return &revokedTokenStorage{postgresql}
+}
+
+func (r revokedTokenStorage) CreateRevokedTokens(ctx context.Context, tokens []entity.RevokedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	return r.DB.WithContext(ctx).Create(&tokens).Error
+}
+
+func (r revokedTokenStorage) IsTokenRevoked(ctx context.Context, filter *service.IsTokenRevokedFilter) (bool, error) {
+	stmt := r.DB.
+		WithContext(ctx).
+		Model(&entity.RevokedToken{}).
+		Where("token_id = ?", filter.TokenId)
+
+	if filter.SessionId != "" {
+		stmt = stmt.Or("session_id = ?", filter.SessionId)
+	}
+
+	var count int64
+	err := stmt.Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r revokedTokenStorage) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	result := r.DB.
+		WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&entity.RevokedToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+type userTokenStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.UserTokenStorage = (*userTokenStorage)(nil)
+
+func NewUserTokenStorage(postgresql *database.PostgreSQL) service.UserTokenStorage {
+	return &userTokenStorage{postgresql}
+}
+
+func (u userTokenStorage) CreateUserToken(ctx context.Context, token *entity.UserToken) (*entity.UserToken, error) {
+	err := u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// only the latest token of the same purpose stays valid
+		err := tx.
+			Model(&entity.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserId, token.Purpose).
+			Update("used_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (u userTokenStorage) ConsumeUserToken(ctx context.Context, filter *service.ConsumeUserTokenFilter) (*entity.UserToken, error) {
+	var token entity.UserToken
+	err := u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", filter.TokenHash, filter.Purpose, time.Now()).
+			First(&token).
+			Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		token.UsedAt = &now
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+type personalAccessTokenStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.PersonalAccessTokenStorage = (*personalAccessTokenStorage)(nil)
+
+func NewPersonalAccessTokenStorage(postgresql *database.PostgreSQL) service.PersonalAccessTokenStorage {
+	return &personalAccessTokenStorage{postgresql}
+}
+
+func (p personalAccessTokenStorage) CreatePersonalAccessToken(ctx context.Context, token *entity.PersonalAccessToken) (*entity.PersonalAccessToken, error) {
+	err := p.DB.WithContext(ctx).Create(token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (p personalAccessTokenStorage) GetPersonalAccessToken(ctx context.Context, filter *service.GetPersonalAccessTokenFilter) (*entity.PersonalAccessToken, error) {
+	stmt := p.DB
+
+	if filter.TokenHash != "" {
+		stmt = stmt.Where(entity.PersonalAccessToken{TokenHash: filter.TokenHash})
+	}
+
+	var token entity.PersonalAccessToken
+	err := stmt.
+		WithContext(ctx).
+		First(&token).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (p personalAccessTokenStorage) ListPersonalAccessTokens(ctx context.Context, filter *service.ListPersonalAccessTokensFilter) ([]entity.PersonalAccessToken, error) {
+	var tokens []entity.PersonalAccessToken
+	err := p.DB.
+		WithContext(ctx).
+		Where(entity.PersonalAccessToken{UserId: filter.UserId}).
+		Order("created_at DESC").
+		Find(&tokens).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (p personalAccessTokenStorage) RevokePersonalAccessToken(ctx context.Context, filter *service.RevokePersonalAccessTokenFilter) (bool, error) {
+	result := p.DB.
+		WithContext(ctx).
+		Model(&entity.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", filter.Id, filter.UserId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (p personalAccessTokenStorage) TouchPersonalAccessToken(ctx context.Context, id string, usedAt time.Time) error {
+	return p.DB.
+		WithContext(ctx).
+		Model(&entity.PersonalAccessToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).
+		Error
+}
@@ -2,11 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"github.com/atlant1da-404/droplet/internal/entity"
 	"github.com/atlant1da-404/droplet/internal/service"
 	"github.com/atlant1da-404/droplet/pkg/database"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 type userStorage struct {
@@ -21,6 +24,10 @@ func NewUserStorage(postgresql *database.PostgreSQL) service.UserStorage {
 
 func (u userStorage) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
 	err := u.DB.WithContext(ctx).Create(user).Error
+	// emails are unique regardless of case, so concurrent sign ups with one email fail here
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -53,3 +60,310 @@ func (u userStorage) GetUser(ctx context.Context, filter *service.GetUserFilter)
 
 	return &user, nil
 }
+
+func (u userStorage) UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	err := u.DB.WithContext(ctx).Save(user).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u userStorage) ChangeUserEmail(ctx context.Context, user *entity.User, oldEmail string) (*entity.User, error) {
+	err := u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(user).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.
+			Model(&entity.Node{}).
+			Where("sender_email = ?", oldEmail).
+			Update("sender_email", user.Email).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Model(&entity.Node{}).
+			Where("receiver_email = ?", oldEmail).
+			Update("receiver_email", user.Email).
+			Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u userStorage) ListUsers(ctx context.Context, filter *service.ListUsersFilter) ([]entity.User, int64, error) {
+	stmt := u.DB.Model(&entity.User{})
+
+	if filter.DeletionDueBefore != nil {
+		stmt = stmt.Where("deletion_scheduled_for < ?", *filter.DeletionDueBefore)
+	}
+
+	if filter.UserIds != nil {
+		stmt = stmt.Where("id IN ?", filter.UserIds)
+	}
+
+	var total int64
+	err := stmt.
+		Session(&gorm.Session{}).
+		WithContext(ctx).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var users []entity.User
+	err = stmt.
+		WithContext(ctx).
+		Order("email").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (u userStorage) SearchUsers(ctx context.Context, filter *service.SearchUsersFilter) ([]entity.User, error) {
+	stmt := u.DB.
+		Model(&entity.User{}).
+		Where("id <> ? AND disabled = ? AND deletion_scheduled_for IS NULL", filter.SearcherId, false).
+		Where("NOT EXISTS (?)", u.DB.
+			Model(&entity.Block{}).
+			Select("1").
+			Where("blocks.user_id = users.id AND blocks.blocked_user_id = ?", filter.SearcherId),
+		)
+
+	if filter.UsernamePrefix != "" {
+		// served by idx_users_username_prefix
+		stmt = stmt.Where("lower(username) LIKE ?", escapeLike(strings.ToLower(filter.UsernamePrefix))+"%")
+	}
+
+	if filter.Email != "" {
+		stmt = stmt.Where("email = ?", filter.Email)
+	}
+
+	if len(filter.UserIds) > 0 {
+		stmt = stmt.Where("id IN ?", filter.UserIds)
+	}
+
+	if len(filter.Hidden) > 0 {
+		stmt = stmt.Where("NOT EXISTS (?)", u.whereDiscoverability(filter.Hidden))
+	}
+
+	if len(filter.ContactsOfContacts) > 0 {
+		contactIds := u.DB.
+			Model(&entity.Contact{}).
+			Select("CASE WHEN requester_id = ? THEN addressee_id ELSE requester_id END", filter.SearcherId).
+			Where("status = ? AND (requester_id = ? OR addressee_id = ?)", entity.ContactStatusAccepted, filter.SearcherId, filter.SearcherId)
+		contactOfContact := u.DB.
+			Model(&entity.Contact{}).
+			Select("1").
+			Where("status = ?", entity.ContactStatusAccepted).
+			Where("(requester_id = users.id AND addressee_id IN (?)) OR (addressee_id = users.id AND requester_id IN (?))", contactIds, contactIds)
+
+		stmt = stmt.Where(
+			"NOT EXISTS (?) OR users.id IN (?) OR EXISTS (?)",
+			u.whereDiscoverability(filter.ContactsOfContacts), contactIds, contactOfContact,
+		)
+	}
+
+	var users []entity.User
+	err := stmt.
+		WithContext(ctx).
+		Order("username, id").
+		Limit(filter.Limit).
+		Find(&users).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// whereDiscoverability selects accounts of the user from outer query whose discoverability is one of values.
+func (u userStorage) whereDiscoverability(values []string) *gorm.DB {
+	return u.DB.
+		Model(&entity.Account{}).
+		Select("1").
+		Joins("JOIN account_settings ON account_settings.account_id = accounts.id").
+		Where("accounts.user_id = users.id AND account_settings.discoverability IN ?", values)
+}
+
+// escapeLike escapes wildcards of LIKE pattern, so the value matches only literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (u userStorage) DeleteUser(ctx context.Context, userId string) error {
+	return u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user entity.User
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(&user, "id = ?", userId).
+			Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var accountIds []string
+		err = tx.
+			Model(&entity.Account{}).
+			Where("user_id = ?", userId).
+			Pluck("id", &accountIds).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if len(accountIds) > 0 {
+			for _, model := range []interface{}{&entity.AccountDevices{}, &entity.AccountSettings{}} {
+				err = tx.Where("account_id IN ?", accountIds).Delete(model).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = tx.Where("sender_email = ? OR receiver_email = ?", user.Email, user.Email).Delete(&entity.Node{}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&entity.Account{},
+			&entity.RefreshToken{},
+			&entity.UserToken{},
+			&entity.RecoveryCode{},
+			&entity.UserIdentity{},
+			&entity.PersonalAccessToken{},
+			&entity.AuditEvent{},
+			&entity.DataExport{},
+			&entity.DeviceKey{},
+		} {
+			err = tx.Where("user_id = ?", userId).Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Where("requester_id = ? OR addressee_id = ?", userId, userId).Delete(&entity.Contact{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("user_id = ? OR blocked_user_id = ?", userId, userId).Delete(&entity.Block{}).Error
+		if err != nil {
+			return err
+		}
+
+		var groupIds []string
+		err = tx.
+			Model(&entity.GroupMember{}).
+			Where("user_id = ?", userId).
+			Pluck("group_id", &groupIds).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if len(groupIds) > 0 {
+			err = tx.Where("user_id = ?", userId).Delete(&entity.GroupMember{}).Error
+			if err != nil {
+				return err
+			}
+
+			// groups nobody else is member of are deleted, the longest standing member
+			// takes over group which was left without owner
+			err = tx.
+				Where("id IN ? AND NOT EXISTS (SELECT 1 FROM group_members WHERE group_members.group_id = groups.id)", groupIds).
+				Delete(&entity.Group{}).
+				Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.
+				Model(&entity.GroupMember{}).
+				Where("id IN (?)", tx.
+					Model(&entity.GroupMember{}).
+					Select("DISTINCT ON (group_id) id").
+					Where("group_id IN ?", groupIds).
+					Where("group_id NOT IN (?)", tx.Model(&entity.GroupMember{}).Select("group_id").Where("role = ?", entity.GroupRoleOwner)).
+					Order("group_id, created_at, id"),
+				).
+				Update("role", entity.GroupRoleOwner).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Where("key = ?", "email:"+strings.ToLower(user.Email)).Delete(&entity.LoginThrottle{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("key = ?", "search:"+userId).Delete(&entity.RateLimit{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
+
+type recoveryCodeStorage struct {
+	*database.PostgreSQL
+}
+
+var _ service.RecoveryCodeStorage = (*recoveryCodeStorage)(nil)
+
+func NewRecoveryCodeStorage(postgresql *database.PostgreSQL) service.RecoveryCodeStorage {
+	return &recoveryCodeStorage{postgresql}
+}
+
+func (r recoveryCodeStorage) ReplaceRecoveryCodes(ctx context.Context, userId string, codes []entity.RecoveryCode) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userId).Delete(&entity.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r recoveryCodeStorage) ConsumeRecoveryCode(ctx context.Context, userId, codeHash string) (bool, error) {
+	result := r.DB.
+		WithContext(ctx).
+		Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
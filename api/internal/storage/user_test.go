@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"github.com/atlant1da-404/droplet/internal/entity"
+	"github.com/atlant1da-404/droplet/internal/service"
+	"github.com/jackc/pgx/v5/pgconn"
+	"reflect"
+	"testing"
+)
+
+func TestDeleteUser(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, map[string]recordedRows{
+		"users":         {columns: []string{"id", "email"}, values: [][]driver.Value{{"user-1", "User@Droplet.local"}}},
+		"accounts":      {columns: []string{"id"}, values: [][]driver.Value{{"account-1"}, {"account-2"}}},
+		"group_members": {columns: []string{"group_id"}, values: [][]driver.Value{{"group-1"}}},
+	})
+
+	err := NewUserStorage(postgresql).DeleteUser(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	// user is locked first, so nothing can be added for the user while the records are erased
+	wantQueries := []string{
+		"BEGIN",
+		`SELECT * FROM "users" WHERE id = $1 LIMIT 1 FOR UPDATE`,
+		`SELECT "id" FROM "accounts" WHERE user_id = $1`,
+		`DELETE FROM "account_devices" WHERE account_id IN ($1,$2)`,
+		`DELETE FROM "account_settings" WHERE account_id IN ($1,$2)`,
+		`DELETE FROM "nodes" WHERE sender_email = $1 OR receiver_email = $2`,
+		`DELETE FROM "accounts" WHERE user_id = $1`,
+		`DELETE FROM "refresh_tokens" WHERE user_id = $1`,
+		`DELETE FROM "user_tokens" WHERE user_id = $1`,
+		`DELETE FROM "recovery_codes" WHERE user_id = $1`,
+		`DELETE FROM "user_identities" WHERE user_id = $1`,
+		`DELETE FROM "personal_access_tokens" WHERE user_id = $1`,
+		`DELETE FROM "audit_events" WHERE user_id = $1`,
+		`DELETE FROM "data_exports" WHERE user_id = $1`,
+		`DELETE FROM "device_keys" WHERE user_id = $1`,
+		`DELETE FROM "contacts" WHERE requester_id = $1 OR addressee_id = $2`,
+		`DELETE FROM "blocks" WHERE user_id = $1 OR blocked_user_id = $2`,
+		`SELECT "group_id" FROM "group_members" WHERE user_id = $1`,
+		`DELETE FROM "group_members" WHERE user_id = $1`,
+		`DELETE FROM "groups" WHERE id IN ($1) AND NOT EXISTS (SELECT 1 FROM group_members WHERE group_members.group_id = groups.id)`,
+		`UPDATE "group_members" SET "role"=$1 WHERE id IN (SELECT DISTINCT ON (group_id) id FROM "group_members" WHERE group_id IN ($2) AND group_id NOT IN (SELECT "group_id" FROM "group_members" WHERE role = $3) ORDER BY group_id, created_at, id)`,
+		`DELETE FROM "login_throttles" WHERE key = $1`,
+		`DELETE FROM "rate_limits" WHERE key = $1`,
+		`DELETE FROM "users" WHERE "users"."id" = $1`,
+		"COMMIT",
+	}
+	if got := connector.queries(""); !reflect.DeepEqual(got, wantQueries) {
+		t.Fatalf("DeleteUser() queries:\n%q\nwant:\n%q", got, wantQueries)
+	}
+
+	wantArgs := map[string][]interface{}{
+		`DELETE FROM "nodes" WHERE sender_email = $1 OR receiver_email = $2`: {"User@Droplet.local", "User@Droplet.local"},
+		`DELETE FROM "login_throttles" WHERE key = $1`:                       {"email:user@droplet.local"},
+		`DELETE FROM "rate_limits" WHERE key = $1`:                           {"search:user-1"},
+		`DELETE FROM "users" WHERE "users"."id" = $1`:                        {"user-1"},
+	}
+	for query, want := range wantArgs {
+		if got := connector.args(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s args = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestDeleteUserWithoutAccounts(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, map[string]recordedRows{
+		"users": {columns: []string{"id", "email"}, values: [][]driver.Value{{"user-1", "user@droplet.local"}}},
+	})
+
+	err := NewUserStorage(postgresql).DeleteUser(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	for _, query := range connector.queries(`DELETE FROM "account_`) {
+		t.Errorf("DeleteUser() executed %s for user without accounts", query)
+	}
+	if got := connector.queries(`DELETE FROM "users"`); len(got) != 1 {
+		t.Fatalf("DeleteUser() deleted users %d times, want 1", len(got))
+	}
+}
+
+func TestDeleteUserNotFound(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, nil)
+
+	err := NewUserStorage(postgresql).DeleteUser(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	// user may have been erased by a concurrent sweep already
+	if got := connector.queries("DELETE"); len(got) != 0 {
+		t.Fatalf("DeleteUser() of missing user executed %q", got)
+	}
+}
+
+func TestCreateUserEmailTaken(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, nil)
+	connector.failures = map[string]error{`INSERT INTO "users"`: &pgconn.PgError{Code: "23505"}}
+
+	user, err := NewUserStorage(postgresql).CreateUser(context.Background(), &entity.User{Email: "user@droplet.local"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user != nil {
+		t.Fatalf("CreateUser() = %+v, want nil for taken email", user)
+	}
+}
+
+func TestChangeUserEmailTaken(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, nil)
+	connector.failures = map[string]error{`UPDATE "users"`: &pgconn.PgError{Code: "23505"}}
+
+	user, err := NewUserStorage(postgresql).ChangeUserEmail(context.Background(), &entity.User{Id: "user-1", Email: "new@droplet.local"}, "old@droplet.local")
+	if err != nil {
+		t.Fatalf("ChangeUserEmail() error = %v", err)
+	}
+	if user != nil {
+		t.Fatalf("ChangeUserEmail() = %+v, want nil for taken email", user)
+	}
+
+	// transfers stay with the old email when the user keeps it
+	if got := connector.queries(`UPDATE "nodes"`); len(got) != 0 {
+		t.Fatalf("ChangeUserEmail() moved transfers with %q", got)
+	}
+	if got := connector.queries("ROLLBACK"); len(got) != 1 {
+		t.Fatalf("ChangeUserEmail() rolled back %d times, want 1", len(got))
+	}
+}
+
+func TestSearchUsersDiscoverability(t *testing.T) {
+	postgresql, connector := newRecordingPostgreSQL(t, nil)
+
+	_, err := NewUserStorage(postgresql).SearchUsers(context.Background(), &service.SearchUsersFilter{
+		SearcherId:         "searcher",
+		UsernamePrefix:     "Al_",
+		Hidden:             []string{entity.DiscoverabilityNobody},
+		ContactsOfContacts: []string{entity.DiscoverabilityContactsOfContacts},
+		Limit:              20,
+	})
+	if err != nil {
+		t.Fatalf("SearchUsers() error = %v", err)
+	}
+
+	// everyone is not mentioned, as such users are never filtered out, nobody is never found
+	// and contacts_of_contacts is found only by contacts of the user and by their contacts
+	contactIds := `SELECT CASE WHEN requester_id = $%d THEN addressee_id ELSE requester_id END FROM "contacts" WHERE status = $%d AND (requester_id = $%d OR addressee_id = $%d)`
+	discoverability := `SELECT 1 FROM "accounts" JOIN account_settings ON account_settings.account_id = accounts.id WHERE accounts.user_id = users.id AND account_settings.discoverability IN ($%d)`
+	wantQuery := `SELECT * FROM "users" WHERE (id <> $1 AND disabled = $2 AND deletion_scheduled_for IS NULL)` +
+		` AND NOT EXISTS (SELECT 1 FROM "blocks" WHERE blocks.user_id = users.id AND blocks.blocked_user_id = $3)` +
+		` AND lower(username) LIKE $4` +
+		` AND NOT EXISTS (` + fmt.Sprintf(discoverability, 5) + `)` +
+		` AND (NOT EXISTS (` + fmt.Sprintf(discoverability, 6) + `)` +
+		` OR users.id IN (` + fmt.Sprintf(contactIds, 7, 8, 9, 10) + `)` +
+		` OR EXISTS (SELECT 1 FROM "contacts" WHERE status = $11` +
+		` AND ((requester_id = users.id AND addressee_id IN (` + fmt.Sprintf(contactIds, 12, 13, 14, 15) + `))` +
+		` OR (addressee_id = users.id AND requester_id IN (` + fmt.Sprintf(contactIds, 16, 17, 18, 19) + `)))))` +
+		` ORDER BY username, id LIMIT 20`
+	if got := connector.queries("SELECT"); !reflect.DeepEqual(got, []string{wantQuery}) {
+		t.Fatalf("SearchUsers() queries:\n%q\nwant:\n%q", got, []string{wantQuery})
+	}
+
+	args := connector.args(wantQuery)
+	wantArgs := map[int]interface{}{
+		3: "searcher",
+		4: `al\_%`,
+		5: entity.DiscoverabilityNobody,
+		6: entity.DiscoverabilityContactsOfContacts,
+		8: entity.ContactStatusAccepted,
+	}
+	for position, want := range wantArgs {
+		if len(args) < position || args[position-1] != want {
+			t.Errorf("SearchUsers() arg $%d = %v, want %v", position, args, want)
+		}
+	}
+}
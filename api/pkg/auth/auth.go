@@ -1,16 +1,26 @@
 package auth
 
+import "time"
+
 type Authenticator interface {
 	GenerateToken(options *GenerateTokenClaimsOptions) (string, error)
 	ParseToken(accessToken string) (*ParseTokenClaimsOutput, error)
+	// PublicKeys returns verification keys of all active signing keys.
+	PublicKeys() []JSONWebKey
 }
 
 type GenerateTokenClaimsOptions struct {
-	UserId   string
-	UserName string
+	UserId    string
+	UserName  string
+	SessionId string
+	Role      string
 }
 
 type ParseTokenClaimsOutput struct {
-	UserId   string
-	Username string
+	UserId    string
+	Username  string
+	SessionId string
+	Role      string
+	TokenId   string
+	ExpiresAt time.Time
 }
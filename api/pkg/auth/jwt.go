@@ -4,31 +4,87 @@ import (
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"sort"
 	"time"
 )
 
+const _defaultAccessTokenTTL = 15 * time.Minute
+
 type jwtAuthenticator struct {
-	signKey string
+	signKey        string
+	accessTokenTTL time.Duration
+	// activeKey signs new tokens, while any key from keys can verify them.
+	activeKey *SigningKey
+	keys      map[string]*SigningKey
+}
+
+// Option - represents jwt authenticator option.
+type Option func(*jwtAuthenticator)
+
+// AccessTokenTTL - configures lifetime of generated access tokens.
+func AccessTokenTTL(ttl time.Duration) Option {
+	return func(s *jwtAuthenticator) {
+		s.accessTokenTTL = ttl
+	}
 }
 
-func NewAuth() Authenticator {
-	return &jwtAuthenticator{}
+// SignKey - configures HMAC secret which is used when no asymmetric keys are configured.
+func SignKey(signKey string) Option {
+	return func(s *jwtAuthenticator) {
+		s.signKey = signKey
+	}
+}
+
+// SigningKeys - configures asymmetric keys. Tokens are signed with the key with activeKeyId,
+// other keys are kept only for verification of tokens issued before the rotation.
+func SigningKeys(activeKeyId string, keys ...*SigningKey) Option {
+	return func(s *jwtAuthenticator) {
+		for _, key := range keys {
+			s.keys[key.Id] = key
+			if key.Id == activeKeyId {
+				s.activeKey = key
+			}
+		}
+	}
+}
+
+func NewAuth(opts ...Option) (Authenticator, error) {
+	authenticator := &jwtAuthenticator{
+		accessTokenTTL: _defaultAccessTokenTTL,
+		keys:           make(map[string]*SigningKey),
+	}
+
+	// add custom options
+	for _, opt := range opts {
+		opt(authenticator)
+	}
+
+	if len(authenticator.keys) > 0 && authenticator.activeKey == nil {
+		return nil, fmt.Errorf("active signing key is not found among configured keys")
+	}
+	if len(authenticator.keys) == 0 && authenticator.signKey == "" {
+		return nil, fmt.Errorf("neither signing keys nor sign key are configured")
+	}
+
+	return authenticator, nil
 }
 
 type MyCustomClaims struct {
-	Username string `json:"username"`
-	UserId   string `json:"userId"`
+	Username  string `json:"username"`
+	UserId    string `json:"userId"`
+	SessionId string `json:"sessionId"`
+	Role      string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func (s *jwtAuthenticator) GenerateToken(tokenClaims *GenerateTokenClaimsOptions) (string, error) {
-	mySigningKey := []byte(s.signKey)
-
 	claims := MyCustomClaims{
-		Username: tokenClaims.UserName,
-		UserId:   tokenClaims.UserId,
+		Username:  tokenClaims.UserName,
+		UserId:    tokenClaims.UserId,
+		SessionId: tokenClaims.SessionId,
+		Role:      tokenClaims.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(560 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "droplet-api",
@@ -37,9 +93,16 @@ func (s *jwtAuthenticator) GenerateToken(tokenClaims *GenerateTokenClaimsOptions
 			Audience:  []string{"droplet"},
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	signedToken, err := token.SignedString(mySigningKey)
+	if s.activeKey == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(s.signKey))
+	}
+
+	token := jwt.NewWithClaims(s.activeKey.Method, claims)
+	token.Header["kid"] = s.activeKey.Id
+
+	signedToken, err := token.SignedString(s.activeKey.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -48,14 +111,7 @@ func (s *jwtAuthenticator) GenerateToken(tokenClaims *GenerateTokenClaimsOptions
 }
 
 func (s *jwtAuthenticator) ParseToken(accessToken string) (*ParseTokenClaimsOutput, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(s.signKey), nil
-	})
+	token, err := jwt.Parse(accessToken, s.verificationKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse jwt token: %w", err)
 	}
@@ -74,6 +130,72 @@ func (s *jwtAuthenticator) ParseToken(accessToken string) (*ParseTokenClaimsOutp
 	if userId == nil {
 		return nil, fmt.Errorf("token is not valid")
 	}
+	tokenId := claims["jti"]
+	if tokenId == nil {
+		return nil, fmt.Errorf("token is not valid")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, fmt.Errorf("token is not valid")
+	}
+
+	var sessionId string
+	if claims["sessionId"] != nil {
+		sessionId = fmt.Sprint(claims["sessionId"])
+	}
+
+	var role string
+	if claims["role"] != nil {
+		role = fmt.Sprint(claims["role"])
+	}
+
+	return &ParseTokenClaimsOutput{
+		UserId:    fmt.Sprint(userId),
+		Username:  fmt.Sprint(username),
+		SessionId: sessionId,
+		Role:      role,
+		TokenId:   fmt.Sprint(tokenId),
+		ExpiresAt: expiresAt.Time,
+	}, nil
+}
+
+func (s *jwtAuthenticator) PublicKeys() []JSONWebKey {
+	publicKeys := make([]JSONWebKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		publicKeys = append(publicKeys, key.JSONWebKey())
+	}
+
+	sort.Slice(publicKeys, func(i, j int) bool {
+		return publicKeys[i].KeyId < publicKeys[j].KeyId
+	})
+
+	return publicKeys
+}
+
+// verificationKey picks key by "kid" header and makes sure that token is signed
+// with the algorithm of that key, so an attacker cannot downgrade it.
+func (s *jwtAuthenticator) verificationKey(token *jwt.Token) (interface{}, error) {
+	if len(s.keys) == 0 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return []byte(s.signKey), nil
+	}
+
+	keyId, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("token has no key id")
+	}
+
+	key, ok := s.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", keyId)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
 
-	return &ParseTokenClaimsOutput{UserId: fmt.Sprint(userId), Username: fmt.Sprint(username)}, nil
+	return key.PublicKey, nil
 }
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _testClaims = &GenerateTokenClaimsOptions{
+	UserId:    "user-1",
+	UserName:  "user",
+	SessionId: "session-1",
+	Role:      "admin",
+}
+
+func newTestSigningKeys(t *testing.T) (*SigningKey, *SigningKey) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %v", err)
+	}
+	rsaSigningKey, err := ParseSigningKey("rsa", pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	}))
+	if err != nil {
+		t.Fatalf("ParseSigningKey() error = %v", err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("failed to marshal ed25519 key: %v", err)
+	}
+	edSigningKey, err := ParseSigningKey("ed25519", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParseSigningKey() error = %v", err)
+	}
+
+	return rsaSigningKey, edSigningKey
+}
+
+func newTestAuth(t *testing.T, opts ...Option) Authenticator {
+	t.Helper()
+
+	authenticator, err := NewAuth(opts...)
+	if err != nil {
+		t.Fatalf("NewAuth() error = %v", err)
+	}
+
+	return authenticator
+}
+
+func TestParseToken(t *testing.T) {
+	rsaKey, edKey := newTestSigningKeys(t)
+
+	tests := []struct {
+		name    string
+		issuer  Authenticator
+		parser  Authenticator
+		wantErr string
+	}{
+		{
+			name:   "hmac",
+			issuer: newTestAuth(t, SignKey("secret")),
+			parser: newTestAuth(t, SignKey("secret")),
+		},
+		{
+			name:    "hmac with other secret",
+			issuer:  newTestAuth(t, SignKey("secret")),
+			parser:  newTestAuth(t, SignKey("other secret")),
+			wantErr: "signature is invalid",
+		},
+		{
+			name:   "rsa",
+			issuer: newTestAuth(t, SigningKeys("rsa", rsaKey)),
+			parser: newTestAuth(t, SigningKeys("rsa", rsaKey)),
+		},
+		{
+			name:   "ed25519",
+			issuer: newTestAuth(t, SigningKeys("ed25519", edKey)),
+			parser: newTestAuth(t, SigningKeys("ed25519", edKey)),
+		},
+		{
+			name:   "signed before rotation",
+			issuer: newTestAuth(t, SigningKeys("rsa", rsaKey)),
+			parser: newTestAuth(t, SigningKeys("ed25519", rsaKey, edKey)),
+		},
+		{
+			name:    "signed by retired key",
+			issuer:  newTestAuth(t, SigningKeys("rsa", rsaKey)),
+			parser:  newTestAuth(t, SigningKeys("ed25519", edKey)),
+			wantErr: "unknown key id",
+		},
+		{
+			name:    "hmac token once keys are configured",
+			issuer:  newTestAuth(t, SignKey("secret")),
+			parser:  newTestAuth(t, SigningKeys("rsa", rsaKey)),
+			wantErr: "token has no key id",
+		},
+		{
+			name:    "expired",
+			issuer:  newTestAuth(t, SignKey("secret"), AccessTokenTTL(-time.Minute)),
+			parser:  newTestAuth(t, SignKey("secret")),
+			wantErr: "token is expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.issuer.GenerateToken(_testClaims)
+			if err != nil {
+				t.Fatalf("GenerateToken() error = %v", err)
+			}
+
+			claims, err := tt.parser.ParseToken(token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseToken() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseToken() error = %v", err)
+			}
+
+			if claims.UserId != _testClaims.UserId || claims.Username != _testClaims.UserName ||
+				claims.SessionId != _testClaims.SessionId || claims.Role != _testClaims.Role {
+				t.Errorf("ParseToken() = %+v, want claims of %+v", claims, _testClaims)
+			}
+			if claims.TokenId == "" {
+				t.Errorf("token has no id")
+			}
+		})
+	}
+}
+
+func TestParseTokenRejectsAlgorithmOfOtherKey(t *testing.T) {
+	rsaKey, edKey := newTestSigningKeys(t)
+	authenticator := newTestAuth(t, SigningKeys("rsa", rsaKey))
+
+	// token names the rsa key but is signed with ed25519, which must not be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"username": "user",
+		"userId":   "user-1",
+		"jti":      "token-1",
+		"exp":      time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = "rsa"
+	signed, err := token.SignedString(edKey.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	_, err = authenticator.ParseToken(signed)
+	if err == nil || !strings.Contains(err.Error(), "unexpected signing method") {
+		t.Fatalf("ParseToken() error = %v, want unexpected signing method", err)
+	}
+}
+
+func TestPublicKeys(t *testing.T) {
+	rsaKey, edKey := newTestSigningKeys(t)
+
+	keys := newTestAuth(t, SigningKeys("rsa", rsaKey, edKey)).PublicKeys()
+	if len(keys) != 2 {
+		t.Fatalf("PublicKeys() returned %d keys, want 2", len(keys))
+	}
+
+	want := []JSONWebKey{
+		{KeyType: "OKP", KeyId: "ed25519", Algorithm: "EdDSA", Curve: "Ed25519"},
+		{KeyType: "RSA", KeyId: "rsa", Algorithm: "RS256"},
+	}
+	for i, key := range keys {
+		if key.KeyType != want[i].KeyType || key.KeyId != want[i].KeyId ||
+			key.Algorithm != want[i].Algorithm || key.Curve != want[i].Curve || key.Use != "sig" {
+			t.Errorf("PublicKeys()[%d] = %+v, want %+v", i, key, want[i])
+		}
+	}
+	if keys[0].X == "" || keys[1].N == "" || keys[1].E != "AQAB" {
+		t.Errorf("PublicKeys() are missing key material: %+v", keys)
+	}
+}
+
+func TestNewAuthRequiresKey(t *testing.T) {
+	rsaKey, _ := newTestSigningKeys(t)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "no keys", wantErr: true},
+		{name: "empty sign key", opts: []Option{SignKey("")}, wantErr: true},
+		{name: "unknown active key", opts: []Option{SigningKeys("other", rsaKey)}, wantErr: true},
+		{name: "sign key", opts: []Option{SignKey("secret")}},
+		{name: "signing keys", opts: []Option{SigningKeys(rsaKey.Id, rsaKey)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuth(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuth() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// SigningKey is an asymmetric key used to sign and verify access tokens.
+type SigningKey struct {
+	// Id is published in "kid" header of signed tokens.
+	Id         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// LoadSigningKey creates signing key from PEM encoded private key or from path to the PEM file.
+func LoadSigningKey(id, value string) (*SigningKey, error) {
+	pemBytes := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		var err error
+		pemBytes, err = os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+	}
+
+	return ParseSigningKey(id, pemBytes)
+}
+
+// ParseSigningKey creates signing key from PEM encoded PKCS #8 (RSA or Ed25519) or PKCS #1 (RSA) private key.
+func ParseSigningKey(id string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode pem block of key %q", id)
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block type %q of key %q", block.Type, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", id, err)
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{Id: id, Method: jwt.SigningMethodRS256, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{Id: id, Method: jwt.SigningMethodEdDSA, PrivateKey: key, PublicKey: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T of key %q", privateKey, id)
+	}
+}
+
+// JSONWebKey represents public part of signing key as described in RFC 7517.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// RSA public key parameters.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP public key parameters.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JSONWebKey returns public part of the key in JWK format.
+func (k *SigningKey) JSONWebKey() JSONWebKey {
+	jwk := JSONWebKey{Use: "sig", KeyId: k.Id, Algorithm: k.Method.Alg()}
+
+	switch key := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+
+	return jwk
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// _opaqueTokenLength is a number of random bytes in opaque token.
+const _opaqueTokenLength = 32
+
+// GenerateOpaqueToken returns random url-safe token which carries no claims
+// and can be validated only by looking it up in storage.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, _opaqueTokenLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashOpaqueToken returns SHA-256 hex digest of opaque token that is safe to keep in storage.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
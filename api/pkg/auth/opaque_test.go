@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token, err := GenerateOpaqueToken()
+		if err != nil {
+			t.Fatalf("GenerateOpaqueToken() error = %v", err)
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			t.Fatalf("token %q is not url-safe base64: %v", token, err)
+		}
+		if len(decoded) != _opaqueTokenLength {
+			t.Fatalf("token has %d random bytes, want %d", len(decoded), _opaqueTokenLength)
+		}
+		if seen[token] {
+			t.Fatalf("token %q generated twice", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestHashOpaqueToken(t *testing.T) {
+	// SHA-256 test vector from FIPS 180-2
+	got := HashOpaqueToken("abc")
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Fatalf("HashOpaqueToken() = %s, want %s", got, want)
+	}
+}
@@ -53,6 +53,8 @@ func NewPostgreSQL(cfg PostgreSQLConfig, opts ...Option) (*PostgreSQL, error) {
 	)
 	sql.DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
 		PrepareStmt: true,
+		// constraint violations are returned as gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to postgresql: %w", err)
@@ -0,0 +1,75 @@
+// Package devicekey validates long-term public keys which devices publish for end-to-end encrypted transfers.
+// Every device has X25519 key which senders encrypt to and Ed25519 key which signs device statements.
+package devicekey
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	_x25519KeySize = 32
+	// _registrationContext separates registration signatures from anything else the key may sign.
+	_registrationContext = "droplet-device-key-v1"
+)
+
+// PublicKeys - represents public keys of single device.
+type PublicKeys struct {
+	Encryption []byte
+	Signing    ed25519.PublicKey
+}
+
+// Parse decodes base64 encoded X25519 and Ed25519 public keys and validates their sizes.
+func Parse(encryptionKey, signingKey string) (*PublicKeys, error) {
+	encryption, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	if len(encryption) != _x25519KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", _x25519KeySize)
+	}
+	// all-zero point yields all-zero shared secret
+	if subtle.ConstantTimeCompare(encryption, make([]byte, _x25519KeySize)) == 1 {
+		return nil, fmt.Errorf("encryption key must not be zero")
+	}
+
+	signing, err := base64.StdEncoding.DecodeString(signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("signing key is not valid base64: %w", err)
+	}
+	if len(signing) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("signing key must be %d bytes", ed25519.PublicKeySize)
+	}
+
+	return &PublicKeys{Encryption: encryption, Signing: signing}, nil
+}
+
+// Fingerprint returns hex encoded SHA-256 of both keys, users compare it to detect key changes.
+func (k *PublicKeys) Fingerprint() string {
+	sum := sha256.Sum256(append(append([]byte{}, k.Signing...), k.Encryption...))
+	return hex.EncodeToString(sum[:])
+}
+
+// RegistrationMessage returns bytes which trusted device signs to vouch for new keys of the device.
+func RegistrationMessage(accountId, deviceId, encryptionKey, signingKey string) []byte {
+	return []byte(strings.Join([]string{_registrationContext, accountId, deviceId, encryptionKey, signingKey}, "\n"))
+}
+
+// Verify reports whether base64 encoded signature of message is made by signingKey.
+func Verify(signingKey, message []byte, signature string) bool {
+	if len(signingKey) != ed25519.PublicKeySize {
+		return false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(signingKey, message, decoded)
+}
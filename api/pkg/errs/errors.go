@@ -5,6 +5,14 @@ type Err struct {
 	Message string            `json:"message"`
 	Code    string            `json:"code"`
 	Details map[string]string `json:"details"`
+	Fields  []FieldError      `json:"fields,omitempty"`
+}
+
+// FieldError describes why value of a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func New(message, code string) *Err {
@@ -15,6 +23,16 @@ func (e *Err) Error() string {
 	return e.Message
 }
 
+// WithDetails returns copy of the error with given details, so shared errors are not modified.
+func (e *Err) WithDetails(details map[string]string) *Err {
+	return &Err{Message: e.Message, Code: e.Code, Details: details, Fields: e.Fields}
+}
+
+// WithFields returns copy of the error with given invalid fields, so shared errors are not modified.
+func (e *Err) WithFields(fields ...FieldError) *Err {
+	return &Err{Message: e.Message, Code: e.Code, Details: e.Details, Fields: fields}
+}
+
 // IsExpected finds Err{} inside passed error.
 func IsExpected(err error) bool {
 	_, ok := err.(*Err)
@@ -29,3 +47,21 @@ func GetCode(err error) string {
 	}
 	return v.Code
 }
+
+// GetDetails returns details of given error or nil if error is not custom
+func GetDetails(err error) map[string]string {
+	v, ok := err.(*Err)
+	if !ok {
+		return nil
+	}
+	return v.Details
+}
+
+// GetFields returns invalid fields of given error or nil if error is not custom
+func GetFields(err error) []FieldError {
+	v, ok := err.(*Err)
+	if !ok {
+		return nil
+	}
+	return v.Fields
+}
@@ -0,0 +1,135 @@
+package hash
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+var _testParams = Argon2Params{Memory: 64, Iterations: 2, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+// salt and key are base64 of 16 and 32 zero bytes
+const (
+	_testSalt = "AAAAAAAAAAAAAAAAAAAAAA"
+	_testKey  = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+)
+
+func TestDecodeArgon2idHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		encoded    string
+		wantParams Argon2Params
+		wantErr    bool
+	}{
+		{
+			name:       "valid",
+			encoded:    "$argon2id$v=19$m=65536,t=3,p=2$" + _testSalt + "$" + _testKey,
+			wantParams: Argon2Params{Memory: 65536, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+		},
+		{name: "argon2i", encoded: "$argon2i$v=19$m=65536,t=3,p=2$" + _testSalt + "$" + _testKey, wantErr: true},
+		{name: "missing key", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + _testSalt, wantErr: true},
+		{name: "unsupported version", encoded: "$argon2id$v=16$m=65536,t=3,p=2$" + _testSalt + "$" + _testKey, wantErr: true},
+		{name: "malformed version", encoded: "$argon2id$version$m=65536,t=3,p=2$" + _testSalt + "$" + _testKey, wantErr: true},
+		{name: "malformed parameters", encoded: "$argon2id$v=19$t=3,m=65536,p=2$" + _testSalt + "$" + _testKey, wantErr: true},
+		{name: "padded salt", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + _testSalt + "==$" + _testKey, wantErr: true},
+		{name: "malformed key", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + _testSalt + "$!", wantErr: true},
+		{name: "empty", encoded: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, salt, key, err := decodeArgon2idHash(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeArgon2idHash() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeArgon2idHash() error = %v", err)
+			}
+			if *params != tt.wantParams {
+				t.Errorf("params = %+v, want %+v", *params, tt.wantParams)
+			}
+			if len(salt) != 16 || len(key) != 32 {
+				t.Errorf("decoded %d bytes of salt and %d bytes of key, want 16 and 32", len(salt), len(key))
+			}
+		})
+	}
+}
+
+func TestArgon2idCompareHash(t *testing.T) {
+	hasher := NewArgon2idHash(_testParams)
+
+	hashed, err := hasher.GenerateHash("correct horse")
+	if err != nil {
+		t.Fatalf("GenerateHash() error = %v", err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to generate bcrypt hash: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hashed   string
+		password string
+		wantErr  bool
+	}{
+		{name: "argon2id", hashed: hashed, password: "correct horse"},
+		{name: "argon2id wrong password", hashed: hashed, password: "battery staple", wantErr: true},
+		{name: "bcrypt", hashed: string(legacy), password: "correct horse"},
+		{name: "bcrypt wrong password", hashed: string(legacy), password: "battery staple", wantErr: true},
+		{name: "unknown format", hashed: "plain", password: "plain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hasher.CompareHash([]byte(tt.hashed), []byte(tt.password))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompareHash() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to generate bcrypt hash: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params Argon2Params
+		// hashed overrides hash generated with params
+		hashed string
+		want   bool
+	}{
+		{name: "current parameters", params: _testParams, want: false},
+		{name: "stronger parameters", params: Argon2Params{Memory: 128, Iterations: 3, Parallelism: 4, SaltLength: 32, KeyLength: 64}, want: false},
+		{name: "less memory", params: Argon2Params{Memory: 32, Iterations: 2, Parallelism: 2, SaltLength: 16, KeyLength: 32}, want: true},
+		{name: "fewer iterations", params: Argon2Params{Memory: 64, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}, want: true},
+		{name: "lower parallelism", params: Argon2Params{Memory: 64, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}, want: true},
+		{name: "shorter salt", params: Argon2Params{Memory: 64, Iterations: 2, Parallelism: 2, SaltLength: 8, KeyLength: 32}, want: true},
+		{name: "shorter key", params: Argon2Params{Memory: 64, Iterations: 2, Parallelism: 2, SaltLength: 16, KeyLength: 16}, want: true},
+		{name: "bcrypt", hashed: string(legacy), want: true},
+		{name: "malformed", hashed: "$argon2id$v=19$m=64", want: true},
+	}
+
+	hasher := NewArgon2idHash(_testParams)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashed := tt.hashed
+			if hashed == "" {
+				hashed, err = NewArgon2idHash(tt.params).GenerateHash("password")
+				if err != nil {
+					t.Fatalf("GenerateHash() error = %v", err)
+				}
+			}
+
+			if got := hasher.NeedsRehash(hashed); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
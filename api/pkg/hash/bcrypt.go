@@ -2,6 +2,8 @@ package hash
 
 import "golang.org/x/crypto/bcrypt"
 
+const _defaultBcryptCost = 8
+
 type bcryptor struct {
 }
 
@@ -10,7 +12,7 @@ func NewHash() Hash {
 }
 
 func (b *bcryptor) GenerateHash(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 8)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), _defaultBcryptCost)
 	if err != nil {
 		return "", err
 	}
@@ -25,3 +27,12 @@ func (b *bcryptor) CompareHash(hashedPassword, password []byte) error {
 	}
 	return nil
 }
+
+func (b *bcryptor) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	if err != nil {
+		return true
+	}
+
+	return cost < _defaultBcryptCost
+}
@@ -3,4 +3,7 @@ package hash
 type Hash interface {
 	GenerateHash(password string) (string, error)
 	CompareHash(hashedPassword, password []byte) error
+	// NeedsRehash reports whether hash was produced by other algorithm or weaker parameters
+	// than currently configured ones.
+	NeedsRehash(hashedPassword string) bool
 }
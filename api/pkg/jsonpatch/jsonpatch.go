@@ -0,0 +1,482 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7396) documents.
+// Documents are decoded with json.Number, so integers survive patching without float rounding.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Operation - represents single JSON Patch operation.
+// Value is kept raw, so explicit null can be told apart from missing value.
+type Operation struct {
+	Op    string          `json:"op" enums:"add,remove,replace,move,copy,test"`
+	Path  string          `json:"path" example:"/accountSettings/theme"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+// Patch - represents JSON Patch document, operations are applied in order.
+type Patch []Operation
+
+// Validate checks that every operation is known and has members it requires.
+func (p Patch) Validate() error {
+	for i, operation := range p {
+		_, err := ParsePointer(operation.Path)
+		if err != nil {
+			return fmt.Errorf("operation %d: invalid path: %w", i, err)
+		}
+
+		switch operation.Op {
+		case OpAdd, OpReplace, OpTest:
+			if len(operation.Value) == 0 {
+				return fmt.Errorf("operation %d: %s requires value", i, operation.Op)
+			}
+		case OpMove, OpCopy:
+			_, err = ParsePointer(operation.From)
+			if err != nil {
+				return fmt.Errorf("operation %d: invalid from: %w", i, err)
+			}
+		case OpRemove:
+		default:
+			return fmt.Errorf("operation %d: unknown op %q", i, operation.Op)
+		}
+	}
+
+	return nil
+}
+
+// Apply applies the patch to JSON document. Patch is atomic: when any operation fails
+// (including failed test), error is returned and the document is left as it was.
+func (p Patch) Apply(document []byte) ([]byte, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := decode(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	for i, operation := range p {
+		root, err = operation.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (o Operation) apply(root interface{}) (interface{}, error) {
+	// pointers are already validated by Validate
+	path, _ := ParsePointer(o.Path)
+
+	switch o.Op {
+	case OpAdd:
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		return add(root, path, value)
+
+	case OpRemove:
+		root, _, err := remove(root, path)
+		return root, err
+
+	case OpReplace:
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		return replace(root, path, value)
+
+	case OpMove:
+		from, _ := ParsePointer(o.From)
+		if len(from) < len(path) && isPrefix(from, path) {
+			return nil, fmt.Errorf("cannot move value into one of its children")
+		}
+		root, value, err := remove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+
+	case OpCopy:
+		from, _ := ParsePointer(o.From)
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, deepCopy(value))
+
+	case OpTest:
+		expected, err := decode(o.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		actual, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, expected) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("unknown op %q", o.Op)
+}
+
+// MergePatch applies merge patch to JSON document: members of patch object replace members
+// of the document, null removes member and nested objects are merged recursively.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	root, err := decode(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	return json.Marshal(mergePatch(root, patchValue))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// MergePatchPaths returns pointers to every value merge patch sets or removes. Objects are
+// descended into, arrays and scalars replace target as a whole and are reported as is.
+// Paths are sorted, so errors about them come in stable order.
+func MergePatchPaths(patch []byte) ([]string, error) {
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	var paths []string
+	var walk func(value interface{}, tokens []string)
+	walk = func(value interface{}, tokens []string) {
+		object, ok := value.(map[string]interface{})
+		if !ok || len(object) == 0 {
+			paths = append(paths, FormatPointer(tokens...))
+			return
+		}
+		for key, member := range object {
+			walk(member, append(tokens[:len(tokens):len(tokens)], key))
+		}
+	}
+	walk(patchValue, nil)
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// ParsePointer splits JSON Pointer (RFC 6901) into unescaped reference tokens,
+// empty pointer refers to the whole document.
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("pointer must start with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// ~1 is unescaped first, so ~01 turns into ~1 and not into /
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// FormatPointer joins reference tokens into JSON Pointer.
+func FormatPointer(tokens ...string) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteByte('/')
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+
+	return builder.String()
+}
+
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return value, nil
+}
+
+func get(root interface{}, path []string) (interface{}, error) {
+	value := root
+	for _, token := range path {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			member, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			value = member
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			value = container[index]
+		default:
+			return nil, fmt.Errorf("cannot reference %q in scalar value", token)
+		}
+	}
+
+	return value, nil
+}
+
+// modify finds parent of the target and replaces it with result of change, so change
+// is free to return new slice when array grows or shrinks.
+func modify(root interface{}, path []string, change func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return change(root, path[0])
+	}
+
+	child, err := get(root, path[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = modify(child, path[1:], change)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := root.(type) {
+	case map[string]interface{}:
+		container[path[0]] = child
+	case []interface{}:
+		// index is already validated by get
+		index, _ := strconv.Atoi(path[0])
+		container[index] = child
+	}
+
+	return root, nil
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			if token == "-" {
+				return append(container, value), nil
+			}
+			index, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		default:
+			return nil, fmt.Errorf("cannot add %q to scalar value", token)
+		}
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove whole document")
+	}
+
+	var removed interface{}
+	root, err := modify(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			member, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			removed = member
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[index]
+			return append(container[:index], container[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %q from scalar value", token)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, removed, nil
+}
+
+func replace(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			container[index] = value
+			return container, nil
+		default:
+			return nil, fmt.Errorf("cannot replace %q in scalar value", token)
+		}
+	})
+}
+
+// arrayIndex parses array index token, leading zeros are not allowed by RFC 6901.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+
+	return index, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, member := range v {
+			copied[key] = deepCopy(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copied[i] = deepCopy(element)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// equal compares JSON values, numbers are equal when they have the same value
+// regardless of notation (1 and 1.0).
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, member := range x {
+			other, ok := y[key]
+			if !ok || !equal(member, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		xr, xok := new(big.Rat).SetString(x.String())
+		yr, yok := new(big.Rat).SetString(y.String())
+		return xok && yok && xr.Cmp(yr) == 0
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,306 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// assertJSON compares documents regardless of member order and formatting.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	// numbers are compared as written, so lost precision is not hidden by float rounding
+	gotValue, err := decode(got)
+	if err != nil {
+		t.Fatalf("result %s is not valid JSON: %v", got, err)
+	}
+	wantValue, err := decode([]byte(want))
+	if err != nil {
+		t.Fatalf("expected %s is not valid JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// cases follow examples of RFC 6902 appendix A
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "add object member",
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:     `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:     "add array element",
+			document: `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:     `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:     "append to array",
+			document: `{"foo":["bar"]}`,
+			patch:    `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:     `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:     "remove object member",
+			document: `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"remove","path":"/baz"}]`,
+			want:     `{"foo":"bar"}`,
+		},
+		{
+			name:     "remove array element",
+			document: `{"foo":["bar","qux","baz"]}`,
+			patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			want:     `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:     "replace value",
+			document: `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:     `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:     "move value",
+			document: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:     `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:     "move array element",
+			document: `{"foo":["all","grass","cows","eat"]}`,
+			patch:    `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:     `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:     "copy value",
+			document: `{"foo":{"bar":1}}`,
+			patch:    `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			want:     `{"foo":{"bar":1},"baz":{"bar":2}}`,
+		},
+		{
+			name:     "successful test",
+			document: `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:    `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			want:     `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:     "escaped pointer",
+			document: `{"/":9,"~1":10}`,
+			patch:    `[{"op":"test","path":"/~01","value":10},{"op":"replace","path":"/~1","value":8}]`,
+			want:     `{"/":8,"~1":10}`,
+		},
+		{
+			name:     "large integer keeps precision",
+			document: `{"size":9007199254740993}`,
+			patch:    `[{"op":"add","path":"/copy","value":9007199254740993}]`,
+			want:     `{"size":9007199254740993,"copy":9007199254740993}`,
+		},
+		{
+			name:     "failed test",
+			document: `{"baz":"qux"}`,
+			patch:    `[{"op":"test","path":"/baz","value":"bar"}]`,
+			wantErr:  "test failed",
+		},
+		{
+			name:     "add to missing parent",
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			wantErr:  "operation 0",
+		},
+		{
+			name:     "remove missing member",
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"remove","path":"/baz"}]`,
+			wantErr:  "operation 0",
+		},
+		{
+			name:     "array index with leading zero",
+			document: `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"replace","path":"/foo/01","value":"qux"}]`,
+			wantErr:  "operation 0",
+		},
+		{
+			name:     "array index out of range",
+			document: `{"foo":["bar"]}`,
+			patch:    `[{"op":"add","path":"/foo/2","value":"qux"}]`,
+			wantErr:  "operation 0",
+		},
+		{
+			name:     "move into own child",
+			document: `{"foo":{"bar":1}}`,
+			patch:    `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			wantErr:  "cannot move value into one of its children",
+		},
+		{
+			name:     "later operation fails",
+			document: `{"foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/foo","value":"baz"},{"op":"test","path":"/foo","value":"bar"}]`,
+			wantErr:  "operation 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch Patch
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("failed to decode patch: %v", err)
+			}
+
+			document := []byte(tt.document)
+			got, err := patch.Apply(document)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				// patch is atomic, failed operations leave the document untouched
+				if !bytes.Equal(document, []byte(tt.document)) {
+					t.Fatalf("Apply() changed document to %s", document)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   Patch
+		wantErr string
+	}{
+		{name: "valid", patch: Patch{{Op: OpRemove, Path: "/foo"}, {Op: OpMove, From: "/a", Path: "/b"}}},
+		{name: "unknown op", patch: Patch{{Op: "merge", Path: "/foo"}}, wantErr: "unknown op"},
+		{name: "relative path", patch: Patch{{Op: OpRemove, Path: "foo"}}, wantErr: "invalid path"},
+		{name: "add without value", patch: Patch{{Op: OpAdd, Path: "/foo"}}, wantErr: "requires value"},
+		{name: "test without value", patch: Patch{{Op: OpTest, Path: "/foo"}}, wantErr: "requires value"},
+		{name: "move with relative from", patch: Patch{{Op: OpMove, From: "a", Path: "/b"}}, wantErr: "invalid from"},
+		{name: "explicit null value", patch: Patch{{Op: OpReplace, Path: "/foo", Value: json.RawMessage("null")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.patch.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// cases follow examples of RFC 7396 appendix A
+	tests := []struct {
+		document string
+		patch    string
+		want     string
+	}{
+		{document: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{document: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{document: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{document: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{document: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{document: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{document: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{document: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{document: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{document: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{document: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{document: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{document: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{document: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{document: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.document), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatchPaths(t *testing.T) {
+	tests := []struct {
+		patch string
+		want  []string
+	}{
+		{patch: `{"accountSettings":{"theme":"dark","notifications":{"securityEvents":false}}}`, want: []string{"/accountSettings/notifications/securityEvents", "/accountSettings/theme"}},
+		{patch: `{"id":null}`, want: []string{"/id"}},
+		{patch: `{"accountDevices":[{"name":"laptop"}]}`, want: []string{"/accountDevices"}},
+		{patch: `{"accountSettings":{}}`, want: []string{"/accountSettings"}},
+		{patch: `{"a/b":{"c~d":1}}`, want: []string{"/a~1b/c~0d"}},
+		{patch: `"value"`, want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			got, err := MergePatchPaths([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatchPaths() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("MergePatchPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		tokens  []string
+	}{
+		{pointer: "", tokens: nil},
+		{pointer: "/foo", tokens: []string{"foo"}},
+		{pointer: "/foo/0", tokens: []string{"foo", "0"}},
+		{pointer: "/", tokens: []string{""}},
+		{pointer: "/a~1b", tokens: []string{"a/b"}},
+		{pointer: "/m~0n", tokens: []string{"m~n"}},
+		{pointer: "/~01", tokens: []string{"~1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			tokens, err := ParsePointer(tt.pointer)
+			if err != nil {
+				t.Fatalf("ParsePointer() error = %v", err)
+			}
+			if !reflect.DeepEqual(tokens, tt.tokens) {
+				t.Fatalf("ParsePointer() = %q, want %q", tokens, tt.tokens)
+			}
+			if got := FormatPointer(tokens...); got != tt.pointer {
+				t.Fatalf("FormatPointer() = %q, want %q", got, tt.pointer)
+			}
+		})
+	}
+
+	_, err := ParsePointer("foo")
+	if err == nil {
+		t.Fatalf("ParsePointer() accepted pointer without leading /")
+	}
+}
@@ -0,0 +1,14 @@
+package mail
+
+import "context"
+
+type Mailer interface {
+	// Send delivers message to its recipient.
+	Send(ctx context.Context, message *Message) error
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
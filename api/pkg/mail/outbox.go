@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox is a mailer for local development and tests, which keeps sent messages
+// in memory and optionally writes them as .eml files to the directory.
+type Outbox struct {
+	mu       sync.Mutex
+	dir      string
+	from     string
+	messages []Message
+}
+
+var _ Mailer = (*Outbox)(nil)
+
+// NewOutbox creates outbox. Messages are kept only in memory when dir is empty.
+func NewOutbox(dir, from string) *Outbox {
+	return &Outbox{dir: dir, from: from}
+}
+
+func (o *Outbox) Send(ctx context.Context, message *Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.dir != "" {
+		err := os.MkdirAll(o.dir, 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+
+		name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+		err = os.WriteFile(filepath.Join(o.dir, name), encodeMessage(o.from, message), 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write message to outbox: %w", err)
+		}
+	}
+
+	o.messages = append(o.messages, *message)
+	return nil
+}
+
+// Messages returns all messages sent through outbox.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]Message, len(o.messages))
+	copy(messages, o.messages)
+
+	return messages
+}
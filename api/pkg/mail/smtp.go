@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig - represents SMTP server config.
+// Timeout limits whole delivery of single message, including dial.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+type smtpMailer struct {
+	config SMTPConfig
+}
+
+var _ Mailer = (*smtpMailer)(nil)
+
+func NewSMTPMailer(config SMTPConfig) Mailer {
+	return &smtpMailer{config: config}
+}
+
+// Send delivers message the same way smtp.SendMail does, but stops once ctx is done
+// or timeout passes, so unresponsive server cannot hang the caller.
+func (s *smtpMailer) Send(ctx context.Context, message *Message) error {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.config.Host, s.config.Port))
+	if err != nil {
+		return fmt.Errorf("failed to dial smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return fmt.Errorf("failed to set smtp deadline: %w", err)
+		}
+	}
+
+	// unblock pending reads and writes when ctx is cancelled before its deadline
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = s.deliver(conn, message)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to send mail: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+func (s *smtpMailer) deliver(conn net.Conn, message *Message) error {
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.config.Host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			err = client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = client.Mail(s.config.From)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(encodeMessage(s.config.From, message))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// encodeMessage builds plain text RFC 5322 message.
+func encodeMessage(from string, message *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(message.Body)
+
+	return b.Bytes()
+}
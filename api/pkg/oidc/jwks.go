@@ -0,0 +1,202 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// _minRefreshInterval limits how often unknown key id may trigger JWKS download.
+const _minRefreshInterval = time.Minute
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyId   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// keySet caches provider signing keys and refreshes them when token is signed with unknown key.
+type keySet struct {
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	url         string
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time
+}
+
+func (k *keySet) setURL(url string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.url = url
+}
+
+func (k *keySet) get(ctx context.Context, keyId string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.keys[keyId]; ok {
+		return key, nil
+	}
+	if time.Since(k.refreshedAt) < _minRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", keyId)
+	}
+
+	err := k.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := k.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyId)
+	}
+
+	return key, nil
+}
+
+func (k *keySet) refresh(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create jwks request: %w", err)
+	}
+
+	response, err := k.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get jwks: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get jwks: unexpected status %d", response.StatusCode)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&document)
+	if err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// keys of unsupported types are skipped
+			continue
+		}
+		keys[jwk.KeyId] = key
+	}
+
+	k.keys = keys
+	k.refreshedAt = time.Now()
+
+	return nil
+}
+
+func (j jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.KeyType)
+	}
+}
+
+type idTokenClaims struct {
+	Claims
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken checks signature against provider JWKS, issuer, audience and expiration of ID token.
+func (p *provider) verifyIDToken(ctx context.Context, discovery *discoveryDocument, rawIDToken string) (*Claims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			keyId, _ := token.Header["kid"].(string)
+			return p.keys.get(ctx, keyId)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("id token has no expiration")
+	}
+
+	claims.Claims.Subject = claims.RegisteredClaims.Subject
+	if claims.Claims.Subject == "" {
+		return nil, fmt.Errorf("id token has no subject")
+	}
+
+	return &claims.Claims, nil
+}
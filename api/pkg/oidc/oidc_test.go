@@ -0,0 +1,245 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/atlant1da-404/droplet/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	_testClientID     = "droplet"
+	_testClientSecret = "secret"
+	_testRedirectURL  = "http://localhost:8082/api/v1/auth/oidc/test/callback"
+)
+
+var _testIdentity = oidctest.Identity{
+	Subject:           "subject-1",
+	Email:             "user@example.com",
+	EmailVerified:     true,
+	PreferredUsername: "user",
+}
+
+func newTestServer(t *testing.T) *oidctest.Server {
+	t.Helper()
+
+	server, err := oidctest.NewServer(_testClientID, _testClientSecret)
+	if err != nil {
+		t.Fatalf("failed to start provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestProvider(issuerURL string) Provider {
+	return NewProvider(ProviderConfig{
+		IssuerURL:    issuerURL,
+		ClientID:     _testClientID,
+		ClientSecret: _testClientSecret,
+		RedirectURL:  _testRedirectURL,
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	server := newTestServer(t)
+
+	authorizationURL, err := newTestProvider(server.Issuer()).AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("failed to parse authorization url: %v", err)
+	}
+	if got, want := parsed.Scheme+"://"+parsed.Host+parsed.Path, server.URL+"/authorize"; got != want {
+		t.Errorf("authorization endpoint = %s, want %s", got, want)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             _testClientID,
+		"redirect_uri":          _testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := parsed.Query().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// example from RFC 7636 appendix B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Fatalf("CodeChallenge() = %s, want %s", got, want)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	foreignKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// setup may tamper the server and returns code verifier and nonce used for exchange
+		setup   func(server *oidctest.Server) (codeVerifier, nonce string)
+		wantErr string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "nonce mismatch",
+			setup: func(server *oidctest.Server) (string, string) {
+				return "verifier", "other nonce"
+			},
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "pkce verifier mismatch",
+			setup: func(server *oidctest.Server) (string, string) {
+				return "other verifier", "nonce"
+			},
+			wantErr: "pkce verification failed",
+		},
+		{
+			name: "wrong issuer",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.Tamper = func(claims jwt.MapClaims) { claims["iss"] = "https://attacker.example.com" }
+				return "verifier", "nonce"
+			},
+			wantErr: "issuer",
+		},
+		{
+			name: "wrong audience",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.Tamper = func(claims jwt.MapClaims) { claims["aud"] = "other client" }
+				return "verifier", "nonce"
+			},
+			wantErr: "audience",
+		},
+		{
+			name: "expired",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.Tamper = func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }
+				return "verifier", "nonce"
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "without expiration",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.Tamper = func(claims jwt.MapClaims) { delete(claims, "exp") }
+				return "verifier", "nonce"
+			},
+			wantErr: "no expiration",
+		},
+		{
+			name: "without subject",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.Tamper = func(claims jwt.MapClaims) { delete(claims, "sub") }
+				return "verifier", "nonce"
+			},
+			wantErr: "no subject",
+		},
+		{
+			name: "signed with unpublished key",
+			setup: func(server *oidctest.Server) (string, string) {
+				server.SigningKey = foreignKey
+				return "verifier", "nonce"
+			},
+			wantErr: "signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			provider := newTestProvider(server.Issuer())
+
+			authorizationURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+			if err != nil {
+				t.Fatalf("AuthCodeURL() error = %v", err)
+			}
+			code, _, err := server.Authorize(authorizationURL, _testIdentity)
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+
+			codeVerifier, nonce := "verifier", "nonce"
+			if tt.setup != nil {
+				codeVerifier, nonce = tt.setup(server)
+			}
+
+			claims, err := provider.Exchange(context.Background(), code, codeVerifier, nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+
+			want := Claims{
+				Subject:           _testIdentity.Subject,
+				Email:             _testIdentity.Email,
+				EmailVerified:     true,
+				PreferredUsername: _testIdentity.PreferredUsername,
+				Nonce:             "nonce",
+			}
+			if *claims != want {
+				t.Fatalf("Exchange() = %+v, want %+v", *claims, want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	server := newTestServer(t)
+	provider := newTestProvider(server.Issuer())
+
+	authorizationURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code, _, err := server.Authorize(authorizationURL, _testIdentity)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	_, err = provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatalf("first Exchange() error = %v", err)
+	}
+	_, err = provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err == nil {
+		t.Fatalf("second Exchange() succeeded with used code")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := newTestServer(t)
+
+	// same server under other host name publishes issuer which differs from configured one
+	issuerURL := strings.Replace(server.Issuer(), "127.0.0.1", "localhost", 1)
+	_, err := newTestProvider(issuerURL).AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("AuthCodeURL() error = %v, want issuer mismatch", err)
+	}
+}
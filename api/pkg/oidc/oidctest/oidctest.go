@@ -0,0 +1,223 @@
+// Package oidctest provides local OpenID Connect provider for tests of relying party code.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const _keyId = "oidctest"
+
+// Identity - represents user the provider authenticates.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type authorization struct {
+	identity      Identity
+	clientId      string
+	redirectURL   string
+	nonce         string
+	codeChallenge string
+}
+
+// Server is OpenID Connect provider serving discovery document, JWKS and token endpoint.
+// Authorization endpoint is not served, tests call Authorize instead of following redirects.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+	// Tamper is called with claims of every issued ID token before signing,
+	// so tests can produce tokens with wrong issuer, audience, nonce or expiration.
+	Tamper func(claims jwt.MapClaims)
+	// SigningKey signs issued ID tokens, it is the key published in JWKS unless replaced.
+	SigningKey *rsa.PrivateKey
+
+	publicKey *rsa.PublicKey
+
+	mu             sync.Mutex
+	authorizations map[string]authorization
+}
+
+// NewServer starts provider for the client. Caller has to call Close when finished.
+func NewServer(clientId, clientSecret string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	s := &Server{
+		ClientID:       clientId,
+		ClientSecret:   clientSecret,
+		SigningKey:     key,
+		publicKey:      &key.PublicKey,
+		authorizations: make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+
+	return s, nil
+}
+
+// Issuer returns issuer identifier of the provider.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize plays the user who signs in at authorization url and returns code and state
+// the provider would pass to redirect url.
+func (s *Server) Authorize(authorizationURL string, identity Identity) (code, state string, err error) {
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse authorization url: %w", err)
+	}
+	query := parsed.Query()
+
+	if query.Get("response_type") != "code" {
+		return "", "", fmt.Errorf("unsupported response type %q", query.Get("response_type"))
+	}
+	if query.Get("client_id") != s.ClientID {
+		return "", "", fmt.Errorf("unknown client %q", query.Get("client_id"))
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", "", fmt.Errorf("pkce with S256 is required")
+	}
+
+	code, err = random()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	s.mu.Lock()
+	s.authorizations[code] = authorization{
+		identity:      identity,
+		clientId:      query.Get("client_id"),
+		redirectURL:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	s.mu.Unlock()
+
+	return code, query.Get("state"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.Issuer(),
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": _keyId,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	// codes are single use, so the authorization is removed before it is checked
+	s.mu.Lock()
+	authorization, ok := s.authorizations[r.PostForm.Get("code")]
+	delete(s.authorizations, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case r.PostForm.Get("client_id") != authorization.clientId || r.PostForm.Get("client_secret") != s.ClientSecret:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	case r.PostForm.Get("redirect_uri") != authorization.redirectURL:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case codeChallenge(r.PostForm.Get("code_verifier")) != authorization.codeChallenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "pkce verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                s.Issuer(),
+		"aud":                authorization.clientId,
+		"sub":                authorization.identity.Subject,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              authorization.nonce,
+		"email":              authorization.identity.Email,
+		"email_verified":     authorization.identity.EmailVerified,
+		"name":               authorization.identity.Name,
+		"preferred_username": authorization.identity.PreferredUsername,
+	}
+	if s.Tamper != nil {
+		s.Tamper(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = _keyId
+	idToken, err := token.SignedString(s.SigningKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "oidctest",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func random() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
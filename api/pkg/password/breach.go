@@ -0,0 +1,124 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// _prefixLength is length of SHA-1 hash prefix which splits corpus into ranges (k-anonymity model).
+const _prefixLength = 5
+
+// BreachCorpus checks whether password is known from public data breaches.
+type BreachCorpus interface {
+	IsBreached(password string) (bool, error)
+}
+
+// LoadBreachCorpus opens SHA-1 breach corpus in "HASH[:COUNT]" line format. Path is either a single
+// file, which is loaded into memory, or a directory of range files named by 5 character hash prefix
+// (e.g. "21BD1" or "21BD1.txt") holding "SUFFIX[:COUNT]" lines, which are read on demand.
+func LoadBreachCorpus(path string) (BreachCorpus, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat breach corpus: %w", err)
+	}
+
+	if info.IsDir() {
+		return &rangeDirectory{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach corpus: %w", err)
+	}
+	defer file.Close()
+
+	corpus := &memoryCorpus{ranges: make(map[string]map[string]struct{})}
+	err = readHashes(file, func(hash string) {
+		if len(hash) != sha1.Size*2 {
+			return
+		}
+
+		prefix, suffix := hash[:_prefixLength], hash[_prefixLength:]
+		if corpus.ranges[prefix] == nil {
+			corpus.ranges[prefix] = make(map[string]struct{})
+		}
+		corpus.ranges[prefix][suffix] = struct{}{}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read breach corpus: %w", err)
+	}
+
+	return corpus, nil
+}
+
+type memoryCorpus struct {
+	ranges map[string]map[string]struct{}
+}
+
+func (m *memoryCorpus) IsBreached(password string) (bool, error) {
+	prefix, suffix := splitHash(password)
+	_, ok := m.ranges[prefix][suffix]
+	return ok, nil
+}
+
+type rangeDirectory struct {
+	dir string
+}
+
+func (r *rangeDirectory) IsBreached(password string) (bool, error) {
+	prefix, suffix := splitHash(password)
+
+	file, err := os.Open(filepath.Join(r.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(r.dir, prefix+".txt"))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open range file: %w", err)
+	}
+	defer file.Close()
+
+	var found bool
+	err = readHashes(file, func(hash string) {
+		if hash == suffix {
+			found = true
+		}
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to read range file: %w", err)
+	}
+
+	return found, nil
+}
+
+// splitHash returns upper case hex SHA-1 of the password split into range prefix and suffix.
+func splitHash(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:_prefixLength], hash[_prefixLength:]
+}
+
+// readHashes calls fn with upper cased hash of every non-empty line, occurrence counts are dropped.
+func readHashes(reader io.Reader, fn func(hash string)) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		fn(strings.ToUpper(hash))
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,82 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sha1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	_breachedPassword = "password"
+	_breachedPrefix   = "5BAA6"
+	_breachedSuffix   = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func assertBreached(t *testing.T, corpus BreachCorpus, password string, want bool) {
+	t.Helper()
+
+	breached, err := corpus.IsBreached(password)
+	if err != nil {
+		t.Fatalf("IsBreached(%q) error = %v", password, err)
+	}
+	if breached != want {
+		t.Fatalf("IsBreached(%q) = %v, want %v", password, breached, want)
+	}
+}
+
+func TestLoadBreachCorpusFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	// lower case hash with count, malformed line and blank line are all tolerated
+	writeFile(t, path, "\n5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:3861493\nnot-a-hash\n")
+
+	corpus, err := LoadBreachCorpus(path)
+	if err != nil {
+		t.Fatalf("LoadBreachCorpus() error = %v", err)
+	}
+
+	assertBreached(t, corpus, _breachedPassword, true)
+	assertBreached(t, corpus, "river-stone42", false)
+}
+
+func TestLoadBreachCorpusRangeDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+	}{
+		{name: "bare prefix", fileName: _breachedPrefix},
+		{name: "txt extension", fileName: _breachedPrefix + ".txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, tt.fileName), "0018A45C4D1DEF81644B54AB7F969B88D65:1\n"+_breachedSuffix+":3861493\n")
+
+			corpus, err := LoadBreachCorpus(dir)
+			if err != nil {
+				t.Fatalf("LoadBreachCorpus() error = %v", err)
+			}
+
+			assertBreached(t, corpus, _breachedPassword, true)
+			// missing range file means no password of that range is breached
+			assertBreached(t, corpus, "river-stone42", false)
+		})
+	}
+}
+
+func TestLoadBreachCorpusMissing(t *testing.T) {
+	_, err := LoadBreachCorpus(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatal("LoadBreachCorpus() error = nil, want error")
+	}
+}
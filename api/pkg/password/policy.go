@@ -0,0 +1,116 @@
+// Package password implements password strength rules and screening against breached passwords.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// _minPersonalInfoLength skips too short usernames and email parts, otherwise
+// e.g. user "al" could not use any password containing these letters.
+const _minPersonalInfoLength = 3
+
+const (
+	ViolationTooShort     = "password_too_short"
+	ViolationTooLong      = "password_too_long"
+	ViolationTooWeak      = "password_too_weak"
+	ViolationPersonalInfo = "password_contains_personal_info"
+	ViolationBreached     = "password_breached"
+)
+
+// Policy - represents password requirements. Character classes are lower case letters,
+// upper case letters, digits and other symbols.
+type Policy struct {
+	MinLength           int
+	MaxLength           int
+	MinCharacterClasses int
+}
+
+// Violation describes single broken password requirement.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+// Check returns requirements the password does not meet. Personal values, such as
+// username or email, must not be contained in the password.
+func (p Policy) Check(password string, personal ...string) []Violation {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Code:    ViolationTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Code:    ViolationTooLong,
+			Message: fmt.Sprintf("password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	if characterClasses(password) < p.MinCharacterClasses {
+		violations = append(violations, Violation{
+			Code:    ViolationTooWeak,
+			Message: fmt.Sprintf("password must contain at least %d of: lower case letters, upper case letters, digits, symbols", p.MinCharacterClasses),
+		})
+	}
+
+	lowerPassword := strings.ToLower(password)
+	for _, value := range personalValues(personal) {
+		if strings.Contains(lowerPassword, value) {
+			violations = append(violations, Violation{
+				Code:    ViolationPersonalInfo,
+				Message: "password must not contain username or email",
+			})
+			break
+		}
+	}
+
+	return violations
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+// personalValues returns lower cased values together with local parts of emails.
+func personalValues(personal []string) []string {
+	values := make([]string, 0, len(personal)*2)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, ok := strings.Cut(value, "@"); ok && utf8.RuneCountInString(local) >= _minPersonalInfoLength {
+			values = append(values, local)
+		}
+		if utf8.RuneCountInString(value) >= _minPersonalInfoLength {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,56 @@
+package password
+
+import (
+	"reflect"
+	"testing"
+)
+
+func violationCodes(violations []Violation) []string {
+	var codes []string
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+
+	return codes
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 10, MaxLength: 20, MinCharacterClasses: 2}
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		want     []string
+	}{
+		{name: "valid", password: "river-stone42", personal: []string{"alice", "alice@droplet.local"}},
+		{name: "too short", password: "river-42", want: []string{ViolationTooShort}},
+		{name: "length counts characters, not bytes", password: "ріка-камінь-річка"},
+		{name: "too long", password: "river-stone-river-stone", want: []string{ViolationTooLong}},
+		{name: "single character class", password: "riverstones", want: []string{ViolationTooWeak}},
+		{name: "short and weak", password: "river", want: []string{ViolationTooShort, ViolationTooWeak}},
+		{name: "contains username", password: "my-Alice-2024", personal: []string{"alice"}, want: []string{ViolationPersonalInfo}},
+		{name: "contains email local part", password: "alice.b-2024", personal: []string{"alice.b@droplet.local"}, want: []string{ViolationPersonalInfo}},
+		{name: "contains whole email", password: "x-bob@droplet.local", personal: []string{"bob@droplet.local"}, want: []string{ViolationPersonalInfo}},
+		{name: "short username is ignored", password: "al-river-stone", personal: []string{"al"}},
+		{name: "empty personal value is ignored", password: "river-stone42", personal: []string{"", " "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violationCodes(policy.Check(tt.password, tt.personal...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckWithoutMaxLength(t *testing.T) {
+	policy := Policy{MinLength: 1, MinCharacterClasses: 1}
+
+	violations := policy.Check(string(make([]byte, 1000)) + "a")
+	if len(violations) != 0 {
+		t.Fatalf("Check() = %v, want no violations", violationCodes(violations))
+	}
+}